@@ -1,10 +1,16 @@
 package buildcodegraph
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
 )
 
 func (a *Activities) CopyAstControlRdfGraphs(results []BuildCodeGraphState, commonFolder string) (string, error) {
@@ -45,15 +51,95 @@ func (a *Activities) CopyAstControlRdfGraphs(results []BuildCodeGraphState, comm
 		}
 	}
 
+	// Report the APIs whose RDF was rejected, so a graph with missing APIs does not pass unnoticed.
+	if err := writeRejectedRdfReport(results, commonFolder); err != nil {
+		return "", err
+	}
+
 	// Combine all RDF files into a single file.
 	combinedRdfFilePath := filepath.Join(commonFolder, "combined_rdf.ttl")
-	if _, err := CallUnifyRdfsApi(commonFolder, combinedRdfFilePath); err != nil {
+	if err := unifyRdfFiles(commonFolder, combinedRdfFilePath); err != nil {
 		return "", fmt.Errorf("failed to combine RDF files: %w", err)
 	}
 
 	return combinedRdfFilePath, nil
 }
 
+// unifyRdfFiles parses every Turtle file under folder, merges them into one graph and writes it to
+// outputFile. Files that fail to parse are reported and skipped.
+func unifyRdfFiles(folder, outputFile string) error {
+	unified := rdf.NewGraph()
+
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip directories, non-Turtle files and the output of a previous run.
+		if d.IsDir() || !strings.HasSuffix(path, ".ttl") || path == outputFile {
+			return nil
+		}
+
+		graph, err := rdf.ParseTurtleFile(path)
+		if err != nil {
+			fmt.Printf("Skipping unparsable RDF file: %v\n", err)
+			return nil // continue with other files
+		}
+		unified.Merge(graph)
+		fmt.Printf("Parsed %s\n", path)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk RDF files: %w", err)
+	}
+
+	if err := rdf.WriteTurtleFile(unified, outputFile); err != nil {
+		return fmt.Errorf("failed to write unified graph: %w", err)
+	}
+	fmt.Printf("Unified graph saved to %s\n", outputFile)
+	return nil
+}
+
+// rejectedRepoRdf lists the rejected RDF of one repository in rejected_rdf.json.
+type rejectedRepoRdf struct {
+	RepoURL  string        `json:"repoUrl"`
+	Commit   string        `json:"commit,omitempty"`
+	Rejected []RejectedRdf `json:"rejected"`
+}
+
+// writeRejectedRdfReport writes the rejected RDF of every repository to rejected_rdf.json in the common
+// folder and prints a warning per repository. Without rejections it removes the report of an earlier run.
+func writeRejectedRdfReport(results []BuildCodeGraphState, commonFolder string) error {
+	reportPath := filepath.Join(commonFolder, "rejected_rdf.json")
+	var report []rejectedRepoRdf
+	for _, state := range results {
+		if len(state.RejectedRdf) == 0 {
+			continue
+		}
+		apis := 0
+		for _, rejected := range state.RejectedRdf {
+			apis += len(rejected.Apis)
+		}
+		fmt.Printf("WARNING: %s is missing %d API(s) from %d rejected control flow file(s), see %s\n",
+			state.RepoURL, apis, len(state.RejectedRdf), reportPath)
+		report = append(report, rejectedRepoRdf{RepoURL: state.RepoURL, Commit: state.Commit, Rejected: state.RejectedRdf})
+	}
+	if len(report) == 0 {
+		if err := os.Remove(reportPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old rejected RDF report: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode rejected RDF report: %w", err)
+	}
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write rejected RDF report: %w", err)
+	}
+	return nil
+}
+
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -0,0 +1,332 @@
+package buildcodegraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Reasons an annotation is rejected, besides RdfTruncated.
+const (
+	AnnotationMissing = "no annotation"      // The response holds no JSON object.
+	AnnotationInvalid = "invalid annotation" // The JSON does not fill the schema or names unknown RPCs.
+	UnknownService    = "unknown service"    // No canonical service is known for the control flow file.
+)
+
+// serviceAnnotation is what the LLM reports about the APIs of a service through structured output. Go code,
+// not the LLM, turns it into RDF with canonical URIs, see annotationGraph.
+type serviceAnnotation struct {
+	Apis []apiAnnotation `json:"apis"`
+}
+
+// apiAnnotation describes one RPC of a service.
+type apiAnnotation struct {
+	Name           string          `json:"name"` // The RPC name.
+	Description    string          `json:"description"`
+	Dependencies   []dependencyUse `json:"dependencies"` // The databases, collections and tables the RPC uses.
+	Resources      []resourceUse   `json:"resources"`    // The cloud resources, topics and configuration keys it uses.
+	CalledServices []calledService `json:"calledServices"`
+}
+
+// dependencyUse is a database, or a collection or table of one, an RPC reads or writes.
+type dependencyUse struct {
+	DatabaseType     string `json:"databaseType"` // For example "MongoDB"; "unknown" if the code does not tell.
+	Database         string `json:"database"`
+	Collection       string `json:"collection,omitempty"`
+	Access           string `json:"access"` // read, write or readwrite.
+	ConnectionString string `json:"connectionString,omitempty"`
+}
+
+// resourceUse is a cloud resource, topic or configuration key an RPC uses.
+type resourceUse struct {
+	Kind   string `json:"kind"`           // cloudResource, topic or configKey.
+	Type   string `json:"type,omitempty"` // The resource type of a cloud resource, for example "KeyVault".
+	Name   string `json:"name"`
+	Access string `json:"access"` // read, write, readwrite or use for cloud resources, publish or subscribe for topics, read for keys.
+}
+
+// calledService is an RPC of another service an RPC calls.
+type calledService struct {
+	Service string `json:"service"`       // The fully qualified proto service name, for example "auth.Login".
+	Rpc     string `json:"rpc,omitempty"` // The RPC called, if the code tells.
+}
+
+// annotationOutput asks the LLM to fill a serviceAnnotation.
+var annotationOutput = &llm.Output{
+	Name:        "annotate_apis",
+	Description: "Describe what the RPCs of a service do and which databases, resources and other services they use.",
+	Schema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "apis": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "The RPC name, exactly as listed."},
+          "description": {"type": "string", "description": "What the RPC does, in one or two sentences."},
+          "dependencies": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "databaseType": {"type": "string", "description": "For example MongoDB, PostgreSQL or MySQL; unknown if the code does not tell."},
+                "database": {"type": "string"},
+                "collection": {"type": "string", "description": "The collection or table, if known."},
+                "access": {"type": "string", "enum": ["read", "write", "readwrite"]},
+                "connectionString": {"type": "string", "description": "The connection string or endpoint, if visible."}
+              },
+              "required": ["databaseType", "database", "access"]
+            }
+          },
+          "resources": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "kind": {"type": "string", "enum": ["cloudResource", "topic", "configKey"]},
+                "type": {"type": "string", "description": "The type of a cloud resource, for example FileStorage or KeyVault."},
+                "name": {"type": "string"},
+                "access": {"type": "string", "enum": ["read", "write", "readwrite", "use", "publish", "subscribe"]}
+              },
+              "required": ["kind", "name", "access"]
+            }
+          },
+          "calledServices": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "service": {"type": "string", "description": "The fully qualified proto service name, for example auth.Login."},
+                "rpc": {"type": "string"}
+              },
+              "required": ["service"]
+            }
+          }
+        },
+        "required": ["name", "description", "dependencies", "resources", "calledServices"]
+      }
+    }
+  },
+  "required": ["apis"]
+}`),
+}
+
+// annotateApis asks the LLM to annotate the RPCs of a chunk of a service's control flow, checks the
+// annotation and converts it to an RDF fragment that must conform to the GraphMind shapes. Problems and
+// shape violations are fed back to the LLM for a bounded number of repair attempts before an
+// *RdfValidationError is returned. The LLM response the fragment was made from is returned with it for
+// provenance.
+func annotateApis(ctx context.Context, client llm.Client, prompt, repoURL string, service *CanonicalService, rpcs []string) (*rdf.Graph, llm.Response, error) {
+	req := llm.Request{Prompt: prompt, Output: annotationOutput}
+	for attempt := 1; ; attempt++ {
+		response, err := client.Complete(ctx, req)
+		if err != nil {
+			return nil, response, fmt.Errorf("LLM call failed: %w", err)
+		}
+		annotation, reason, problems := checkAnnotation(response, service, rpcs)
+		var graph *rdf.Graph
+		if len(problems) == 0 {
+			graph = annotationGraph(repoURL, service, annotation)
+			if report := ontology.Validate(graph); !report.Conforms {
+				reason = RdfShapeViolations
+				for _, result := range report.Results {
+					problems = append(problems, result.String())
+				}
+			}
+		}
+		if len(problems) == 0 {
+			return graph, response, nil
+		}
+		if attempt > maxRdfRepairAttempts {
+			return nil, response, &RdfValidationError{Reason: reason, Output: string(response.JSON), Violations: problems}
+		}
+
+		fmt.Printf("Annotation is rejected (%s) with %d problem(s), asking the LLM to repair it (attempt %d of %d)\n",
+			reason, len(problems), attempt, maxRdfRepairAttempts)
+		req.Prompt = fmt.Sprintf("%s\n\nYour previous annotation was rejected. Annotate the RPCs again, fixing these problems:\n- %s\n\nPrevious annotation:\n%s",
+			prompt, strings.Join(problems, "\n- "), response.JSON)
+	}
+}
+
+// checkAnnotation parses and checks the structured output of an LLM response: every RPC of the chunk must
+// be annotated, only RPCs of the service may be, and every value must be one the schema allows.
+func checkAnnotation(response llm.Response, service *CanonicalService, rpcs []string) (serviceAnnotation, string, []string) {
+	if response.Truncated() {
+		return serviceAnnotation{}, RdfTruncated, []string{fmt.Sprintf("the response was cut off at the output token limit (stop reason %q), "+
+			"so the annotation is incomplete; describe the RPCs more concisely", response.StopReason)}
+	}
+	if len(response.JSON) == 0 {
+		return serviceAnnotation{}, AnnotationMissing, []string{"the response does not contain the annotation as a JSON object"}
+	}
+	var annotation serviceAnnotation
+	if err := json.Unmarshal(response.JSON, &annotation); err != nil {
+		return serviceAnnotation{}, AnnotationInvalid, []string{fmt.Sprintf("the annotation does not match the schema: %v", err)}
+	}
+
+	var problems []string
+	annotated := map[string]bool{}
+	for i, api := range annotation.Apis {
+		if _, ok := service.ApiURIs[api.Name]; !ok {
+			problems = append(problems, fmt.Sprintf("apis[%d]: %q is not an RPC of the service", i, api.Name))
+		}
+		annotated[api.Name] = true
+		if strings.TrimSpace(api.Description) == "" {
+			problems = append(problems, fmt.Sprintf("%s: the description is empty", api.Name))
+		}
+		for j, dependency := range api.Dependencies {
+			if strings.TrimSpace(dependency.Database) == "" {
+				problems = append(problems, fmt.Sprintf("%s: dependencies[%d] has no database name", api.Name, j))
+			}
+			if !oneOf(dependency.Access, "read", "write", "readwrite") {
+				problems = append(problems, fmt.Sprintf("%s: dependencies[%d] has access %q, expected read, write or readwrite", api.Name, j, dependency.Access))
+			}
+		}
+		for j, resource := range api.Resources {
+			if strings.TrimSpace(resource.Name) == "" {
+				problems = append(problems, fmt.Sprintf("%s: resources[%d] has no name", api.Name, j))
+			}
+			var ok bool
+			switch resource.Kind {
+			case "cloudResource":
+				ok = oneOf(resource.Access, "read", "write", "readwrite", "use")
+			case "topic":
+				ok = oneOf(resource.Access, "publish", "subscribe")
+			case "configKey":
+				ok = oneOf(resource.Access, "read", "use")
+			default:
+				problems = append(problems, fmt.Sprintf("%s: resources[%d] has kind %q, expected cloudResource, topic or configKey", api.Name, j, resource.Kind))
+				continue
+			}
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: resources[%d] is a %s with access %q it cannot have", api.Name, j, resource.Kind, resource.Access))
+			}
+		}
+		for j, call := range api.CalledServices {
+			if strings.TrimSpace(call.Service) == "" {
+				problems = append(problems, fmt.Sprintf("%s: calledServices[%d] has no service", api.Name, j))
+			}
+		}
+	}
+	for _, rpc := range rpcs {
+		if _, ok := service.ApiURIs[rpc]; ok && !annotated[rpc] {
+			problems = append(problems, fmt.Sprintf("the RPC %s is not annotated", rpc))
+		}
+	}
+	if len(problems) > 0 {
+		return annotation, AnnotationInvalid, problems
+	}
+	return annotation, "", nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// annotationGraph converts the annotation of a service's RPCs to an RDF fragment with canonical URIs: the
+// service in its repository, its APIs, and the databases, collections, resources and RPCs they use.
+func annotationGraph(repoURL string, service *CanonicalService, annotation serviceAnnotation) *rdf.Graph {
+	g := ontology.NewGraph()
+	add := func(subject rdf.IRI, predicate rdf.IRI, object rdf.Term) {
+		g.Add(rdf.Triple{Subject: subject, Predicate: predicate, Object: object})
+	}
+	node := func(uri, class rdf.IRI, name string) rdf.IRI {
+		add(uri, rdf.RDFType, class)
+		add(uri, ontology.Name, rdf.NewLiteral(name))
+		return uri
+	}
+	access := func(api, resource rdf.IRI, mode string) {
+		switch mode {
+		case "read":
+			add(api, ontology.ReadsFrom, resource)
+		case "write":
+			add(api, ontology.WritesTo, resource)
+		case "readwrite":
+			add(api, ontology.ReadsFrom, resource)
+			add(api, ontology.WritesTo, resource)
+		default:
+			add(api, ontology.UsesResource, resource)
+		}
+	}
+
+	serviceURI := node(rdf.IRI(service.URI), ontology.Service, shortServiceName(service.ProtoService))
+	add(ontology.RepositoryURI(repoURL), ontology.HasService, serviceURI)
+	for _, annotated := range annotation.Apis {
+		api := node(rdf.IRI(service.ApiURIs[annotated.Name]), ontology.Api, annotated.Name)
+		add(serviceURI, ontology.HasApi, api)
+		add(api, ontology.Description, rdf.NewLiteral(strings.TrimSpace(annotated.Description)))
+
+		for _, dependency := range annotated.Dependencies {
+			databaseType := valueOr(dependency.DatabaseType, "unknown")
+			database := node(ontology.DatabaseURI(databaseType, dependency.Database), ontology.Database, dependency.Database)
+			add(database, ontology.DatabaseType, rdf.NewLiteral(databaseType))
+			if connection := redactCredentials(dependency.ConnectionString); connection != "" {
+				add(database, ontology.ConnectionString, rdf.NewLiteral(connection))
+			}
+			target := database
+			if dependency.Collection != "" {
+				target = node(ontology.CollectionURI(database, dependency.Collection), ontology.Collection, dependency.Collection)
+				add(database, ontology.HasCollection, target)
+			}
+			access(api, target, dependency.Access)
+		}
+
+		for _, resource := range annotated.Resources {
+			switch resource.Kind {
+			case "cloudResource":
+				resourceType := valueOr(resource.Type, "unknown")
+				cloud := node(ontology.CloudResourceURI(resourceType, resource.Name), ontology.CloudResource, resource.Name)
+				add(cloud, ontology.ResourceType, rdf.NewLiteral(resourceType))
+				access(api, cloud, resource.Access)
+			case "topic":
+				topic := node(ontology.TopicURI(resource.Name), ontology.Topic, resource.Name)
+				if resource.Access == "subscribe" {
+					add(api, ontology.SubscribesTo, topic)
+				} else {
+					add(api, ontology.PublishesTo, topic)
+				}
+			case "configKey":
+				add(api, ontology.UsesConfig, node(ontology.ConfigKeyURI(resource.Name), ontology.ConfigKey, resource.Name))
+			}
+		}
+
+		// Called RPCs are described by the repositories that implement them, so only the call is stated.
+		for _, call := range annotated.CalledServices {
+			target := ontology.ServiceURI(call.Service)
+			if call.Rpc != "" {
+				target = ontology.ApiURI(call.Service, call.Rpc)
+			}
+			add(api, ontology.Calls, target)
+		}
+	}
+	return g
+}
+
+func valueOr(value, fallback string) string {
+	if value = strings.TrimSpace(value); value == "" {
+		return fallback
+	}
+	return value
+}
+
+// redactCredentials removes the user name and password from a connection string.
+func redactCredentials(connection string) string {
+	connection = strings.TrimSpace(connection)
+	u, err := url.Parse(connection)
+	if err != nil || u.User == nil {
+		return connection
+	}
+	u.User = nil
+	return u.String()
+}
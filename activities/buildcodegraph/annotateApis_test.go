@@ -0,0 +1,222 @@
+package buildcodegraph
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// testService is an orders service with the RPCs GetOrder and PlaceOrder.
+func testService() *CanonicalService {
+	return &CanonicalService{
+		Name:         "OrderServer",
+		ProtoService: "orders.OrderService",
+		URI:          string(ontology.ServiceURI("orders.OrderService")),
+		ApiURIs: map[string]string{
+			"GetOrder":   string(ontology.ApiURI("orders.OrderService", "GetOrder")),
+			"PlaceOrder": string(ontology.ApiURI("orders.OrderService", "PlaceOrder")),
+		},
+	}
+}
+
+// getOrderJSON is a valid annotation of GetOrder; its dependencies, resources and calls are spliced in.
+func getOrderJSON(uses string) string {
+	return `{"name": "GetOrder", "description": "Returns an order.", "dependencies": [], "resources": [], "calledServices": []` + uses + `}`
+}
+
+func TestCheckAnnotation(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		stopReason   string
+		rpcs         []string
+		wantReason   string
+		wantProblems []string // Parts of the problems, in order.
+	}{
+		{
+			name: "valid",
+			text: `{"apis": [` + getOrderJSON(`, "dependencies": [{"databaseType": "MongoDB", "database": "orders", "access": "readwrite"}],
+				"resources": [{"kind": "topic", "name": "order-events", "access": "publish"}, {"kind": "configKey", "name": "DB_URL", "access": "read"}]`) + `]}`,
+			rpcs: []string{"GetOrder"},
+		},
+		{
+			name: "JSON in prose",
+			text: `Here is the annotation: {"apis": [` + getOrderJSON("") + `]} Hope it helps.`,
+			rpcs: []string{"GetOrder"},
+		},
+		{
+			name:         "truncated",
+			text:         `{"apis": []}`,
+			stopReason:   "max_tokens",
+			wantReason:   RdfTruncated,
+			wantProblems: []string{`cut off at the output token limit (stop reason "max_tokens")`},
+		},
+		{
+			name:         "no JSON",
+			text:         "I cannot annotate these RPCs.",
+			wantReason:   AnnotationMissing,
+			wantProblems: []string{"does not contain the annotation"},
+		},
+		{
+			name:         "wrong types",
+			text:         `{"apis": {"name": "GetOrder"}}`,
+			wantReason:   AnnotationInvalid,
+			wantProblems: []string{"does not match the schema"},
+		},
+		{
+			name: "unknown and missing RPCs",
+			text: `{"apis": [{"name": "DeleteOrder", "description": "Deletes an order."}, ` + getOrderJSON("") + `]}`,
+			// Unknown RPCs of the chunk are not asked for.
+			rpcs:         []string{"GetOrder", "PlaceOrder", "Unknown"},
+			wantReason:   AnnotationInvalid,
+			wantProblems: []string{`apis[0]: "DeleteOrder" is not an RPC of the service`, "the RPC PlaceOrder is not annotated"},
+		},
+		{
+			name: "invalid values",
+			text: `{"apis": [{"name": "GetOrder", "description": " ",
+				"dependencies": [{"databaseType": "MongoDB", "database": "", "access": "delete"}],
+				"resources": [{"kind": "topic", "name": "events", "access": "read"}, {"kind": "queue", "name": "jobs", "access": "publish"},
+					{"kind": "cloudResource", "name": " ", "access": "use"}],
+				"calledServices": [{"service": ""}]}]}`,
+			rpcs:       []string{"GetOrder"},
+			wantReason: AnnotationInvalid,
+			wantProblems: []string{
+				"GetOrder: the description is empty",
+				"GetOrder: dependencies[0] has no database name",
+				`GetOrder: dependencies[0] has access "delete", expected read, write or readwrite`,
+				`GetOrder: resources[0] is a topic with access "read" it cannot have`,
+				`GetOrder: resources[1] has kind "queue"`,
+				"GetOrder: resources[2] has no name",
+				"GetOrder: calledServices[0] has no service",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := llm.NewFake(llm.Rule{Text: tt.text, StopReason: tt.stopReason}).
+				Complete(context.Background(), llm.Request{Output: annotationOutput})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, reason, problems := checkAnnotation(response, testService(), tt.rpcs)
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if len(problems) != len(tt.wantProblems) {
+				t.Fatalf("problems:\n%s\nwant %d", strings.Join(problems, "\n"), len(tt.wantProblems))
+			}
+			for i, want := range tt.wantProblems {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want %q in it", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnnotationGraph(t *testing.T) {
+	service := testService()
+	annotation := serviceAnnotation{Apis: []apiAnnotation{
+		{
+			Name:        "GetOrder",
+			Description: " Returns an order. ",
+			Dependencies: []dependencyUse{
+				{DatabaseType: "MongoDB", Database: "shop", Collection: "orders", Access: "read", ConnectionString: "mongodb://admin:secret@db:27017/shop"},
+				{Database: "cache", Access: "readwrite"},
+			},
+			Resources: []resourceUse{
+				{Kind: "cloudResource", Type: "KeyVault", Name: "secrets", Access: "use"},
+				{Kind: "cloudResource", Name: "bucket", Access: "write"},
+				{Kind: "topic", Name: "order-events", Access: "subscribe"},
+				{Kind: "configKey", Name: "DB_URL", Access: "read"},
+			},
+			CalledServices: []calledService{{Service: "auth.Login", Rpc: "Check"}, {Service: "billing.Billing"}},
+		},
+		{Name: "PlaceOrder", Description: "Places an order.", Resources: []resourceUse{{Kind: "topic", Name: "order-events", Access: "publish"}}},
+	}}
+	g := annotationGraph("https://github.com/org/orders", service, annotation)
+
+	getOrder, placeOrder := rdf.IRI(service.ApiURIs["GetOrder"]), rdf.IRI(service.ApiURIs["PlaceOrder"])
+	shop := ontology.DatabaseURI("MongoDB", "shop")
+	orders := ontology.CollectionURI(shop, "orders")
+	cache := ontology.DatabaseURI("unknown", "cache")
+	secrets := ontology.CloudResourceURI("KeyVault", "secrets")
+	bucket := ontology.CloudResourceURI("unknown", "bucket")
+	events := ontology.TopicURI("order-events")
+	want := []rdf.Triple{
+		{Subject: ontology.RepositoryURI("https://github.com/org/orders"), Predicate: ontology.HasService, Object: rdf.IRI(service.URI)},
+		{Subject: rdf.IRI(service.URI), Predicate: rdf.RDFType, Object: ontology.Service},
+		{Subject: rdf.IRI(service.URI), Predicate: ontology.Name, Object: rdf.NewLiteral("OrderService")},
+		{Subject: rdf.IRI(service.URI), Predicate: ontology.HasApi, Object: getOrder},
+		{Subject: getOrder, Predicate: rdf.RDFType, Object: ontology.Api},
+		{Subject: getOrder, Predicate: ontology.Name, Object: rdf.NewLiteral("GetOrder")},
+		{Subject: getOrder, Predicate: ontology.Description, Object: rdf.NewLiteral("Returns an order.")},
+		{Subject: shop, Predicate: ontology.DatabaseType, Object: rdf.NewLiteral("MongoDB")},
+		{Subject: shop, Predicate: ontology.ConnectionString, Object: rdf.NewLiteral("mongodb://db:27017/shop")},
+		{Subject: shop, Predicate: ontology.HasCollection, Object: orders},
+		{Subject: orders, Predicate: rdf.RDFType, Object: ontology.Collection},
+		{Subject: getOrder, Predicate: ontology.ReadsFrom, Object: orders},
+		{Subject: cache, Predicate: ontology.DatabaseType, Object: rdf.NewLiteral("unknown")},
+		{Subject: getOrder, Predicate: ontology.ReadsFrom, Object: cache},
+		{Subject: getOrder, Predicate: ontology.WritesTo, Object: cache},
+		{Subject: secrets, Predicate: ontology.ResourceType, Object: rdf.NewLiteral("KeyVault")},
+		{Subject: getOrder, Predicate: ontology.UsesResource, Object: secrets},
+		{Subject: bucket, Predicate: ontology.ResourceType, Object: rdf.NewLiteral("unknown")},
+		{Subject: getOrder, Predicate: ontology.WritesTo, Object: bucket},
+		{Subject: getOrder, Predicate: ontology.SubscribesTo, Object: events},
+		{Subject: placeOrder, Predicate: ontology.PublishesTo, Object: events},
+		{Subject: getOrder, Predicate: ontology.UsesConfig, Object: ontology.ConfigKeyURI("DB_URL")},
+		{Subject: getOrder, Predicate: ontology.Calls, Object: ontology.ApiURI("auth.Login", "Check")},
+		{Subject: getOrder, Predicate: ontology.Calls, Object: ontology.ServiceURI("billing.Billing")},
+	}
+	for _, triple := range want {
+		if !g.Contains(triple) {
+			t.Errorf("the graph lacks %s", triple)
+		}
+	}
+	if g.Contains(rdf.Triple{Subject: getOrder, Predicate: ontology.ReadsFrom, Object: shop}) {
+		t.Error("GetOrder reads the database rather than its collection")
+	}
+	// The called services are described by their own repositories.
+	if types := g.Objects(ontology.ServiceURI("billing.Billing"), rdf.RDFType); len(types) != 0 {
+		t.Errorf("the called service is typed %v", types)
+	}
+	if report := ontology.Validate(g); !report.Conforms {
+		t.Errorf("the graph does not conform: %v", report.Results)
+	}
+}
+
+func TestAnnotateApisRepairsShapeViolations(t *testing.T) {
+	// The same database written with two spellings of its type gets two gm:databaseType values.
+	invalid := `{"apis": [` + getOrderJSON(`, "dependencies": [{"databaseType": "MongoDB", "database": "orders", "access": "read"},
+		{"databaseType": "mongodb", "database": "orders", "access": "write"}]`) + `]}`
+	valid := `{"apis": [` + getOrderJSON(`, "dependencies": [{"databaseType": "MongoDB", "database": "orders", "access": "readwrite"}]`) + `]}`
+	client := llm.NewFake(llm.Rule{Contains: "Previous annotation", Text: valid}, llm.Rule{Text: invalid})
+
+	g, response, err := annotateApis(context.Background(), client, "Annotate GetOrder.", "https://github.com/org/orders", testService(), []string{"GetOrder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := client.Requests()
+	if len(requests) != 2 || !strings.Contains(requests[1].Prompt, "databaseType") || !strings.Contains(requests[1].Prompt, `"mongodb"`) {
+		t.Fatalf("requests = %+v, want a repair naming the violation and the annotation", requests)
+	}
+	if response.ID != "fake-2" {
+		t.Errorf("response = %s, want the repaired one", response.ID)
+	}
+	if types := g.Objects(ontology.DatabaseURI("MongoDB", "orders"), ontology.DatabaseType); len(types) != 1 {
+		t.Errorf("database types = %v", types)
+	}
+
+	// An annotation that is never repaired is rejected with the shape violations.
+	_, _, err = annotateApis(context.Background(), llm.NewFake(llm.Rule{Text: invalid}), "Annotate GetOrder.", "https://github.com/org/orders", testService(), []string{"GetOrder"})
+	var validationErr *RdfValidationError
+	if !errors.As(err, &validationErr) || validationErr.Reason != RdfShapeViolations || len(validationErr.Violations) == 0 {
+		t.Errorf("err = %v, want shape violations", err)
+	}
+}
@@ -83,6 +83,14 @@ func (a *Activities) BuildAstControlFlow(ctx context.Context, state BuildCodeGra
 		}
 	}
 
+	// Mint canonical URIs for the services so every LLM call refers to them the same way.
+	protos := findProtoServices(state.LocalRepoPath)
+	site := func(node ast.Node) SourceRange { return sourceRange(fset, state.LocalRepoPath, node) }
+	state.Services = canonicalServices(state.LocalRepoPath, registered, services, protos, site)
+
+	// Record the RPCs the services call on other services so they can be linked across repositories.
+	state.OutboundCalls = findServiceCalls(astFiles, services, state.Services, site, newGoPackages(state.LocalRepoPath))
+
 	state.AstControlFlowFolderPath = tmpDir
 	return state, nil
 }
@@ -92,9 +100,27 @@ type ServiceInfo struct {
 	Methods []*ast.FuncDecl
 }
 
-// findRegisteredServices finds function calls that register gRPC services.
-func findRegisteredServices(f *ast.File) map[string]bool {
-	services := make(map[string]bool)
+// SourceRange is a range of lines in a file of a repository.
+type SourceRange struct {
+	File      string // The path relative to the repository root, with forward slashes.
+	StartLine int
+	EndLine   int
+}
+
+// sourceRange returns the lines a syntax node spans.
+func sourceRange(fset *token.FileSet, repoPath string, node ast.Node) SourceRange {
+	start, end := fset.Position(node.Pos()), fset.Position(node.End())
+	file, err := filepath.Rel(repoPath, start.Filename)
+	if err != nil {
+		file = start.Filename
+	}
+	return SourceRange{File: filepath.ToSlash(file), StartLine: start.Line, EndLine: end.Line}
+}
+
+// findRegisteredServices finds function calls that register gRPC services. It maps the name of each
+// registered service to the registering function, for example "RegisterLoginServer".
+func findRegisteredServices(f *ast.File) map[string]string {
+	services := make(map[string]string)
 	ast.Inspect(f, func(n ast.Node) bool {
 		call, ok := n.(*ast.CallExpr)
 		if !ok {
@@ -121,7 +147,7 @@ func findRegisteredServices(f *ast.File) map[string]bool {
 		default:
 			return true
 		}
-		services[serviceName] = true
+		services[serviceName] = selector.Sel.Name
 		return true
 	})
 	return services
@@ -166,7 +192,6 @@ func generateServiceFile(service ServiceInfo, pkgName, outputFolder string, fset
 		builder.WriteString(buf.String())
 		builder.WriteString("\n\n")
 	}
-	fileName := fmt.Sprintf("%s_control_flow.go", strings.ToLower(service.Name))
-	outputPath := filepath.Join(outputFolder, fileName)
+	outputPath := filepath.Join(outputFolder, controlFlowFileName(service.Name))
 	return os.WriteFile(outputPath, []byte(builder.String()), 0644)
 }
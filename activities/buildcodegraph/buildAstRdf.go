@@ -2,12 +2,31 @@ package buildcodegraph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
 )
 
+// RejectedRdf is a control flow file whose annotation was rejected, so the APIs it describes are
+// missing from the graph.
+type RejectedRdf struct {
+	File     string   `json:"file"`
+	Service  string   `json:"service,omitempty"` // The proto service of the file, if known.
+	Apis     []string `json:"apis"`              // The RPCs of the service, if known.
+	Reason   string   `json:"reason"`            // One of the Rdf* or Annotation* reasons, or UnknownService.
+	Problems []string `json:"problems"`
+}
+
+// buildAstRdfLLM is the LLM of BuildAstRdf unless configured otherwise.
+var buildAstRdfLLM = llm.Config{Use: "BuildAstRdf", Provider: llm.Anthropic, Model: "claude-3-5-sonnet-20241022", MaxTokens: 2048}
+
 func (a *Activities) BuildAstRdf(ctx context.Context, state BuildCodeGraphState) (BuildCodeGraphState, error) {
 	tmpDir, _ := os.MkdirTemp("", "rdfControlFlow-*")
 
@@ -17,7 +36,7 @@ func (a *Activities) BuildAstRdf(ctx context.Context, state BuildCodeGraphState)
 	}
 
 	// 2. Use default prompt file path if none provided.
-	promptFilePath := "prompts/generate_ast_rdf.txt"
+	promptFilePath := "prompts/annotate_apis.txt"
 
 	// 3. Read the prompt template from the file.
 	promptTemplate, err := ReadFileToString(promptFilePath)
@@ -25,8 +44,15 @@ func (a *Activities) BuildAstRdf(ctx context.Context, state BuildCodeGraphState)
 		return state, fmt.Errorf("failed to read prompt file: %w", err)
 	}
 
-	// 4. Read the current repository RDF graph.
-	currRdf, err := ReadFileToString(state.RepoRdfGraph)
+	promptVersion := provenance.PromptVersion(filepath.Base(promptFilePath), promptTemplate)
+
+	client, cfg, err := a.llmClient(buildAstRdfLLM)
+	if err != nil {
+		return state, err
+	}
+
+	// 4. Read the repository RDF graph, which the fragment of every API is merged into.
+	repoGraph, err := rdf.ParseTurtleFile(state.RepoRdfGraph)
 	if err != nil {
 		return state, fmt.Errorf("failed to read RDF graph file: %w", err)
 	}
@@ -38,30 +64,152 @@ func (a *Activities) BuildAstRdf(ctx context.Context, state BuildCodeGraphState)
 		if err != nil {
 			return state, fmt.Errorf("failed to read AST control flow file: %w", err)
 		}
+		service := findCanonicalService(state.Services, file)
+		reject := func(rpcs []string, reason string, problems []string) {
+			entry := RejectedRdf{File: file, Apis: rpcs, Reason: reason, Problems: problems}
+			if entry.Apis == nil {
+				entry.Apis = []string{}
+			}
+			if service != nil {
+				entry.Service = service.ProtoService
+			}
+			state.RejectedRdf = append(state.RejectedRdf, entry)
+		}
 
-		// 6. Substitute placeholders in the prompt template.
-		prompt := strings.ReplaceAll(promptTemplate, "{{.ProjectRDF}}", currRdf)
-		prompt = strings.ReplaceAll(prompt, "{{.ApiControlFlow}}", content)
+		// 6. The canonical service gives the URIs of the annotated APIs, so files without one are rejected.
+		if service == nil {
+			problem := "no proto service is known for the control flow file"
+			fmt.Printf("Rejected RDF for %s: %s\n", file, problem)
+			reject(nil, UnknownService, []string{problem})
+			continue
+		}
 
-		// 7. Call LLM to generate RDF.
-		response, err := CallClaudeApi(ctx, prompt)
-		if err != nil {
-			return state, fmt.Errorf("LLM call failed: %w", err)
+		// 7. Substitute placeholders in the prompt template.
+		render := func(chunk controlFlowChunk) string {
+			prompt := strings.ReplaceAll(promptTemplate, "{{.KnownNodes}}", knownNodesPrompt(repoGraph))
+			prompt = strings.ReplaceAll(prompt, "{{.Service}}", service.ProtoService)
+			prompt = strings.ReplaceAll(prompt, "{{.Rpcs}}", strings.Join(chunk.rpcs, "\n"))
+			return strings.ReplaceAll(prompt, "{{.ApiControlFlow}}", chunk.content)
 		}
 
-		// write rdf to a file
-		apiRdf, err := ExtractTurtleRDF(response)
-		if err != nil {
-			fmt.Printf("Failed parsinng RDF %s: %v\n", response, err)
-			continue // continue with other files
+		// 8. Split the file by RPC if its prompt is over the budget of the model. RPCs too large even alone
+		// are rejected rather than sent.
+		chunks, tooLarge, err := controlFlowChunks(content, serviceRpcs(service), func(chunk controlFlowChunk) bool { return cfg.Fits(render(chunk)) })
+		if len(tooLarge) > 0 {
+			problem := fmt.Sprintf("the prompt is over the budget of %d tokens", cfg.PromptBudget())
+			if err != nil {
+				problem = fmt.Sprintf("%s and cannot be split by RPC: %v", problem, err)
+			} else {
+				problem = fmt.Sprintf("%s even for %s alone", problem, strings.Join(tooLarge, ", "))
+			}
+			fmt.Printf("Rejected RDF for %s: %s\n", file, problem)
+			reject(tooLarge, RdfTooLarge, []string{problem})
 		}
+		for i, chunk := range chunks {
+			if len(chunk.rpcs) == 0 {
+				// Nothing in the chunk can be annotated, so it is not worth a call.
+				fmt.Printf("Skipped %s: it implements no RPC of %s\n", file, service.ProtoService)
+				continue
+			}
+			part := file
+			if len(chunks) > 1 {
+				part = fmt.Sprintf("%s (part %d of %d: %s)", file, i+1, len(chunks), strings.Join(chunk.rpcs, ", "))
+			}
 
-		_, err = WriteStringToFile(apiRdf, tmpDir, "api_*.ttl")
-		if err != nil {
-			return state, fmt.Errorf("failed to write RDF file: %w", err)
+			// 9. Call LLM to annotate the APIs through structured output and convert the annotation to an RDF
+			// fragment with canonical URIs, repairing it until it checks and conforms to the shapes.
+			graph, response, err := annotateApis(ctx, client, render(chunk), state.RepoURL, service, chunk.rpcs)
+			var validationErr *RdfValidationError
+			if errors.As(err, &validationErr) {
+				fmt.Printf("Rejected RDF for %s: %v\n", part, err)
+				reject(chunk.rpcs, validationErr.Reason, validationErr.Violations)
+				continue // continue with other chunks and files; the rejection is reported by CopyAstControlRdfGraphs
+			}
+			if err != nil {
+				return state, llmActivityError(err)
+			}
+			recordApiProvenance(graph, service, provenance.Source{
+				Repository:    state.RepoURL,
+				Commit:        state.Commit,
+				Activity:      "BuildAstRdf",
+				Model:         response.Model,
+				PromptVersion: promptVersion,
+				ResponseID:    response.ID,
+				Evidence:      provenance.LLMInference,
+			})
+
+			// 10. Merge the fragment into the repository graph, keeping every fact the graph already has.
+			merge := mergeFragment(repoGraph, graph)
+			fmt.Printf("Merged RDF for %s: %d new and %d known triple(s)\n", part, merge.added, merge.existing)
+			for _, conflict := range merge.conflicts {
+				fmt.Printf("Dropped conflicting triple from %s: %s\n", part, conflict)
+			}
 		}
 	}
 
+	// 11. Write the merged repository graph.
+	if _, err := WriteStringToFile(rdf.ToTurtle(repoGraph), tmpDir, "repo_*.ttl"); err != nil {
+		return state, fmt.Errorf("failed to write RDF file: %w", err)
+	}
+
+	// 12. Warn about the fragments that were rejected so missing APIs do not go unnoticed. They are listed
+	// in detail by CopyAstControlRdfGraphs.
+	if len(state.RejectedRdf) > 0 {
+		fmt.Printf("WARNING: RDF of %d part(s) of %d control flow file(s) rejected, their APIs are missing from the graph; see rejected_rdf.json in the build folder\n", len(state.RejectedRdf), len(files))
+	}
+
 	state.AstControlRdfGraph = tmpDir
 	return state, nil
 }
+
+// serviceRpcs returns the RPCs of a service, sorted, or nil if the service is unknown.
+func serviceRpcs(service *CanonicalService) []string {
+	if service == nil {
+		return nil
+	}
+	var rpcs []string
+	for rpc := range service.ApiURIs {
+		rpcs = append(rpcs, rpc)
+	}
+	sort.Strings(rpcs)
+	return rpcs
+}
+
+// recordApiProvenance records where the triples generated for a service come from. Triples about an API
+// point at the method implementing it and all other triples at the service's methods. llm holds the
+// repository and the LLM response the triples were generated from.
+func recordApiProvenance(g *rdf.Graph, service *CanonicalService, llm provenance.Source) {
+	at := func(lines SourceRange) provenance.Source {
+		source := llm
+		source.File, source.StartLine, source.EndLine = lines.File, lines.StartLine, lines.EndLine
+		return source
+	}
+
+	if service == nil {
+		provenance.RecordRemaining(g, llm)
+		return
+	}
+	for rpc, lines := range service.ApiSources {
+		api := rdf.IRI(service.ApiURIs[rpc])
+		for _, t := range append(g.Match(api, "", nil), g.Match(nil, "", api)...) {
+			provenance.Record(g, t, at(lines))
+		}
+	}
+
+	recorded := provenance.All(g)
+	var remaining []rdf.Triple
+	for _, t := range provenance.Strip(g).Triples() {
+		if len(recorded[t]) == 0 {
+			remaining = append(remaining, t)
+		}
+	}
+	if len(service.Sources) == 0 {
+		provenance.RecordRemaining(g, llm)
+		return
+	}
+	for _, lines := range service.Sources {
+		for _, t := range remaining {
+			provenance.Record(g, t, at(lines))
+		}
+	}
+}
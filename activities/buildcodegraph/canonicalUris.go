@@ -0,0 +1,228 @@
+package buildcodegraph
+
+import (
+	"fmt"
+	"go/ast"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+)
+
+// CanonicalService ties a gRPC service registered in main.go to its canonical URIs.
+type CanonicalService struct {
+	Name            string                 // The Go type implementing the service.
+	ProtoService    string                 // The fully qualified proto service name, for example "auth.Login".
+	URI             string                 // The canonical service URI.
+	ApiURIs         map[string]string      // The canonical API URI of each RPC, keyed by RPC name.
+	ControlFlowFile string                 // The name of the control flow file generated for the service.
+	ApiSources      map[string]SourceRange // The method implementing each RPC, keyed by RPC name.
+	Sources         []SourceRange          // The lines spanned by the service's methods in each file.
+}
+
+// protoService is a gRPC service declared in a .proto file or a generated *_grpc.pb.go file.
+type protoService struct {
+	FullName string
+	Rpcs     []string
+}
+
+var (
+	goModulePattern     = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	protoPackagePattern = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+	protoServicePattern = regexp.MustCompile(`\bservice\s+(\w+)\s*\{`)
+	protoRpcPattern     = regexp.MustCompile(`\brpc\s+(\w+)\s*\(`)
+	protoCommentPattern = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+	grpcServiceName     = regexp.MustCompile(`ServiceName:\s*"([\w.]+)"`)
+	grpcMethodName      = regexp.MustCompile(`(?:MethodName|StreamName):\s*"(\w+)"`)
+)
+
+// canonicalServices computes canonical URIs for the registered services. registered maps the Go type of
+// each service to the function that registers it, for example "RegisterLoginServer", and protos holds the
+// services found by findProtoServices. site locates syntax nodes in the repository.
+func canonicalServices(repoPath string, registered map[string]string, services []ServiceInfo, protos map[string]protoService, site func(ast.Node) SourceRange) []CanonicalService {
+	modulePath := readModulePath(repoPath)
+
+	var result []CanonicalService
+	for _, service := range services {
+		shortName := strings.TrimSuffix(strings.TrimPrefix(registered[service.Name], "Register"), "Server")
+		if shortName == "" {
+			shortName = service.Name
+		}
+
+		canonical := CanonicalService{
+			Name:            service.Name,
+			ProtoService:    ontology.QualifiedServiceName(modulePath, shortName),
+			ApiURIs:         map[string]string{},
+			ControlFlowFile: controlFlowFileName(service.Name),
+			ApiSources:      map[string]SourceRange{},
+		}
+
+		// Prefer the RPCs declared in the proto definition over the Go methods, which include helpers.
+		var rpcs []string
+		if proto, ok := protos[shortName]; ok {
+			canonical.ProtoService = proto.FullName
+			rpcs = proto.Rpcs
+		} else {
+			for _, method := range service.Methods {
+				if method.Name.IsExported() {
+					rpcs = append(rpcs, method.Name.Name)
+				}
+			}
+		}
+
+		canonical.URI = string(ontology.ServiceURI(canonical.ProtoService))
+		for _, rpc := range rpcs {
+			canonical.ApiURIs[rpc] = string(ontology.ApiURI(canonical.ProtoService, rpc))
+		}
+
+		// Remember where the methods are so triples about them can point back at the code.
+		files := map[string]int{}
+		for _, method := range service.Methods {
+			lines := site(method)
+			if _, ok := canonical.ApiURIs[method.Name.Name]; ok {
+				canonical.ApiSources[method.Name.Name] = lines
+			}
+			i, ok := files[lines.File]
+			if !ok {
+				files[lines.File] = len(canonical.Sources)
+				canonical.Sources = append(canonical.Sources, lines)
+				continue
+			}
+			canonical.Sources[i].StartLine = min(canonical.Sources[i].StartLine, lines.StartLine)
+			canonical.Sources[i].EndLine = max(canonical.Sources[i].EndLine, lines.EndLine)
+		}
+		sort.Slice(canonical.Sources, func(i, j int) bool { return canonical.Sources[i].File < canonical.Sources[j].File })
+		result = append(result, canonical)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// readModulePath returns the module path declared in the repository's go.mod, or "" if there is none.
+func readModulePath(repoPath string) string {
+	content, err := os.ReadFile(filepath.Join(repoPath, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	if m := goModulePattern.FindSubmatch(content); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// findProtoServices collects gRPC services from .proto files and generated *_grpc.pb.go files, keyed by
+// their unqualified name.
+func findProtoServices(repoPath string) map[string]protoService {
+	services := map[string]protoService{}
+	add := func(fullName string, rpcs []string) {
+		shortName := fullName[strings.LastIndex(fullName, ".")+1:]
+		if existing, ok := services[shortName]; ok && len(existing.Rpcs) >= len(rpcs) {
+			return
+		}
+		services[shortName] = protoService{FullName: fullName, Rpcs: rpcs}
+	}
+
+	_ = filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable paths
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case strings.HasSuffix(path, ".proto"):
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			for fullName, rpcs := range parseProtoFile(string(content)) {
+				add(fullName, rpcs)
+			}
+		case strings.HasSuffix(path, "_grpc.pb.go"):
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			for fullName, rpcs := range parseGrpcServiceDescs(string(content)) {
+				add(fullName, rpcs)
+			}
+		}
+		return nil
+	})
+	return services
+}
+
+// parseProtoFile returns the RPC names of every service in a .proto file keyed by qualified service name.
+func parseProtoFile(content string) map[string][]string {
+	content = protoCommentPattern.ReplaceAllString(content, "")
+	protoPackage := ""
+	if m := protoPackagePattern.FindStringSubmatch(content); m != nil {
+		protoPackage = m[1]
+	}
+
+	services := map[string][]string{}
+	for _, loc := range protoServicePattern.FindAllStringSubmatchIndex(content, -1) {
+		name := content[loc[2]:loc[3]]
+		body := content[loc[1]:]
+		// Cut the body at the brace closing the service block.
+		depth := 1
+		for i, r := range body {
+			if r == '{' {
+				depth++
+			} else if r == '}' {
+				depth--
+				if depth == 0 {
+					body = body[:i]
+					break
+				}
+			}
+		}
+		var rpcs []string
+		for _, m := range protoRpcPattern.FindAllStringSubmatch(body, -1) {
+			rpcs = append(rpcs, m[1])
+		}
+		services[ontology.QualifiedServiceName(protoPackage, name)] = rpcs
+	}
+	return services
+}
+
+// parseGrpcServiceDescs returns the method names of every grpc.ServiceDesc in generated code keyed by the
+// qualified service name.
+func parseGrpcServiceDescs(content string) map[string][]string {
+	services := map[string][]string{}
+	for _, desc := range strings.Split(content, "grpc.ServiceDesc{")[1:] {
+		m := grpcServiceName.FindStringSubmatch(desc)
+		if m == nil {
+			continue
+		}
+		var rpcs []string
+		for _, method := range grpcMethodName.FindAllStringSubmatch(desc, -1) {
+			rpcs = append(rpcs, method[1])
+		}
+		services[m[1]] = rpcs
+	}
+	return services
+}
+
+// findCanonicalService returns the canonical service whose control flow file has the given name.
+func findCanonicalService(services []CanonicalService, controlFlowFile string) *CanonicalService {
+	for i := range services {
+		if services[i].ControlFlowFile == controlFlowFile {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+func controlFlowFileName(serviceName string) string {
+	return fmt.Sprintf("%s_control_flow.go", strings.ToLower(serviceName))
+}
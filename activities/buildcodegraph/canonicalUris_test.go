@@ -0,0 +1,127 @@
+package buildcodegraph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProtoFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]string
+	}{
+		{
+			name: "package and several services",
+			content: `syntax = "proto3";
+package shop.orders.v1;
+
+import "google/api/annotations.proto";
+
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order) {
+    option (google.api.http) = { get: "/v1/orders/{id}" };
+  }
+  rpc ListOrders (ListOrdersRequest) returns (stream Order);
+}
+
+service Admin { rpc Purge(PurgeRequest) returns (PurgeResponse); }
+
+message GetOrderRequest { string id = 1; }`,
+			want: map[string][]string{
+				"shop.orders.v1.OrderService": {"GetOrder", "ListOrders"},
+				"shop.orders.v1.Admin":        {"Purge"},
+			},
+		},
+		{
+			name: "comments are ignored",
+			content: `// package old.name;
+package auth;
+/* service Legacy { rpc Old(A) returns (B); } */
+service Login {
+  // rpc Commented(A) returns (B);
+  rpc Check(CheckRequest) returns (CheckResponse); // rpc Trailing(A) returns (B);
+}`,
+			want: map[string][]string{"auth.Login": {"Check"}},
+		},
+		{
+			name:    "no package",
+			content: "service Ping { rpc Ping(Empty) returns (Empty); }",
+			want:    map[string][]string{"Ping": {"Ping"}},
+		},
+		{
+			name:    "service without RPCs",
+			content: "package empty;\nservice Nothing {}\nmessage M {}",
+			want:    map[string][]string{"empty.Nothing": nil},
+		},
+		{
+			name:    "messages only",
+			content: "package types;\nmessage Order { string id = 1; }",
+			want:    map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseProtoFile(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("services = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGrpcServiceDescs(t *testing.T) {
+	content := `package orderspb
+
+var OrderService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shop.orders.v1.OrderService",
+	HandlerType: (*OrderServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetOrder", Handler: _OrderService_GetOrder_Handler},
+		{MethodName: "PlaceOrder", Handler: _OrderService_PlaceOrder_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "WatchOrders", Handler: _OrderService_WatchOrders_Handler, ServerStreams: true},
+	},
+	Metadata: "orders.proto",
+}
+
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shop.orders.v1.Admin",
+	Methods:     []grpc.MethodDesc{},
+}
+
+var Broken_ServiceDesc = grpc.ServiceDesc{
+	HandlerType: (*BrokenServer)(nil),
+}
+`
+	want := map[string][]string{
+		"shop.orders.v1.OrderService": {"GetOrder", "PlaceOrder", "WatchOrders"},
+		"shop.orders.v1.Admin":        nil,
+	}
+	if got := parseGrpcServiceDescs(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("services = %v, want %v", got, want)
+	}
+	if got := parseGrpcServiceDescs("package empty\n"); len(got) != 0 {
+		t.Errorf("services of a file without descriptors = %v", got)
+	}
+}
+
+func TestFindProtoServices(t *testing.T) {
+	root := writeRepo(t, map[string]string{
+		"proto/orders.proto": "package shop.orders;\nservice OrderService { rpc GetOrder(A) returns (B); }",
+		// The generated code knows every RPC, so it wins over the partial proto.
+		"orderspb/orders_grpc.pb.go":      grpcFile("orderspb", "shop.orders.OrderService", "GetOrder", "PlaceOrder"),
+		"proto/auth.proto":                "package auth;\nservice Login { rpc Check(A) returns (B); rpc Refresh(A) returns (B); }",
+		"authpb/auth_grpc.pb.go":          grpcFile("authpb", "auth.Login", "Check"),
+		".git/objects/x.proto":            "package git;\nservice Hidden { rpc H(A) returns (B); }",
+		"node_modules/dep/dep.proto":      "package dep;\nservice Dep { rpc D(A) returns (B); }",
+		"vendor/example.com/x/x.proto.md": "service NotProto { rpc X(A) returns (B); }",
+	})
+	want := map[string]protoService{
+		"OrderService": {FullName: "shop.orders.OrderService", Rpcs: []string{"GetOrder", "PlaceOrder"}},
+		"Login":        {FullName: "auth.Login", Rpcs: []string{"Check", "Refresh"}},
+	}
+	if got := findProtoServices(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("services = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,289 @@
+package buildcodegraph
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// packChunks groups the items 0..n-1, in order, into as few chunks as fit: fits reports whether a group of
+// items fits the prompt. The items that do not fit even alone are returned separately.
+func packChunks(n int, fits func(group []int) bool) (chunks [][]int, oversize []int) {
+	var current []int
+	for i := 0; i < n; i++ {
+		if fits(append(current[:len(current):len(current)], i)) {
+			current = append(current, i)
+			continue
+		}
+		if len(current) > 0 {
+			chunks = append(chunks, current)
+			current = nil
+		}
+		if fits([]int{i}) {
+			current = []int{i}
+		} else {
+			oversize = append(oversize, i)
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks, oversize
+}
+
+// controlFlowChunk is a part of a control flow file small enough for one prompt.
+type controlFlowChunk struct {
+	content string
+	rpcs    []string // The RPCs the chunk implements.
+}
+
+// controlFlowChunks splits a control flow file whose prompt is too large by RPC. Each chunk holds the
+// file's package clause and imports, as many RPC methods as fit, and the other declarations of the file
+// those methods use, directly or through each other. rpcs are the RPCs of the whole file; fits reports
+// whether the prompt of a chunk fits. The RPCs too large even alone are returned by name; if the file
+// cannot be parsed, all its RPCs are. Declarations no RPC uses are left out.
+func controlFlowChunks(content string, rpcs []string, fits func(controlFlowChunk) bool) ([]controlFlowChunk, []string, error) {
+	if whole := (controlFlowChunk{content: content, rpcs: rpcs}); fits(whole) {
+		return []controlFlowChunk{whole}, nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, rpcs, fmt.Errorf("failed to split control flow file by RPC: %w", err)
+	}
+
+	isRpc := map[string]bool{}
+	for _, rpc := range rpcs {
+		isRpc[rpc] = true
+	}
+	// The header is everything up to the end of the imports, which every chunk needs.
+	headerEnd := fset.Position(file.Name.End()).Offset
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			headerEnd = fset.Position(decl.End()).Offset
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	header := content[:headerEnd] + "\n\n"
+
+	// Find the RPC methods and the declarations every declaration uses.
+	texts := make([]string, len(decls))
+	declared := map[string][]int{}
+	var rpcDecls []int
+	for i, decl := range decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		texts[i] = content[fset.Position(start).Offset:fset.Position(decl.End()).Offset] + "\n\n"
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && isRpc[fn.Name.Name] {
+			rpcDecls = append(rpcDecls, i)
+			continue
+		}
+		for _, name := range declNames(decl) {
+			declared[name] = append(declared[name], i)
+		}
+	}
+	uses := func(decl ast.Decl) []int {
+		var used []int
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				used = append(used, declared[ident.Name]...)
+			}
+			return true
+		})
+		return used
+	}
+	// needed returns the declarations a group of RPC methods needs, in the order of the file.
+	needed := func(group []int) []int {
+		seen := map[int]bool{}
+		queue := []int{}
+		for _, g := range group {
+			seen[rpcDecls[g]] = true
+			queue = append(queue, rpcDecls[g])
+		}
+		for len(queue) > 0 {
+			decl := queue[0]
+			queue = queue[1:]
+			for _, used := range uses(decls[decl]) {
+				if !seen[used] {
+					seen[used] = true
+					queue = append(queue, used)
+				}
+			}
+		}
+		var result []int
+		for i := range decls {
+			if seen[i] {
+				result = append(result, i)
+			}
+		}
+		return result
+	}
+	chunk := func(group []int) controlFlowChunk {
+		var b strings.Builder
+		b.WriteString(header)
+		for _, i := range needed(group) {
+			b.WriteString(texts[i])
+		}
+		c := controlFlowChunk{content: b.String()}
+		for _, g := range group {
+			c.rpcs = append(c.rpcs, decls[rpcDecls[g]].(*ast.FuncDecl).Name.Name)
+		}
+		return c
+	}
+
+	groups, oversize := packChunks(len(rpcDecls), func(group []int) bool { return fits(chunk(group)) })
+	chunks := make([]controlFlowChunk, len(groups))
+	for i, group := range groups {
+		chunks[i] = chunk(group)
+	}
+	var tooLarge []string
+	for _, g := range oversize {
+		tooLarge = append(tooLarge, decls[rpcDecls[g]].(*ast.FuncDecl).Name.Name)
+	}
+	return chunks, tooLarge, nil
+}
+
+// declNames returns the names a declaration declares: a function or method, or the types, variables and
+// constants of a declaration group.
+func declNames(decl ast.Decl) []string {
+	var names []string
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		names = append(names, d.Name.Name)
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// fitFileList returns the file list of a repository as it fits the prompt. A list too large is summarised
+// by directory, listing the files of the top directory levels and counting the files below them, as deep as
+// fits. If even the summary by top-level directory does not fit, it is split into chunks that do, each of
+// which is prompted for separately.
+func fitFileList(files []string, fits func(string) bool) ([]string, error) {
+	full := strings.Join(files, "\n")
+	if fits(full) {
+		return []string{full}, nil
+	}
+	deepest := 0
+	for _, file := range files {
+		deepest = max(deepest, strings.Count(file, "/"))
+	}
+	for depth := deepest - 1; depth >= 1; depth-- {
+		if summary := strings.Join(summarizeByDirectory(files, depth), "\n"); fits(summary) {
+			fmt.Printf("File list of %d files summarised by directory to depth %d to fit the prompt\n", len(files), depth)
+			return []string{summary}, nil
+		}
+	}
+
+	lines := summarizeByDirectory(files, 1)
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		parts[i] = line + "\n"
+	}
+	// Leave room for the part line of the largest possible chunk count.
+	groups, oversize := packChunks(len(parts), func(group []int) bool {
+		var b strings.Builder
+		b.WriteString(fileListPart(len(parts), len(parts)))
+		for _, line := range group {
+			b.WriteString(parts[line])
+		}
+		return fits(b.String())
+	})
+	if len(oversize) > 0 {
+		return nil, fmt.Errorf("the prompt is over the budget even for a file list of one line: %s", lines[oversize[0]])
+	}
+	chunks := make([]string, len(groups))
+	for i, group := range groups {
+		var b strings.Builder
+		b.WriteString(fileListPart(i+1, len(groups)))
+		for _, line := range group {
+			b.WriteString(parts[line])
+		}
+		chunks[i] = strings.TrimSuffix(b.String(), "\n")
+	}
+	fmt.Printf("File list of %d files split into %d chunks to fit the prompt\n", len(files), len(chunks))
+	return chunks, nil
+}
+
+// fileListPart is the line a chunk of a file list starts with.
+func fileListPart(part, parts int) string {
+	return fmt.Sprintf("(Part %d of %d of the file list; the other parts are described separately.)\n", part, parts)
+}
+
+// summarizeByDirectory lists the files at most depth directories deep, and for each directory at that
+// depth the number of files below it by extension, for example "services/orders/ (124 file(s): 98 .go,
+// 20 .proto, 6 .yaml)". The lines are sorted.
+func summarizeByDirectory(files []string, depth int) []string {
+	type counts struct {
+		files       int
+		byExtension map[string]int
+	}
+	var lines []string
+	dirs := map[string]*counts{}
+	for _, file := range files {
+		file = strings.ReplaceAll(file, "\\", "/")
+		parts := strings.Split(file, "/")
+		if len(parts)-1 <= depth {
+			lines = append(lines, file)
+			continue
+		}
+		dir := strings.Join(parts[:depth], "/") + "/"
+		if dirs[dir] == nil {
+			dirs[dir] = &counts{byExtension: map[string]int{}}
+		}
+		dirs[dir].files++
+		ext := path.Ext(file)
+		if ext == "" {
+			ext = "no extension"
+		}
+		dirs[dir].byExtension[ext]++
+	}
+	for dir, c := range dirs {
+		exts := make([]string, 0, len(c.byExtension))
+		for ext := range c.byExtension {
+			exts = append(exts, ext)
+		}
+		sort.Slice(exts, func(i, j int) bool {
+			if c.byExtension[exts[i]] != c.byExtension[exts[j]] {
+				return c.byExtension[exts[i]] > c.byExtension[exts[j]]
+			}
+			return exts[i] < exts[j]
+		})
+		var kinds []string
+		for i, ext := range exts {
+			if i == 5 {
+				kinds = append(kinds, "...")
+				break
+			}
+			kinds = append(kinds, fmt.Sprintf("%d %s", c.byExtension[ext], ext))
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d file(s): %s)", dir, c.files, strings.Join(kinds, ", ")))
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,139 @@
+package buildcodegraph
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ordersControlFlow = `package orders
+
+import (
+	"context"
+	"fmt"
+)
+
+// OrderService implements shop.Orders.
+type OrderService struct{}
+
+// Get returns an order.
+func (s *OrderService) Get(ctx context.Context, id string) (string, error) {
+	return loadOrder(id)
+}
+
+// Put stores an order.
+func (s *OrderService) Put(ctx context.Context, id string) error {
+	fmt.Println("storing", id, "in the orders collection of the shop database, which takes a while")
+	return nil
+}
+
+func loadOrder(id string) (string, error) {
+	return fmt.Sprintf("order %s from the orders collection", id), nil
+}
+
+func unused() {}
+`
+
+func TestControlFlowChunks(t *testing.T) {
+	rpcs := []string{"Get", "Put"}
+	tests := []struct {
+		name         string
+		limit        int // The largest chunk content that fits.
+		wantRpcs     [][]string
+		wantTooLarge []string
+	}{
+		{name: "whole file fits", limit: len(ordersControlFlow), wantRpcs: [][]string{{"Get", "Put"}}},
+		{name: "one RPC per chunk", limit: 400, wantRpcs: [][]string{{"Get"}, {"Put"}}},
+		{name: "Get too large with its helper", limit: 330, wantRpcs: [][]string{{"Put"}}, wantTooLarge: []string{"Get"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, tooLarge, err := controlFlowChunks(ordersControlFlow, rpcs, func(c controlFlowChunk) bool { return len(c.content) <= tt.limit })
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotRpcs [][]string
+			for _, c := range chunks {
+				gotRpcs = append(gotRpcs, c.rpcs)
+			}
+			if !reflect.DeepEqual(gotRpcs, tt.wantRpcs) {
+				t.Errorf("chunk RPCs = %v, want %v", gotRpcs, tt.wantRpcs)
+			}
+			if !reflect.DeepEqual(tooLarge, tt.wantTooLarge) {
+				t.Errorf("too large = %v, want %v", tooLarge, tt.wantTooLarge)
+			}
+			if len(chunks) < 2 {
+				return
+			}
+			for _, c := range chunks {
+				if !strings.Contains(c.content, `"context"`) || !strings.Contains(c.content, "type OrderService struct") {
+					t.Errorf("chunk %v lacks the imports or the service type:\n%s", c.rpcs, c.content)
+				}
+				if strings.Contains(c.content, "func unused") {
+					t.Errorf("chunk %v holds a declaration no RPC uses", c.rpcs)
+				}
+				if uses := strings.Contains(c.content, "func loadOrder"); uses != (c.rpcs[0] == "Get") {
+					t.Errorf("chunk %v has loadOrder: %v", c.rpcs, uses)
+				}
+			}
+		})
+	}
+}
+
+func TestFitFileList(t *testing.T) {
+	files := []string{"go.mod", "services/orders/get.go", "services/orders/put.go", "services/users/get.go", "web/app.ts"}
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "fits", limit: 1000, want: []string{strings.Join(files, "\n")}},
+		{name: "summarised", limit: 60, want: []string{"go.mod\nservices/ (3 file(s): 3 .go)\nweb/app.ts"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fitFileList(files, func(fileList string) bool { return len(fileList) <= tt.limit })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fitFileList = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("split", func(t *testing.T) {
+		var files []string
+		for i := 0; i < 10; i++ {
+			files = append(files, fmt.Sprintf("module%d/internal/file.go", i))
+		}
+		const limit = 150
+		parts, err := fitFileList(files, func(fileList string) bool { return len(fileList) <= limit })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parts) < 2 {
+			t.Fatalf("got %d part(s), want the list split", len(parts))
+		}
+		lines := 0
+		for i, part := range parts {
+			if len(part) > limit {
+				t.Errorf("part %d has %d bytes, over the limit of %d", i+1, len(part), limit)
+			}
+			if want := fileListPart(i+1, len(parts)); !strings.HasPrefix(part, want) {
+				t.Errorf("part %d does not start with %q", i+1, want)
+			}
+			lines += strings.Count(part, "\n")
+		}
+		if lines != len(files) {
+			t.Errorf("the parts list %d directories, want %d", lines, len(files))
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		if _, err := fitFileList(files, func(string) bool { return false }); err == nil {
+			t.Error("fitFileList succeeded with a budget nothing fits")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package buildcodegraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SaiNageswarS/GraphMind/diff"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// DiffBuild compares the graphs a build stored with the previous build and writes the report to
+// graph_diff.json and graph_diff.md in the common folder. It returns the path of the Markdown summary, or
+// an empty path for the first build.
+func (a *Activities) DiffBuild(ctx context.Context, commonFolder, buildID string) (string, error) {
+	if a.Store == nil {
+		return "", fmt.Errorf("graph store is not configured")
+	}
+
+	// 1. Compare the build with the one before it.
+	report, err := diff.Builds(a.Store, "", buildID)
+	if errors.Is(err, store.ErrNotFound) {
+		fmt.Printf("No previous build to compare build %s with\n", buildID)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// 2. Write the report as JSON and Markdown.
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph diff: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(commonFolder, "graph_diff.json"), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write graph diff: %w", err)
+	}
+	summaryPath := filepath.Join(commonFolder, "graph_diff.md")
+	if err := os.WriteFile(summaryPath, []byte(report.Markdown()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write graph diff summary: %w", err)
+	}
+
+	fmt.Printf("Graph diff against %s: %d APIs added, %d removed, %d dependencies added, %d dropped\n",
+		report.From, len(report.AddedApis), len(report.RemovedApis), len(report.AddedDependencies), len(report.RemovedDependencies))
+	return summaryPath, nil
+}
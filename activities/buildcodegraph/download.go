@@ -6,19 +6,36 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/store"
 )
 
 // DownloadRepoInput contains the Git repo URL to clone
 type BuildCodeGraphState struct {
-	RepoURL                  string // The Git repository URL to clone.
-	LocalRepoPath            string // The local path to the cloned repository.
-	RepoRdfGraph             string // The RDF graph generated from the repository files.
-	AstControlFlowFolderPath string // The path to the folder containing AST control flow files.
-	AstControlRdfGraph       string // The RDF graph generated from the AST control flow files.
+	RepoURL                  string             // The Git repository URL to clone.
+	Branch                   string             // The branch to build; the default branch of the repository if empty.
+	Commit                   string             // The commit to build; the head of the branch if empty. Set to the full SHA once cloned.
+	CommitTime               time.Time          // The committer date of the commit.
+	LocalRepoPath            string             // The local path to the cloned repository.
+	RepoRdfGraph             string             // The RDF graph generated from the repository files.
+	AstControlFlowFolderPath string             // The path to the folder containing AST control flow files.
+	Services                 []CanonicalService // The registered gRPC services with their canonical URIs.
+	OutboundCalls            []ServiceCall      // The RPCs the registered services call through gRPC clients.
+	AstControlRdfGraph       string             // The RDF graph generated from the AST control flow files.
+	RejectedRdf              []RejectedRdf      // The control flow files whose generated RDF was rejected.
 }
 
 // Activities defines all build_code_graph activities
-type Activities struct{}
+type Activities struct {
+	Store *store.Store // The graph store builds are saved to.
+
+	// LLM, if set, answers the prompts of every activity instead of the LLM configured for it, for
+	// example a fake in tests.
+	LLM llm.Client
+}
 
 // DownloadRepo clones a Git repository (with submodules) into a temp dir
 func (a *Activities) DownloadRepo(ctx context.Context, state BuildCodeGraphState) (BuildCodeGraphState, error) {
@@ -30,8 +47,12 @@ func (a *Activities) DownloadRepo(ctx context.Context, state BuildCodeGraphState
 
 	targetDir := filepath.Join(tmpDir, "repo")
 
-	// Clone the repository with submodules
-	cmd := exec.CommandContext(ctx, "git", "clone", "--recurse-submodules", state.RepoURL, targetDir)
+	// 1. Clone the repository with submodules, at the requested branch if any.
+	args := []string{"clone", "--recurse-submodules"}
+	if state.Branch != "" {
+		args = append(args, "--branch", state.Branch)
+	}
+	cmd := exec.CommandContext(ctx, "git", append(args, state.RepoURL, targetDir)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -41,6 +62,39 @@ func (a *Activities) DownloadRepo(ctx context.Context, state BuildCodeGraphState
 		return state, fmt.Errorf("git clone failed: %w", err)
 	}
 
+	// 2. Check out the requested commit, so a build can be pinned to a point in history.
+	if state.Commit != "" {
+		cmd := exec.CommandContext(ctx, "git", "-C", targetDir, "-c", "advice.detachedHead=false", "checkout", "--recurse-submodules", state.Commit)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return state, fmt.Errorf("git checkout %s failed: %w", state.Commit, err)
+		}
+	}
+
+	// 3. Record the branch, commit and commit date, which tag the build in the graph store and let the
+	// provenance of every triple point at the code it was built from.
+	out, err := exec.CommandContext(ctx, "git", "-C", targetDir, "show", "--no-patch", "--format=%H %cI", "HEAD").Output()
+	if err != nil {
+		return state, fmt.Errorf("git show failed: %w", err)
+	}
+	commit, date, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	commitTime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return state, fmt.Errorf("failed to parse commit date %q: %w", date, err)
+	}
+	if state.Branch == "" {
+		out, err := exec.CommandContext(ctx, "git", "-C", targetDir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+		if err != nil {
+			return state, fmt.Errorf("git rev-parse failed: %w", err)
+		}
+		if branch := strings.TrimSpace(string(out)); branch != "HEAD" {
+			state.Branch = branch
+		}
+	}
+
 	state.LocalRepoPath = targetDir
+	state.Commit = commit
+	state.CommitTime = commitTime.UTC()
 	return state, nil
 }
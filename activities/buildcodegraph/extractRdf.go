@@ -0,0 +1,154 @@
+package buildcodegraph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// turtleLanguages are the info strings of code fences that hold Turtle or a subset of it.
+var turtleLanguages = map[string]bool{
+	"turtle":    true,
+	"ttl":       true,
+	"rdf":       true,
+	"n3":        true,
+	"nt":        true,
+	"ntriples":  true,
+	"n-triples": true,
+}
+
+// turtleStart matches a line that starts Turtle: a prefix or base declaration, or a subject (an IRI, a
+// prefixed name or a blank node) followed by a predicate, which tells it apart from prose like "Note: ...".
+var turtleStart = regexp.MustCompile(`^\s*(@prefix\s|@base\s|(?i:prefix)\s+[\w.-]*:\s|(?i:base)\s+<|` +
+	`(<[^>\s]*>|_:\S+|[A-Za-z][\w.-]*:[\w.-]*)\s+(a|<[^>\s]*>|[A-Za-z][\w.-]*:[\w.-]*)\s)`)
+
+// codeBlock is a fenced code block of a Markdown text.
+type codeBlock struct {
+	language string // The first word of the info string, lower cased.
+	content  string
+	closed   bool // False if the text ended before the closing fence.
+}
+
+// ExtractTurtleRDF extracts the Turtle RDF content from an LLM response. All code blocks fenced as turtle,
+// ttl or a related language are concatenated; without those, plain fenced blocks that look like Turtle are
+// used, and without any fences the lines from the first Turtle statement to the last line ending a
+// statement. Whether the result parses is left to the caller.
+func ExtractTurtleRDF(text string) (string, error) {
+	blocks := codeBlocks(text)
+
+	var turtle []string
+	for _, block := range blocks {
+		if turtleLanguages[block.language] {
+			turtle = append(turtle, block.content)
+		}
+	}
+	if len(turtle) == 0 {
+		for _, block := range blocks {
+			if block.language == "" && looksLikeTurtle(block.content) {
+				turtle = append(turtle, block.content)
+			}
+		}
+	}
+	if len(turtle) == 0 && len(blocks) == 0 {
+		if unfenced := unfencedTurtle(text); unfenced != "" {
+			turtle = append(turtle, unfenced)
+		}
+	}
+	if len(turtle) == 0 {
+		return "", fmt.Errorf("no turtle RDF found")
+	}
+	return strings.Join(turtle, "\n\n"), nil
+}
+
+// codeBlocks returns the fenced code blocks of a Markdown text. A block left open at the end of the text,
+// as in a truncated response, runs to the end.
+func codeBlocks(text string) []codeBlock {
+	var blocks []codeBlock
+	var current *codeBlock
+	var fence string
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current == nil {
+			marker := fenceMarker(trimmed)
+			if marker == "" {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(trimmed, marker))
+			current = &codeBlock{}
+			if len(fields) > 0 {
+				current.language = strings.ToLower(fields[0])
+			}
+			fence, lines = marker, nil
+			continue
+		}
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			current.content, current.closed = strings.Join(lines, "\n"), true
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if current != nil {
+		current.content = strings.Join(lines, "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// fenceMarker returns the backticks or tildes opening a code fence on a line, or "" if the line does not
+// open one.
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// looksLikeTurtle reports whether a text has a line that starts Turtle.
+func looksLikeTurtle(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if turtleStart.MatchString(line + " ") {
+			return true
+		}
+	}
+	return false
+}
+
+// unfencedTurtle returns the Turtle in a response without code fences: the lines from the first line that
+// starts Turtle to the last line that ends a statement, which drops the prose around it. After a statement
+// ends, a line that neither starts another statement nor is a comment ends the Turtle.
+func unfencedTurtle(text string) string {
+	lines := strings.Split(text, "\n")
+	start := -1
+	for i, line := range lines {
+		if turtleStart.MatchString(line + " ") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	end, ended := -1, false
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if ended && !turtleStart.MatchString(lines[i]+" ") {
+			break
+		}
+		if ended = strings.HasSuffix(trimmed, "."); ended {
+			end = i
+		}
+	}
+	if end < 0 {
+		return ""
+	}
+	return strings.Join(lines[start:end+1], "\n")
+}
@@ -0,0 +1,142 @@
+package buildcodegraph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTurtleRDF(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string // "" if no Turtle should be found.
+	}{
+		{
+			name:     "turtle fence",
+			response: "Here is the graph:\n```turtle\n@prefix ex: <http://example.com/> .\nex:a ex:p ex:b .\n```\nDone.",
+			want:     "@prefix ex: <http://example.com/> .\nex:a ex:p ex:b .",
+		},
+		{
+			name:     "related language and tilde fence",
+			response: "~~~ TTL\nex:a a ex:Service .\n~~~",
+			want:     "ex:a a ex:Service .",
+		},
+		{
+			name:     "several blocks",
+			response: "```ttl\n@prefix ex: <http://example.com/> .\n```\nand the APIs:\n```turtle\nex:a ex:hasApi ex:b .\n```",
+			want:     "@prefix ex: <http://example.com/> .\n\nex:a ex:hasApi ex:b .",
+		},
+		{
+			name:     "turtle fences win over plain ones",
+			response: "```\nex:x ex:p ex:y .\n```\n```turtle\nex:a ex:p ex:b .\n```",
+			want:     "ex:a ex:p ex:b .",
+		},
+		{
+			name:     "plain fence that looks like turtle",
+			response: "```\n# The graph\n<http://example.com/a> <http://example.com/p> \"x\" .\n```\n```\nnot turtle at all\n```",
+			want:     "# The graph\n<http://example.com/a> <http://example.com/p> \"x\" .",
+		},
+		{
+			name:     "truncated block",
+			response: "```turtle\nex:a ex:p ex:b .\nex:c ex:p",
+			want:     "ex:a ex:p ex:b .\nex:c ex:p",
+		},
+		{
+			name:     "unfenced",
+			response: "Sure. Note: the graph follows.\n@prefix ex: <http://example.com/> .\nex:a ex:p ex:b ;\n  ex:q ex:c .\nLet me know if you need more.",
+			want:     "@prefix ex: <http://example.com/> .\nex:a ex:p ex:b ;\n  ex:q ex:c .",
+		},
+		{
+			name:     "unfenced SPARQL-style prefix",
+			response: "PREFIX ex: <http://example.com/>\nex:a ex:p ex:b .",
+			want:     "PREFIX ex: <http://example.com/>\nex:a ex:p ex:b .",
+		},
+		{name: "prose", response: "Note: I could not find any services in this code."},
+		{name: "fenced code of another language", response: "```go\nfunc main() {}\n```"},
+		{name: "unfenced statement that never ends", response: "ex:a ex:p ex:b"},
+		{name: "empty", response: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractTurtleRDF(tt.response)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("found %q in a response without Turtle", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("extracted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []codeBlock
+	}{
+		{
+			name: "language and info string",
+			text: "intro\n```Turtle title=graph\na\n  b\n```\nafter",
+			want: []codeBlock{{language: "turtle", content: "a\n  b", closed: true}},
+		},
+		{
+			name: "longer fence holds a shorter one",
+			text: "````\n```\ninner\n```\n````",
+			want: []codeBlock{{content: "```\ninner\n```", closed: true}},
+		},
+		{
+			name: "indented fences",
+			text: "  ```ttl\n  a\n  ```\n~~~\nb\n~~~",
+			want: []codeBlock{{language: "ttl", content: "  a", closed: true}, {content: "b", closed: true}},
+		},
+		{
+			name: "empty block",
+			text: "```\n```",
+			want: []codeBlock{{closed: true}},
+		},
+		{
+			name: "unclosed block",
+			text: "```turtle\na\nb",
+			want: []codeBlock{{language: "turtle", content: "a\nb"}},
+		},
+		{
+			name: "two backticks are not a fence",
+			text: "``a``\nplain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codeBlocks(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocks = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnfencedTurtle(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"prose around", "Here it is:\n@prefix ex: <http://example.com/> .\nex:a ex:p ex:b .\nThanks.", "@prefix ex: <http://example.com/> .\nex:a ex:p ex:b ."},
+		{"blank node subject", "_:b0 a ex:Api .", "_:b0 a ex:Api ."},
+		{"IRI subject", "<http://example.com/a> <http://example.com/p> 1 .", "<http://example.com/a> <http://example.com/p> 1 ."},
+		{"statement continues over lines", "ex:a ex:p ex:b ,\n  ex:c .\ntrailing", "ex:a ex:p ex:b ,\n  ex:c ."},
+		{"colon in prose", "Note: nothing here.", ""},
+		{"no end of statement", "@prefix ex: <http://example.com/>\nex:a ex:p ex:b", ""},
+		{"nothing", "", ""},
+	}
+	for _, tt := range tests {
+		if got := unfencedTurtle(tt.text); got != tt.want {
+			t.Errorf("%s: unfencedTurtle = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
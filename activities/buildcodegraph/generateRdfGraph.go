@@ -6,10 +6,20 @@ import (
 	"io/fs"
 	"path/filepath"
 	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"go.temporal.io/sdk/temporal"
 )
 
+// generateRdfGraphLLM is the LLM of GenerateRDFGraph unless configured otherwise.
+var generateRdfGraphLLM = llm.Config{Use: "GenerateRDFGraph", Provider: llm.OpenAI, Model: "gpt-4o", MaxTokens: 4096}
+
 // GenerateRDFGraph reads a prompt template from a file, substitutes the file list and repository URL,
-// and calls OpenAI to generate an RDF graph of the repository based solely on its files.
+// and calls the LLM, GPT-4o by default, to generate an RDF graph of the repository based solely on its
+// files.
 func (a *Activities) GenerateRDFGraph(ctx context.Context, state BuildCodeGraphState) (BuildCodeGraphState, error) {
 	// 1. Retrieve a list of files from the provided folder path.
 	files, err := getFileList(state.LocalRepoPath)
@@ -26,10 +36,7 @@ func (a *Activities) GenerateRDFGraph(ctx context.Context, state BuildCodeGraphS
 		return state, fmt.Errorf("failed to read prompt file: %w", err)
 	}
 
-	// 4. Build the file list string.
-	fileListStr := strings.Join(files, "\n")
-
-	// 5. Look for language-specific configuration files and combine their contents.
+	// 4. Look for language-specific configuration files and combine their contents.
 	configFiles := []string{"go.mod", "build.gradle", "packages.json", "requirements.txt"}
 	var additionalInfoBuilder strings.Builder
 	for _, fileName := range configFiles {
@@ -48,24 +55,52 @@ func (a *Activities) GenerateRDFGraph(ctx context.Context, state BuildCodeGraphS
 		additionalInfo = "None"
 	}
 
-	// 6. Substitute placeholders in the prompt template.
-	prompt := strings.ReplaceAll(promptTemplate, "{{.FileList}}", fileListStr)
-	prompt = strings.ReplaceAll(prompt, "{{.AdditionalInfo}}", additionalInfo)
-	prompt = strings.ReplaceAll(prompt, "{{.RepoURL}}", state.RepoURL)
+	// 5. Substitute placeholders in the prompt template.
+	render := func(fileList string) string {
+		prompt := strings.ReplaceAll(promptTemplate, "{{.FileList}}", fileList)
+		prompt = strings.ReplaceAll(prompt, "{{.AdditionalInfo}}", additionalInfo)
+		prompt = strings.ReplaceAll(prompt, "{{.RepoURL}}", state.RepoURL)
+		prompt = strings.ReplaceAll(prompt, "{{.Ontology}}", ontology.Summary())
+		return strings.ReplaceAll(prompt, "{{.RepositoryURI}}", string(ontology.RepositoryURI(state.RepoURL)))
+	}
 
-	// 7. Call OpenAI API using GPT-4o to generate RDF.
-	response, err := CallOpenAI(ctx, prompt)
+	// 6. Build the file list, summarised by directory or split into chunks if it is over the prompt budget.
+	client, cfg, err := a.llmClient(generateRdfGraphLLM)
 	if err != nil {
-		return state, fmt.Errorf("OpenAI call failed: %w", err)
+		return state, err
 	}
-
-	response, err = ExtractTurtleRDF(response)
+	fileLists, err := fitFileList(files, func(fileList string) bool { return cfg.Fits(render(fileList)) })
 	if err != nil {
-		return state, fmt.Errorf("failed to extract Turtle RDF: %w", err)
+		return state, temporal.NewNonRetryableApplicationError("the repository prompt is too large", LLMRequestRejected, err)
+	}
+
+	// 7. Call the LLM to generate RDF for each file list, repairing it until it validates, and merge the
+	// graphs.
+	promptVersion := provenance.PromptVersion(filepath.Base(promptFilePath), promptTemplate)
+	graph := rdf.NewGraph()
+	for _, fileList := range fileLists {
+		part, response, err := generateValidatedRdf(ctx, client, render(fileList), cfg.MaxTokens)
+		if err != nil {
+			return state, llmActivityError(fmt.Errorf("failed to generate repository RDF: %w", err))
+		}
+		ontology.Canonicalize(part)
+		provenance.RecordRemaining(part, provenance.Source{
+			Repository:    state.RepoURL,
+			Commit:        state.Commit,
+			Activity:      "GenerateRDFGraph",
+			Model:         response.Model,
+			PromptVersion: promptVersion,
+			ResponseID:    response.ID,
+			Evidence:      provenance.LLMInference,
+		})
+		merge := mergeFragment(graph, part)
+		for _, conflict := range merge.conflicts {
+			fmt.Printf("Dropped conflicting triple from a part of the repository graph: %s\n", conflict)
+		}
 	}
 
 	// 8. Write the RDF content to a file.
-	rdfPath, err := WriteStringToFile(response, "", "repo_metadata_*.ttl")
+	rdfPath, err := WriteStringToFile(rdf.ToTurtle(graph), "", "repo_metadata_*.ttl")
 	if err != nil {
 		return state, fmt.Errorf("failed to write RDF file: %w", err)
 	}
@@ -0,0 +1,118 @@
+package buildcodegraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"go.temporal.io/sdk/temporal"
+)
+
+// inRepoRoot runs a test from the repository root, where the activities find their prompts.
+func inRepoRoot(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+}
+
+func TestGenerateRDFGraph(t *testing.T) {
+	inRepoRoot(t)
+	repoURL := "https://github.com/example/orders"
+	repo := writeRepo(t, map[string]string{
+		"go.mod":  "module github.com/example/orders\n\ngo 1.22\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	node := ontology.RepositoryURI(repoURL)
+	valid := fmt.Sprintf("```turtle\n@prefix gm: <http://graphmind.io/ontology#> .\n%s a gm:Repository ;\n  gm:repoUrl \"%s\" ;\n  gm:language \"Go\" ;\n  gm:name \"orders\" .\n```", node, repoURL)
+
+	tests := []struct {
+		name      string
+		rules     []llm.Rule
+		wantCalls int
+		wantFail  bool
+		wantError string // The Temporal error type of a failure, if it has one.
+	}{
+		{
+			name:      "valid",
+			rules:     []llm.Rule{{Contains: "module github.com/example/orders", Text: valid}},
+			wantCalls: 1,
+		},
+		{
+			name: "repaired",
+			rules: []llm.Rule{
+				{Contains: "Problems Found", Text: valid},
+				{Contains: "module github.com/example/orders", Text: "The repository is a Go service."},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:      "rejected",
+			rules:     []llm.Rule{{Text: "The repository is a Go service."}},
+			wantCalls: 1 + maxRdfRepairAttempts,
+			wantFail:  true,
+			wantError: LLMOutputRejected,
+		},
+		{
+			name:      "failing LLM",
+			rules:     []llm.Rule{{Error: "connection refused"}},
+			wantCalls: 1,
+			wantFail:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := llm.NewFake(tt.rules...)
+			a := &Activities{LLM: fake}
+			state, err := a.GenerateRDFGraph(context.Background(), BuildCodeGraphState{RepoURL: repoURL, LocalRepoPath: repo, Commit: "abc1234"})
+			if got := len(fake.Requests()); got != tt.wantCalls {
+				t.Errorf("the LLM was called %d time(s), want %d", got, tt.wantCalls)
+			}
+			if tt.wantFail {
+				if err == nil {
+					t.Fatal("GenerateRDFGraph succeeded")
+				}
+				var appErr *temporal.ApplicationError
+				if tt.wantError != "" && (!errors.As(err, &appErr) || appErr.Type() != tt.wantError || !appErr.NonRetryable()) {
+					t.Fatalf("error = %v, want a non-retryable %s", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(state.RepoRdfGraph)
+
+			graph, err := rdf.ParseTurtleFile(state.RepoRdfGraph)
+			if err != nil {
+				t.Fatal(err)
+			}
+			language := rdf.Triple{Subject: node, Predicate: ontology.Language, Object: rdf.NewLiteral("Go")}
+			if !graph.Contains(language) {
+				t.Fatalf("the graph lacks %s:\n%s", language, rdf.ToTurtle(graph))
+			}
+			sources := provenance.Sources(graph, language)
+			if len(sources) != 1 || sources[0].Activity != "GenerateRDFGraph" || sources[0].Model != llm.FakeProvider || sources[0].Commit != "abc1234" {
+				t.Errorf("sources = %+v, want one from GenerateRDFGraph by the fake at abc1234", sources)
+			}
+
+			prompt := fake.Requests()[0].Prompt
+			for _, want := range []string{"gm:Repository", "main.go", repoURL} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("the prompt lacks %q", want)
+				}
+			}
+		})
+	}
+}
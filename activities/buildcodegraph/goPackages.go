@@ -0,0 +1,242 @@
+package buildcodegraph
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// goRequirePattern matches the module requirements of a go.mod file, in require blocks or on one line.
+var goRequirePattern = regexp.MustCompile(`(?m)^\s*(?:require\s+)?([\w.~/-]+)\s+(v[\w.+-]+)\s*(?://.*)?$`)
+
+// goModule is a Go module whose go.mod is in the repository.
+type goModule struct {
+	path     string            // The module path.
+	dir      string            // The directory of its go.mod, relative to the repository root, with forward slashes.
+	requires map[string]string // The versions of the required modules, keyed by module path.
+}
+
+// goPackages tells the import paths of the repository's Go files, whether an import path is a package
+// generated by protoc-gen-go-grpc and which services it declares. Packages are found in the repository
+// itself, in its vendor directories and in the module cache.
+type goPackages struct {
+	repoPath string
+	modules  []goModule
+	grpc     map[string]bool              // Whether an import path holds generated gRPC code, for the paths looked up.
+	services map[string]map[string]string // The services declared by an import path, by unqualified name, for the paths looked up.
+}
+
+func newGoPackages(repoPath string) *goPackages {
+	p := &goPackages{repoPath: repoPath, grpc: map[string]bool{}, services: map[string]map[string]string{}}
+	_ = filepath.WalkDir(repoPath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable paths
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "vendor", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil
+		}
+		m := goModulePattern.FindSubmatch(content)
+		if m == nil {
+			return nil
+		}
+		dir, _ := filepath.Rel(repoPath, filepath.Dir(file))
+		module := goModule{path: string(m[1]), dir: filepath.ToSlash(dir), requires: map[string]string{}}
+		for _, require := range goRequirePattern.FindAllSubmatch(content, -1) {
+			module.requires[string(require[1])] = string(require[2])
+		}
+		p.modules = append(p.modules, module)
+		return nil
+	})
+	return p
+}
+
+// importPath returns the import path of the package of a file, given by its path relative to the
+// repository root: the path of the nearest enclosing module followed by the file's directory within it.
+// Without a module, the directory itself is returned.
+func (p *goPackages) importPath(file string) string {
+	dir := path.Dir(filepath.ToSlash(file))
+	depth := func(module *goModule) int {
+		if module.dir == "." {
+			return 0
+		}
+		return len(module.dir)
+	}
+	var nearest *goModule
+	for i := range p.modules {
+		module := &p.modules[i]
+		if module.dir == "." || dir == module.dir || strings.HasPrefix(dir, module.dir+"/") {
+			if nearest == nil || depth(module) > depth(nearest) {
+				nearest = module
+			}
+		}
+	}
+	if nearest == nil {
+		return dir
+	}
+	rel := dir
+	if nearest.dir != "." {
+		rel = strings.TrimPrefix(strings.TrimPrefix(dir, nearest.dir), "/")
+	}
+	if rel == "." || rel == "" {
+		return nearest.path
+	}
+	return nearest.path + "/" + rel
+}
+
+// isGrpc reports whether the package with an import path holds generated gRPC code, a *.pb.go file with
+// a grpc.ServiceDesc. A package whose source cannot be found is taken for a gRPC package only if its
+// import path says so, with a last element ending in "pb" or a "proto" or "grpc" element.
+func (p *goPackages) isGrpc(importPath string) bool {
+	if grpc, ok := p.grpc[importPath]; ok {
+		return grpc
+	}
+	grpc, found := false, false
+	for _, dir := range p.packageDirs(importPath) {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			grpc, found = hasGrpcCode(dir), true
+			break
+		}
+	}
+	if !found {
+		grpc = looksLikeGrpcPackage(importPath)
+	}
+	p.grpc[importPath] = grpc
+	return grpc
+}
+
+// protoService returns the fully qualified name of the service with an unqualified name whose client the
+// generated gRPC package with an import path declares, or "" if the package cannot be found.
+func (p *goPackages) protoService(importPath, service string) string {
+	services, ok := p.services[importPath]
+	if !ok {
+		services = map[string]string{}
+		for _, dir := range p.packageDirs(importPath) {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			files, _ := filepath.Glob(filepath.Join(dir, "*.pb.go"))
+			for _, file := range files {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					continue
+				}
+				for fullName := range parseGrpcServiceDescs(string(content)) {
+					services[shortServiceName(fullName)] = fullName
+				}
+			}
+			break
+		}
+		p.services[importPath] = services
+	}
+	return services[service]
+}
+
+// packageDirs returns the directories a package may be in: its module in the repository, the vendor
+// directories and the module cache.
+func (p *goPackages) packageDirs(importPath string) []string {
+	var dirs []string
+	for _, module := range p.modules {
+		if rest, ok := pathWithin(importPath, module.path); ok {
+			dirs = append(dirs, filepath.Join(p.repoPath, filepath.FromSlash(module.dir), filepath.FromSlash(rest)))
+		}
+	}
+	for _, module := range p.modules {
+		dirs = append(dirs, filepath.Join(p.repoPath, filepath.FromSlash(module.dir), "vendor", filepath.FromSlash(importPath)))
+	}
+	if cache := goModCache(); cache != "" {
+		for _, module := range p.modules {
+			best := ""
+			for required := range module.requires {
+				if _, ok := pathWithin(importPath, required); ok && len(required) > len(best) {
+					best = required
+				}
+			}
+			if best != "" {
+				rest, _ := pathWithin(importPath, best)
+				dirs = append(dirs, filepath.Join(cache, filepath.FromSlash(escapeModulePath(best)+"@"+module.requires[best]), filepath.FromSlash(rest)))
+			}
+		}
+	}
+	return dirs
+}
+
+// pathWithin returns the rest of an import path within a module path, if the package is in the module.
+func pathWithin(importPath, modulePath string) (string, bool) {
+	if importPath == modulePath {
+		return "", true
+	}
+	if strings.HasPrefix(importPath, modulePath+"/") {
+		return importPath[len(modulePath)+1:], true
+	}
+	return "", false
+}
+
+// hasGrpcCode reports whether a directory has a generated *.pb.go file declaring a grpc.ServiceDesc, as
+// *_grpc.pb.go files, and the *.pb.go files of older generators, do.
+func hasGrpcCode(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.pb.go"))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err == nil && strings.Contains(string(content), "grpc.ServiceDesc") {
+			return true
+		}
+	}
+	return false
+}
+
+func looksLikeGrpcPackage(importPath string) bool {
+	elements := strings.Split(importPath, "/")
+	if strings.HasSuffix(elements[len(elements)-1], "pb") {
+		return true
+	}
+	for _, element := range elements[1:] {
+		if strings.Contains(element, "proto") || strings.Contains(element, "grpc") {
+			return true
+		}
+	}
+	return false
+}
+
+// goModCache returns the module cache directory: GOMODCACHE, or else pkg/mod of the first GOPATH entry or
+// of ~/go.
+func goModCache() string {
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
+
+// escapeModulePath escapes a module path as the module cache does: every upper case letter becomes "!"
+// followed by the letter in lower case.
+func escapeModulePath(modulePath string) string {
+	var b strings.Builder
+	for _, r := range modulePath {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
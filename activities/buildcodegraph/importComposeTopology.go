@@ -0,0 +1,484 @@
+package buildcodegraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"gopkg.in/yaml.v3"
+)
+
+const composeNamespace = "http://graphmind.io/compose/"
+
+// composeFile is the subset of the docker-compose file format GraphMind reads.
+type composeFile struct {
+	Name     string                       `yaml:"name"`
+	Services map[string]composeService    `yaml:"services"`
+	Networks map[string]*composeNetworkIn `yaml:"networks"`
+
+	lines map[string]SourceRange // The lines of every service and network, keyed by "services/<name>" or "networks/<name>".
+}
+
+type composeService struct {
+	Image         string         `yaml:"image"`
+	ContainerName string         `yaml:"container_name"`
+	Build         composeBuild   `yaml:"build"`
+	Environment   composeMapping `yaml:"environment"`
+	DependsOn     composeNames   `yaml:"depends_on"`
+	Networks      composeNames   `yaml:"networks"`
+	Ports         composePorts   `yaml:"ports"`
+}
+
+type composeNetworkIn struct {
+	Driver   string `yaml:"driver"`
+	External bool   `yaml:"external"`
+}
+
+// composeBuild accepts both the short ("build: ./dir") and long ("build: {context: ./dir}") syntax.
+type composeBuild struct {
+	Context    string `yaml:"context"`
+	Dockerfile string `yaml:"dockerfile"`
+}
+
+func (b *composeBuild) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Context = node.Value
+		return nil
+	}
+	type plain composeBuild
+	return node.Decode((*plain)(b))
+}
+
+// composeMapping accepts both the map ("KEY: value") and list ("- KEY=value") syntax.
+type composeMapping map[string]string
+
+func (m *composeMapping) UnmarshalYAML(node *yaml.Node) error {
+	*m = composeMapping{}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			key, value, _ := strings.Cut(item, "=")
+			(*m)[key] = value
+		}
+	case yaml.MappingNode:
+		var items map[string]*string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		for key, value := range items {
+			if value == nil {
+				(*m)[key] = ""
+			} else {
+				(*m)[key] = *value
+			}
+		}
+	}
+	return nil
+}
+
+// composeNames accepts both the list ("- db") and map ("db: {condition: ...}") syntax.
+type composeNames []string
+
+func (n *composeNames) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*n = items
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			*n = append(*n, node.Content[i].Value)
+		}
+	}
+	sort.Strings(*n)
+	return nil
+}
+
+// composePorts accepts both the short ("8080:80") and long ("{target: 80, published: 8080}") syntax.
+type composePorts []string
+
+func (p *composePorts) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode {
+			*p = append(*p, item.Value)
+			continue
+		}
+		var long struct {
+			Target    string `yaml:"target"`
+			Published string `yaml:"published"`
+			Protocol  string `yaml:"protocol"`
+		}
+		if err := item.Decode(&long); err != nil {
+			return err
+		}
+		port := long.Target
+		if long.Published != "" {
+			port = long.Published + ":" + port
+		}
+		if long.Protocol != "" {
+			port += "/" + long.Protocol
+		}
+		*p = append(*p, port)
+	}
+	return nil
+}
+
+// ImportComposeTopology reads docker-compose files, both the ones passed explicitly and the ones found in
+// the cloned repositories, and writes their services, images, environment, dependencies, networks and
+// ports as RDF into the common folder. Compose services are linked to the repositories they deploy and to
+// the service nodes of those repositories, so the deterministic wiring can be checked against the
+// LLM-inferred edges.
+func (a *Activities) ImportComposeTopology(ctx context.Context, results []BuildCodeGraphState, composeFiles []string, commonFolder string) (string, error) {
+	// 1. Collect compose files from the input and from every cloned repository.
+	paths := append([]string{}, composeFiles...)
+	for _, state := range results {
+		if state.LocalRepoPath == "" {
+			continue
+		}
+		found, err := findComposeFiles(state.LocalRepoPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to search compose files in %s: %w", state.LocalRepoPath, err)
+		}
+		paths = append(paths, found...)
+	}
+
+	topologyPath := filepath.Join(commonFolder, "compose_topology.ttl")
+	if len(paths) == 0 {
+		fmt.Println("No docker-compose files found")
+		// Remove the topology of an earlier build so StoreGraphs does not store it with this one.
+		if err := os.Remove(topologyPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to remove stale compose topology: %w", err)
+		}
+		return "", nil
+	}
+
+	// 2. Find the service nodes each repository's graphs define, which compose services are linked to.
+	services := map[string][]rdf.IRI{}
+	for _, state := range results {
+		services[state.RepoURL] = repoServiceNodes(state)
+	}
+
+	// 3. Parse each compose file and add it to the topology graph.
+	topology := ontology.NewGraph()
+	for _, path := range paths {
+		compose, err := parseComposeFile(path)
+		if err != nil {
+			fmt.Printf("Failed to parse compose file %s: %v\n", path, err)
+			continue
+		}
+		if compose.Name == "" {
+			compose.Name = composeProjectName(path, results)
+		}
+		addComposeTopology(topology, path, compose, results, services)
+	}
+
+	// 4. Write the topology next to the other RDF files so it is merged with them.
+	if err := os.MkdirAll(commonFolder, 0755); err != nil {
+		return "", err
+	}
+	if err := rdf.WriteTurtleFile(topology, topologyPath); err != nil {
+		return "", fmt.Errorf("failed to write compose topology: %w", err)
+	}
+
+	return topologyPath, nil
+}
+
+var composeFilePattern = regexp.MustCompile(`^(docker-)?compose(\.[\w-]+)?\.ya?ml$`)
+
+// findComposeFiles walks the repository and returns every docker-compose file in it.
+func findComposeFiles(repoPath string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "vendor", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if composeFilePattern.MatchString(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func parseComposeFile(path string) (composeFile, error) {
+	var compose composeFile
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return compose, err
+	}
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		return compose, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return compose, err
+	}
+	compose.lines = composeLines(&document)
+	return compose, nil
+}
+
+// composeProjectName names a compose project whose file does not. Docker Compose uses the name of the
+// directory, but every repository is cloned into a directory named "repo", so a file in a cloned repository
+// is named after the repository and its directory within it, for example "orders" or "orders/deploy".
+func composeProjectName(path string, results []BuildCodeGraphState) string {
+	for _, state := range results {
+		if state.LocalRepoPath == "" {
+			continue
+		}
+		rel, err := filepath.Rel(state.LocalRepoPath, filepath.Dir(path))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rel == "." {
+			return repoName(state.RepoURL)
+		}
+		return repoName(state.RepoURL) + "/" + filepath.ToSlash(rel)
+	}
+	return filepath.Base(filepath.Dir(path))
+}
+
+// repoServiceNodes returns the gm:Service nodes built for a repository: the canonical services of its
+// protos and the services its RDF graphs describe.
+func repoServiceNodes(state BuildCodeGraphState) []rdf.IRI {
+	seen := map[rdf.IRI]bool{}
+	for _, service := range state.Services {
+		seen[rdf.IRI(service.URI)] = true
+	}
+	for _, path := range []string{state.RepoRdfGraph, state.AstControlRdfGraph} {
+		if path == "" {
+			continue
+		}
+		g, err := rdf.ParseTurtleFile(path)
+		if err != nil {
+			fmt.Printf("Failed to read services of %s from %s: %v\n", state.RepoURL, path, err)
+			continue
+		}
+		for _, node := range g.Subjects(rdf.RDFType, ontology.Service) {
+			if iri, ok := node.(rdf.IRI); ok {
+				seen[iri] = true
+			}
+		}
+	}
+	nodes := make([]rdf.IRI, 0, len(seen))
+	for node := range seen {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	return nodes
+}
+
+// addComposeTopology adds one compose project to the topology graph. The triples of every service and
+// network point at its lines in the compose file. A compose service that deploys a repository runs the
+// services of that repository, which are listed by repository URL.
+func addComposeTopology(g *rdf.Graph, path string, compose composeFile, results []BuildCodeGraphState, services map[string][]rdf.IRI) {
+	project := rdf.IRI(composeNamespace + uriSegment(compose.Name) + "/")
+	network := func(name string) rdf.IRI { return project + rdf.IRI("network/"+uriSegment(name)) }
+	service := func(name string) rdf.IRI { return project + rdf.IRI("service/"+uriSegment(name)) }
+
+	file := composeFileSource(path, results)
+	source := file
+	add := func(s rdf.Term, p rdf.IRI, o rdf.Term) { provenance.Add(g, s, p, o, source) }
+	at := func(key string) provenance.Source {
+		lines := file
+		if r, ok := compose.lines[key]; ok {
+			lines.StartLine, lines.EndLine = r.StartLine, r.EndLine
+		}
+		return lines
+	}
+
+	add(project, rdf.RDFType, ontology.ComposeProject)
+	add(project, ontology.Name, rdf.NewLiteral(compose.Name))
+	add(project, rdf.RDFSComment, rdf.NewLiteral("Imported from "+path))
+
+	for name, definition := range compose.Networks {
+		source = at("networks/" + name)
+		add(network(name), rdf.RDFType, ontology.Network)
+		add(network(name), ontology.Name, rdf.NewLiteral(name))
+		if definition != nil && definition.Driver != "" {
+			add(network(name), ontology.Driver, rdf.NewLiteral(definition.Driver))
+		}
+	}
+
+	for name, definition := range compose.Services {
+		source = at("services/" + name)
+		node := service(name)
+		add(node, rdf.RDFType, ontology.ComposeService)
+		add(node, ontology.Name, rdf.NewLiteral(name))
+		add(node, ontology.PartOf, project)
+		if definition.Image != "" {
+			add(node, ontology.Image, rdf.NewLiteral(definition.Image))
+		}
+		if definition.ContainerName != "" {
+			add(node, ontology.ContainerName, rdf.NewLiteral(definition.ContainerName))
+		}
+		for _, port := range definition.Ports {
+			add(node, ontology.ExposesPort, rdf.NewLiteral(port))
+		}
+		for _, dependency := range definition.DependsOn {
+			add(node, ontology.DependsOn, service(dependency))
+		}
+		for _, attached := range definition.Networks {
+			add(node, ontology.AttachedTo, network(attached))
+		}
+
+		// Environment variables get stable IRIs so re-imports do not duplicate them.
+		for key, value := range definition.Environment {
+			variable := node + rdf.IRI("/env/"+uriSegment(key))
+			add(node, ontology.Environment, variable)
+			add(variable, rdf.RDFType, ontology.EnvironmentVariable)
+			add(variable, ontology.Key, rdf.NewLiteral(key))
+			add(variable, ontology.Value, rdf.NewLiteral(redactEnvValue(key, value)))
+		}
+
+		if repoURL := matchComposeServiceToRepo(path, name, definition, results); repoURL != "" {
+			repo := ontology.RepositoryURI(repoURL)
+			add(node, ontology.DeploysRepository, repo)
+			add(repo, rdf.RDFType, ontology.Repository)
+			add(repo, ontology.RepoURL, rdf.NewLiteral(repoURL))
+			for _, runs := range services[repoURL] {
+				add(node, ontology.RunsService, runs)
+			}
+		}
+	}
+}
+
+// composeFileSource returns the provenance of a compose file: the repository it was cloned with, or just
+// its path when it was passed to the workflow.
+func composeFileSource(path string, results []BuildCodeGraphState) provenance.Source {
+	for _, state := range results {
+		if state.LocalRepoPath == "" {
+			continue
+		}
+		rel, err := filepath.Rel(state.LocalRepoPath, path)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return provenance.Source{Repository: state.RepoURL, Commit: state.Commit, File: filepath.ToSlash(rel), Activity: "ImportComposeTopology",
+				Evidence: provenance.Manifest}
+		}
+	}
+	return provenance.Source{File: filepath.ToSlash(path), Activity: "ImportComposeTopology", Evidence: provenance.Manifest}
+}
+
+// composeLines finds the lines of the services and networks of a parsed compose document.
+func composeLines(document *yaml.Node) map[string]SourceRange {
+	lines := map[string]SourceRange{}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return lines
+	}
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		section, entries := root.Content[i].Value, root.Content[i+1]
+		if (section != "services" && section != "networks") || entries.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(entries.Content); j += 2 {
+			key := entries.Content[j]
+			lines[section+"/"+key.Value] = SourceRange{StartLine: key.Line, EndLine: lastLine(entries.Content[j+1])}
+		}
+	}
+	return lines
+}
+
+// lastLine returns the last line a YAML node starts on, including its children.
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		line = max(line, lastLine(child))
+	}
+	return line
+}
+
+// matchComposeServiceToRepo finds the repository a compose service deploys, first by its build context and
+// then by comparing the image and service name with the repository name.
+func matchComposeServiceToRepo(composePath, name string, service composeService, results []BuildCodeGraphState) string {
+	if service.Build.Context != "" {
+		buildPath := service.Build.Context
+		if !filepath.IsAbs(buildPath) {
+			buildPath = filepath.Join(filepath.Dir(composePath), buildPath)
+		}
+		for _, state := range results {
+			if state.LocalRepoPath == "" {
+				continue
+			}
+			rel, err := filepath.Rel(state.LocalRepoPath, buildPath)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				return state.RepoURL
+			}
+		}
+	}
+
+	candidates := []string{normalizeName(name)}
+	if service.Image != "" {
+		image := service.Image
+		if at := strings.Index(image, "@"); at >= 0 {
+			image = image[:at]
+		}
+		image = image[strings.LastIndex(image, "/")+1:]
+		if colon := strings.Index(image, ":"); colon >= 0 {
+			image = image[:colon]
+		}
+		candidates = append([]string{normalizeName(image)}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		for _, state := range results {
+			if candidate != "" && candidate == normalizeName(repoName(state.RepoURL)) {
+				return state.RepoURL
+			}
+		}
+	}
+	return ""
+}
+
+// repoName returns the last path segment of a repository URL without the .git suffix.
+func repoName(repoURL string) string {
+	name := strings.TrimSuffix(strings.TrimRight(repoURL, "/"), ".git")
+	return name[strings.LastIndexAny(name, "/:")+1:]
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]`)
+
+func normalizeName(name string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "")
+}
+
+var secretEnvKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|credential)`)
+
+// redactEnvValue hides values of environment variables that look like secrets.
+func redactEnvValue(key, value string) string {
+	if value != "" && secretEnvKey.MatchString(key) {
+		return "<redacted>"
+	}
+	return value
+}
+
+var unsafeURIChars = regexp.MustCompile(`[^A-Za-z0-9._~-]`)
+
+// uriSegment makes a name safe to use as a single IRI path segment.
+func uriSegment(name string) string {
+	return unsafeURIChars.ReplaceAllString(name, "_")
+}
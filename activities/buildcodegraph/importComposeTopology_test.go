@@ -0,0 +1,189 @@
+package buildcodegraph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+func TestImportComposeTopologyRemovesStaleTopology(t *testing.T) {
+	commonFolder := t.TempDir()
+	stale := filepath.Join(commonFolder, "compose_topology.ttl")
+	if err := os.WriteFile(stale, []byte("<http://example.org/a> <http://example.org/p> <http://example.org/b> .\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := (&Activities{}).ImportComposeTopology(context.Background(), nil, nil, commonFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "" {
+		t.Errorf("path = %q, want none", path)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("the topology of the earlier build is still there: %v", err)
+	}
+}
+
+func TestParseComposeFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string // The definition of a service named "api".
+		want    composeService
+	}{
+		{
+			name:    "list forms",
+			service: "environment:\n      - DB_HOST=db\n      - EMPTY\n      - URL=http://x?a=b\n    depends_on: [db, cache]\n    networks: [front, back]",
+			want: composeService{
+				Environment: composeMapping{"DB_HOST": "db", "EMPTY": "", "URL": "http://x?a=b"},
+				DependsOn:   composeNames{"cache", "db"},
+				Networks:    composeNames{"back", "front"},
+			},
+		},
+		{
+			name:    "map forms",
+			service: "environment:\n      DB_HOST: db\n      PORT: 5432\n      EMPTY:\n    depends_on:\n      db: {condition: service_healthy}\n      cache: {condition: service_started}\n    networks:\n      front:\n      back: {aliases: [api]}",
+			want: composeService{
+				Environment: composeMapping{"DB_HOST": "db", "PORT": "5432", "EMPTY": ""},
+				DependsOn:   composeNames{"cache", "db"},
+				Networks:    composeNames{"back", "front"},
+			},
+		},
+		{
+			name:    "short build and ports",
+			service: "build: ./api\n    ports: [\"8080:80\", \"9090\"]",
+			want:    composeService{Build: composeBuild{Context: "./api"}, Ports: composePorts{"8080:80", "9090"}},
+		},
+		{
+			name:    "long build and ports",
+			service: "build:\n      context: ./api\n      dockerfile: Dockerfile.prod\n    ports:\n      - target: 80\n        published: 8080\n        protocol: udp\n      - target: 90",
+			want: composeService{
+				Build: composeBuild{Context: "./api", Dockerfile: "Dockerfile.prod"},
+				Ports: composePorts{"8080:80/udp", "90"},
+			},
+		},
+		{
+			name:    "image and container",
+			service: "image: ghcr.io/org/api:1.2\n    container_name: api-1",
+			want:    composeService{Image: "ghcr.io/org/api:1.2", ContainerName: "api-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeRepo(t, map[string]string{"docker-compose.yml": "services:\n  api:\n    " + tt.service + "\n"})
+			compose, err := parseComposeFile(filepath.Join(dir, "docker-compose.yml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := compose.Services["api"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("service = %+v, want %+v", got, tt.want)
+			}
+			if lines := compose.lines["services/api"]; lines.StartLine != 2 || lines.EndLine < 3 {
+				t.Errorf("lines = %+v, want from line 2", lines)
+			}
+		})
+	}
+}
+
+func TestRedactEnvValue(t *testing.T) {
+	tests := []struct {
+		key, value, want string
+	}{
+		{"DB_PASSWORD", "hunter2", "<redacted>"},
+		{"github_token", "ghp_x", "<redacted>"},
+		{"STRIPE_APIKEY", "sk_x", "<redacted>"},
+		{"API_KEY", "k", "<redacted>"},
+		{"PRIVATE_KEY", "-----BEGIN", "<redacted>"},
+		{"ClientSecret", "s", "<redacted>"},
+		{"DB_PASSWORD", "", ""},
+		{"DB_HOST", "db", "db"},
+		{"KEYCLOAK_URL", "http://auth", "http://auth"},
+	}
+	for _, tt := range tests {
+		if got := redactEnvValue(tt.key, tt.value); got != tt.want {
+			t.Errorf("redactEnvValue(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMatchComposeServiceToRepo(t *testing.T) {
+	root := t.TempDir()
+	results := []BuildCodeGraphState{
+		{RepoURL: "https://github.com/org/order-service.git", LocalRepoPath: filepath.Join(root, "a", "repo")},
+		{RepoURL: "git@github.com:org/payments.git", LocalRepoPath: filepath.Join(root, "b", "repo")},
+		{RepoURL: "https://github.com/org/infra"},
+	}
+	composePath := filepath.Join(root, "b", "repo", "deploy", "docker-compose.yml")
+	tests := []struct {
+		name    string
+		service string
+		def     composeService
+		want    string
+	}{
+		{"build context", "worker", composeService{Build: composeBuild{Context: "../../../a/repo/cmd"}}, results[0].RepoURL},
+		{"build context of the compose repository", "worker", composeService{Build: composeBuild{Context: ".."}}, results[1].RepoURL},
+		{"image", "api", composeService{Image: "ghcr.io/org/Order_Service:1.2@sha256:abc"}, results[0].RepoURL},
+		{"image before service name", "payments", composeService{Image: "org/order-service"}, results[0].RepoURL},
+		{"service name", "payments", composeService{Image: "postgres:16"}, results[1].RepoURL},
+		{"no match", "cache", composeService{Image: "redis:7"}, ""},
+	}
+	for _, tt := range tests {
+		if got := matchComposeServiceToRepo(composePath, tt.service, tt.def, results); got != tt.want {
+			t.Errorf("%s: matched %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImportComposeTopology(t *testing.T) {
+	// Both repositories are cloned into directories named "repo" and have an unnamed compose file.
+	orders := filepath.Join(writeRepo(t, map[string]string{
+		"repo/docker-compose.yml": "services:\n  api:\n    build: .\n    environment:\n      - DB_PASSWORD=hunter2\n      - DB_HOST=db\n    depends_on: [db]\n  db:\n    image: postgres:16\n",
+	}), "repo")
+	payments := filepath.Join(writeRepo(t, map[string]string{
+		"repo/deploy/compose.yaml": "services:\n  api:\n    image: org/payments:latest\n",
+		"repo/payments.ttl": `@prefix gm: <http://graphmind.io/ontology#> .
+			<http://example.com/payments> a gm:Service ; gm:name "payments" .`,
+	}), "repo")
+	results := []BuildCodeGraphState{
+		{
+			RepoURL: "https://github.com/org/orders", LocalRepoPath: orders, Commit: "abc1234",
+			Services: []CanonicalService{{ProtoService: "orders.Orders", URI: string(ontology.ServiceURI("orders.Orders"))}},
+		},
+		{RepoURL: "https://github.com/org/payments", LocalRepoPath: payments, RepoRdfGraph: filepath.Join(payments, "payments.ttl")},
+	}
+
+	path, err := (&Activities{}).ImportComposeTopology(context.Background(), results, nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rdf.ParseTurtleFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ordersAPI := rdf.IRI(composeNamespace + "orders/service/api")
+	paymentsAPI := rdf.IRI(composeNamespace + "payments_deploy/service/api")
+	for _, want := range []rdf.Triple{
+		{Subject: rdf.IRI(composeNamespace + "orders/"), Predicate: ontology.Name, Object: rdf.NewLiteral("orders")},
+		{Subject: rdf.IRI(composeNamespace + "payments_deploy/"), Predicate: ontology.Name, Object: rdf.NewLiteral("payments/deploy")},
+		{Subject: ordersAPI, Predicate: ontology.DependsOn, Object: rdf.IRI(composeNamespace + "orders/service/db")},
+		{Subject: ordersAPI, Predicate: ontology.DeploysRepository, Object: ontology.RepositoryURI("https://github.com/org/orders")},
+		{Subject: ordersAPI, Predicate: ontology.RunsService, Object: ontology.ServiceURI("orders.Orders")},
+		{Subject: ordersAPI + "/env/DB_PASSWORD", Predicate: ontology.Value, Object: rdf.NewLiteral("<redacted>")},
+		{Subject: ordersAPI + "/env/DB_HOST", Predicate: ontology.Value, Object: rdf.NewLiteral("db")},
+		{Subject: paymentsAPI, Predicate: ontology.DeploysRepository, Object: ontology.RepositoryURI("https://github.com/org/payments")},
+		{Subject: paymentsAPI, Predicate: ontology.RunsService, Object: rdf.IRI("http://example.com/payments")},
+	} {
+		if !g.Contains(want) {
+			t.Errorf("the topology lacks %s", want)
+		}
+	}
+	if runs := g.Objects(rdf.IRI(composeNamespace+"orders/service/db"), ontology.RunsService); len(runs) != 0 {
+		t.Errorf("the database runs %v", runs)
+	}
+}
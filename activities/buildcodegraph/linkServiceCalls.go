@@ -0,0 +1,112 @@
+package buildcodegraph
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// LinkServiceCalls matches the outbound gRPC calls found in every repository to the services defined by
+// the repositories and writes a gm:calls edge between the calling and the called API for each match into
+// the common folder, so the combined graph becomes an end-to-end call graph. Calls to services that none
+// of the repositories define are skipped.
+func (a *Activities) LinkServiceCalls(ctx context.Context, results []BuildCodeGraphState, commonFolder string) (string, error) {
+	// 1. Index the services defined by every repository by their unqualified name.
+	defined := map[string][]definedService{}
+	for _, state := range results {
+		for _, service := range state.Services {
+			name := shortServiceName(service.ProtoService)
+			defined[name] = append(defined[name], definedService{CanonicalService: service, RepoURL: state.RepoURL})
+		}
+	}
+
+	// 2. Match every outbound call to the API it calls.
+	links := ontology.NewGraph()
+	linked := 0
+	for _, state := range results {
+		for _, call := range state.OutboundCalls {
+			callee := matchCalledService(call, defined[call.Service])
+			if callee == nil {
+				fmt.Printf("No service found for call %s.%s from %s\n", call.Service, call.Rpc, call.CallerApiURI)
+				continue
+			}
+			apiURI, ok := callee.ApiURIs[call.Rpc]
+			if !ok {
+				fmt.Printf("Service %s has no RPC %s called from %s\n", callee.ProtoService, call.Rpc, call.CallerApiURI)
+				continue
+			}
+			provenance.Add(links, rdf.IRI(call.CallerApiURI), ontology.Calls, rdf.IRI(apiURI), provenance.Source{
+				Repository: state.RepoURL,
+				Commit:     state.Commit,
+				File:       call.Site.File,
+				StartLine:  call.Site.StartLine,
+				EndLine:    call.Site.EndLine,
+				Activity:   "LinkServiceCalls",
+				Evidence:   provenance.StaticAnalysis,
+			})
+			linked++
+		}
+	}
+	fmt.Printf("Linked %d service calls\n", linked)
+
+	// 3. Write the links next to the other RDF files so they are merged with them.
+	if err := os.MkdirAll(commonFolder, 0755); err != nil {
+		return "", err
+	}
+	linksPath := filepath.Join(commonFolder, "service_calls.ttl")
+	if err := rdf.WriteTurtleFile(links, linksPath); err != nil {
+		return "", fmt.Errorf("failed to write service calls: %w", err)
+	}
+
+	return linksPath, nil
+}
+
+// definedService is a service together with the repository that defines it.
+type definedService struct {
+	CanonicalService
+	RepoURL string
+}
+
+// matchCalledService picks the service a call refers to among the services with the same unqualified name.
+// The fully qualified name decides when the generated client package declaring it was found, so a service
+// of the caller's own repository does not win over a service of the same name it calls elsewhere.
+// Otherwise the client's import path must point to exactly one of them, through the proto package, the Go
+// module path or the repository path.
+func matchCalledService(call ServiceCall, candidates []definedService) *CanonicalService {
+	if call.ProtoService != "" {
+		for i := range candidates {
+			if candidates[i].ProtoService == call.ProtoService {
+				return &candidates[i].CanonicalService
+			}
+		}
+		return nil
+	}
+	if len(candidates) == 1 {
+		return &candidates[0].CanonicalService
+	}
+
+	importPath := strings.ToLower(call.ImportPath)
+	var match *CanonicalService
+	for i := range candidates {
+		qualifier := strings.ToLower(strings.TrimSuffix(candidates[i].ProtoService, "."+call.Service))
+		repoPath := strings.TrimPrefix(string(ontology.RepositoryURI(candidates[i].RepoURL)), ontology.IDNamespace+"repo/")
+		if importPath == qualifier || strings.HasSuffix(importPath, "/"+qualifier) ||
+			strings.HasPrefix(importPath, qualifier+"/") || strings.HasPrefix(importPath, repoPath+"/") {
+			if match != nil {
+				return nil // ambiguous
+			}
+			match = &candidates[i].CanonicalService
+		}
+	}
+	return match
+}
+
+func shortServiceName(protoService string) string {
+	return protoService[strings.LastIndex(protoService, ".")+1:]
+}
@@ -0,0 +1,44 @@
+package buildcodegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SaiNageswarS/GraphMind/lint"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// LintGraph checks the combined graph for quality problems and writes the report to graph_lint.json next
+// to it. Issues are reported, not failed on, so a build with a flawed graph is still stored and can be
+// inspected. It returns the path of the report.
+func (a *Activities) LintGraph(combinedRdfFilePath string) (string, error) {
+	// 1. Load the combined graph.
+	graph, err := rdf.ParseTurtleFile(combinedRdfFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read combined graph: %w", err)
+	}
+
+	// 2. Check it.
+	report := lint.Lint(graph)
+	fmt.Printf("Graph lint found %d error(s), %d warning(s) and %d info(s)\n", report.Errors, report.Warnings, report.Infos)
+	for _, issue := range report.Issues {
+		if issue.Severity == lint.Error {
+			fmt.Printf("ERROR: %s %s: %s\n", issue.Rule, issue.Node, issue.Message)
+		}
+	}
+
+	// 3. Write the report.
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph lint report: %w", err)
+	}
+	reportPath := filepath.Join(filepath.Dir(combinedRdfFilePath), "graph_lint.json")
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write graph lint report: %w", err)
+	}
+	fmt.Printf("Graph lint report saved to %s\n", reportPath)
+
+	return reportPath, nil
+}
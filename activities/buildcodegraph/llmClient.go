@@ -0,0 +1,54 @@
+package buildcodegraph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Temporal error types of failed LLM calls.
+const (
+	LLMConfigurationError = "LLMConfigurationError" // The LLM of an activity is misconfigured, for example without an API key.
+	LLMRequestRejected    = "LLMRequestRejected"    // The API rejected a request it will reject again, for example with 401 or 400.
+	LLMUnavailable        = "LLMUnavailable"        // The API was rate limited, overloaded or failing after the client's own retries.
+	LLMOutputRejected     = "LLMOutputRejected"     // The LLM's output stayed invalid after all repair attempts.
+)
+
+// llmClient returns the LLM of an activity with its configuration, which sets the prompt budget: a.LLM if
+// set, and otherwise the client configured for the activity, see llm.Load. A misconfigured LLM fails the
+// activity without retries.
+func (a *Activities) llmClient(defaults llm.Config) (llm.Client, llm.Config, error) {
+	cfg := llm.Load(defaults)
+	if a.LLM != nil {
+		return a.LLM, cfg, nil
+	}
+	client, err := llm.New(cfg)
+	if err != nil {
+		return nil, cfg, temporal.NewNonRetryableApplicationError(fmt.Sprintf("failed to create the LLM client of %s", defaults.Use), LLMConfigurationError, err)
+	}
+	return client, cfg, nil
+}
+
+// llmActivityError classifies an activity error caused by an LLM API for Temporal. Errors the API will
+// return again become non-retryable, so the workflow fails at once instead of retrying, and retryable ones
+// ask Temporal to wait as long as the API's Retry-After asked. Output rejected after all repair attempts
+// is non-retryable too: a retry would get the same cached responses. Other errors are returned as they are.
+func llmActivityError(err error) error {
+	var validationErr *RdfValidationError
+	if errors.As(err, &validationErr) {
+		return temporal.NewNonRetryableApplicationError("the LLM output is rejected", LLMOutputRejected, err)
+	}
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	if !apiErr.Retryable() {
+		return temporal.NewNonRetryableApplicationError("the LLM API rejected the request", LLMRequestRejected, err)
+	}
+	return temporal.NewApplicationErrorWithOptions("the LLM API is unavailable", LLMUnavailable, temporal.ApplicationErrorOptions{
+		Cause:          err,
+		NextRetryDelay: apiErr.RetryAfter,
+	})
+}
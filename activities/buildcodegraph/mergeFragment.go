@@ -0,0 +1,108 @@
+package buildcodegraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// singleValued are the properties the SHACL shapes allow once per node. A fragment cannot change the
+// value the repository graph already has for them.
+var singleValued = map[rdf.IRI]bool{
+	ontology.RepoURL:      true,
+	ontology.Name:         true,
+	ontology.DatabaseType: true,
+	ontology.ResourceType: true,
+}
+
+// fragmentMerge counts what merging a fragment into the repository graph did.
+type fragmentMerge struct {
+	added     int      // Triples new to the repository graph.
+	existing  int      // Triples the repository graph already had.
+	conflicts []string // Triples dropped because they contradict a single-valued property of the graph.
+}
+
+// mergeFragment adds the triples of a fragment, such as the RDF of an API or of a part of a file list,
+// with their provenance, to the repository graph.
+// The merge only adds: a triple giving a single-valued property a second value is dropped and reported
+// instead, so no fact of the repository graph or of an earlier fragment can be lost or rewritten. Blank
+// nodes of the fragment get fresh labels, as with rdf.Graph.Merge, so they never join blank nodes of the
+// graph that happen to share a label.
+func mergeFragment(repo, fragment *rdf.Graph) fragmentMerge {
+	blanks := map[rdf.BlankNode]rdf.BlankNode{}
+	relabel := func(term rdf.Term) rdf.Term {
+		b, ok := term.(rdf.BlankNode)
+		if !ok {
+			return term
+		}
+		if _, ok := blanks[b]; !ok {
+			blanks[b] = repo.NewBlankNode()
+		}
+		return blanks[b]
+	}
+
+	var merge fragmentMerge
+	for _, t := range provenance.Strip(fragment).Triples() {
+		merged := rdf.Triple{Subject: relabel(t.Subject), Predicate: t.Predicate, Object: relabel(t.Object)}
+		if repo.Contains(merged) {
+			merge.existing++
+		} else if current := repo.Object(merged.Subject, merged.Predicate); singleValued[merged.Predicate] && current != nil {
+			merge.conflicts = append(merge.conflicts, fmt.Sprintf("%s (the graph has %s)", strings.TrimSuffix(merged.String(), " ."), current))
+			continue
+		} else {
+			repo.Add(merged)
+			merge.added++
+		}
+		for _, source := range provenance.Sources(fragment, t) {
+			provenance.Record(repo, merged, source)
+		}
+	}
+	for prefix, namespace := range fragment.Prefixes {
+		if _, ok := repo.Prefixes[prefix]; !ok {
+			repo.BindPrefix(prefix, namespace)
+		}
+	}
+	return merge
+}
+
+// knownNodeClasses are the classes of the nodes an API fragment may refer to, in the order they are listed.
+var knownNodeClasses = []rdf.IRI{
+	ontology.Repository,
+	ontology.Service,
+	ontology.Api,
+	ontology.Database,
+	ontology.Collection,
+	ontology.CloudResource,
+	ontology.Topic,
+	ontology.ConfigKey,
+}
+
+// knownNodesPrompt lists the nodes of the repository graph one per line with their class and name, so the
+// LLM refers to them by URI instead of being sent, and repeating, the whole graph.
+func knownNodesPrompt(repo *rdf.Graph) string {
+	var lines []string
+	for _, class := range knownNodeClasses {
+		var nodes []string
+		for _, node := range repo.Subjects(rdf.RDFType, class) {
+			iri, ok := node.(rdf.IRI)
+			if !ok {
+				continue
+			}
+			line := fmt.Sprintf("%s (gm:%s)", iri, rdf.LocalName(class))
+			if name := repo.Object(iri, ontology.Name); name != nil {
+				line += " " + rdf.Value(name)
+			}
+			nodes = append(nodes, line)
+		}
+		sort.Strings(nodes)
+		lines = append(lines, nodes...)
+	}
+	if len(lines) == 0 {
+		return "None"
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,57 @@
+package buildcodegraph
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+func mustParse(t *testing.T, turtle string) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(turtle, "")
+	if err != nil {
+		t.Fatalf("ParseTurtle: %v", err)
+	}
+	return g
+}
+
+func TestMergeFragmentKeepsBlankNodesApart(t *testing.T) {
+	repo := rdf.NewGraph()
+	mergeFragment(repo, mustParse(t, `@prefix ex: <http://example.org/> . ex:a ex:p [ ex:name "A" ] .`))
+	mergeFragment(repo, mustParse(t, `@prefix ex: <http://example.org/> . ex:b ex:p [ ex:name "B" ] .`))
+
+	name := rdf.IRI("http://example.org/name")
+	for _, subject := range []rdf.IRI{"http://example.org/a", "http://example.org/b"} {
+		blank := repo.Object(subject, "http://example.org/p")
+		if blank == nil {
+			t.Fatalf("%s has no ex:p", subject)
+		}
+		if names := repo.Objects(blank, name); len(names) != 1 {
+			t.Errorf("the blank node of %s has names %v, want one", subject, names)
+		}
+	}
+}
+
+func TestMergeFragment(t *testing.T) {
+	repo := mustParse(t, `@prefix gm: <http://graphmind.io/ontology#> .
+<http://graphmind.io/id/service/shop.Orders> a gm:Service ; gm:name "Orders" .`)
+	fragment := mustParse(t, `@prefix gm: <http://graphmind.io/ontology#> .
+<http://graphmind.io/id/service/shop.Orders> a gm:Service ; gm:name "OrderService" ;
+    gm:hasApi <http://graphmind.io/id/service/shop.Orders/Get> .`)
+	source := provenance.Source{Repository: "https://github.com/x/shop", Activity: "BuildAstRdf", Evidence: provenance.LLMInference}
+	provenance.RecordRemaining(fragment, source)
+
+	merge := mergeFragment(repo, fragment)
+	if merge.added != 1 || merge.existing != 1 || len(merge.conflicts) != 1 {
+		t.Fatalf("merge = %+v, want 1 added, 1 existing and 1 conflict", merge)
+	}
+	if got := rdf.Value(repo.Object(rdf.IRI("http://graphmind.io/id/service/shop.Orders"), ontology.Name)); got != "Orders" {
+		t.Errorf("name = %q, want the name the graph had", got)
+	}
+	added := rdf.Triple{Subject: rdf.IRI("http://graphmind.io/id/service/shop.Orders"), Predicate: ontology.HasApi, Object: rdf.IRI("http://graphmind.io/id/service/shop.Orders/Get")}
+	if sources := provenance.Sources(repo, added); len(sources) != 1 || sources[0].Activity != "BuildAstRdf" {
+		t.Errorf("sources of the added triple = %+v, want the fragment's source", sources)
+	}
+}
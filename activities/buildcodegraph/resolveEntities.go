@@ -0,0 +1,51 @@
+package buildcodegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/resolve"
+)
+
+// ResolveEntities merges nodes of the combined graph that describe the same entity under different URIs.
+// High-confidence merges are applied to the combined graph in place; lower-confidence candidates are
+// written, together with the applied merges, to entity_review.json next to it. It returns the path of the
+// review file.
+func (a *Activities) ResolveEntities(combinedRdfFilePath string) (string, error) {
+	// 1. Load the combined graph.
+	graph, err := rdf.ParseTurtleFile(combinedRdfFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read combined graph: %w", err)
+	}
+
+	// 2. Merge duplicate entities.
+	result := resolve.Resolve(graph, resolve.DefaultOptions())
+	fmt.Printf("Merged %d duplicate entities, %d candidates need review\n", len(result.Merged), len(result.Review))
+
+	// 3. Save the resolved graph over the combined graph. The owl:sameAs links and aliases the merges
+	// added are attributed to this activity.
+	if len(result.Merged) > 0 {
+		provenance.Prune(graph)
+		provenance.RecordRemaining(graph, provenance.Source{Activity: "ResolveEntities"})
+		if err := rdf.WriteTurtleFile(graph, combinedRdfFilePath); err != nil {
+			return "", fmt.Errorf("failed to write resolved graph: %w", err)
+		}
+	}
+
+	// 4. Write the review file.
+	report, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode entity review: %w", err)
+	}
+	reviewFilePath := filepath.Join(filepath.Dir(combinedRdfFilePath), "entity_review.json")
+	if err := os.WriteFile(reviewFilePath, report, 0644); err != nil {
+		return "", fmt.Errorf("failed to write entity review: %w", err)
+	}
+	fmt.Printf("Entity review saved to %s\n", reviewFilePath)
+
+	return reviewFilePath, nil
+}
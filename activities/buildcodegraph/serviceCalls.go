@@ -0,0 +1,429 @@
+package buildcodegraph
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ServiceCall is an RPC that one of the repository's APIs makes through a generated gRPC client.
+type ServiceCall struct {
+	CallerApiURI string      // The canonical URI of the calling API.
+	Service      string      // The unqualified name of the called service, for example "Login".
+	ProtoService string      // The fully qualified name of the called service, if its generated client package is found.
+	ImportPath   string      // The import path of the generated client package.
+	Rpc          string      // The called RPC.
+	Site         SourceRange // The call expression.
+}
+
+// grpcClient identifies a generated gRPC client by the service it calls.
+type grpcClient struct {
+	Service    string
+	ImportPath string
+}
+
+var (
+	clientConstructorName = regexp.MustCompile(`^New(\w+)Client$`)
+	clientTypeName        = regexp.MustCompile(`^(\w+)Client$`)
+)
+
+// clientBindings records the package variables, struct fields and functions that hold or return gRPC
+// clients anywhere in the repository. Types are not checked, so bindings are matched by name.
+type clientBindings struct {
+	vars   map[string]grpcClient
+	fields map[string]grpcClient
+	funcs  map[string]grpcClient
+}
+
+// fileScope is what the code of a file refers to by name.
+type fileScope struct {
+	pkg     string            // The import path of the file's package.
+	imports map[string]string // The import path of every import, keyed by the name the file refers to it by.
+	clients map[string]string // The imports that are generated gRPC packages, the only ones whose types are clients.
+}
+
+func newFileScope(file *ast.File, fileName string, packages *goPackages) *fileScope {
+	scope := &fileScope{pkg: packages.importPath(fileName), imports: fileImports(file), clients: map[string]string{}}
+	for name, path := range scope.imports {
+		if packages.isGrpc(path) {
+			scope.clients[name] = path
+		}
+	}
+	return scope
+}
+
+// funcCalls is what one function does that matters for call linking: the RPCs it calls directly and the
+// functions of the repository it calls, which may call RPCs in turn.
+type funcCalls struct {
+	rpcs    []ServiceCall
+	callees []string
+}
+
+// findServiceCalls finds the RPCs every API of the registered services calls through generated gRPC
+// clients (pb.NewXxxClient), following calls into helper functions and methods of the repository. site
+// locates the call expressions in the repository, and packages tells the import paths of its files, which
+// imported packages are generated gRPC code and the fully qualified names of the services they declare.
+func findServiceCalls(files []*ast.File, services []ServiceInfo, canonical []CanonicalService, site func(ast.Node) SourceRange, packages *goPackages) []ServiceCall {
+	scopes := map[*ast.File]*fileScope{}
+	for _, file := range files {
+		scopes[file] = newFileScope(file, site(file).File, packages)
+	}
+	bindings := collectClientBindings(files, scopes)
+
+	// Index what every function of the repository calls.
+	calls := map[string]*funcCalls{}
+	keys := map[*ast.FuncDecl]string{}
+	for _, file := range files {
+		scope := scopes[file]
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			key := funcKey(scope.pkg, fn)
+			keys[fn] = key
+			calls[key] = collectFuncCalls(scope, fn, bindings, site)
+		}
+	}
+
+	var result []ServiceCall
+	seen := map[ServiceCall]bool{}
+	for _, service := range services {
+		canonicalService := findServiceByName(canonical, service.Name)
+		if canonicalService == nil {
+			continue
+		}
+		for _, method := range service.Methods {
+			callerURI, ok := canonicalService.ApiURIs[method.Name.Name]
+			if !ok {
+				continue // not an RPC
+			}
+			for _, call := range reachableRpcs(keys[method], calls) {
+				call.CallerApiURI = callerURI
+				call.ProtoService = packages.protoService(call.ImportPath, call.Service)
+				if !seen[call] {
+					seen[call] = true
+					result = append(result, call)
+				}
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CallerApiURI != result[j].CallerApiURI {
+			return result[i].CallerApiURI < result[j].CallerApiURI
+		}
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+		if result[i].Rpc != result[j].Rpc {
+			return result[i].Rpc < result[j].Rpc
+		}
+		if result[i].Site.File != result[j].Site.File {
+			return result[i].Site.File < result[j].Site.File
+		}
+		return result[i].Site.StartLine < result[j].Site.StartLine
+	})
+	return result
+}
+
+// reachableRpcs returns the RPCs called by a function or any repository function it calls.
+func reachableRpcs(key string, calls map[string]*funcCalls) []ServiceCall {
+	var rpcs []ServiceCall
+	visited := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		fn, ok := calls[key]
+		if !ok || visited[key] {
+			return
+		}
+		visited[key] = true
+		rpcs = append(rpcs, fn.rpcs...)
+		for _, callee := range fn.callees {
+			visit(callee)
+		}
+	}
+	visit(key)
+	return rpcs
+}
+
+// collectClientBindings finds the package variables, struct fields and function results typed as or
+// assigned from gRPC clients.
+func collectClientBindings(files []*ast.File, scopes map[*ast.File]*fileScope) clientBindings {
+	bindings := clientBindings{
+		vars:   map[string]grpcClient{},
+		fields: map[string]grpcClient{},
+		funcs:  map[string]grpcClient{},
+	}
+
+	// Package variables are needed to tell assignments to them apart from local variables.
+	packageVars := map[string]bool{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok {
+				for _, spec := range gen.Specs {
+					if value, ok := spec.(*ast.ValueSpec); ok {
+						for _, name := range value.Names {
+							packageVars[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, file := range files {
+		clients := scopes[file].clients
+		for _, decl := range file.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok {
+				for _, spec := range gen.Specs {
+					if value, ok := spec.(*ast.ValueSpec); ok {
+						bindValueSpec(value, clients, bindings.vars)
+					}
+				}
+			}
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.StructType:
+				for _, field := range node.Fields.List {
+					if client, ok := clientFromType(field.Type, clients); ok {
+						for _, name := range field.Names {
+							bindings.fields[name.Name] = client
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if node.Type.Results != nil && len(node.Type.Results.List) > 0 {
+					if client, ok := clientFromType(node.Type.Results.List[0].Type, clients); ok {
+						bindings.funcs[node.Name.Name] = client
+					}
+				}
+			case *ast.AssignStmt:
+				for i, rhs := range node.Rhs {
+					client, ok := clientFromConstructor(rhs, clients)
+					if !ok || i >= len(node.Lhs) {
+						continue
+					}
+					switch lhs := node.Lhs[i].(type) {
+					case *ast.SelectorExpr:
+						bindings.fields[lhs.Sel.Name] = client
+					case *ast.Ident:
+						if packageVars[lhs.Name] && node.Tok == token.ASSIGN {
+							bindings.vars[lhs.Name] = client
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return bindings
+}
+
+// collectFuncCalls lists the RPCs a function calls on gRPC clients and the repository functions it calls.
+func collectFuncCalls(scope *fileScope, fn *ast.FuncDecl, bindings clientBindings, site func(ast.Node) SourceRange) *funcCalls {
+	result := &funcCalls{}
+
+	// Local variables and parameters holding clients.
+	locals := map[string]grpcClient{}
+	for _, param := range fn.Type.Params.List {
+		if client, ok := clientFromType(param.Type, scope.clients); ok {
+			for _, name := range param.Names {
+				locals[name.Name] = client
+			}
+		}
+	}
+
+	recvName, recvType := "", ""
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		recvType = receiverTypeName(fn.Recv.List[0].Type)
+		if len(fn.Recv.List[0].Names) > 0 {
+			recvName = fn.Recv.List[0].Names[0].Name
+		}
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ValueSpec:
+			bindValueSpec(node, scope.clients, locals)
+		case *ast.AssignStmt:
+			for i, rhs := range node.Rhs {
+				if i >= len(node.Lhs) {
+					break
+				}
+				ident, ok := node.Lhs[i].(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if client, ok := resolveClient(rhs, locals, scope, bindings); ok {
+					locals[ident.Name] = client
+				}
+			}
+		case *ast.CallExpr:
+			switch fun := node.Fun.(type) {
+			case *ast.SelectorExpr:
+				if client, ok := resolveClient(fun.X, locals, scope, bindings); ok {
+					result.rpcs = append(result.rpcs, ServiceCall{Service: client.Service, ImportPath: client.ImportPath, Rpc: fun.Sel.Name, Site: site(node)})
+				} else if ident, ok := fun.X.(*ast.Ident); ok {
+					if recvName != "" && ident.Name == recvName {
+						result.callees = append(result.callees, scope.pkg+"."+recvType+"."+fun.Sel.Name)
+					} else if path, ok := scope.imports[ident.Name]; ok {
+						result.callees = append(result.callees, path+"."+fun.Sel.Name)
+					}
+				}
+			case *ast.Ident:
+				result.callees = append(result.callees, scope.pkg+"."+fun.Name)
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// resolveClient reports which gRPC client an expression evaluates to, if any.
+func resolveClient(expr ast.Expr, locals map[string]grpcClient, scope *fileScope, bindings clientBindings) (grpcClient, bool) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return resolveClient(e.X, locals, scope, bindings)
+	case *ast.Ident:
+		if client, ok := locals[e.Name]; ok {
+			return client, true
+		}
+		client, ok := bindings.vars[e.Name]
+		return client, ok
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if _, isPackage := scope.imports[ident.Name]; isPackage {
+				client, ok := bindings.vars[e.Sel.Name]
+				return client, ok
+			}
+		}
+		client, ok := bindings.fields[e.Sel.Name]
+		return client, ok
+	case *ast.CallExpr:
+		if client, ok := clientFromConstructor(e, scope.clients); ok {
+			return client, true
+		}
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			client, ok := bindings.funcs[fun.Name]
+			return client, ok
+		case *ast.SelectorExpr:
+			client, ok := bindings.funcs[fun.Sel.Name]
+			return client, ok
+		}
+	}
+	return grpcClient{}, false
+}
+
+// bindValueSpec records variables declared with a client type or initialised by a client constructor.
+// clients are the generated gRPC packages the file imports, keyed by the name it refers to them by.
+func bindValueSpec(spec *ast.ValueSpec, clients map[string]string, into map[string]grpcClient) {
+	for i, name := range spec.Names {
+		if client, ok := clientFromType(spec.Type, clients); ok {
+			into[name.Name] = client
+		} else if i < len(spec.Values) {
+			if client, ok := clientFromConstructor(spec.Values[i], clients); ok {
+				into[name.Name] = client
+			}
+		}
+	}
+}
+
+// clientFromConstructor matches calls such as pb.NewLoginClient(conn) of a generated gRPC package, one of
+// clients. Constructors of other libraries, like redis.NewUniversalClient, do not match.
+func clientFromConstructor(expr ast.Expr, clients map[string]string) (grpcClient, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return grpcClient{}, false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return grpcClient{}, false
+	}
+	pkg, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return grpcClient{}, false
+	}
+	path, imported := clients[pkg.Name]
+	m := clientConstructorName.FindStringSubmatch(selector.Sel.Name)
+	if !imported || m == nil {
+		return grpcClient{}, false
+	}
+	return grpcClient{Service: m[1], ImportPath: path}, true
+}
+
+// clientFromType matches client types such as pb.LoginClient of a generated gRPC package, one of clients.
+// Types of other client libraries, like redis.UniversalClient or azblob.ContainerClient, do not match.
+func clientFromType(expr ast.Expr, clients map[string]string) (grpcClient, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return grpcClient{}, false
+	}
+	pkg, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return grpcClient{}, false
+	}
+	path, imported := clients[pkg.Name]
+	m := clientTypeName.FindStringSubmatch(selector.Sel.Name)
+	if !imported || m == nil {
+		return grpcClient{}, false
+	}
+	return grpcClient{Service: m[1], ImportPath: path}, true
+}
+
+// fileImports maps the name each import is referred to by in the file to its import path.
+func fileImports(f *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// funcKey names a function or method by the import path of its package, so functions of packages with
+// the same name, like internal/db and pkg/db, are told apart.
+func funcKey(pkg string, fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return pkg + "." + receiverTypeName(fn.Recv.List[0].Type) + "." + fn.Name.Name
+	}
+	return pkg + "." + fn.Name.Name
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(e.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// findServiceByName returns the canonical service implemented by the Go type with the given name.
+func findServiceByName(services []CanonicalService, name string) *CanonicalService {
+	for i := range services {
+		if services[i].Name == name {
+			return &services[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package buildcodegraph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeRepo writes the files of a repository, keyed by their path relative to its root, to a temporary
+// directory and returns it.
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// grpcFile returns a generated *_grpc.pb.go file declaring a service with the given RPCs.
+func grpcFile(pkg, service string, rpcs ...string) string {
+	var methods []string
+	for _, rpc := range rpcs {
+		methods = append(methods, "{MethodName: \""+rpc+"\"}")
+	}
+	return "package " + pkg + "\n\nimport \"google.golang.org/grpc\"\n\n" +
+		"var ServiceDesc = grpc.ServiceDesc{ServiceName: \"" + service + "\", Methods: []grpc.MethodDesc{" + strings.Join(methods, ", ") + "}}\n"
+}
+
+var shopRepo = map[string]string{
+	"go.mod": "module example.com/shop\n\ngo 1.22\n\nrequire (\n\tgithub.com/redis/go-redis/v9 v9.0.0\n)\n",
+	"main.go": `package main
+
+import pb "example.com/shop/orderspb"
+
+func main() {
+	pb.RegisterOrdersServer(server, OrderService)
+}
+`,
+	"orders.go": `package main
+
+import (
+	"context"
+
+	idb "example.com/shop/internal/db"
+	pdb "example.com/shop/pkg/db"
+	"github.com/redis/go-redis/v9"
+)
+
+type OrderService struct {
+	cache redis.UniversalClient
+}
+
+func (s *OrderService) Get(ctx context.Context) error {
+	s.cache.Get(ctx, "order")
+	return idb.Save(ctx)
+}
+
+func (s *OrderService) Put(ctx context.Context) error {
+	return pdb.Save(ctx)
+}
+`,
+	"internal/db/db.go": `package db
+
+import (
+	"context"
+
+	authpb "example.com/shop/authpb"
+)
+
+var auth authpb.LoginClient
+
+func Save(ctx context.Context) error {
+	_, err := auth.Check(ctx, nil)
+	return err
+}
+`,
+	"pkg/db/db.go": `package db
+
+import (
+	"context"
+
+	billingpb "example.com/shop/billingpb"
+)
+
+func Save(ctx context.Context) error {
+	client := billingpb.NewBillingClient(nil)
+	_, err := client.Charge(ctx, nil)
+	return err
+}
+`,
+	"orderspb/orders_grpc.pb.go":   grpcFile("orderspb", "shop.Orders", "Get", "Put"),
+	"authpb/auth_grpc.pb.go":       grpcFile("authpb", "auth.Login", "Check"),
+	"billingpb/billing_grpc.pb.go": grpcFile("billingpb", "billing.Billing", "Charge"),
+}
+
+func TestFindServiceCalls(t *testing.T) {
+	t.Setenv("GOMODCACHE", t.TempDir())
+	repo := writeRepo(t, shopRepo)
+
+	state, err := (&Activities{}).BuildAstControlFlow(context.Background(), BuildCodeGraphState{LocalRepoPath: repo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(state.AstControlFlowFolderPath)
+
+	type call struct{ caller, service, protoService, importPath, rpc string }
+	var got []call
+	for _, c := range state.OutboundCalls {
+		got = append(got, call{filepath.Base(c.CallerApiURI), c.Service, c.ProtoService, c.ImportPath, c.Rpc})
+	}
+	// Get and Put call functions named db.Save of different packages, and the redis client of Get is not a
+	// gRPC client.
+	want := []call{
+		{"Get", "Login", "auth.Login", "example.com/shop/authpb", "Check"},
+		{"Put", "Billing", "billing.Billing", "example.com/shop/billingpb", "Charge"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outbound calls = %+v, want %+v", got, want)
+	}
+}
+
+func TestGoPackages(t *testing.T) {
+	t.Setenv("GOMODCACHE", t.TempDir())
+	repo := writeRepo(t, map[string]string{
+		"go.mod":                      "module example.com/shop\n",
+		"tools/go.mod":                "module example.com/shop/tools\n",
+		"authpb/auth_grpc.pb.go":      grpcFile("authpb", "auth.Login", "Check"),
+		"internal/db/db.go":           "package db\n",
+		"vendor/example.org/api/x.go": "package api\n",
+	})
+	packages := newGoPackages(repo)
+
+	importPaths := map[string]string{
+		"main.go":           "example.com/shop",
+		"internal/db/db.go": "example.com/shop/internal/db",
+		"tools/gen/main.go": "example.com/shop/tools/gen",
+		"tools/main.go":     "example.com/shop/tools",
+	}
+	for file, want := range importPaths {
+		if got := packages.importPath(file); got != want {
+			t.Errorf("importPath(%q) = %q, want %q", file, got, want)
+		}
+	}
+
+	grpc := map[string]bool{
+		"example.com/shop/authpb":                              true,
+		"example.com/shop/internal/db":                         false,
+		"example.org/api":                                      false, // vendored, without generated code
+		"github.com/redis/go-redis/v9":                         false,
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob": false,
+		"github.com/acme/protos/gen/go/billing":                true, // not found, but named like generated code
+		"github.com/acme/apis/billingpb":                       true,
+	}
+	for importPath, want := range grpc {
+		if got := packages.isGrpc(importPath); got != want {
+			t.Errorf("isGrpc(%q) = %v, want %v", importPath, got, want)
+		}
+	}
+}
+
+func TestFindServiceCallsToOtherRepositories(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("GOMODCACHE", cache)
+	writeFile := func(name, content string) {
+		path := filepath.Join(cache, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("example.com/auth@v1.0.0/authpb/auth_grpc.pb.go", grpcFile("authpb", "auth.v1.Login", "Check"))
+
+	// The orders repository has a Login service of its own, but calls the one of the auth module.
+	repo := writeRepo(t, map[string]string{
+		"go.mod": "module example.com/orders\n\ngo 1.22\n\nrequire example.com/auth v1.0.0\n",
+		"main.go": `package main
+
+import (
+	"context"
+
+	authpb "example.com/auth/authpb"
+	billingpb "example.com/billing/billingpb"
+	pb "example.com/orders/orderspb"
+)
+
+type Orders struct {
+	auth    authpb.LoginClient
+	billing billingpb.BillingClient
+}
+
+func (s *Orders) Get(ctx context.Context) error {
+	_, err := s.auth.Check(ctx, nil)
+	return err
+}
+
+func (s *Orders) Pay(ctx context.Context) error {
+	_, err := s.billing.Charge(ctx, nil)
+	return err
+}
+
+func main() {
+	pb.RegisterOrdersServer(server, Orders)
+}
+`,
+		"orderspb/orders_grpc.pb.go": grpcFile("orderspb", "orders.v1.Orders", "Get", "Pay"),
+		"orderspb/login_grpc.pb.go":  grpcFile("orderspb", "orders.v1.Login", "Check"),
+	})
+
+	state, err := (&Activities{}).BuildAstControlFlow(context.Background(), BuildCodeGraphState{LocalRepoPath: repo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(state.AstControlFlowFolderPath)
+
+	got := map[string]string{}
+	for _, call := range state.OutboundCalls {
+		got[call.Service] = call.ProtoService
+	}
+	// The billing client is in no module the repository requires, so its service stays unqualified.
+	want := map[string]string{"Login": "auth.v1.Login", "Billing": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("called services = %v, want %v", got, want)
+	}
+}
+
+func TestMatchCalledService(t *testing.T) {
+	service := func(protoService, repoURL string) definedService {
+		return definedService{CanonicalService: CanonicalService{ProtoService: protoService}, RepoURL: repoURL}
+	}
+	ordersLogin := service("orders.v1.Login", "https://github.com/org/orders")
+	authLogin := service("auth.v1.Login", "https://github.com/org/auth")
+	legacyLogin := service("github.com_org_legacy.Login", "https://github.com/org/legacy")
+
+	tests := []struct {
+		name       string
+		call       ServiceCall
+		candidates []definedService
+		want       string
+	}{
+		{
+			name:       "fully qualified name",
+			call:       ServiceCall{Service: "Login", ProtoService: "auth.v1.Login", ImportPath: "github.com/org/orders/authpb"},
+			candidates: []definedService{ordersLogin, authLogin},
+			want:       "auth.v1.Login",
+		},
+		{
+			name:       "fully qualified name of no repository",
+			call:       ServiceCall{Service: "Login", ProtoService: "sso.Login"},
+			candidates: []definedService{ordersLogin},
+		},
+		{
+			name:       "only candidate",
+			call:       ServiceCall{Service: "Login", ImportPath: "example.com/anything"},
+			candidates: []definedService{authLogin},
+			want:       "auth.v1.Login",
+		},
+		{
+			name:       "proto package in the import path",
+			call:       ServiceCall{Service: "Login", ImportPath: "github.com/acme/gen/auth.v1"},
+			candidates: []definedService{ordersLogin, authLogin},
+			want:       "auth.v1.Login",
+		},
+		{
+			name:       "repository in the import path",
+			call:       ServiceCall{Service: "Login", ImportPath: "github.com/org/legacy/loginpb"},
+			candidates: []definedService{ordersLogin, legacyLogin},
+			want:       "github.com_org_legacy.Login",
+		},
+		{
+			name:       "no match",
+			call:       ServiceCall{Service: "Login", ImportPath: "github.com/org/sso/loginpb"},
+			candidates: []definedService{ordersLogin, authLogin},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if match := matchCalledService(tt.call, tt.candidates); match != nil {
+				got = match.ProtoService
+			}
+			if got != tt.want {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
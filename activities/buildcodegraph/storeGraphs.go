@@ -0,0 +1,162 @@
+package buildcodegraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/resolve"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// StoreGraphs saves a multi-repository build in the graph store as one named graph per repository, holding
+// its API graphs and the gRPC links of its calls and tagged with the branch and commit it was built from,
+// and one graph for the docker-compose topology. The
+// entity merges ResolveEntities applied to the combined graph are applied to every stored graph, so the
+// union of the stored graphs matches the combined graph. It returns the names of the stored graphs.
+func (a *Activities) StoreGraphs(ctx context.Context, results []BuildCodeGraphState, commonFolder, buildID string) ([]string, error) {
+	if a.Store == nil {
+		return nil, fmt.Errorf("graph store is not configured")
+	}
+
+	// 1. Load the graph of every repository.
+	type repoGraph struct {
+		state BuildCodeGraphState
+		graph *rdf.Graph
+	}
+	var repos []repoGraph
+	for _, state := range results {
+		if state.AstControlRdfGraph == "" {
+			continue
+		}
+		graph, err := loadRdfFolder(state.AstControlRdfGraph)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repoGraph{state: state, graph: graph})
+	}
+
+	// 2. Add every service call link to the graph of the repository that makes the call.
+	links, err := loadOptionalRdfFile(filepath.Join(commonFolder, "service_calls.ttl"))
+	if err != nil {
+		return nil, err
+	}
+	if links != nil {
+		for _, t := range provenance.Strip(links).Triples() {
+			linked := false
+			for _, repo := range repos {
+				if len(repo.graph.Match(t.Subject, "", nil)) > 0 {
+					repo.graph.Add(t)
+					for _, source := range provenance.Sources(links, t) {
+						provenance.Record(repo.graph, t, source)
+					}
+					linked = true
+					break
+				}
+			}
+			if !linked {
+				fmt.Printf("No repository graph contains the caller of %s\n", t)
+			}
+		}
+	}
+
+	compose, err := loadOptionalRdfFile(filepath.Join(commonFolder, "compose_topology.ttl"))
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Apply the entity merges of the combined graph.
+	var review resolve.Result
+	data, err := os.ReadFile(filepath.Join(commonFolder, "entity_review.json"))
+	if err == nil {
+		if err := json.Unmarshal(data, &review); err != nil {
+			return nil, fmt.Errorf("failed to parse entity review: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read entity review: %w", err)
+	}
+	resolved := provenance.Source{Activity: "ResolveEntities"}
+	for _, repo := range repos {
+		resolve.Apply(repo.graph, review.Merged)
+		provenance.Prune(repo.graph)
+		provenance.RecordRemaining(repo.graph, resolved)
+	}
+	if compose != nil {
+		resolve.Apply(compose, review.Merged)
+		provenance.Prune(compose)
+		provenance.RecordRemaining(compose, resolved)
+	}
+
+	// 4. Write each graph as a new version.
+	var names []string
+	for _, repo := range repos {
+		name := ontology.RepositoryGraphURI(repo.state.RepoURL)
+		info, err := a.Store.Put(name, repo.graph, store.GraphInfo{
+			Repository: repo.state.RepoURL,
+			Branch:     repo.state.Branch,
+			Commit:     repo.state.Commit,
+			CommitTime: repo.state.CommitTime,
+			Build:      buildID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Stored %s version %d at commit %s with %d triples\n", name, info.Version, info.Commit, info.Triples)
+		names = append(names, string(name))
+	}
+	if compose != nil {
+		info, err := a.Store.Put(ontology.ComposeGraphURI, compose, store.GraphInfo{Build: buildID})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Stored %s version %d with %d triples\n", ontology.ComposeGraphURI, info.Version, info.Triples)
+		names = append(names, string(ontology.ComposeGraphURI))
+	}
+
+	return names, nil
+}
+
+// loadRdfFolder parses every Turtle file in a folder into one graph. Files that fail to parse are reported
+// and skipped, as when the combined graph is built.
+func loadRdfFolder(folder string) (*rdf.Graph, error) {
+	graph := ontology.NewGraph()
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".ttl") {
+			return nil
+		}
+		parsed, err := rdf.ParseTurtleFile(path)
+		if err != nil {
+			fmt.Printf("Skipping unparsable RDF file: %v\n", err)
+			return nil
+		}
+		graph.Merge(parsed)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RDF files in %s: %w", folder, err)
+	}
+	return graph, nil
+}
+
+// loadOptionalRdfFile parses a Turtle file, returning nil if the file does not exist.
+func loadOptionalRdfFile(path string) (*rdf.Graph, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	graph, err := rdf.ParseTurtleFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return graph, nil
+}
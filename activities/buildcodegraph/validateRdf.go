@@ -0,0 +1,133 @@
+package buildcodegraph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// maxRdfRepairAttempts is how many times the LLM is asked to fix an invalid RDF response.
+const maxRdfRepairAttempts = 2
+
+// maxRdfOutputTokens caps the output token budget a truncated RDF response is retried with. It is the
+// output limit of GPT-4o.
+const maxRdfOutputTokens = 16384
+
+// Reasons generated RDF is rejected, from the most to the least severe.
+const (
+	RdfTruncated       = "truncated"        // The response was cut off at the output token limit.
+	RdfMissing         = "no turtle"        // The response contains no Turtle.
+	RdfSyntaxError     = "syntax error"     // The Turtle does not parse.
+	RdfShapeViolations = "shape violations" // The graph violates the SHACL shapes.
+	RdfTooLarge        = "too large"        // The prompt is over the budget of the model, so it was not sent.
+)
+
+// RdfValidationError is returned when generated RDF is still invalid after all repair attempts.
+type RdfValidationError struct {
+	Reason     string   // Why the last attempt was rejected, one of the Rdf* or Annotation* reasons.
+	Output     string   // The last Turtle or annotation the LLM produced.
+	Violations []string // The problems of the last attempt.
+}
+
+func (e *RdfValidationError) Error() string {
+	return fmt.Sprintf("generated RDF is rejected (%s) after %d repair attempts: %s", e.Reason, maxRdfRepairAttempts, strings.Join(e.Violations, "; "))
+}
+
+// generateValidatedRdf calls the LLM with the prompt, extracts the Turtle from its response, parses it and
+// validates it against the GraphMind SHACL shapes. A response cut off at the output token limit is asked
+// for again with twice the budget, starting from maxTokens, up to maxRdfOutputTokens. Syntax errors and
+// violations are fed back to the LLM for a bounded number of repair attempts before an
+// *RdfValidationError is returned. The LLM response the graph was taken from is returned with it for
+// provenance.
+func generateValidatedRdf(ctx context.Context, client llm.Client, prompt string, maxTokens int) (*rdf.Graph, llm.Response, error) {
+	req := llm.Request{Prompt: prompt, MaxTokens: maxTokens}
+	response, err := client.Complete(ctx, req)
+	if err != nil {
+		return nil, response, fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		check := checkRdfResponse(response)
+		if len(check.violations) == 0 {
+			return check.graph, response, nil
+		}
+		if attempt > maxRdfRepairAttempts {
+			return nil, response, &RdfValidationError{Reason: check.reason, Output: check.turtle, Violations: check.violations}
+		}
+
+		if check.reason == RdfTruncated && req.MaxTokens > 0 && req.MaxTokens < maxRdfOutputTokens {
+			req.MaxTokens = min(2*req.MaxTokens, maxRdfOutputTokens)
+			fmt.Printf("Generated RDF is truncated, asking the LLM again with %d output tokens (attempt %d of %d)\n",
+				req.MaxTokens, attempt, maxRdfRepairAttempts)
+		} else {
+			fmt.Printf("Generated RDF is rejected (%s) with %d problem(s), asking the LLM to repair it (attempt %d of %d)\n",
+				check.reason, len(check.violations), attempt, maxRdfRepairAttempts)
+			repairPrompt, err := buildRepairPrompt(check.turtle, check.violations)
+			if err != nil {
+				return nil, response, err
+			}
+			req.Prompt = repairPrompt
+		}
+		response, err = client.Complete(ctx, req)
+		if err != nil {
+			return nil, response, fmt.Errorf("LLM repair call failed: %w", err)
+		}
+	}
+}
+
+// rdfCheck is the outcome of checking the RDF in an LLM response.
+type rdfCheck struct {
+	turtle     string // The extracted Turtle, or the whole response when none was found.
+	graph      *rdf.Graph
+	reason     string // Why the RDF is rejected, empty if it is accepted.
+	violations []string
+}
+
+// checkRdfResponse extracts, parses and validates the Turtle in an LLM response. A response cut off at the
+// output token limit is rejected even if what it holds parses, since the graph is incomplete.
+func checkRdfResponse(response llm.Response) rdfCheck {
+	turtle, err := ExtractTurtleRDF(response.Text)
+	if response.Truncated() {
+		if err != nil {
+			turtle = response.Text
+		}
+		return rdfCheck{turtle: turtle, reason: RdfTruncated, violations: []string{
+			fmt.Sprintf("the response was cut off at the output token limit (stop reason %q), so the graph is incomplete; "+
+				"write the complete graph more concisely", response.StopReason)}}
+	}
+	if err != nil {
+		return rdfCheck{turtle: response.Text, reason: RdfMissing, violations: []string{"the response does not contain Turtle in a ```turtle code block"}}
+	}
+
+	graph, err := rdf.ParseTurtle(turtle, "")
+	if err != nil {
+		return rdfCheck{turtle: turtle, reason: RdfSyntaxError, violations: []string{err.Error()}}
+	}
+
+	report := ontology.Validate(graph)
+	if report.Conforms {
+		return rdfCheck{turtle: turtle, graph: graph}
+	}
+	check := rdfCheck{turtle: turtle, graph: graph, reason: RdfShapeViolations}
+	for _, result := range report.Results {
+		check.violations = append(check.violations, result.String())
+	}
+	return check
+}
+
+func buildRepairPrompt(turtle string, violations []string) (string, error) {
+	promptTemplate, err := ReadFileToString("prompts/repair_rdf.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to read repair prompt file: %w", err)
+	}
+
+	prompt := strings.ReplaceAll(promptTemplate, "{{.Rdf}}", turtle)
+	prompt = strings.ReplaceAll(prompt, "{{.Violations}}", "- "+strings.Join(violations, "\n- "))
+	prompt = strings.ReplaceAll(prompt, "{{.Ontology}}", ontology.Summary())
+	prompt = strings.ReplaceAll(prompt, "{{.Shapes}}", ontology.Shapes)
+	return prompt, nil
+}
@@ -0,0 +1,80 @@
+package buildcodegraph
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+)
+
+// budgetClient answers with a complete graph only when a request allows at least need output tokens, and
+// cuts it off otherwise.
+type budgetClient struct {
+	need     int
+	requests []llm.Request
+}
+
+func (c *budgetClient) Complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+	c.requests = append(c.requests, req)
+	if req.MaxTokens < c.need {
+		return llm.Response{Text: "```turtle\n@prefix gm: <http://graphmind.io/ontology#> .\n<http://example.com/r> a gm:Repo", StopReason: "max_tokens"}, nil
+	}
+	return llm.Response{Text: "```turtle\n@prefix gm: <http://graphmind.io/ontology#> .\n<http://example.com/r> gm:language \"Go\" .\n```", StopReason: "end_turn"}, nil
+}
+
+func TestGenerateValidatedRdfRetriesTruncated(t *testing.T) {
+	inRepoRoot(t)
+	tests := []struct {
+		name       string
+		need       int
+		maxTokens  int
+		wantBudget []int
+		wantReason string // The rejection reason, if the graph is rejected.
+	}{
+		{name: "complete", need: 1000, maxTokens: 4096, wantBudget: []int{4096}},
+		{name: "doubled", need: 5000, maxTokens: 4096, wantBudget: []int{4096, 8192}},
+		{name: "doubled twice", need: 12000, maxTokens: 4096, wantBudget: []int{4096, 8192, 16384}},
+		{name: "capped", need: 12000, maxTokens: 5000, wantBudget: []int{5000, 10000, 16384}},
+		{name: "over the cap", need: 20000, maxTokens: 8192, wantBudget: []int{8192, 16384, 16384}, wantReason: RdfTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &budgetClient{need: tt.need}
+			graph, _, err := generateValidatedRdf(context.Background(), client, "Describe the repository.", tt.maxTokens)
+
+			var budgets []int
+			for _, req := range client.requests {
+				budgets = append(budgets, req.MaxTokens)
+			}
+			if !reflect.DeepEqual(budgets, tt.wantBudget) {
+				t.Fatalf("budgets = %v, want %v", budgets, tt.wantBudget)
+			}
+
+			if tt.wantReason != "" {
+				var validationErr *RdfValidationError
+				if !errors.As(err, &validationErr) || validationErr.Reason != tt.wantReason {
+					t.Fatalf("err = %v, want a %s rejection", err, tt.wantReason)
+				}
+				// At the cap, the LLM is asked to write the graph more concisely.
+				if client.requests[2].Prompt == "Describe the repository." {
+					t.Error("the last request at the cap is not a repair prompt")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if graph.Len() != 1 {
+				t.Errorf("graph has %d triples, want 1", graph.Len())
+			}
+			// A larger budget asks for the same graph, not a repair.
+			for _, req := range client.requests {
+				if req.Prompt != "Describe the repository." {
+					t.Errorf("prompt = %q, want the original prompt", req.Prompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// Package diagram draws architecture diagrams of a GraphMind graph as Mermaid flowcharts or Graphviz DOT.
+// Diagrams are computed from the graph alone, so every node and edge in them is in the graph: the whole
+// system at service level, a service with its neighbourhood, or any set of nodes such as the impact set of
+// a spec. Repositories are drawn as boxes around their services and APIs, and edges only an LLM inferred
+// are dashed. Nodes can be highlighted, for example the ones a spec changes.
+package diagram
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/SaiNageswarS/GraphMind/impact"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Node is a node of a diagram.
+type Node struct {
+	ID          string // A short identifier unique within the diagram, "n0", "n1", ...
+	URI         rdf.IRI
+	Name        string
+	Class       string // The local name of the most specific GraphMind class, for example "Api".
+	Repository  string // The ID of the repository box the node is drawn in, or "".
+	Highlighted bool
+}
+
+// Edge is an edge of a diagram. In the system view it stands for all edges of a relation between the APIs
+// of two services, or between the APIs of a service and a resource.
+type Edge struct {
+	From, To *Node
+	Relation string // The local name of the predicate, for example "calls".
+	Count    int    // The number of triples the edge stands for.
+	Inferred bool   // Whether every triple of the edge was only inferred by an LLM.
+}
+
+// Repository is a repository box of a diagram.
+type Repository struct {
+	ID    string // "r0", "r1", ...
+	URI   rdf.IRI
+	Label string // The repository URL, or else its name.
+}
+
+// Diagram is a set of nodes and edges of a graph, ready to render.
+type Diagram struct {
+	Repositories []*Repository
+	Nodes        []*Node
+	Edges        []*Edge
+}
+
+// relations are the predicates drawn as edges. gm:hasService is not among them: a service is drawn inside
+// the box of its repository instead.
+var relations = []rdf.IRI{
+	ontology.HasApi,
+	ontology.HasCollection,
+	ontology.Calls,
+	ontology.ReadsFrom,
+	ontology.WritesTo,
+	ontology.PublishesTo,
+	ontology.SubscribesTo,
+	ontology.UsesResource,
+	ontology.UsesConfig,
+	ontology.DependsOn,
+	ontology.PartOf,
+	ontology.DeploysRepository,
+	ontology.RunsService,
+	ontology.AttachedTo,
+}
+
+// systemClasses are the classes of the nodes of the system view.
+var systemClasses = []rdf.IRI{
+	ontology.Service,
+	ontology.Database,
+	ontology.Collection,
+	ontology.Topic,
+	ontology.CloudResource,
+	ontology.ConfigKey,
+}
+
+// nodeClasses orders the classes from most to least specific for naming the class of a node.
+var nodeClasses = []rdf.IRI{
+	ontology.Api,
+	ontology.Service,
+	ontology.Collection,
+	ontology.Database,
+	ontology.Topic,
+	ontology.ConfigKey,
+	ontology.CloudResource,
+	ontology.Resource,
+	ontology.ComposeService,
+	ontology.ComposeProject,
+	ontology.Network,
+	ontology.EnvironmentVariable,
+}
+
+// builder collects the nodes and edges of a diagram.
+type builder struct {
+	graph   *rdf.Graph
+	sources map[rdf.Triple][]provenance.Source
+	nodes   map[rdf.IRI]*Node
+	edges   map[[3]string]*Edge
+}
+
+func newBuilder(g *rdf.Graph) *builder {
+	return &builder{graph: provenance.Strip(g), sources: provenance.All(g), nodes: map[rdf.IRI]*Node{}, edges: map[[3]string]*Edge{}}
+}
+
+// System draws the services of all repositories and the resources they use. The edges of an API are drawn
+// from its service, so an edge stands for every call, read or write between the APIs of two services or
+// between a service's APIs and a resource.
+func System(g *rdf.Graph) *Diagram {
+	b := newBuilder(g)
+	for _, class := range systemClasses {
+		for _, node := range b.graph.Subjects(rdf.RDFType, class) {
+			if iri, ok := node.(rdf.IRI); ok {
+				b.add(iri)
+			}
+		}
+	}
+	lift := func(node rdf.IRI) rdf.IRI {
+		if service, ok := b.container(node, ontology.HasApi); ok {
+			return service
+		}
+		return node
+	}
+	for _, predicate := range relations {
+		if predicate == ontology.HasApi {
+			continue
+		}
+		for _, t := range b.graph.Match(nil, predicate, nil) {
+			subject, object, ok := iris(t)
+			if !ok {
+				continue
+			}
+			from, to := lift(subject), lift(object)
+			if from != to && b.nodes[from] != nil && b.nodes[to] != nil {
+				b.edge(from, to, t)
+			}
+		}
+	}
+	return b.diagram()
+}
+
+// Nodes draws the given nodes and every edge of the graph between them.
+func Nodes(g *rdf.Graph, nodes []rdf.IRI) *Diagram {
+	b := newBuilder(g)
+	for _, node := range nodes {
+		b.add(node)
+	}
+	b.induced()
+	return b.diagram()
+}
+
+// Service draws a service, its APIs and every node they are linked to in either direction, with all edges
+// between them.
+func Service(g *rdf.Graph, service rdf.IRI) *Diagram {
+	b := newBuilder(g)
+	center := []rdf.IRI{service}
+	for _, api := range b.graph.Objects(service, ontology.HasApi) {
+		if iri, ok := api.(rdf.IRI); ok {
+			center = append(center, iri)
+		}
+	}
+	for _, node := range center {
+		b.add(node)
+		for _, t := range append(b.graph.Match(node, "", nil), b.graph.Match(nil, "", node)...) {
+			if subject, object, ok := iris(t); ok && isRelation(t.Predicate) {
+				b.add(subject)
+				b.add(object)
+			}
+		}
+	}
+	b.induced()
+	return b.diagram()
+}
+
+// Impact draws the nodes of impact analyses, the analyzed nodes and everything reached from them, with all
+// edges between them.
+func Impact(g *rdf.Graph, results ...*impact.Result) *Diagram {
+	var nodes []rdf.IRI
+	for _, result := range results {
+		nodes = append(nodes, rdf.IRI(result.Root.URI))
+		for _, impacted := range append(append([]impact.Impacted{}, result.Dependents...), result.Dependencies...) {
+			nodes = append(nodes, rdf.IRI(impacted.URI))
+		}
+	}
+	return Nodes(g, nodes)
+}
+
+// Highlight marks the nodes with the given URIs and returns how many of them the diagram has.
+func (d *Diagram) Highlight(uris ...rdf.IRI) int {
+	wanted := map[rdf.IRI]bool{}
+	for _, uri := range uris {
+		wanted[uri] = true
+	}
+	found := 0
+	for _, node := range d.Nodes {
+		if wanted[node.URI] {
+			node.Highlighted = true
+			found++
+		}
+	}
+	return found
+}
+
+// add adds a node unless it is a repository, which is drawn as the box around its services.
+func (b *builder) add(node rdf.IRI) {
+	if b.nodes[node] != nil || b.graph.Contains(rdf.Triple{Subject: node, Predicate: rdf.RDFType, Object: ontology.Repository}) {
+		return
+	}
+	n := &Node{URI: node, Name: b.name(node)}
+	for _, class := range nodeClasses {
+		if b.graph.Contains(rdf.Triple{Subject: node, Predicate: rdf.RDFType, Object: class}) {
+			n.Class = rdf.LocalName(class)
+			break
+		}
+	}
+	b.nodes[node] = n
+}
+
+// induced adds every edge of the graph between two nodes of the diagram.
+func (b *builder) induced() {
+	for _, predicate := range relations {
+		for _, t := range b.graph.Match(nil, predicate, nil) {
+			if subject, object, ok := iris(t); ok && subject != object && b.nodes[subject] != nil && b.nodes[object] != nil {
+				b.edge(subject, object, t)
+			}
+		}
+	}
+}
+
+// edge adds a triple to the edge between two nodes for its relation.
+func (b *builder) edge(from, to rdf.IRI, t rdf.Triple) {
+	key := [3]string{string(from), string(t.Predicate), string(to)}
+	e := b.edges[key]
+	if e == nil {
+		e = &Edge{From: b.nodes[from], To: b.nodes[to], Relation: rdf.LocalName(t.Predicate), Inferred: true}
+		b.edges[key] = e
+	}
+	e.Count++
+	source, ok := provenance.Strongest(b.sources[t])
+	if !ok || source.Evidence != provenance.LLMInference {
+		e.Inferred = false
+	}
+}
+
+// diagram orders the nodes and edges, numbers them and places services and APIs in their repositories.
+func (b *builder) diagram() *Diagram {
+	d := &Diagram{}
+	for _, n := range b.nodes {
+		d.Nodes = append(d.Nodes, n)
+	}
+	sort.Slice(d.Nodes, func(i, j int) bool { return d.Nodes[i].URI < d.Nodes[j].URI })
+
+	repositories := map[rdf.IRI]*Repository{}
+	repositoryOf := map[*Node]rdf.IRI{}
+	for i, n := range d.Nodes {
+		n.ID = fmt.Sprintf("n%d", i)
+		service := n.URI
+		if n.Class == rdf.LocalName(ontology.Api) {
+			service, _ = b.container(n.URI, ontology.HasApi)
+		}
+		repository, ok := b.container(service, ontology.HasService)
+		if !ok {
+			continue
+		}
+		repositoryOf[n] = repository
+		if repositories[repository] == nil {
+			label := b.name(repository)
+			if url := b.graph.Object(repository, ontology.RepoURL); url != nil {
+				label = rdf.Value(url)
+			}
+			repositories[repository] = &Repository{URI: repository, Label: label}
+			d.Repositories = append(d.Repositories, repositories[repository])
+		}
+	}
+	sort.Slice(d.Repositories, func(i, j int) bool { return d.Repositories[i].URI < d.Repositories[j].URI })
+	for i, r := range d.Repositories {
+		r.ID = fmt.Sprintf("r%d", i)
+	}
+	for n, repository := range repositoryOf {
+		n.Repository = repositories[repository].ID
+	}
+
+	for _, e := range b.edges {
+		d.Edges = append(d.Edges, e)
+	}
+	sort.Slice(d.Edges, func(i, j int) bool {
+		a, c := d.Edges[i], d.Edges[j]
+		if a.From.URI != c.From.URI {
+			return a.From.URI < c.From.URI
+		}
+		if a.To.URI != c.To.URI {
+			return a.To.URI < c.To.URI
+		}
+		return a.Relation < c.Relation
+	})
+	return d
+}
+
+// name returns the gm:name or rdfs:label of a node, or else the local name of its URI.
+func (b *builder) name(node rdf.IRI) string {
+	if name := b.graph.Object(node, ontology.Name); name != nil {
+		return rdf.Value(name)
+	}
+	if label := b.graph.Object(node, rdf.RDFSLabel); label != nil {
+		return rdf.Value(label)
+	}
+	return rdf.LocalName(node)
+}
+
+// container returns the node that has a node through a containment predicate such as gm:hasApi.
+func (b *builder) container(node rdf.IRI, predicate rdf.IRI) (rdf.IRI, bool) {
+	for _, subject := range b.graph.Subjects(predicate, node) {
+		if iri, ok := subject.(rdf.IRI); ok {
+			return iri, true
+		}
+	}
+	return "", false
+}
+
+func isRelation(predicate rdf.IRI) bool {
+	for _, r := range relations {
+		if r == predicate {
+			return true
+		}
+	}
+	return false
+}
+
+func iris(t rdf.Triple) (subject, object rdf.IRI, ok bool) {
+	subject, ok = t.Subject.(rdf.IRI)
+	if !ok {
+		return "", "", false
+	}
+	object, ok = t.Object.(rdf.IRI)
+	return subject, object, ok
+}
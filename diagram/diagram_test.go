@@ -0,0 +1,159 @@
+package diagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/impact"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// testGraph has an order service in a repository whose two APIs call the Charge API of a payment service
+// and use an orders collection. One call was found by static analysis and the other one inferred by an LLM.
+func testGraph(t *testing.T) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(`
+		@prefix gm: <http://graphmind.io/ontology#> .
+		@prefix ex: <http://example.com/> .
+		ex:ordersRepo a gm:Repository ; gm:repoUrl "https://github.com/org/orders" ; gm:hasService ex:orderService .
+		ex:orderService a gm:Service ; gm:name "OrderService" ; gm:hasApi ex:createOrder, ex:getOrder .
+		ex:createOrder a gm:Api ; gm:name "CreateOrder" ; gm:writesTo ex:ordersCollection ; gm:calls ex:charge .
+		ex:getOrder a gm:Api ; gm:name "GetOrder" ; gm:readsFrom ex:ordersCollection ; gm:calls ex:charge .
+		ex:paymentService a gm:Service ; gm:name "Payment \"v2\"" ; gm:hasApi ex:charge .
+		ex:charge a gm:Api ; gm:name "Charge" ; gm:publishesTo ex:events .
+		ex:shopDb a gm:Database ; gm:name "shop" ; gm:hasCollection ex:ordersCollection .
+		ex:ordersCollection a gm:Collection ; gm:name "orders" .
+		ex:events a gm:Topic ; gm:name "payment<events>" .
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provenance.Record(g, rdf.Triple{Subject: ex("createOrder"), Predicate: ontology.Calls, Object: ex("charge")},
+		provenance.Source{Activity: "LinkServiceCalls", File: "client.go"})
+	provenance.Record(g, rdf.Triple{Subject: ex("getOrder"), Predicate: ontology.Calls, Object: ex("charge")},
+		provenance.Source{Activity: "BuildAstRdf", File: "orders.go"})
+	provenance.Record(g, rdf.Triple{Subject: ex("createOrder"), Predicate: ontology.WritesTo, Object: ex("ordersCollection")},
+		provenance.Source{Activity: "BuildAstRdf", File: "orders.go"})
+	return g
+}
+
+func ex(name string) rdf.IRI {
+	return rdf.IRI("http://example.com/" + name)
+}
+
+// summary lists the nodes as "id name class repository" and the edges as "from relation to", marking the
+// inferred ones.
+func summary(d *Diagram) string {
+	var lines []string
+	for _, n := range d.Nodes {
+		lines = append(lines, strings.TrimSpace(strings.Join([]string{n.ID, n.Name, n.Class, n.Repository}, " ")))
+	}
+	for _, e := range d.Edges {
+		line := e.From.Name + " " + e.Relation + " " + e.To.Name
+		if e.Inferred {
+			line += " (inferred)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestSystemMermaid(t *testing.T) {
+	d := System(testGraph(t))
+	if n := d.Highlight(ex("orderService"), ex("shopDb"), ex("createOrder")); n != 2 {
+		t.Errorf("highlighted %d nodes, want 2: APIs are not drawn in the system view", n)
+	}
+	want := `flowchart LR
+  subgraph r0["https://github.com/org/orders"]
+    n1("OrderService<br/><i>Service</i>")
+  end
+  n0>"payment#lt;events#gt;<br/><i>Topic</i>"]
+  n2[("orders<br/><i>Collection</i>")]
+  n3("Payment #quot;v2#quot;<br/><i>Service</i>")
+  n4[("shop<br/><i>Database</i>")]
+  n1 -->|"readsFrom"| n2
+  n1 -.->|"writesTo"| n2
+  n1 -->|"calls ×2"| n3
+  n3 -->|"publishesTo"| n0
+  n4 -->|"hasCollection"| n2
+  classDef highlighted fill:#fde68a,stroke:#b45309,stroke-width:2px
+  class n1,n4 highlighted
+`
+	if got := d.Mermaid(); got != want {
+		t.Errorf("Mermaid:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSystemDOT(t *testing.T) {
+	d := System(testGraph(t))
+	d.Highlight(ex("shopDb"))
+	want := `digraph GraphMind {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  subgraph cluster_r0 {
+    label="https://github.com/org/orders";
+    n1 [label="OrderService\n«Service»", tooltip="http://example.com/orderService", style="rounded"];
+  }
+  n0 [label="payment<events>\n«Topic»", tooltip="http://example.com/events", shape=cds, style="solid"];
+  n2 [label="orders\n«Collection»", tooltip="http://example.com/ordersCollection", shape=cylinder, style="solid"];
+  n3 [label="Payment \"v2\"\n«Service»", tooltip="http://example.com/paymentService", style="rounded"];
+  n4 [label="shop\n«Database»", tooltip="http://example.com/shopDb", shape=cylinder, fillcolor="#fde68a", color="#b45309", style="solid,filled,bold"];
+  n1 -> n2 [label="readsFrom"];
+  n1 -> n2 [label="writesTo", style=dashed];
+  n1 -> n3 [label="calls ×2"];
+  n3 -> n0 [label="publishesTo"];
+  n4 -> n2 [label="hasCollection"];
+}
+`
+	if got := d.DOT(); got != want {
+		t.Errorf("DOT:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestService(t *testing.T) {
+	d := Service(testGraph(t), ex("paymentService"))
+	want := `n0 Charge Api
+n1 CreateOrder Api r0
+n2 payment<events> Topic
+n3 GetOrder Api r0
+n4 Payment "v2" Service
+Charge publishesTo payment<events>
+CreateOrder calls Charge
+GetOrder calls Charge (inferred)
+Payment "v2" hasApi Charge`
+	if got := summary(d); got != want {
+		t.Errorf("diagram:\n%s\nwant:\n%s", got, want)
+	}
+	// The repository of the calling APIs is a box, not a node.
+	if len(d.Repositories) != 1 || d.Repositories[0].Label != "https://github.com/org/orders" {
+		t.Errorf("repositories = %+v", d.Repositories)
+	}
+}
+
+func TestNodesAndImpact(t *testing.T) {
+	g := testGraph(t)
+	d := Nodes(g, []rdf.IRI{ex("getOrder"), ex("ordersCollection"), ex("ordersRepo"), ex("unknown")})
+	want := `n0 GetOrder Api r0
+n1 orders Collection
+n2 unknown
+GetOrder readsFrom orders`
+	if got := summary(d); got != want {
+		t.Errorf("diagram:\n%s\nwant:\n%s", got, want)
+	}
+
+	result, err := impact.New(g).Analyze(ex("charge"), impact.Options{Direction: impact.Dependents, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The callers depend on Charge; its service does not.
+	want = `n0 Charge Api
+n1 CreateOrder Api r0
+n2 GetOrder Api r0
+CreateOrder calls Charge
+GetOrder calls Charge (inferred)`
+	if got := summary(Impact(g, result)); got != want {
+		t.Errorf("impact diagram:\n%s\nwant:\n%s", got, want)
+	}
+}
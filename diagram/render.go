@@ -0,0 +1,161 @@
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Mermaid renders the diagram as a Mermaid flowchart.
+func (d *Diagram) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, r := range d.Repositories {
+		fmt.Fprintf(&b, "  subgraph %s[\"%s\"]\n", r.ID, mermaidText(r.Label))
+		for _, n := range d.Nodes {
+			if n.Repository == r.ID {
+				fmt.Fprintf(&b, "    %s\n", mermaidNode(n))
+			}
+		}
+		b.WriteString("  end\n")
+	}
+	for _, n := range d.Nodes {
+		if n.Repository == "" {
+			fmt.Fprintf(&b, "  %s\n", mermaidNode(n))
+		}
+	}
+	for _, e := range d.Edges {
+		arrow := "-->"
+		if e.Inferred {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", e.From.ID, arrow, mermaidText(edgeLabel(e)), e.To.ID)
+	}
+	var highlighted []string
+	for _, n := range d.Nodes {
+		if n.Highlighted {
+			highlighted = append(highlighted, n.ID)
+		}
+	}
+	if len(highlighted) > 0 {
+		b.WriteString("  classDef highlighted fill:#fde68a,stroke:#b45309,stroke-width:2px\n")
+		fmt.Fprintf(&b, "  class %s highlighted\n", strings.Join(highlighted, ","))
+	}
+	return b.String()
+}
+
+// mermaidNode writes a node with a shape for its class: a cylinder for databases and collections, a flag
+// for topics, a rounded box for services and a box for everything else.
+func mermaidNode(n *Node) string {
+	label := mermaidText(n.Name)
+	if n.Class != "" {
+		label += "<br/><i>" + n.Class + "</i>"
+	}
+	switch n.Class {
+	case "Database", "Collection":
+		return fmt.Sprintf("%s[(\"%s\")]", n.ID, label)
+	case "Topic":
+		return fmt.Sprintf("%s>\"%s\"]", n.ID, label)
+	case "Service":
+		return fmt.Sprintf("%s(\"%s\")", n.ID, label)
+	}
+	return fmt.Sprintf("%s[\"%s\"]", n.ID, label)
+}
+
+// mermaidText escapes the characters that end or break a quoted Mermaid label.
+func mermaidText(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", " ").Replace(s)
+}
+
+// DOT renders the diagram as a Graphviz digraph, with repositories as clusters.
+func (d *Diagram) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph GraphMind {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	for _, r := range d.Repositories {
+		fmt.Fprintf(&b, "  subgraph cluster_%s {\n", r.ID)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(r.Label))
+		for _, n := range d.Nodes {
+			if n.Repository == r.ID {
+				fmt.Fprintf(&b, "    %s\n", dotNode(n))
+			}
+		}
+		b.WriteString("  }\n")
+	}
+	for _, n := range d.Nodes {
+		if n.Repository == "" {
+			fmt.Fprintf(&b, "  %s\n", dotNode(n))
+		}
+	}
+	for _, e := range d.Edges {
+		attributes := "label=" + dotQuote(edgeLabel(e))
+		if e.Inferred {
+			attributes += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", e.From.ID, e.To.ID, attributes)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotNode(n *Node) string {
+	label := n.Name
+	if n.Class != "" {
+		label += "\n«" + n.Class + "»"
+	}
+	attributes := fmt.Sprintf("label=%s, tooltip=%s", dotQuote(label), dotQuote(string(n.URI)))
+	style := "rounded"
+	switch n.Class {
+	case "Database", "Collection":
+		attributes += ", shape=cylinder"
+		style = "solid"
+	case "Topic":
+		attributes += ", shape=cds"
+		style = "solid"
+	}
+	if n.Highlighted {
+		style += ",filled,bold"
+		attributes += `, fillcolor="#fde68a", color="#b45309"`
+	}
+	attributes += ", style=" + dotQuote(style)
+	return fmt.Sprintf("%s [%s];", n.ID, attributes)
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// edgeLabel names the relation of an edge, with the number of triples it stands for if more than one.
+func edgeLabel(e *Edge) string {
+	if e.Count > 1 {
+		return fmt.Sprintf("%s ×%d", e.Relation, e.Count)
+	}
+	return e.Relation
+}
+
+// ErrNoGraphviz is returned by SVG when the Graphviz dot command is not installed.
+var ErrNoGraphviz = errors.New("graphviz dot command not found")
+
+// SVG renders the diagram as SVG with the Graphviz dot command.
+func (d *Diagram) SVG(ctx context.Context) ([]byte, error) {
+	path, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, ErrNoGraphviz
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-Tsvg")
+	cmd.Stdin = strings.NewReader(d.DOT())
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("dot failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,272 @@
+// Package diff compares two builds of a GraphMind graph and reports their architectural differences:
+// services and APIs that were added or removed, dependencies that appeared or disappeared and resources
+// whose properties changed.
+package diff
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Entity is a node of the graph as it appears in a report.
+type Entity struct {
+	URI   string `json:"uri"`
+	Class string `json:"class,omitempty"` // The local name of the most specific GraphMind class, for example "Api".
+	Name  string `json:"name"`
+}
+
+// Dependency is an edge of one of the dependency relations, for example an API writing to a collection.
+type Dependency struct {
+	From     Entity `json:"from"`
+	Relation string `json:"relation"` // The local name of the predicate, for example "writesTo".
+	To       Entity `json:"to"`
+}
+
+// PropertyChange lists the old and new values of a property of an entity.
+type PropertyChange struct {
+	Property string   `json:"property"`
+	Old      []string `json:"old"`
+	New      []string `json:"new"`
+}
+
+// EntityChange is an entity present in both builds whose properties differ.
+type EntityChange struct {
+	Entity
+	Changes []PropertyChange `json:"changes"`
+}
+
+// Report is the difference between two builds.
+type Report struct {
+	From string `json:"from"` // A description of the old build, for example a graph name and version.
+	To   string `json:"to"`
+
+	AddedServices       []Entity       `json:"addedServices"`
+	RemovedServices     []Entity       `json:"removedServices"`
+	AddedApis           []Entity       `json:"addedApis"`
+	RemovedApis         []Entity       `json:"removedApis"`
+	AddedResources      []Entity       `json:"addedResources"`
+	RemovedResources    []Entity       `json:"removedResources"`
+	ChangedResources    []EntityChange `json:"changedResources"`
+	AddedDependencies   []Dependency   `json:"addedDependencies"`
+	RemovedDependencies []Dependency   `json:"removedDependencies"`
+
+	// Counts of all added and removed triples, including the ones not covered above. Triples with blank
+	// nodes are not counted because blank node labels differ between builds.
+	AddedTriples   int `json:"addedTriples"`
+	RemovedTriples int `json:"removedTriples"`
+}
+
+// Empty reports whether the builds have no architectural differences.
+func (r *Report) Empty() bool {
+	return len(r.AddedServices)+len(r.RemovedServices)+len(r.AddedApis)+len(r.RemovedApis)+
+		len(r.AddedResources)+len(r.RemovedResources)+len(r.ChangedResources)+
+		len(r.AddedDependencies)+len(r.RemovedDependencies) == 0
+}
+
+// resourceClasses are the classes reported as resources.
+var resourceClasses = []rdf.IRI{
+	ontology.Database,
+	ontology.Collection,
+	ontology.CloudResource,
+	ontology.Topic,
+	ontology.ConfigKey,
+	ontology.Resource,
+}
+
+// dependencyRelations are the predicates reported as dependencies.
+var dependencyRelations = []rdf.IRI{
+	ontology.Calls,
+	ontology.ReadsFrom,
+	ontology.WritesTo,
+	ontology.PublishesTo,
+	ontology.SubscribesTo,
+	ontology.UsesResource,
+	ontology.UsesConfig,
+	ontology.DependsOn,
+}
+
+// ignoredProperties are not compared. Descriptions are written by the LLM and reworded on every build.
+var ignoredProperties = map[rdf.IRI]bool{
+	ontology.Description: true,
+	rdf.RDFSComment:      true,
+}
+
+// Compare returns the differences between an old and a new build of a graph. Provenance is ignored, so a
+// triple that is justified by different lines in the new build is not a difference.
+func Compare(from, to *rdf.Graph) *Report {
+	from, to = provenance.Strip(from), provenance.Strip(to)
+	r := &Report{}
+	r.AddedServices, r.RemovedServices = compareNodes(from, to, []rdf.IRI{ontology.Service})
+	r.AddedApis, r.RemovedApis = compareNodes(from, to, []rdf.IRI{ontology.Api})
+	r.AddedResources, r.RemovedResources = compareNodes(from, to, resourceClasses)
+	r.ChangedResources = compareProperties(from, to, resourceClasses)
+	r.AddedDependencies = missingDependencies(to, from)
+	r.RemovedDependencies = missingDependencies(from, to)
+
+	for _, t := range to.Triples() {
+		if !hasBlankNode(t) && !from.Contains(t) {
+			r.AddedTriples++
+		}
+	}
+	for _, t := range from.Triples() {
+		if !hasBlankNode(t) && !to.Contains(t) {
+			r.RemovedTriples++
+		}
+	}
+	return r
+}
+
+// compareNodes returns the IRI nodes of the classes that only the new graph has and the ones that only the
+// old graph has.
+func compareNodes(from, to *rdf.Graph, classes []rdf.IRI) (added, removed []Entity) {
+	old, current := nodesOf(from, classes), nodesOf(to, classes)
+	added, removed = []Entity{}, []Entity{}
+	for node := range current {
+		if !old[node] {
+			added = append(added, entity(to, node))
+		}
+	}
+	for node := range old {
+		if !current[node] {
+			removed = append(removed, entity(from, node))
+		}
+	}
+	sortEntities(added)
+	sortEntities(removed)
+	return added, removed
+}
+
+// compareProperties returns the nodes of the classes in both graphs whose literal properties differ.
+func compareProperties(from, to *rdf.Graph, classes []rdf.IRI) []EntityChange {
+	changed := []EntityChange{}
+	current := nodesOf(to, classes)
+	for node := range nodesOf(from, classes) {
+		if !current[node] {
+			continue
+		}
+		oldValues, newValues := literalProperties(from, node), literalProperties(to, node)
+		var properties []rdf.IRI
+		for p := range oldValues {
+			properties = append(properties, p)
+		}
+		for p := range newValues {
+			if _, ok := oldValues[p]; !ok {
+				properties = append(properties, p)
+			}
+		}
+		sort.Slice(properties, func(i, j int) bool { return properties[i] < properties[j] })
+
+		var changes []PropertyChange
+		for _, p := range properties {
+			if !slices.Equal(oldValues[p], newValues[p]) {
+				changes = append(changes, PropertyChange{Property: rdf.LocalName(p), Old: nonNil(oldValues[p]), New: nonNil(newValues[p])})
+			}
+		}
+		if len(changes) > 0 {
+			changed = append(changed, EntityChange{Entity: entity(to, node), Changes: changes})
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].URI < changed[j].URI })
+	return changed
+}
+
+// missingDependencies returns the dependency edges of g that other does not have.
+func missingDependencies(g, other *rdf.Graph) []Dependency {
+	missing := []Dependency{}
+	for _, relation := range dependencyRelations {
+		for _, t := range g.Match(nil, relation, nil) {
+			if hasBlankNode(t) || other.Contains(t) {
+				continue
+			}
+			missing = append(missing, Dependency{From: entity(g, t.Subject), Relation: rdf.LocalName(relation), To: entity(g, t.Object)})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		a, b := missing[i], missing[j]
+		if a.From.URI != b.From.URI {
+			return a.From.URI < b.From.URI
+		}
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		return a.To.URI < b.To.URI
+	})
+	return missing
+}
+
+// nodesOf returns the set of IRI nodes typed with any of the classes.
+func nodesOf(g *rdf.Graph, classes []rdf.IRI) map[rdf.Term]bool {
+	nodes := map[rdf.Term]bool{}
+	for _, class := range classes {
+		for _, node := range g.Subjects(rdf.RDFType, class) {
+			if _, ok := node.(rdf.IRI); ok {
+				nodes[node] = true
+			}
+		}
+	}
+	return nodes
+}
+
+// entityClasses orders the classes from most to least specific for naming the class of an entity.
+var entityClasses = append([]rdf.IRI{ontology.Api, ontology.Service, ontology.Repository, ontology.ComposeService},
+	resourceClasses...)
+
+// entity describes a node by its IRI, class and name.
+func entity(g *rdf.Graph, node rdf.Term) Entity {
+	e := Entity{URI: rdf.Value(node)}
+	for _, class := range entityClasses {
+		if g.Contains(rdf.Triple{Subject: node, Predicate: rdf.RDFType, Object: class}) {
+			e.Class = rdf.LocalName(class)
+			break
+		}
+	}
+	switch {
+	case g.Object(node, ontology.Name) != nil:
+		e.Name = rdf.Value(g.Object(node, ontology.Name))
+	case g.Object(node, rdf.RDFSLabel) != nil:
+		e.Name = rdf.Value(g.Object(node, rdf.RDFSLabel))
+	default:
+		if iri, ok := node.(rdf.IRI); ok {
+			e.Name = rdf.LocalName(iri)
+		} else {
+			e.Name = node.String()
+		}
+	}
+	return e
+}
+
+func sortEntities(entities []Entity) {
+	sort.Slice(entities, func(i, j int) bool { return entities[i].URI < entities[j].URI })
+}
+
+// literalProperties returns the sorted literal values of every compared property of a node.
+func literalProperties(g *rdf.Graph, node rdf.Term) map[rdf.IRI][]string {
+	values := map[rdf.IRI][]string{}
+	for _, t := range g.Match(node, "", nil) {
+		if _, ok := t.Object.(rdf.Literal); !ok || ignoredProperties[t.Predicate] {
+			continue
+		}
+		values[t.Predicate] = append(values[t.Predicate], rdf.Value(t.Object))
+	}
+	for _, v := range values {
+		sort.Strings(v)
+	}
+	return values
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func hasBlankNode(t rdf.Triple) bool {
+	_, subject := t.Subject.(rdf.BlankNode)
+	_, object := t.Object.(rdf.BlankNode)
+	return subject || object
+}
@@ -0,0 +1,195 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+const prefixes = `
+@prefix gm: <http://graphmind.io/ontology#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix ex: <http://example.com/> .
+`
+
+const oldBuild = `
+ex:orders a gm:Service ; gm:name "orders" ; gm:calls ex:billing .
+ex:billing a gm:Service ; gm:name "billing" .
+ex:legacy a gm:Service ; gm:name "legacy" .
+ex:getOrder a gm:Api ; gm:name "GetOrder" ; gm:readsFrom ex:ordersDb .
+ex:ordersDb a gm:Database ; gm:name "orders" ; gm:databaseType "mongodb" ; gm:description "Stores orders." .
+ex:cache a gm:CloudResource ; gm:name "cache" .
+`
+
+const newBuild = `
+ex:orders a gm:Service ; gm:name "orders" .
+ex:billing a gm:Service ; gm:name "billing" .
+ex:payments a gm:Service ; gm:name "payments" .
+ex:getOrder a gm:Api ; gm:name "GetOrder" ; gm:readsFrom ex:ordersDb ; gm:writesTo ex:ordersDb .
+ex:listOrders a gm:Api ; rdfs:label "ListOrders" .
+ex:ordersDb a gm:Database ; gm:name "orders" ; gm:databaseType "postgres" ; gm:description "Holds the orders." .
+ex:events a gm:Topic ; gm:name "order-events" .
+[] ex:note "blank nodes are not counted" .
+`
+
+func mustParse(t *testing.T, document string) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(prefixes+document, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func names(entities []Entity) string {
+	var names []string
+	for _, e := range entities {
+		names = append(names, e.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func dependencies(deps []Dependency) string {
+	var lines []string
+	for _, d := range deps {
+		lines = append(lines, d.From.Name+" "+d.Relation+" "+d.To.Name)
+	}
+	return strings.Join(lines, ", ")
+}
+
+func TestCompare(t *testing.T) {
+	from, to := mustParse(t, oldBuild), mustParse(t, newBuild)
+	// Provenance of an unchanged triple is not a difference.
+	provenance.Record(to, rdf.Triple{Subject: rdf.IRI("http://example.com/orders"), Predicate: ontology.Name, Object: rdf.NewLiteral("orders")},
+		provenance.Source{Activity: "ParseProto", File: "orders.proto", StartLine: 3})
+
+	r := Compare(from, to)
+	for _, c := range []struct{ what, got, want string }{
+		{"added services", names(r.AddedServices), "payments"},
+		{"removed services", names(r.RemovedServices), "legacy"},
+		{"added APIs", names(r.AddedApis), "ListOrders"},
+		{"removed APIs", names(r.RemovedApis), ""},
+		{"added resources", names(r.AddedResources), "order-events"},
+		{"removed resources", names(r.RemovedResources), "cache"},
+		{"added dependencies", dependencies(r.AddedDependencies), "GetOrder writesTo orders"},
+		{"removed dependencies", dependencies(r.RemovedDependencies), "orders calls billing"},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.what, c.got, c.want)
+		}
+	}
+
+	if len(r.ChangedResources) != 1 {
+		t.Fatalf("changed resources = %+v, want ordersDb", r.ChangedResources)
+	}
+	changed := r.ChangedResources[0]
+	if changed.Class != "Database" || len(changed.Changes) != 1 {
+		t.Fatalf("changed resource = %+v, want only the database type of a Database", changed)
+	}
+	if change := changed.Changes[0]; change.Property != "databaseType" || strings.Join(change.Old, ",") != "mongodb" || strings.Join(change.New, ",") != "postgres" {
+		t.Errorf("change = %+v", change)
+	}
+
+	if r.AddedTriples != 9 || r.RemovedTriples != 7 {
+		t.Errorf("triples added %d and removed %d, want 9 and 7", r.AddedTriples, r.RemovedTriples)
+	}
+	if r.Empty() {
+		t.Error("Empty reports no changes")
+	}
+	if same := Compare(from, from); !same.Empty() || same.AddedTriples+same.RemovedTriples != 0 {
+		t.Errorf("comparing a graph with itself gives %+v", same)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	r := Compare(mustParse(t, oldBuild), mustParse(t, newBuild))
+	r.From, r.To = "build 1", "build 2"
+	markdown := r.Markdown()
+	for _, want := range []string{
+		"Comparing `build 1` with `build 2`.",
+		"| Services | 1 | 1 | |",
+		"| Resources | 1 | 1 | 1 |",
+		"## Added services\n\n- **payments** (Service) `http://example.com/payments`",
+		"## Changed resources\n\n- **orders** (Database)\n  - databaseType: `mongodb` → `postgres`",
+		"## New dependencies\n\n- **GetOrder** (Api) *writesTo* **orders** (Database)",
+		"## Dropped dependencies\n\n- **orders** (Service) *calls* **billing** (Service)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("the Markdown lacks %q:\n%s", want, markdown)
+		}
+	}
+
+	same := Compare(mustParse(t, oldBuild), mustParse(t, oldBuild)).Markdown()
+	if !strings.Contains(same, "No architectural changes (0 triples added, 0 removed).") {
+		t.Errorf("Markdown of an empty report:\n%s", same)
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := map[string]string{
+		"build 1":   "`build 1`",
+		"a`b":       "``a`b``",
+		"`quoted`":  "`` `quoted` ``",
+		"a``b`c":    "```a``b`c```",
+		"":          "``",
+		"no quotes": "`no quotes`",
+	}
+	for s, want := range tests {
+		if got := code(s); got != want {
+			t.Errorf("code(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestStoredBuilds(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, compose := rdf.IRI("http://graphmind.io/graph/repo/orders"), rdf.IRI("http://graphmind.io/graph/compose")
+	for _, put := range []struct {
+		name  rdf.IRI
+		data  string
+		build string
+	}{
+		{repo, oldBuild, "run-1"},
+		{compose, `ex:gateway a gm:Service ; gm:name "gateway" .`, "run-1"},
+		{repo, newBuild, "run-2"},
+	} {
+		if _, err := s.Put(put.name, mustParse(t, put.data), store.GraphInfo{Build: put.build}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := Versions(s, repo, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names(versions.AddedServices) != "payments" || versions.From != string(repo)+" version 1" || versions.To != string(repo)+" version 2" {
+		t.Errorf("Versions = %+v", versions)
+	}
+
+	// The compose graph is in both builds, so it is not a difference.
+	builds, err := Builds(s, "", "run-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names(builds.AddedServices) != "payments" || names(builds.RemovedServices) != "legacy" || builds.From != "build run-1" {
+		t.Errorf("Builds = %+v", builds)
+	}
+
+	if _, err := Versions(s, compose, 0, 0); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Versions of a graph with one version: %v, want ErrNotFound", err)
+	}
+	if _, err := Builds(s, "", "run-1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Builds of the first build: %v, want ErrNotFound", err)
+	}
+	if _, err := Builds(s, "run-1", "run-3"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Builds of an unknown build: %v, want ErrNotFound", err)
+	}
+}
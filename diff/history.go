@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"sort"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// HistoryQuery selects the triples whose history is traced. Nil terms are wildcards.
+type HistoryQuery struct {
+	Subject   rdf.Term
+	Predicate rdf.IRI
+	Object    rdf.Term
+	Graph     rdf.IRI // Restricts the history to one stored graph if set.
+	Branch    string  // Restricts the history to the versions built from one branch if set.
+}
+
+// Change is a version of a graph in which triples matching a history query appeared or disappeared.
+type Change struct {
+	Graph      string    `json:"graph"`
+	Version    int       `json:"version"`
+	Repository string    `json:"repository,omitempty"`
+	Branch     string    `json:"branch,omitempty"`
+	Commit     string    `json:"commit,omitempty"`
+	Time       time.Time `json:"time"` // The commit date, or the time the version was written.
+	Build      string    `json:"build,omitempty"`
+	Added      []string  `json:"added"` // Triples in N-Triples syntax.
+	Removed    []string  `json:"removed"`
+}
+
+// History traces the triples matching a query through the versions of the stored graphs, ordered by
+// commit date, and returns the versions in which they changed, oldest first. It answers questions such as
+// when one service started calling another: the first change adding the gm:calls edge.
+func History(s *store.Store, q HistoryQuery) ([]Change, error) {
+	var graphs []store.GraphInfo
+	if q.Graph != "" {
+		graphs = []store.GraphInfo{{Name: q.Graph}}
+	} else {
+		var err error
+		if graphs, err = s.Graphs(); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := []Change{}
+	for _, graph := range graphs {
+		versions, err := s.Versions(graph.Name)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(versions, func(i, j int) bool { return versions[i].Time().Before(versions[j].Time()) })
+
+		previous := map[rdf.Triple]bool{}
+		for _, info := range versions {
+			if q.Branch != "" && info.Branch != q.Branch {
+				continue
+			}
+			g, err := s.Load(info)
+			if err != nil {
+				return nil, err
+			}
+			current := map[rdf.Triple]bool{}
+			for _, t := range provenance.Strip(g).Match(q.Subject, q.Predicate, q.Object) {
+				current[t] = true
+			}
+
+			change := Change{
+				Graph:      string(info.Name),
+				Version:    info.Version,
+				Repository: info.Repository,
+				Branch:     info.Branch,
+				Commit:     info.Commit,
+				Time:       info.Time(),
+				Build:      info.Build,
+				Added:      missingTriples(current, previous),
+				Removed:    missingTriples(previous, current),
+			}
+			if len(change.Added) > 0 || len(change.Removed) > 0 {
+				changes = append(changes, change)
+			}
+			previous = current
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Time.Before(changes[j].Time) })
+	return changes, nil
+}
+
+// missingTriples returns the sorted triples of a that b does not have.
+func missingTriples(a, b map[rdf.Triple]bool) []string {
+	missing := []string{}
+	for t := range a {
+		if !b[t] {
+			missing = append(missing, t.String())
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,99 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown returns a human readable summary of the report: a table of counts followed by a section for
+// every kind of change that occurred.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Graph diff\n\n")
+	if r.From != "" || r.To != "" {
+		fmt.Fprintf(&b, "Comparing %s with %s.\n\n", code(r.From), code(r.To))
+	}
+	if r.Empty() {
+		fmt.Fprintf(&b, "No architectural changes (%d triples added, %d removed).\n", r.AddedTriples, r.RemovedTriples)
+		return b.String()
+	}
+
+	b.WriteString("| | Added | Removed | Changed |\n|---|---:|---:|---:|\n")
+	fmt.Fprintf(&b, "| Services | %d | %d | |\n", len(r.AddedServices), len(r.RemovedServices))
+	fmt.Fprintf(&b, "| APIs | %d | %d | |\n", len(r.AddedApis), len(r.RemovedApis))
+	fmt.Fprintf(&b, "| Resources | %d | %d | %d |\n", len(r.AddedResources), len(r.RemovedResources), len(r.ChangedResources))
+	fmt.Fprintf(&b, "| Dependencies | %d | %d | |\n", len(r.AddedDependencies), len(r.RemovedDependencies))
+	fmt.Fprintf(&b, "| Triples | %d | %d | |\n", r.AddedTriples, r.RemovedTriples)
+
+	writeEntities(&b, "Added services", r.AddedServices)
+	writeEntities(&b, "Removed services", r.RemovedServices)
+	writeEntities(&b, "Added APIs", r.AddedApis)
+	writeEntities(&b, "Removed APIs", r.RemovedApis)
+	writeEntities(&b, "Added resources", r.AddedResources)
+	writeEntities(&b, "Removed resources", r.RemovedResources)
+
+	if len(r.ChangedResources) > 0 {
+		b.WriteString("\n## Changed resources\n\n")
+		for _, change := range r.ChangedResources {
+			fmt.Fprintf(&b, "- %s\n", describe(change.Entity))
+			for _, property := range change.Changes {
+				fmt.Fprintf(&b, "  - %s: %s → %s\n", property.Property, values(property.Old), values(property.New))
+			}
+		}
+	}
+
+	writeDependencies(&b, "New dependencies", r.AddedDependencies)
+	writeDependencies(&b, "Dropped dependencies", r.RemovedDependencies)
+	return b.String()
+}
+
+func writeEntities(b *strings.Builder, title string, entities []Entity) {
+	if len(entities) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for _, e := range entities {
+		fmt.Fprintf(b, "- %s `%s`\n", describe(e), e.URI)
+	}
+}
+
+func writeDependencies(b *strings.Builder, title string, dependencies []Dependency) {
+	if len(dependencies) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for _, d := range dependencies {
+		fmt.Fprintf(b, "- %s *%s* %s\n", describe(d.From), d.Relation, describe(d.To))
+	}
+}
+
+// describe names an entity in bold followed by its class.
+func describe(e Entity) string {
+	if e.Class == "" {
+		return "**" + e.Name + "**"
+	}
+	return fmt.Sprintf("**%s** (%s)", e.Name, e.Class)
+}
+
+func values(v []string) string {
+	if len(v) == 0 {
+		return "*none*"
+	}
+	quoted := make([]string, len(v))
+	for i, s := range v {
+		quoted[i] = code(s)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// code formats text as inline code, using a longer fence if the text contains backticks.
+func code(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
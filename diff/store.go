@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// Versions compares two versions of a stored graph. A from or to of 0 selects the version before the
+// latest and the latest version, respectively.
+func Versions(s *store.Store, name rdf.IRI, from, to int) (*Report, error) {
+	versions, err := s.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		to = versions[len(versions)-1].Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 {
+		return nil, fmt.Errorf("%w: %s has no version before %d", store.ErrNotFound, name, to)
+	}
+
+	old, err := s.GraphVersion(name, from)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.GraphVersion(name, to)
+	if err != nil {
+		return nil, err
+	}
+	report := Compare(old, current)
+	report.From = fmt.Sprintf("%s version %d", string(name), from)
+	report.To = fmt.Sprintf("%s version %d", string(name), to)
+	return report, nil
+}
+
+// Builds compares all graphs of the store as two builds left them, see store.BuildUnion. An empty from
+// selects the build before to.
+func Builds(s *store.Store, from, to string) (*Report, error) {
+	if from == "" {
+		builds, err := s.Builds()
+		if err != nil {
+			return nil, err
+		}
+		for i, build := range builds {
+			if build == to && i > 0 {
+				from = builds[i-1]
+			}
+		}
+		if from == "" {
+			return nil, fmt.Errorf("%w: no build before %s", store.ErrNotFound, to)
+		}
+	}
+
+	old, err := s.BuildUnion(from)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.BuildUnion(to)
+	if err != nil {
+		return nil, err
+	}
+	report := Compare(old, current)
+	report.From = "build " + from
+	report.To = "build " + to
+	return report, nil
+}
+
+// Commits compares the versions of a stored graph built from two commits. An empty from selects the
+// version before the one built from to.
+func Commits(s *store.Store, name rdf.IRI, from, to string) (*Report, error) {
+	current, err := s.CommitVersion(name, to)
+	if err != nil {
+		return nil, err
+	}
+	fromVersion := 0
+	if from != "" {
+		old, err := s.CommitVersion(name, from)
+		if err != nil {
+			return nil, err
+		}
+		fromVersion = old.Version
+	}
+	report, err := Versions(s, name, fromVersion, current.Version)
+	if err != nil {
+		return nil, err
+	}
+	if from != "" {
+		report.From = fmt.Sprintf("%s at commit %s", string(name), from)
+	}
+	report.To = fmt.Sprintf("%s at commit %s", string(name), to)
+	return report, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SaiNageswarS/GraphMind/diff"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// runDiff implements the diff command, which compares two versions of a stored graph, the store as two
+// builds left it, or two Turtle files:
+//
+//	GraphMind diff -graph <name> [-from <n>] [-to <n>] [-format markdown|json] [-output <file>]
+//	GraphMind diff -graph <name> -to-commit <sha> [-from-commit <sha>]
+//	GraphMind diff -build <id> [-from-build <id>]
+//	GraphMind diff -old <old.ttl> -new <new.ttl>
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	graphName := flags.String("graph", "", "name of the stored graph whose versions to compare")
+	from := flags.Int("from", 0, "old version of the graph; the one before -to if 0")
+	to := flags.Int("to", 0, "new version of the graph; the latest if 0")
+	fromCommit := flags.String("from-commit", "", "commit of the old version of -graph; the version before -to-commit if empty")
+	toCommit := flags.String("to-commit", "", "commit of the new version of -graph")
+	build := flags.String("build", "", "build whose graphs to compare with the previous build")
+	fromBuild := flags.String("from-build", "", "build to compare -build with; the build before it if empty")
+	oldFile := flags.String("old", "", "Turtle file of the old graph")
+	newFile := flags.String("new", "", "Turtle file of the new graph")
+	format := flags.String("format", "markdown", "output format: markdown or json")
+	output := flags.String("output", "", "file to write; standard output if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("unknown diff format %q, expected markdown or json", *format)
+	}
+
+	// 1. Compare the selected graphs.
+	var report *diff.Report
+	switch {
+	case *oldFile != "" || *newFile != "":
+		if *oldFile == "" || *newFile == "" {
+			return fmt.Errorf("-old and -new must be given together")
+		}
+		old, err := rdf.ParseTurtleFile(*oldFile)
+		if err != nil {
+			return err
+		}
+		current, err := rdf.ParseTurtleFile(*newFile)
+		if err != nil {
+			return err
+		}
+		report = diff.Compare(old, current)
+		report.From, report.To = *oldFile, *newFile
+	case *graphName != "" || *build != "":
+		graphStore, err := store.Open(graphStoreDir())
+		if err != nil {
+			return err
+		}
+		switch {
+		case *graphName != "" && *toCommit != "":
+			report, err = diff.Commits(graphStore, rdf.IRI(*graphName), *fromCommit, *toCommit)
+		case *graphName != "":
+			report, err = diff.Versions(graphStore, rdf.IRI(*graphName), *from, *to)
+		default:
+			report, err = diff.Builds(graphStore, *fromBuild, *build)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("one of -graph, -build or -old and -new is required")
+	}
+
+	// 2. Write the report.
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	_, err := io.WriteString(w, report.Markdown())
+	return err
+}
@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// nodeLabel is the label of every exported node. The uri property is unique among nodes with this label,
+// which lets the relationship statements find their end nodes through an index.
+const nodeLabel = "Node"
+
+// WriteCypher writes the property graph view of the graph as Cypher statements that load it into Neo4j: a
+// uniqueness constraint on the uri property, one CREATE statement per node and one MATCH ... CREATE
+// statement per relationship. Typed literals become Cypher numbers and booleans where possible, and
+// properties with several values become lists. Relationships get a source property listing the code their
+// triple was derived from.
+func WriteCypher(w io.Writer, g *rdf.Graph) error {
+	pg := newPropertyGraph(g)
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "CREATE CONSTRAINT IF NOT EXISTS FOR (n:%s) REQUIRE n.uri IS UNIQUE;\n", nodeLabel)
+	for _, n := range pg.nodes {
+		labels := ":" + nodeLabel
+		for _, label := range n.labels {
+			labels += ":" + cypherName(label)
+		}
+		props := []string{"uri: " + cypherString(n.uri())}
+		for _, key := range pg.keys {
+			values := n.props[key]
+			switch len(values) {
+			case 0:
+				continue
+			case 1:
+				props = append(props, cypherName(key)+": "+cypherValue(values[0]))
+			default:
+				items := make([]string, len(values))
+				for i, v := range values {
+					items[i] = cypherValue(v)
+				}
+				props = append(props, cypherName(key)+": ["+strings.Join(items, ", ")+"]")
+			}
+		}
+		fmt.Fprintf(bw, "CREATE (%s {%s});\n", labels, strings.Join(props, ", "))
+	}
+	for _, e := range pg.edges {
+		props := ""
+		if locations := e.sourceLocations(); len(locations) > 0 {
+			items := make([]string, len(locations))
+			for i, location := range locations {
+				items[i] = cypherString(location)
+			}
+			props = " {source: [" + strings.Join(items, ", ") + "]}"
+		}
+		fmt.Fprintf(bw, "MATCH (a:%s {uri: %s}), (b:%s {uri: %s}) CREATE (a)-[:%s%s]->(b);\n",
+			nodeLabel, cypherString(e.from.uri()), nodeLabel, cypherString(e.to.uri()), cypherName(e.label), props)
+	}
+	return bw.Flush()
+}
+
+var plainCypherName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// cypherName returns a label, relationship type or property key, quoted with backticks if necessary.
+func cypherName(name string) string {
+	if plainCypherName.MatchString(name) {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func cypherValue(l rdf.Literal) string {
+	switch l.Datatype {
+	case rdf.XSDInteger, rdf.XSDDecimal, rdf.XSDDouble:
+		if numberPattern.MatchString(l.Value) {
+			return strings.TrimPrefix(l.Value, "+")
+		}
+	case rdf.XSDBoolean:
+		if l.Value == "true" || l.Value == "false" {
+			return l.Value
+		}
+	}
+	return cypherString(l.Value)
+}
+
+var numberPattern = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// cypherString returns s as a single-quoted Cypher string literal.
+func cypherString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// WriteDOT writes the property graph view of the graph as a Graphviz digraph. Nodes are labelled with
+// their name and types; literal properties other than the name are left out to keep the drawing readable.
+// Edges link to the code their triple was derived from, which makes them clickable in SVG output.
+func WriteDOT(w io.Writer, g *rdf.Graph) error {
+	pg := newPropertyGraph(g)
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("digraph GraphMind {\n")
+	bw.WriteString("  rankdir=LR;\n")
+	bw.WriteString("  node [shape=box, style=rounded];\n")
+	for _, n := range pg.nodes {
+		label := n.displayName()
+		if len(n.labels) > 0 {
+			label += "\n«" + strings.Join(n.labels, ", ") + "»"
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, tooltip=%s];\n", n.id, dotQuote(label), dotQuote(n.uri()))
+	}
+	for _, e := range pg.edges {
+		attributes := "label=" + dotQuote(e.label)
+		if locations := e.sourceLocations(); len(locations) > 0 {
+			attributes += ", URL=" + dotQuote(locations[0]) + ", tooltip=" + dotQuote(strings.Join(locations, "\n"))
+		}
+		fmt.Fprintf(bw, "  %s -> %s [%s];\n", e.from.id, e.to.id, attributes)
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
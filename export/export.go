@@ -0,0 +1,199 @@
+// Package export writes GraphMind graphs in the formats other graph tools load: the RDF serialisations
+// Turtle, N-Triples and JSON-LD, and the property graph formats GraphML, DOT and Cypher.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Format is an export format.
+type Format struct {
+	Name        string // The name used to select the format, for example "jsonld".
+	ContentType string
+	Extension   string
+	write       func(io.Writer, *rdf.Graph) error
+}
+
+// Formats lists the supported export formats.
+var Formats = []Format{
+	{Name: "turtle", ContentType: "text/turtle", Extension: ".ttl", write: rdf.WriteTurtle},
+	{Name: "ntriples", ContentType: "application/n-triples", Extension: ".nt", write: rdf.WriteNTriples},
+	{Name: "jsonld", ContentType: "application/ld+json", Extension: ".jsonld", write: rdf.WriteJSONLD},
+	{Name: "graphml", ContentType: "application/graphml+xml", Extension: ".graphml", write: WriteGraphML},
+	{Name: "dot", ContentType: "text/vnd.graphviz", Extension: ".dot", write: WriteDOT},
+	{Name: "cypher", ContentType: "application/x-cypher-query", Extension: ".cypher", write: WriteCypher},
+}
+
+// FormatByName returns the format with the given name. Names are case-insensitive.
+func FormatByName(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(f.Name, name) {
+			return f, nil
+		}
+	}
+	return Format{}, fmt.Errorf("unknown export format %q, expected one of %s", name, strings.Join(FormatNames(), ", "))
+}
+
+// FormatNames returns the names of the supported formats.
+func FormatNames() []string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Write writes the graph in the format.
+func (f Format) Write(w io.Writer, g *rdf.Graph) error {
+	return f.write(w, g)
+}
+
+// propertyGraph is the property graph view of an RDF graph used by the GraphML, DOT and Cypher exports.
+// IRIs and blank nodes become nodes, rdf:type objects become node labels, literal objects become node
+// properties and all other triples become edges. Provenance is not shown as nodes; instead every edge
+// carries the sources of its triple.
+type propertyGraph struct {
+	nodes []*pgNode
+	edges []pgEdge
+	keys  []string // Property keys used by any node, sorted.
+}
+
+type pgNode struct {
+	id     string // A short identifier unique within the export, "n0", "n1", ...
+	term   rdf.Term
+	labels []string
+	props  map[string][]rdf.Literal
+}
+
+type pgEdge struct {
+	from, to *pgNode
+	label    string
+	sources  []provenance.Source
+}
+
+func newPropertyGraph(g *rdf.Graph) *propertyGraph {
+	pg := &propertyGraph{}
+	sources := provenance.All(g)
+	g = provenance.Strip(g)
+	names := propertyNames(g)
+	byTerm := map[rdf.Term]*pgNode{}
+	node := func(t rdf.Term) *pgNode {
+		if n, ok := byTerm[t]; ok {
+			return n
+		}
+		n := &pgNode{id: fmt.Sprintf("n%d", len(pg.nodes)), term: t, props: map[string][]rdf.Literal{}}
+		byTerm[t] = n
+		pg.nodes = append(pg.nodes, n)
+		return n
+	}
+
+	keys := map[string]bool{}
+	for _, t := range g.Triples() {
+		subject := node(t.Subject)
+		switch object := t.Object.(type) {
+		case rdf.Literal:
+			key := names[t.Predicate]
+			subject.props[key] = append(subject.props[key], object)
+			keys[key] = true
+		case rdf.IRI:
+			if t.Predicate == rdf.RDFType {
+				subject.labels = append(subject.labels, rdf.LocalName(object))
+				continue
+			}
+			pg.edges = append(pg.edges, pgEdge{from: subject, to: node(object), label: names[t.Predicate], sources: sources[t]})
+		default:
+			pg.edges = append(pg.edges, pgEdge{from: subject, to: node(object), label: names[t.Predicate], sources: sources[t]})
+		}
+	}
+	for key := range keys {
+		pg.keys = append(pg.keys, key)
+	}
+	sort.Strings(pg.keys)
+	return pg
+}
+
+// propertyNames names the predicates of a graph by their local names, qualified with the namespace prefix
+// when two predicates share a local name.
+func propertyNames(g *rdf.Graph) map[rdf.IRI]string {
+	byLocal := map[string][]rdf.IRI{}
+	seen := map[rdf.IRI]bool{}
+	for _, t := range g.Triples() {
+		if !seen[t.Predicate] {
+			seen[t.Predicate] = true
+			local := rdf.LocalName(t.Predicate)
+			byLocal[local] = append(byLocal[local], t.Predicate)
+		}
+	}
+
+	names := map[rdf.IRI]string{}
+	for local, predicates := range byLocal {
+		if len(predicates) == 1 {
+			names[predicates[0]] = local
+			continue
+		}
+		for i, p := range predicates {
+			names[p] = fmt.Sprintf("%s_%s", namespacePrefix(g, p, i), local)
+		}
+	}
+	return names
+}
+
+// namespacePrefix returns the prefix bound to the namespace of an IRI, or "ns" followed by fallback.
+func namespacePrefix(g *rdf.Graph, i rdf.IRI, fallback int) string {
+	best := ""
+	for prefix, namespace := range g.Prefixes {
+		if strings.HasPrefix(string(i), namespace) && (best == "" || len(namespace) > len(g.Prefixes[best])) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return fmt.Sprintf("ns%d", fallback)
+	}
+	return best
+}
+
+// uri returns the identifier a node keeps in property graph formats: the IRI, or "_:label" for blank nodes.
+func (n *pgNode) uri() string {
+	if iri, ok := n.term.(rdf.IRI); ok {
+		return string(iri)
+	}
+	return n.term.String()
+}
+
+// displayName returns a human readable name for a node: its gm:name or rdfs:label, or the local name of
+// its IRI.
+func (n *pgNode) displayName() string {
+	for _, key := range []string{"name", "label"} {
+		if values := n.props[key]; len(values) > 0 {
+			return values[0].Value
+		}
+	}
+	if iri, ok := n.term.(rdf.IRI); ok {
+		return rdf.LocalName(iri)
+	}
+	return n.term.String()
+}
+
+// sourceLocations returns where the triple of an edge comes from: a link to the code for every source in a
+// repository, or the file and lines for the others. Sources without a file are left out.
+func (e pgEdge) sourceLocations() []string {
+	var locations []string
+	seen := map[string]bool{}
+	for _, source := range e.sources {
+		location := source.Link()
+		if location == "" {
+			location = source.Lines()
+		}
+		if location != "" && !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+	return locations
+}
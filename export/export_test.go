@@ -0,0 +1,159 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// testGraph has two services, one calling the other, with a name that needs escaping in every format,
+// typed and repeated literals, and a call found in the code.
+func testGraph(t *testing.T) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(`
+		@prefix gm: <http://graphmind.io/ontology#> .
+		@prefix ex: <http://example.com/> .
+		ex:orders a gm:Service ; gm:name "Order \"Service\"\nv2" ; ex:port 8080 ; ex:public true ; ex:max-size 1.5 ;
+			ex:tag "b", "a's" ; gm:calls ex:billing .
+		ex:billing a gm:Service ; gm:name "billing" .
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provenance.Record(g, rdf.Triple{Subject: rdf.IRI("http://example.com/orders"), Predicate: ontology.Calls, Object: rdf.IRI("http://example.com/billing")},
+		provenance.Source{Repository: "https://github.com/org/orders", Commit: "abc1234", File: "client.go", StartLine: 12, EndLine: 14, Activity: "LinkServiceCalls"})
+	return g
+}
+
+func write(t *testing.T, format string) string {
+	t.Helper()
+	f, err := FormatByName(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := f.Write(&b, testGraph(t)); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestWriteGraphML(t *testing.T) {
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="uri" for="node" attr.name="uri" attr.type="string"/>
+  <key id="labels" for="node" attr.name="labels" attr.type="string"/>
+  <key id="p0" for="node" attr.name="max-size" attr.type="string"/>
+  <key id="p1" for="node" attr.name="name" attr.type="string"/>
+  <key id="p2" for="node" attr.name="port" attr.type="string"/>
+  <key id="p3" for="node" attr.name="public" attr.type="string"/>
+  <key id="p4" for="node" attr.name="tag" attr.type="string"/>
+  <key id="label" for="edge" attr.name="label" attr.type="string"/>
+  <key id="source" for="edge" attr.name="source" attr.type="string"/>
+  <graph id="G" edgedefault="directed">
+    <node id="n0">
+      <data key="uri">http://example.com/billing</data>
+      <data key="labels">:Service</data>
+      <data key="p1">billing</data>
+    </node>
+    <node id="n1">
+      <data key="uri">http://example.com/orders</data>
+      <data key="labels">:Service</data>
+      <data key="p0">1.5</data>
+      <data key="p1">Order &#34;Service&#34;&#xA;v2</data>
+      <data key="p2">8080</data>
+      <data key="p3">true</data>
+      <data key="p4">a&#39;s&#xA;b</data>
+    </node>
+    <edge id="e0" source="n1" target="n0">
+      <data key="label">calls</data>
+      <data key="source">https://github.com/org/orders/blob/abc1234/client.go#L12-L14</data>
+    </edge>
+  </graph>
+</graphml>
+`
+	if got := write(t, "graphml"); got != want {
+		t.Errorf("GraphML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	want := `digraph GraphMind {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  n0 [label="billing\n«Service»", tooltip="http://example.com/billing"];
+  n1 [label="Order \"Service\"\nv2\n«Service»", tooltip="http://example.com/orders"];
+  n1 -> n0 [label="calls", URL="https://github.com/org/orders/blob/abc1234/client.go#L12-L14", tooltip="https://github.com/org/orders/blob/abc1234/client.go#L12-L14"];
+}
+`
+	if got := write(t, "dot"); got != want {
+		t.Errorf("DOT:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteCypher(t *testing.T) {
+	want := `CREATE CONSTRAINT IF NOT EXISTS FOR (n:Node) REQUIRE n.uri IS UNIQUE;
+CREATE (:Node:Service {uri: 'http://example.com/billing', name: 'billing'});
+CREATE (:Node:Service {uri: 'http://example.com/orders', ` + "`max-size`" + `: 1.5, name: 'Order "Service"\nv2', port: 8080, public: true, tag: ['a\'s', 'b']});
+MATCH (a:Node {uri: 'http://example.com/orders'}), (b:Node {uri: 'http://example.com/billing'}) CREATE (a)-[:calls {source: ['https://github.com/org/orders/blob/abc1234/client.go#L12-L14']}]->(b);
+`
+	if got := write(t, "cypher"); got != want {
+		t.Errorf("Cypher:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want string
+	}{
+		{"DOT backslash", dotQuote(`C:\dir`), `"C:\\dir"`},
+		{"DOT quote and newline", dotQuote("a \"b\"\nc"), `"a \"b\"\nc"`},
+		{"Cypher quote and controls", cypherString("it's\\\r\t"), `'it\'s\\\r\t'`},
+		{"Cypher plain name", cypherName("writesTo"), "writesTo"},
+		{"Cypher backtick", cypherName("a`b c"), "`a``b c`"},
+		{"Cypher number", cypherValue(rdf.NewTypedLiteral("+42", rdf.XSDInteger)), "42"},
+		{"Cypher invalid number", cypherValue(rdf.NewTypedLiteral("4x", rdf.XSDInteger)), "'4x'"},
+		{"Cypher invalid boolean", cypherValue(rdf.NewTypedLiteral("yes", rdf.XSDBoolean)), "'yes'"},
+		{"XML", xmlEscape("<a & b>"), "&lt;a &amp; b&gt;"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestPropertyNames(t *testing.T) {
+	g, err := rdf.ParseTurtle(`@prefix gm: <http://graphmind.io/ontology#> . @prefix ex: <http://example.com/> .
+		ex:a gm:name "a" ; ex:name "b" ; <http://other.org/name> "c" ; ex:port 1 .`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := propertyNames(g)
+	for predicate, want := range map[rdf.IRI]string{
+		ontology.Name:             "gm_name",
+		"http://example.com/name": "ex_name",
+		"http://example.com/port": "port",
+	} {
+		if names[predicate] != want {
+			t.Errorf("the name of %s is %q, want %q", predicate, names[predicate], want)
+		}
+	}
+	if name := names["http://other.org/name"]; !strings.HasPrefix(name, "ns") || !strings.HasSuffix(name, "_name") {
+		t.Errorf("the name of an unbound namespace is %q, want ns<n>_name", name)
+	}
+}
+
+func TestFormatByName(t *testing.T) {
+	f, err := FormatByName("JSONLD")
+	if err != nil || f.Name != "jsonld" || f.Extension != ".jsonld" {
+		t.Errorf("FormatByName(JSONLD) = %+v, %v", f, err)
+	}
+	if _, err := FormatByName("svg"); err == nil || !strings.Contains(err.Error(), "turtle, ntriples, jsonld, graphml, dot, cypher") {
+		t.Errorf("FormatByName(svg) = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// WriteGraphML writes the property graph view of the graph as GraphML. Every node has a uri attribute and
+// a labels attribute in the ":Label1:Label2" form Neo4j reads, literal properties become node attributes
+// with multiple values joined by newlines, and every edge has a label attribute naming its predicate and a
+// source attribute locating the code its triple was derived from.
+func WriteGraphML(w io.Writer, g *rdf.Graph) error {
+	pg := newPropertyGraph(g)
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	bw.WriteString(`  <key id="uri" for="node" attr.name="uri" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <key id="labels" for="node" attr.name="labels" attr.type="string"/>` + "\n")
+	keyIDs := map[string]string{}
+	for i, key := range pg.keys {
+		keyIDs[key] = fmt.Sprintf("p%d", i)
+		fmt.Fprintf(bw, `  <key id="p%d" for="node" attr.name="%s" attr.type="string"/>`+"\n", i, xmlEscape(key))
+	}
+	bw.WriteString(`  <key id="label" for="edge" attr.name="label" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <key id="source" for="edge" attr.name="source" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <graph id="G" edgedefault="directed">` + "\n")
+
+	for _, n := range pg.nodes {
+		fmt.Fprintf(bw, `    <node id="%s">`+"\n", n.id)
+		fmt.Fprintf(bw, `      <data key="uri">%s</data>`+"\n", xmlEscape(n.uri()))
+		if len(n.labels) > 0 {
+			fmt.Fprintf(bw, `      <data key="labels">%s</data>`+"\n", xmlEscape(":"+strings.Join(n.labels, ":")))
+		}
+		for _, key := range pg.keys {
+			values := n.props[key]
+			if len(values) == 0 {
+				continue
+			}
+			texts := make([]string, len(values))
+			for i, v := range values {
+				texts[i] = v.Value
+			}
+			fmt.Fprintf(bw, `      <data key="%s">%s</data>`+"\n", keyIDs[key], xmlEscape(strings.Join(texts, "\n")))
+		}
+		bw.WriteString("    </node>\n")
+	}
+	for i, e := range pg.edges {
+		fmt.Fprintf(bw, `    <edge id="e%d" source="%s" target="%s">`+"\n", i, e.from.id, e.to.id)
+		fmt.Fprintf(bw, `      <data key="label">%s</data>`+"\n", xmlEscape(e.label))
+		if locations := e.sourceLocations(); len(locations) > 0 {
+			fmt.Fprintf(bw, `      <data key="source">%s</data>`+"\n", xmlEscape(strings.Join(locations, "\n")))
+		}
+		bw.WriteString("    </edge>\n")
+	}
+
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -2,7 +2,10 @@ module github.com/SaiNageswarS/GraphMind
 
 go 1.22.3
 
-require go.temporal.io/sdk v1.33.1
+require (
+	go.temporal.io/sdk v1.33.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -28,5 +31,4 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240827150818-7e3bb234dfed // indirect
 	google.golang.org/grpc v1.66.0 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,376 @@
+// Package impact computes, deterministically from a GraphMind graph, everything that transitively depends
+// on a node and everything the node depends on, with the path that explains each result. A node depends on
+// the APIs it calls, the resources it reads, writes, publishes to, subscribes to or uses, and on the
+// service, repository or database it is part of. Every edge carries the evidence and confidence of its
+// strongest source, and a path is as confident as the product of its edges.
+package impact
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/resolve"
+)
+
+// Direction selects which side of a node is analyzed.
+type Direction string
+
+const (
+	Dependents   Direction = "dependents"   // The nodes that transitively depend on the node.
+	Dependencies Direction = "dependencies" // The nodes the node transitively depends on.
+	Both         Direction = "both"
+)
+
+// Options limits an analysis.
+type Options struct {
+	Direction     Direction
+	MaxDepth      int     // The longest path followed; 0 for no limit.
+	MinConfidence float64 // Paths less confident than this are not followed; 0 follows all.
+}
+
+// DefaultOptions analyzes both directions without a depth limit.
+func DefaultOptions() Options {
+	return Options{Direction: Both}
+}
+
+// Node is a node of the graph as it appears in a result.
+type Node struct {
+	URI        string `json:"uri"`
+	Class      string `json:"class,omitempty"` // The local name of the most specific GraphMind class, for example "Api".
+	Name       string `json:"name"`
+	Service    string `json:"service,omitempty"`    // The name of the service an API belongs to.
+	Repository string `json:"repository,omitempty"` // The URL, or else the name, of the repository an API or service belongs to.
+}
+
+// Step is an edge of the graph on the path that explains a result.
+type Step struct {
+	Subject  string `json:"subject"`
+	Relation string `json:"relation"` // The local name of the predicate, for example "calls".
+	Object   string `json:"object"`
+
+	Evidence   provenance.Evidence `json:"evidence,omitempty"`   // The evidence of the strongest source of the triple.
+	Confidence float64             `json:"confidence,omitempty"` // The confidence of that source; 0 if the triple has no provenance.
+}
+
+// Impacted is a node reached from the analyzed node. Its path is one of the shortest, ordered from the
+// dependent to the dependency: from the reached node to the analyzed node for dependents, and from the
+// analyzed node to the reached node for dependencies. Steps are the triples as written in the graph.
+type Impacted struct {
+	Node
+	Depth       int    `json:"depth"`
+	Path        []Step `json:"path"`
+	Explanation string `json:"explanation"` // The path in words, for example "Checkout (Api) calls Charge (Api)".
+
+	// Confidence is the product of the confidences of the steps with provenance, or 0 if none has any.
+	Confidence float64 `json:"confidence,omitempty"`
+	// Evidence is the evidence of the least confident step, which the path is only as good as.
+	Evidence provenance.Evidence `json:"evidence,omitempty"`
+}
+
+// Result is the impact of a node.
+type Result struct {
+	Root         Node       `json:"root"`
+	Direction    Direction  `json:"direction"`
+	Dependents   []Impacted `json:"dependents"`
+	Dependencies []Impacted `json:"dependencies"`
+	MaxDepth     int        `json:"maxDepth,omitempty"`
+	Truncated    bool       `json:"truncated"` // Whether the depth limit cut off nodes that would have been reached.
+}
+
+// dependsOn are the predicates whose subject depends on their object.
+var dependsOn = []rdf.IRI{
+	ontology.Calls,
+	ontology.ReadsFrom,
+	ontology.WritesTo,
+	ontology.PublishesTo,
+	ontology.SubscribesTo,
+	ontology.UsesResource,
+	ontology.UsesConfig,
+	ontology.DependsOn,
+	ontology.PartOf,
+	ontology.DeploysRepository,
+	ontology.RunsService,
+	ontology.AttachedTo,
+}
+
+// contains are the predicates whose object is part of, and therefore depends on, their subject.
+var contains = []rdf.IRI{
+	ontology.HasService,
+	ontology.HasApi,
+	ontology.HasCollection,
+}
+
+// edge is a dependency between two nodes together with the triple it comes from.
+type edge struct {
+	node       rdf.IRI // The node at the other end.
+	dependent  rdf.IRI
+	dependency rdf.IRI
+	verb       string // The relation read from the dependent to the dependency.
+	step       Step
+}
+
+// Analyzer answers impact queries over one graph. Build it once with New to analyze many nodes.
+type Analyzer struct {
+	graph        *rdf.Graph
+	dependencies map[rdf.IRI][]edge // The direct dependencies of every node.
+	dependents   map[rdf.IRI][]edge // The direct dependents of every node.
+	aliases      map[rdf.IRI]rdf.IRI
+}
+
+// New indexes the dependencies of a graph with the evidence of each from the graph's provenance. Nodes
+// merged by entity resolution can be referred to by any of their former URIs.
+func New(g *rdf.Graph) *Analyzer {
+	sources := provenance.All(g)
+	g = provenance.Strip(g)
+	a := &Analyzer{
+		graph:        g,
+		dependencies: map[rdf.IRI][]edge{},
+		dependents:   map[rdf.IRI][]edge{},
+		aliases:      map[rdf.IRI]rdf.IRI{},
+	}
+	add := func(dependent, dependency rdf.IRI, verb string, t rdf.Triple) {
+		e := edge{dependent: dependent, dependency: dependency, verb: verb,
+			step: Step{Subject: rdf.Value(t.Subject), Relation: rdf.LocalName(t.Predicate), Object: rdf.Value(t.Object)}}
+		if source, ok := provenance.Strongest(sources[t]); ok {
+			e.step.Evidence, e.step.Confidence = source.Evidence, source.Confidence
+		}
+		e.node = dependency
+		a.dependencies[dependent] = append(a.dependencies[dependent], e)
+		e.node = dependent
+		a.dependents[dependency] = append(a.dependents[dependency], e)
+	}
+	for _, predicate := range dependsOn {
+		for _, t := range g.Match(nil, predicate, nil) {
+			if subject, object, ok := iris(t); ok && subject != object {
+				add(subject, object, rdf.LocalName(predicate), t)
+			}
+		}
+	}
+	for _, predicate := range contains {
+		for _, t := range g.Match(nil, predicate, nil) {
+			if subject, object, ok := iris(t); ok && subject != object {
+				add(object, subject, rdf.LocalName(ontology.PartOf), t)
+			}
+		}
+	}
+	for _, edges := range a.dependencies {
+		sortEdges(edges)
+	}
+	for _, edges := range a.dependents {
+		sortEdges(edges)
+	}
+	for _, t := range g.Match(nil, resolve.OWLSameAs, nil) {
+		if kept, alias, ok := iris(t); ok {
+			a.aliases[alias] = kept
+		}
+	}
+	return a
+}
+
+// Analyze returns the impact of a node, which may be given by its URI or a former URI.
+func (a *Analyzer) Analyze(node rdf.IRI, opts Options) (*Result, error) {
+	root, ok := a.Resolve(node)
+	if !ok {
+		return nil, fmt.Errorf("node %s is not in the graph", node)
+	}
+	if opts.Direction == "" {
+		opts.Direction = Both
+	}
+	result := &Result{Root: a.node(root), Direction: opts.Direction, Dependents: []Impacted{}, Dependencies: []Impacted{}, MaxDepth: opts.MaxDepth}
+	if opts.Direction != Dependencies {
+		var truncated bool
+		result.Dependents, truncated = a.walk(root, a.dependents, true, opts)
+		result.Truncated = result.Truncated || truncated
+	}
+	if opts.Direction != Dependents {
+		var truncated bool
+		result.Dependencies, truncated = a.walk(root, a.dependencies, false, opts)
+		result.Truncated = result.Truncated || truncated
+	}
+	return result, nil
+}
+
+// Resolve returns the node a URI refers to: the URI itself if the graph has it, or the node it was merged
+// into.
+func (a *Analyzer) Resolve(node rdf.IRI) (rdf.IRI, bool) {
+	if kept, ok := a.aliases[node]; ok {
+		return kept, true
+	}
+	if len(a.graph.Match(node, "", nil)) > 0 || len(a.graph.Match(nil, "", node)) > 0 {
+		return node, true
+	}
+	return "", false
+}
+
+// walk visits the nodes reachable from root through the edges breadth first, so every node is reached
+// through one of its shortest paths that is confident enough. Edges are visited in sorted order, which
+// makes the paths deterministic. toRoot tells whether the paths run from the reached nodes to the root.
+// The result is ordered by depth and, within a depth, most confident first.
+func (a *Analyzer) walk(root rdf.IRI, edges map[rdf.IRI][]edge, toRoot bool, opts Options) ([]Impacted, bool) {
+	type visit struct {
+		node rdf.IRI
+		path []edge // From dependent to dependency.
+	}
+	visited := map[rdf.IRI]bool{root: true}
+	queue := []visit{{node: root}}
+	impacted := []Impacted{}
+	truncated := false
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range edges[current.node] {
+			if visited[e.node] {
+				continue
+			}
+			if opts.MaxDepth > 0 && len(current.path) >= opts.MaxDepth {
+				truncated = true
+				break
+			}
+			var path []edge
+			if toRoot {
+				path = append([]edge{e}, current.path...)
+			} else {
+				path = append(append([]edge{}, current.path...), e)
+			}
+			reached := a.impacted(e.node, path)
+			if reached.Confidence > 0 && reached.Confidence < opts.MinConfidence {
+				continue
+			}
+			visited[e.node] = true
+			impacted = append(impacted, reached)
+			queue = append(queue, visit{node: e.node, path: path})
+		}
+	}
+	sort.SliceStable(impacted, func(i, j int) bool {
+		if impacted[i].Depth != impacted[j].Depth {
+			return impacted[i].Depth < impacted[j].Depth
+		}
+		if impacted[i].Confidence != impacted[j].Confidence {
+			return impacted[i].Confidence > impacted[j].Confidence
+		}
+		return impacted[i].URI < impacted[j].URI
+	})
+	return impacted, truncated
+}
+
+// impacted describes a reached node and explains its path in words, naming every node once, for example
+// "Checkout (Api) calls Charge (Api) writesTo payments (Collection)".
+func (a *Analyzer) impacted(node rdf.IRI, path []edge) Impacted {
+	reached := Impacted{Node: a.node(node), Depth: len(path), Path: make([]Step, len(path))}
+	var b strings.Builder
+	b.WriteString(a.label(path[0].dependent))
+	weakest := 0.0
+	for i, e := range path {
+		reached.Path[i] = e.step
+		fmt.Fprintf(&b, " %s %s", e.verb, a.label(e.dependency))
+		if e.step.Confidence == 0 {
+			continue
+		}
+		if reached.Confidence == 0 {
+			reached.Confidence = 1
+		}
+		reached.Confidence *= e.step.Confidence
+		if weakest == 0 || e.step.Confidence < weakest {
+			weakest, reached.Evidence = e.step.Confidence, e.step.Evidence
+		}
+	}
+	reached.Explanation = b.String()
+	return reached
+}
+
+// label names a node with its class, for example "Charge (Api)".
+func (a *Analyzer) label(uri rdf.IRI) string {
+	n := a.node(uri)
+	if n.Class == "" {
+		return n.Name
+	}
+	return fmt.Sprintf("%s (%s)", n.Name, n.Class)
+}
+
+// nodeClasses orders the classes from most to least specific for naming the class of a node.
+var nodeClasses = []rdf.IRI{
+	ontology.Api,
+	ontology.Service,
+	ontology.Repository,
+	ontology.Collection,
+	ontology.Database,
+	ontology.Topic,
+	ontology.ConfigKey,
+	ontology.CloudResource,
+	ontology.Resource,
+	ontology.ComposeService,
+	ontology.ComposeProject,
+	ontology.Network,
+	ontology.EnvironmentVariable,
+}
+
+// node describes a node by its URI, class and name.
+func (a *Analyzer) node(uri rdf.IRI) Node {
+	n := Node{URI: string(uri), Name: rdf.LocalName(uri)}
+	for _, class := range nodeClasses {
+		if a.graph.Contains(rdf.Triple{Subject: uri, Predicate: rdf.RDFType, Object: class}) {
+			n.Class = rdf.LocalName(class)
+			break
+		}
+	}
+	n.Name = a.name(uri)
+
+	// Name the service and repository the node belongs to, which are what a change is made in.
+	service := uri
+	if n.Class == rdf.LocalName(ontology.Api) {
+		service, _ = a.container(uri, ontology.HasApi)
+		if service != "" {
+			n.Service = a.name(service)
+		}
+	}
+	if repository, ok := a.container(service, ontology.HasService); ok && service != "" {
+		n.Repository = a.name(repository)
+		if url := a.graph.Object(repository, ontology.RepoURL); url != nil {
+			n.Repository = rdf.Value(url)
+		}
+	}
+	return n
+}
+
+// name returns the gm:name or rdfs:label of a node, or else the local name of its URI.
+func (a *Analyzer) name(uri rdf.IRI) string {
+	if name := a.graph.Object(uri, ontology.Name); name != nil {
+		return rdf.Value(name)
+	}
+	if label := a.graph.Object(uri, rdf.RDFSLabel); label != nil {
+		return rdf.Value(label)
+	}
+	return rdf.LocalName(uri)
+}
+
+// container returns the node that has a node through a containment predicate such as gm:hasApi.
+func (a *Analyzer) container(uri rdf.IRI, predicate rdf.IRI) (rdf.IRI, bool) {
+	for _, t := range a.graph.Match(nil, predicate, uri) {
+		if container, ok := t.Subject.(rdf.IRI); ok {
+			return container, true
+		}
+	}
+	return "", false
+}
+
+func iris(t rdf.Triple) (subject, object rdf.IRI, ok bool) {
+	subject, ok = t.Subject.(rdf.IRI)
+	if !ok {
+		return "", "", false
+	}
+	object, ok = t.Object.(rdf.IRI)
+	return subject, object, ok
+}
+
+func sortEdges(edges []edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].step.Relation != edges[j].step.Relation {
+			return edges[i].step.Relation < edges[j].step.Relation
+		}
+		return edges[i].node < edges[j].node
+	})
+}
@@ -0,0 +1,221 @@
+package impact
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// testGraph has a Checkout API calling a Charge API of another repository, which writes to a payments
+// collection that a Refund API reads. The call was found by static analysis and the write by an LLM.
+func testGraph(t *testing.T) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(`
+		@prefix gm: <http://graphmind.io/ontology#> .
+		@prefix owl: <http://www.w3.org/2002/07/owl#> .
+		@prefix ex: <http://example.com/> .
+		ex:checkoutRepo a gm:Repository ; gm:name "checkout" ; gm:repoUrl "https://github.com/org/checkout" ; gm:hasService ex:checkoutService .
+		ex:checkoutService a gm:Service ; gm:name "CheckoutService" ; gm:hasApi ex:checkout .
+		ex:checkout a gm:Api ; gm:name "Checkout" ; gm:calls ex:charge .
+		ex:paymentRepo a gm:Repository ; gm:repoUrl "https://github.com/org/payments" ; gm:hasService ex:paymentService .
+		ex:paymentService a gm:Service ; gm:name "PaymentService" ; gm:hasApi ex:charge, ex:refund .
+		ex:charge a gm:Api ; gm:name "Charge" ; gm:writesTo ex:payments .
+		ex:refund a gm:Api ; gm:name "Refund" ; gm:readsFrom ex:payments .
+		ex:paymentsDb a gm:Database ; gm:name "payments DB" ; gm:hasCollection ex:payments .
+		ex:payments a gm:Collection ; gm:name "payments" ; owl:sameAs ex:paymentsTable .
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provenance.Record(g, rdf.Triple{Subject: ex("checkout"), Predicate: ontology.Calls, Object: ex("charge")},
+		provenance.Source{Activity: "LinkServiceCalls", File: "checkout.go", StartLine: 12})
+	provenance.Record(g, rdf.Triple{Subject: ex("charge"), Predicate: ontology.WritesTo, Object: ex("payments")},
+		provenance.Source{Activity: "BuildAstRdf", File: "charge.go"})
+	return g
+}
+
+func ex(name string) rdf.IRI {
+	return rdf.IRI("http://example.com/" + name)
+}
+
+// summary lists impacted nodes as "name depth", in the order of the result.
+func summary(impacted []Impacted) string {
+	var lines []string
+	for _, i := range impacted {
+		lines = append(lines, fmt.Sprintf("%s %d", i.Name, i.Depth))
+	}
+	return strings.Join(lines, ", ")
+}
+
+func TestAnalyze(t *testing.T) {
+	a := New(testGraph(t))
+	tests := []struct {
+		name             string
+		node             string
+		opts             Options
+		wantDependents   string
+		wantDependencies string
+		wantTruncated    bool
+	}{
+		{
+			name:             "both directions",
+			node:             "payments",
+			opts:             DefaultOptions(),
+			wantDependents:   "Charge 1, Refund 1, Checkout 2",
+			wantDependencies: "payments DB 1",
+		},
+		{
+			name:             "former URI",
+			node:             "paymentsTable",
+			opts:             Options{Direction: Dependents},
+			wantDependents:   "Charge 1, Refund 1, Checkout 2",
+			wantDependencies: "",
+		},
+		{
+			// Within a depth, the more confident paths come first.
+			name:             "dependencies",
+			node:             "checkout",
+			opts:             Options{Direction: Dependencies},
+			wantDependencies: "Charge 1, CheckoutService 1, PaymentService 2, payments 2, checkout 2, paymentRepo 3, payments DB 3",
+		},
+		{
+			name:             "depth limit",
+			node:             "checkout",
+			opts:             Options{Direction: Dependencies, MaxDepth: 1},
+			wantDependencies: "Charge 1, CheckoutService 1",
+			wantTruncated:    true,
+		},
+		{
+			name:           "confidence limit",
+			node:           "payments",
+			opts:           Options{Direction: Dependents, MinConfidence: 0.58},
+			wantDependents: "Charge 1, Refund 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := a.Analyze(ex(tt.node), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(result.Dependents); got != tt.wantDependents {
+				t.Errorf("dependents = %q, want %q", got, tt.wantDependents)
+			}
+			if got := summary(result.Dependencies); got != tt.wantDependencies {
+				t.Errorf("dependencies = %q, want %q", got, tt.wantDependencies)
+			}
+			if result.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %v", result.Truncated)
+			}
+		})
+	}
+
+	if _, err := a.Analyze(ex("unknown"), DefaultOptions()); err == nil {
+		t.Error("analyzing a node that is not in the graph succeeded")
+	}
+}
+
+func TestPathsAndConfidence(t *testing.T) {
+	result, err := New(testGraph(t)).Analyze(ex("paymentsTable"), Options{Direction: Dependents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Root.URI != string(ex("payments")) || result.Root.Class != "Collection" {
+		t.Errorf("root = %+v, want the payments collection", result.Root)
+	}
+
+	charge, refund, checkout := result.Dependents[0], result.Dependents[1], result.Dependents[2]
+	if charge.Service != "PaymentService" || charge.Repository != "https://github.com/org/payments" {
+		t.Errorf("Charge = %+v, want an API of PaymentService in the payments repository", charge.Node)
+	}
+	if charge.Confidence != 0.6 || charge.Evidence != provenance.LLMInference {
+		t.Errorf("Charge confidence = %v %s, want 0.6 llm-inference", charge.Confidence, charge.Evidence)
+	}
+	if refund.Confidence != 0 || refund.Evidence != "" {
+		t.Errorf("Refund has no provenance but confidence %v %s", refund.Confidence, refund.Evidence)
+	}
+
+	if want := "Checkout (Api) calls Charge (Api) writesTo payments (Collection)"; checkout.Explanation != want {
+		t.Errorf("explanation = %q, want %q", checkout.Explanation, want)
+	}
+	if len(checkout.Path) != 2 || checkout.Path[0].Relation != "calls" || checkout.Path[0].Evidence != provenance.StaticAnalysis ||
+		checkout.Path[1].Object != string(ex("payments")) {
+		t.Errorf("path = %+v", checkout.Path)
+	}
+	if checkout.Confidence < 0.5699 || checkout.Confidence > 0.5701 || checkout.Evidence != provenance.LLMInference {
+		t.Errorf("Checkout confidence = %v %s, want 0.57 llm-inference", checkout.Confidence, checkout.Evidence)
+	}
+}
+
+func TestFindAndMentions(t *testing.T) {
+	a := New(testGraph(t))
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"http://example.com/charge", "charge"},
+		{"http://example.com/paymentsTable", "payments"},
+		{"CHARGE", "charge"},
+		{"payments DB", "paymentsDb"},
+		{"nothing", ""},
+	}
+	for _, tt := range tests {
+		if got := localNames(a.Find(tt.ref)); got != tt.want {
+			t.Errorf("Find(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+
+	mentions := []struct {
+		text string
+		want string
+	}{
+		{"Add a refund reason to Payments and rename CheckoutService.", "checkoutService payments refund"},
+		{"Store prepayments_v2 in the payments DB", "payments paymentsDb"},
+		{"Charge-back handling", "charge"},
+		{"Nothing relevant", ""},
+	}
+	for _, tt := range mentions {
+		if got := localNames(a.Mentions(tt.text)); got != tt.want {
+			t.Errorf("Mentions(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func localNames(nodes []rdf.IRI) string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, rdf.LocalName(node))
+	}
+	return strings.Join(names, " ")
+}
+
+func TestText(t *testing.T) {
+	a := New(testGraph(t))
+	result, err := a.Analyze(ex("payments"), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Text()
+	for _, want := range []string{
+		"Impact of payments (Collection) <http://example.com/payments>:\n",
+		"Depends on it:\n- Charge (Api of PaymentService in https://github.com/org/payments) <http://example.com/charge>, depth 1, confidence 0.60 (weakest step llm-inference): Charge (Api) writesTo payments (Collection)\n",
+		"- Refund (Api of PaymentService in https://github.com/org/payments) <http://example.com/refund>, depth 1: Refund (Api) readsFrom payments (Collection)\n",
+		"It depends on:\n- payments DB (Database) <http://example.com/paymentsDb>, depth 1: payments (Collection) partOf payments DB (Database)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("the text lacks %q:\n%s", want, text)
+		}
+	}
+
+	result, err = a.Analyze(ex("refund"), Options{Direction: Dependents, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Impact of Refund (Api of PaymentService in https://github.com/org/payments) <http://example.com/refund>:\nDepends on it: nothing\n"; result.Text() != want {
+		t.Errorf("Text = %q, want %q", result.Text(), want)
+	}
+}
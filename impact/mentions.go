@@ -0,0 +1,91 @@
+package impact
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// minMentionLength keeps short names such as "db" or "id" from matching everywhere.
+const minMentionLength = 3
+
+// Find returns the nodes a reference names: the node with that URI or former URI, or else the nodes whose
+// gm:name is the reference, ignoring case.
+func (a *Analyzer) Find(ref string) []rdf.IRI {
+	if node, ok := a.Resolve(rdf.IRI(ref)); ok {
+		return []rdf.IRI{node}
+	}
+	var found []rdf.IRI
+	for _, node := range a.named() {
+		if strings.EqualFold(rdf.Value(a.graph.Object(node, ontology.Name)), ref) {
+			found = append(found, node)
+		}
+	}
+	return found
+}
+
+// Mentions returns the nodes whose gm:name occurs in a text as a whole word, ignoring case, for example the
+// orders collection in "Add a discount field to orders". They are the starting points for the impact of a
+// specification.
+func (a *Analyzer) Mentions(text string) []rdf.IRI {
+	text = strings.ToLower(text)
+	var mentioned []rdf.IRI
+	for _, node := range a.named() {
+		name := strings.ToLower(rdf.Value(a.graph.Object(node, ontology.Name)))
+		if len(name) >= minMentionLength && containsWord(text, name) {
+			mentioned = append(mentioned, node)
+		}
+	}
+	return mentioned
+}
+
+// named returns the nodes of the analyzed classes that have a gm:name, sorted.
+func (a *Analyzer) named() []rdf.IRI {
+	seen := map[rdf.IRI]bool{}
+	for _, class := range nodeClasses {
+		for _, node := range a.graph.Subjects(rdf.RDFType, class) {
+			if iri, ok := node.(rdf.IRI); ok && a.graph.Object(iri, ontology.Name) != nil {
+				seen[iri] = true
+			}
+		}
+	}
+	nodes := make([]rdf.IRI, 0, len(seen))
+	for node := range seen {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	return nodes
+}
+
+// containsWord reports whether word occurs in text without a letter or digit directly before or after it.
+func containsWord(text, word string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		if !wordRuneBefore(text, start) && !wordRuneAfter(text, end) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func wordRuneBefore(text string, i int) bool {
+	r, size := utf8.DecodeLastRuneInString(text[:i])
+	return size > 0 && isWordRune(r)
+}
+
+func wordRuneAfter(text string, i int) bool {
+	r, size := utf8.DecodeRuneInString(text[i:])
+	return size > 0 && isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,59 @@
+package impact
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Text writes a result for a prompt or a reader: the analyzed node, then every dependent and dependency
+// with its depth, the confidence of the path and the path that explains it.
+func (r *Result) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Impact of %s <%s>:\n", label(r.Root), r.Root.URI)
+	if r.Direction != Dependencies {
+		writeImpacted(&b, "Depends on it", r.Dependents)
+	}
+	if r.Direction != Dependents {
+		writeImpacted(&b, "It depends on", r.Dependencies)
+	}
+	if r.Truncated {
+		fmt.Fprintf(&b, "(Stopped at depth %d; more distant nodes are not listed.)\n", r.MaxDepth)
+	}
+	return b.String()
+}
+
+func writeImpacted(b *strings.Builder, title string, impacted []Impacted) {
+	if len(impacted) == 0 {
+		fmt.Fprintf(b, "%s: nothing\n", title)
+		return
+	}
+	fmt.Fprintf(b, "%s:\n", title)
+	for _, i := range impacted {
+		fmt.Fprintf(b, "- %s <%s>, depth %d%s: %s\n", label(i.Node), i.URI, i.Depth, confidence(i), i.Explanation)
+	}
+}
+
+// label names a node with its class and, for APIs and services, where it is defined, for example
+// "Charge (Api of PaymentService in github.com/org/payments)".
+func label(n Node) string {
+	if n.Class == "" {
+		return n.Name
+	}
+	details := n.Class
+	if n.Service != "" {
+		details += " of " + n.Service
+	}
+	if n.Repository != "" {
+		details += " in " + n.Repository
+	}
+	return fmt.Sprintf("%s (%s)", n.Name, details)
+}
+
+// confidence describes how well a path is supported, for example ", confidence 0.57 (weakest step
+// llm-inference)", or "" if its edges have no provenance.
+func confidence(i Impacted) string {
+	if i.Confidence == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", confidence %.2f (weakest step %s)", i.Confidence, i.Evidence)
+}
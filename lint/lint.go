@@ -0,0 +1,321 @@
+// Package lint checks a GraphMind graph for quality problems that SHACL validation of single fragments
+// cannot see: references to nodes nothing describes, nodes linked to nothing, APIs outside any service,
+// untyped nodes, different nodes with the same name and terms outside the ontology. It reports them with a
+// severity so builds and reviews can tell broken graphs from untidy ones.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/resolve"
+)
+
+// Severity tells how much an issue matters.
+type Severity string
+
+const (
+	Error   Severity = "error"   // The graph states something wrong or incomplete; analyses over it can be wrong.
+	Warning Severity = "warning" // The graph is probably missing or duplicating something.
+	Info    Severity = "info"    // Worth a look, but often intended.
+)
+
+// Rules.
+const (
+	DanglingReference = "dangling-reference"  // An object that is never described.
+	OrphanNode        = "orphan-node"         // A typed node without any link to or from another node.
+	ApiWithoutService = "api-without-service" // An API no service has through gm:hasApi.
+	UntypedNode       = "untyped-node"        // A described node without an rdf:type.
+	UnknownType       = "unknown-type"        // A database or cloud resource whose type is missing or "unknown".
+	DuplicateName     = "duplicate-name"      // Nodes of the same class and container with the same name.
+	UndefinedTerm     = "undefined-term"      // A GraphMind class or predicate the ontology does not declare.
+	ForeignPredicate  = "foreign-predicate"   // A predicate outside the GraphMind ontology and the standard vocabularies it uses.
+)
+
+// Issue is a problem found in the graph.
+type Issue struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Node     string   `json:"node"`              // The node the issue is about.
+	Related  []string `json:"related,omitempty"` // Other nodes or terms involved, for example the duplicates of a name.
+	Message  string   `json:"message"`
+}
+
+// Report lists the issues of a graph, errors first.
+type Report struct {
+	Issues   []Issue `json:"issues"`
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Infos    int     `json:"infos"`
+}
+
+// Count returns the number of issues at or above a severity.
+func (r *Report) Count(min Severity) int {
+	switch min {
+	case Error:
+		return r.Errors
+	case Warning:
+		return r.Errors + r.Warnings
+	default:
+		return len(r.Issues)
+	}
+}
+
+// ParseSeverity returns the severity with the given name.
+func ParseSeverity(name string) (Severity, error) {
+	switch s := Severity(strings.ToLower(name)); s {
+	case Error, Warning, Info:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown severity %q, expected error, warning or info", name)
+}
+
+// allowedPredicates are the predicates outside the GraphMind namespace that graphs use on purpose.
+var allowedPredicates = map[rdf.IRI]bool{
+	rdf.RDFType:       true,
+	rdf.RDFSLabel:     true,
+	rdf.RDFSComment:   true,
+	resolve.OWLSameAs: true,
+}
+
+// Lint checks a graph. Provenance is ignored.
+func Lint(g *rdf.Graph) *Report {
+	l := &linter{graph: provenance.Strip(g)}
+	l.danglingReferences()
+	l.untypedNodes()
+	l.orphanNodes()
+	l.apisWithoutService()
+	l.unknownTypes()
+	l.duplicateNames()
+	l.terms()
+
+	report := &Report{Issues: l.issues}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Severity != b.Severity {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Node < b.Node
+	})
+	for _, issue := range report.Issues {
+		switch issue.Severity {
+		case Error:
+			report.Errors++
+		case Warning:
+			report.Warnings++
+		default:
+			report.Infos++
+		}
+	}
+	if report.Issues == nil {
+		report.Issues = []Issue{}
+	}
+	return report
+}
+
+var severityRank = map[Severity]int{Error: 0, Warning: 1, Info: 2}
+
+type linter struct {
+	graph  *rdf.Graph
+	issues []Issue
+}
+
+func (l *linter) add(rule string, severity Severity, node rdf.Term, related []string, format string, args ...any) {
+	l.issues = append(l.issues, Issue{
+		Rule:     rule,
+		Severity: severity,
+		Node:     rdf.Value(node),
+		Related:  related,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// links returns the triples that link two nodes, leaving out types and owl:sameAs aliases, which point at
+// classes and at URIs that were merged away.
+func (l *linter) links() []rdf.Triple {
+	var links []rdf.Triple
+	for _, t := range l.graph.Triples() {
+		if _, ok := t.Object.(rdf.Literal); ok || t.Predicate == rdf.RDFType || t.Predicate == resolve.OWLSameAs {
+			continue
+		}
+		links = append(links, t)
+	}
+	return links
+}
+
+// danglingReferences reports the nodes that are linked to but have no triples of their own, such as a
+// service called under a URI no repository defines.
+func (l *linter) danglingReferences() {
+	referrers := map[rdf.Term][]string{}
+	for _, t := range l.links() {
+		if len(l.graph.Match(t.Object, "", nil)) == 0 {
+			referrers[t.Object] = append(referrers[t.Object], fmt.Sprintf("%s %s", rdf.Value(t.Subject), rdf.LocalName(t.Predicate)))
+		}
+	}
+	for _, node := range sortedKeys(referrers) {
+		l.add(DanglingReference, Error, node, referrers[node],
+			"%s is referenced %d time(s) but nothing describes it", rdf.Value(node), len(referrers[node]))
+	}
+}
+
+// untypedNodes reports the nodes that are described but have no rdf:type.
+func (l *linter) untypedNodes() {
+	for _, node := range l.graph.SubjectTerms() {
+		if l.graph.Object(node, rdf.RDFType) == nil {
+			l.add(UntypedNode, Warning, node, nil, "%s has no rdf:type", rdf.Value(node))
+		}
+	}
+}
+
+// orphanNodes reports the typed nodes that no link connects to another node.
+func (l *linter) orphanNodes() {
+	linked := map[rdf.Term]bool{}
+	for _, t := range l.links() {
+		linked[t.Subject] = true
+		linked[t.Object] = true
+	}
+	for _, node := range l.graph.Subjects(rdf.RDFType, nil) {
+		if !linked[node] {
+			l.add(OrphanNode, Warning, node, l.classes(node), "%s is not linked to any other node", l.label(node))
+		}
+	}
+}
+
+// apisWithoutService reports the APIs no service has through gm:hasApi.
+func (l *linter) apisWithoutService() {
+	for _, api := range l.graph.Subjects(rdf.RDFType, ontology.Api) {
+		if len(l.graph.Subjects(ontology.HasApi, api)) == 0 {
+			l.add(ApiWithoutService, Error, api, nil, "API %s belongs to no service", l.label(api))
+		}
+	}
+}
+
+// unknownTypes reports the databases and cloud resources whose type is missing or "unknown", which the
+// canonical URIs of the ontology depend on.
+func (l *linter) unknownTypes() {
+	for _, check := range []struct {
+		class, property rdf.IRI
+	}{{ontology.Database, ontology.DatabaseType}, {ontology.CloudResource, ontology.ResourceType}} {
+		for _, node := range l.graph.Subjects(rdf.RDFType, check.class) {
+			value := l.graph.Object(node, check.property)
+			switch {
+			case value == nil:
+				l.add(UnknownType, Warning, node, nil, "%s has no %s", l.label(node), rdf.LocalName(check.property))
+			case strings.EqualFold(rdf.Value(value), "unknown"):
+				l.add(UnknownType, Info, node, nil, "the %s of %s is unknown", rdf.LocalName(check.property), l.label(node))
+			}
+		}
+	}
+}
+
+// containers are the predicates that scope the names of their objects: two APIs named "Get" of different
+// services, or two collections named "users" of different databases, are not duplicates.
+var containers = map[rdf.IRI]rdf.IRI{
+	ontology.Api:        ontology.HasApi,
+	ontology.Collection: ontology.HasCollection,
+}
+
+// duplicateNames reports nodes of the same class and container that share a name, ignoring case. They are
+// usually one entity under two URIs that entity resolution did not merge.
+func (l *linter) duplicateNames() {
+	groups := map[string][]string{}
+	for _, t := range l.graph.Match(nil, rdf.RDFType, nil) {
+		class, ok := t.Object.(rdf.IRI)
+		name := l.graph.Object(t.Subject, ontology.Name)
+		if !ok || name == nil || !strings.HasPrefix(string(class), ontology.Namespace) {
+			continue
+		}
+		key := string(class) + "\x00" + strings.ToLower(rdf.Value(name))
+		if predicate, ok := containers[class]; ok {
+			for _, container := range l.graph.Subjects(predicate, t.Subject) {
+				key += "\x00" + rdf.Value(container)
+			}
+		}
+		groups[key] = append(groups[key], rdf.Value(t.Subject))
+	}
+	keys := make([]string, 0, len(groups))
+	for key, nodes := range groups {
+		if len(nodes) > 1 {
+			sort.Strings(nodes)
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return groups[keys[i]][0] < groups[keys[j]][0] })
+	for _, key := range keys {
+		nodes := groups[key]
+		class := rdf.IRI(strings.SplitN(key, "\x00", 2)[0])
+		l.add(DuplicateName, Warning, rdf.IRI(nodes[0]), nodes[1:], "%d %s nodes are named %q",
+			len(nodes), rdf.LocalName(class), rdf.Value(l.graph.Object(rdf.IRI(nodes[0]), ontology.Name)))
+	}
+}
+
+// terms reports the GraphMind classes and predicates the ontology does not declare, and the predicates
+// of other vocabularies, which prompts and queries do not know about.
+func (l *linter) terms() {
+	uses := map[rdf.IRI][]string{}
+	kinds := map[rdf.IRI]string{}
+	use := func(iri rdf.IRI, kind string, subject rdf.Term) {
+		if len(uses[iri]) < 5 {
+			uses[iri] = append(uses[iri], rdf.Value(subject))
+		}
+		kinds[iri] = kind
+	}
+	for _, t := range l.graph.Triples() {
+		if !allowedPredicates[t.Predicate] && !ontology.IsDefined(t.Predicate) {
+			use(t.Predicate, "predicate", t.Subject)
+		}
+		if class, ok := t.Object.(rdf.IRI); ok && t.Predicate == rdf.RDFType &&
+			strings.HasPrefix(string(class), ontology.Namespace) && !ontology.IsDefined(class) {
+			use(class, "class", t.Subject)
+		}
+	}
+	iris := make([]rdf.IRI, 0, len(uses))
+	for iri := range uses {
+		iris = append(iris, iri)
+	}
+	sort.Slice(iris, func(i, j int) bool { return iris[i] < iris[j] })
+	for _, iri := range iris {
+		if strings.HasPrefix(string(iri), ontology.Namespace) {
+			l.add(UndefinedTerm, Error, iri, uses[iri], "%s %s is not defined by the GraphMind ontology", kinds[iri], rdf.LocalName(iri))
+		} else {
+			l.add(ForeignPredicate, Warning, iri, uses[iri], "predicate %s is outside the GraphMind ontology", string(iri))
+		}
+	}
+}
+
+// label names a node with its gm:name, for example "OrderService (<http://...>)".
+func (l *linter) label(node rdf.Term) string {
+	if name := l.graph.Object(node, ontology.Name); name != nil {
+		return fmt.Sprintf("%s (%s)", rdf.Value(name), rdf.Value(node))
+	}
+	return rdf.Value(node)
+}
+
+// classes returns the local names of the classes of a node, sorted.
+func (l *linter) classes(node rdf.Term) []string {
+	var classes []string
+	for _, class := range l.graph.Objects(node, rdf.RDFType) {
+		if iri, ok := class.(rdf.IRI); ok {
+			classes = append(classes, rdf.LocalName(iri))
+		}
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// sortedKeys returns the nodes of a map, sorted.
+func sortedKeys(m map[rdf.Term][]string) []rdf.Term {
+	keys := make([]rdf.Term, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return rdf.CompareTerms(keys[i], keys[j]) < 0 })
+	return keys
+}
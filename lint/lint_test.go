@@ -0,0 +1,178 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+const prefixes = `
+@prefix gm: <http://graphmind.io/ontology#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix owl: <http://www.w3.org/2002/07/owl#> .
+@prefix ex: <http://example.com/> .
+`
+
+func mustParse(t *testing.T, document string) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(prefixes+document, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// short writes a node with the example namespace left out and the ontology namespace as gm:.
+func short(node string) string {
+	node = strings.TrimPrefix(node, "http://example.com/")
+	return strings.Replace(node, ontology.Namespace, "gm:", 1)
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		rule        string
+		want        []string // "severity node related..." for the issues of the rule, in report order.
+		wantMessage string   // The message of the first issue.
+	}{
+		{
+			name:        "dangling reference",
+			data:        `ex:a a gm:Service ; gm:name "a" ; gm:calls ex:b . ex:c a gm:Service ; gm:calls ex:b .`,
+			rule:        DanglingReference,
+			want:        []string{"error b http://example.com/a calls http://example.com/c calls"},
+			wantMessage: "http://example.com/b is referenced 2 time(s) but nothing describes it",
+		},
+		{
+			name: "untyped node",
+			data: `ex:a gm:name "a" . ex:b a gm:Service ; gm:name "b" .`,
+			rule: UntypedNode,
+			want: []string{"warning a"},
+		},
+		{
+			name: "orphan node",
+			data: `ex:a a gm:Service ; gm:name "a" .
+				ex:b a gm:Service ; gm:calls ex:c . ex:c a gm:Service .
+				ex:d a gm:Service ; owl:sameAs ex:e .`,
+			rule:        OrphanNode,
+			want:        []string{"warning a Service", "warning d Service"},
+			wantMessage: "a (http://example.com/a) is not linked to any other node",
+		},
+		{
+			name: "API without service",
+			data: `ex:svc a gm:Service ; gm:hasApi ex:get . ex:get a gm:Api . ex:put a gm:Api ; gm:name "Put" .`,
+			rule: ApiWithoutService,
+			want: []string{"error put"},
+		},
+		{
+			name: "unknown type",
+			data: `ex:db a gm:Database . ex:mongo a gm:Database ; gm:databaseType "Unknown" .
+				ex:pg a gm:Database ; gm:databaseType "postgres" . ex:bucket a gm:CloudResource .`,
+			rule:        UnknownType,
+			want:        []string{"warning bucket", "warning db", "info mongo"},
+			wantMessage: "http://example.com/bucket has no resourceType",
+		},
+		{
+			name: "duplicate names",
+			data: `ex:a a gm:Service ; gm:name "Orders" . ex:b a gm:Service ; gm:name "orders" .
+				ex:s1 a gm:Service ; gm:hasApi ex:get1 . ex:s2 a gm:Service ; gm:hasApi ex:get2 .
+				ex:get1 a gm:Api ; gm:name "Get" . ex:get2 a gm:Api ; gm:name "Get" .
+				ex:s1 gm:hasApi ex:get3 . ex:get3 a gm:Api ; gm:name "get" .`,
+			rule:        DuplicateName,
+			want:        []string{"warning a http://example.com/b", "warning get1 http://example.com/get3"},
+			wantMessage: `2 Service nodes are named "Orders"`,
+		},
+		{
+			name: "undefined and foreign terms",
+			data: `ex:a a gm:Service, gm:Microservice ; gm:owner "x" ; ex:team "y" ; rdfs:label "A" ; gm:name "a" .`,
+			rule: UndefinedTerm + "|" + ForeignPredicate,
+			want: []string{
+				"error gm:Microservice http://example.com/a",
+				"error gm:owner http://example.com/a",
+				"warning team http://example.com/a",
+			},
+			wantMessage: "class Microservice is not defined by the GraphMind ontology",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Lint(mustParse(t, tt.data))
+			var got []string
+			var message string
+			for _, issue := range report.Issues {
+				if !strings.Contains("|"+tt.rule+"|", "|"+issue.Rule+"|") {
+					continue
+				}
+				if got == nil {
+					message = issue.Message
+				}
+				got = append(got, strings.Join(append([]string{string(issue.Severity), short(issue.Node)}, issue.Related...), " "))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("issues:\n%s\nwant:\n%s\nreport:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"), report.Text())
+			}
+			if tt.wantMessage != "" && message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestLintCleanGraph(t *testing.T) {
+	g := mustParse(t, `
+		ex:repo a gm:Repository ; gm:name "orders" ; gm:hasService ex:orders .
+		ex:orders a gm:Service ; gm:name "OrderService" ; gm:hasApi ex:get ; rdfs:label "orders" .
+		ex:get a gm:Api ; gm:name "GetOrder" ; gm:readsFrom ex:ordersCollection .
+		ex:db a gm:Database ; gm:name "orders DB" ; gm:databaseType "mongodb" ; gm:hasCollection ex:ordersCollection .
+		ex:ordersCollection a gm:Collection ; gm:name "orders" .`)
+	// Provenance is not linted.
+	provenance.Record(g, rdf.Triple{Subject: rdf.IRI("http://example.com/get"), Predicate: ontology.ReadsFrom, Object: rdf.IRI("http://example.com/ordersCollection")},
+		provenance.Source{Activity: "BuildAstRdf", File: "orders.go"})
+
+	report := Lint(g)
+	if len(report.Issues) != 0 {
+		t.Errorf("issues in a clean graph:\n%s", report.Text())
+	}
+	if report.Issues == nil {
+		t.Error("Issues is nil rather than empty")
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := Lint(mustParse(t, `ex:a gm:calls ex:b . ex:db a gm:Database ; gm:databaseType "unknown" ; gm:name "db" .`))
+	// a calls a dangling b, has no type; db is an orphan of unknown type.
+	if report.Errors != 1 || report.Warnings != 2 || report.Infos != 1 {
+		t.Fatalf("counts %d, %d, %d:\n%s", report.Errors, report.Warnings, report.Infos, report.Text())
+	}
+	for min, want := range map[Severity]int{Error: 1, Warning: 3, Info: 4} {
+		if got := report.Count(min); got != want {
+			t.Errorf("Count(%s) = %d, want %d", min, got, want)
+		}
+	}
+
+	want := `1 error(s), 2 warning(s), 1 info(s)
+[error] dangling-reference http://example.com/b: http://example.com/b is referenced 1 time(s) but nothing describes it
+    related: http://example.com/a calls
+[warning] orphan-node http://example.com/db: db (http://example.com/db) is not linked to any other node
+    related: Database
+[warning] untyped-node http://example.com/a: http://example.com/a has no rdf:type
+[info] unknown-type http://example.com/db: the databaseType of db (http://example.com/db) is unknown
+`
+	if got := report.Text(); got != want {
+		t.Errorf("Text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for name, want := range map[string]Severity{"error": Error, "Warning": Warning, "INFO": Info} {
+		if got, err := ParseSeverity(name); err != nil || got != want {
+			t.Errorf("ParseSeverity(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("ParseSeverity accepted fatal")
+	}
+}
@@ -0,0 +1,20 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Text writes a report for a reader: the counts, then one line per issue, for example
+// "[error] dangling-reference http://graphmind.io/id/service/payments: ...".
+func (r *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d error(s), %d warning(s), %d info(s)\n", r.Errors, r.Warnings, r.Infos)
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&b, "[%s] %s %s: %s\n", issue.Severity, issue.Rule, issue.Node, issue.Message)
+		if len(issue.Related) > 0 {
+			fmt.Fprintf(&b, "    related: %s\n", strings.Join(issue.Related, ", "))
+		}
+	}
+	return b.String()
+}
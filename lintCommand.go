@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SaiNageswarS/GraphMind/lint"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// runLint implements the lint command, which checks a stored graph, or a Turtle file, for quality problems
+// and fails when there are issues at or above the -fail-on severity:
+//
+//	GraphMind lint [-graph <name> [-version <n>]] [-commit <sha> | -as-of <date>] [-input <file.ttl>]
+//	               [-format text|json] [-output <file>] [-fail-on error|warning|info|none]
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	graphName := flags.String("graph", "", "name of the stored graph to check; the union of all graphs if empty")
+	version := flags.Int("version", 0, "version of the stored graph to check; the latest if 0")
+	commit := flags.String("commit", "", "check the stored graphs as they were at this commit")
+	asOf := flags.String("as-of", "", "check the stored graphs as they were at this date or RFC 3339 time")
+	input := flags.String("input", "", "Turtle file to check instead of a stored graph")
+	format := flags.String("format", "text", "output format: text or json")
+	output := flags.String("output", "", "file to write; standard output if empty")
+	failOn := flags.String("fail-on", "error", "fail when there are issues of this severity or worse: error, warning, info or none")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown lint format %q, expected text or json", *format)
+	}
+	var threshold lint.Severity
+	if *failOn != "none" {
+		var err error
+		if threshold, err = lint.ParseSeverity(*failOn); err != nil {
+			return err
+		}
+	}
+
+	// 1. Load the graph from the input file or the store.
+	var graph *rdf.Graph
+	var err error
+	switch {
+	case *input != "":
+		graph, err = rdf.ParseTurtleFile(*input)
+	default:
+		var graphStore *store.Store
+		if graphStore, err = store.Open(graphStoreDir()); err != nil {
+			return err
+		}
+		graph, err = loadStoredGraph(graphStore, *graphName, *version, *commit, *asOf)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 2. Check it and write the report.
+	report := lint.Lint(graph)
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		_, err = io.WriteString(w, report.Text())
+	}
+	if err != nil {
+		return err
+	}
+
+	// 3. Fail on issues at or above the threshold.
+	if threshold != "" && report.Count(threshold) > 0 {
+		return fmt.Errorf("%d issue(s) of severity %s or worse", report.Count(threshold), threshold)
+	}
+	return nil
+}
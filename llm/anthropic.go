@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicClient calls the Anthropic messages API.
+type anthropicClient struct {
+	cfg Config
+}
+
+func (c *anthropicClient) Complete(ctx context.Context, req Request) (Response, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = c.cfg.MaxTokens
+	}
+	payload := map[string]interface{}{
+		"model": c.cfg.Model,
+		"messages": []map[string]string{
+			{
+				"role":    "user",
+				"content": req.Prompt,
+			},
+		},
+		"max_tokens": maxTokens,
+	}
+	if req.Output != nil {
+		// Structured output is the input of a tool the model is made to call.
+		payload["tools"] = []map[string]interface{}{{
+			"name":         req.Output.Name,
+			"description":  req.Output.Description,
+			"input_schema": req.Output.Schema,
+		}}
+		payload["tool_choice"] = map[string]string{"type": "tool", "name": req.Output.Name}
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.cfg.BaseURL+"/messages", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.cfg.APIKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to call Claude API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError("Claude", resp)
+	}
+
+	var result struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Response{}, fmt.Errorf("failed to decode Claude response: %w", err)
+	}
+	if len(result.Content) == 0 {
+		return Response{}, fmt.Errorf("no content returned from Claude")
+	}
+	response := Response{Model: result.Model, ID: result.ID, StopReason: result.StopReason}
+	var text strings.Builder
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			text.WriteString(content.Text)
+		case "tool_use":
+			response.JSON = content.Input
+		}
+	}
+	response.Text = strings.TrimSpace(text.String())
+	return response, nil
+}
@@ -0,0 +1,232 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache modes.
+const (
+	CacheOn      = "on"      // Answer from the cache when possible and store new responses.
+	CacheOff     = "off"     // Neither read nor write the cache.
+	CacheRefresh = "refresh" // Call the LLM even when the cache has the response, and store the new one.
+)
+
+// Defaults of the cache.
+const (
+	defaultCacheDir = "llm_cache"
+	defaultCacheTTL = 30 * 24 * time.Hour
+)
+
+// CacheKey is what identifies a response in the cache: the provider, model and parameters of the request
+// and the hash of its prompt and requested output. The API key is not part of it.
+type CacheKey struct {
+	Kind       string `json:"kind,omitempty"` // KindEmbedding for an embedding, empty for a completion.
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	BaseURL    string `json:"baseUrl,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	MaxTokens  int    `json:"maxTokens"`
+	PromptHash string `json:"promptHash"`           // The SHA-256 of the prompt, hex encoded.
+	OutputHash string `json:"outputHash,omitempty"` // The SHA-256 of the requested output's name and schema.
+}
+
+// Hash returns the content address of a key, the hex encoded SHA-256 of its JSON.
+func (k CacheKey) Hash() string {
+	data, _ := json.Marshal(k)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheEntry is a cached response as stored in its file.
+type cacheEntry struct {
+	Key      CacheKey  `json:"key"`
+	Created  time.Time `json:"created"`
+	Response Response  `json:"response"`
+}
+
+// Cache is a persistent cache of LLM responses in a directory, one JSON file per response named by the hash
+// of its key. Entries older than the TTL are ignored; a TTL of 0 keeps them forever. Concurrent workers
+// can share the directory.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// Get returns the response cached for a key, if there is one within the TTL.
+func (c *Cache) Get(key CacheKey) (Response, bool) {
+	data, err := os.ReadFile(c.path(key.Hash()))
+	if err != nil {
+		return Response{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || c.expired(entry) {
+		return Response{}, false
+	}
+	return entry.Response, true
+}
+
+// Put stores the response of a key.
+func (c *Cache) Put(key CacheKey, response Response) error {
+	data, err := json.MarshalIndent(cacheEntry{Key: key, Created: time.Now().UTC(), Response: response}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	path := c.path(key.Hash())
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// Write to a temporary file and rename it, so readers never see a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// CacheStats describes the entries of a cache.
+type CacheStats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+}
+
+// Stats counts the entries of the cache.
+func (c *Cache) Stats() (CacheStats, error) {
+	var stats CacheStats
+	err := c.walk(func(path string, entry cacheEntry, size int64) error {
+		stats.Entries++
+		stats.Bytes += size
+		if c.expired(entry) {
+			stats.Expired++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Prune removes the entries older than the TTL, and the unreadable ones, and returns how many it removed.
+func (c *Cache) Prune() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, entry cacheEntry, size int64) error {
+		if !c.expired(entry) {
+			return nil
+		}
+		removed++
+		return os.Remove(path)
+	})
+	return removed, err
+}
+
+// Clear removes every entry of the cache.
+func (c *Cache) Clear() error {
+	if err := os.RemoveAll(c.Dir); err != nil {
+		return fmt.Errorf("failed to clear LLM cache: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.Dir, hash[:2], hash+".json")
+}
+
+func (c *Cache) expired(entry cacheEntry) bool {
+	return c.TTL > 0 && time.Since(entry.Created) > c.TTL
+}
+
+// walk calls fn for every entry of the cache. An unreadable entry is passed as a zero entry, which is
+// expired whatever the TTL.
+func (c *Cache) walk(fn func(path string, entry cacheEntry, size int64) error) error {
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var entry cacheEntry
+		data, err := os.ReadFile(path)
+		if err == nil && json.Unmarshal(data, &entry) != nil {
+			entry = cacheEntry{}
+		}
+		if entry.Created.IsZero() {
+			entry.Created = time.Unix(0, 0)
+		}
+		return fn(path, entry, info.Size())
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read LLM cache: %w", err)
+	}
+	return nil
+}
+
+// cachedClient answers from a cache before calling its client, and caches what the client returns.
+type cachedClient struct {
+	client Client
+	cache  *Cache
+	cfg    Config
+}
+
+func (c *cachedClient) Complete(ctx context.Context, req Request) (Response, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = c.cfg.MaxTokens
+	}
+	promptHash := sha256.Sum256([]byte(req.Prompt))
+	key := CacheKey{
+		Provider:   c.cfg.Provider,
+		Model:      c.cfg.Model,
+		BaseURL:    c.cfg.BaseURL,
+		APIVersion: c.cfg.APIVersion,
+		MaxTokens:  maxTokens,
+		PromptHash: hex.EncodeToString(promptHash[:]),
+	}
+	if req.Output != nil {
+		outputHash := sha256.Sum256(append([]byte(req.Output.Name+"\x00"), req.Output.Schema...))
+		key.OutputHash = hex.EncodeToString(outputHash[:])
+	}
+	if c.cfg.Cache != CacheRefresh {
+		if response, ok := c.cache.Get(key); ok {
+			return response, nil
+		}
+	}
+	response, err := c.client.Complete(ctx, req)
+	if err != nil {
+		return response, err
+	}
+	if response.Truncated() || (req.Output != nil && len(response.JSON) == 0) {
+		// An incomplete response is not worth keeping: the next call should get a new one.
+		return response, nil
+	}
+	if err := c.cache.Put(key, response); err != nil {
+		// A failure to cache only costs a call next time.
+		log.Printf("Failed to cache LLM response: %v", err)
+	}
+	return response, nil
+}
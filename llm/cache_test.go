@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCachedClientSkipsIncompleteResponses(t *testing.T) {
+	output := &Output{Name: "answer", Schema: json.RawMessage(`{"type": "object"}`)}
+	tests := []struct {
+		name   string
+		rule   Rule
+		output *Output
+		cached bool
+	}{
+		{name: "complete", rule: Rule{Text: "ok"}, cached: true},
+		{name: "truncated", rule: Rule{Text: "o", StopReason: "max_tokens"}},
+		{name: "truncated by length", rule: Rule{Text: "o", StopReason: "length"}},
+		{name: "json", rule: Rule{Text: `{"a": 1}`}, output: output, cached: true},
+		{name: "json missing", rule: Rule{Text: "no json here"}, output: output},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFake(tt.rule)
+			client := &cachedClient{client: fake, cache: &Cache{Dir: t.TempDir()}, cfg: Config{Provider: OpenAI, Model: "m", Cache: CacheOn}}
+			for i := 0; i < 2; i++ {
+				if _, err := client.Complete(context.Background(), Request{Prompt: "p", Output: tt.output}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := 2
+			if tt.cached {
+				want = 1
+			}
+			if got := len(fake.Requests()); got != want {
+				t.Errorf("the LLM was called %d time(s), want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// NoProvider is the provider of a use that is turned off. Only embeddings can be turned off, see NewEmbedder.
+const NoProvider = "none"
+
+// KindEmbedding is the kind of the cache keys of embeddings.
+const KindEmbedding = "embedding"
+
+// defaultEmbeddingModel is the OpenAI embedding model of a use that names none.
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+// embeddingBatchSize is the number of texts sent in one embeddings request.
+const embeddingBatchSize = 256
+
+// Embedder returns an embedding vector for every text, in order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// NewEmbedder returns an embedder for a configuration, calling the embeddings API of OpenAI, an Azure
+// OpenAI deployment or an OpenAI-compatible server. Like the clients of New, it keeps within the configured
+// rate limits, retries retryable errors and caches its vectors, one cache entry per text. It returns nil
+// if embeddings are turned off with the provider NoProvider, and for the fake, so offline runs send no text
+// anywhere. Anthropic has no embeddings API.
+func NewEmbedder(cfg Config) (Embedder, error) {
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = defaultMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+	e := &embeddingClient{cfg: cfg}
+	switch cfg.Provider {
+	case NoProvider, FakeProvider:
+		return nil, nil
+	case Anthropic:
+		return nil, fmt.Errorf("anthropic has no embeddings API, choose another provider for %s or turn it off with %q", cfg.Use, NoProvider)
+	case OpenAI:
+		if e.cfg.APIKey == "" {
+			e.cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		if e.cfg.APIKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+		}
+		if e.cfg.BaseURL == "" {
+			e.cfg.BaseURL = "https://api.openai.com/v1"
+		}
+		if e.cfg.Model == "" {
+			e.cfg.Model = defaultEmbeddingModel
+		}
+		e.url, e.name = strings.TrimSuffix(e.cfg.BaseURL, "/")+"/embeddings", "OpenAI"
+	case Azure:
+		if e.cfg.APIKey == "" {
+			e.cfg.APIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+		}
+		if e.cfg.BaseURL == "" {
+			e.cfg.BaseURL = os.Getenv("AZURE_OPENAI_ENDPOINT")
+		}
+		if e.cfg.APIKey == "" || e.cfg.BaseURL == "" || e.cfg.Model == "" {
+			return nil, fmt.Errorf("azure OpenAI needs an API key, an endpoint and a deployment as the model")
+		}
+		if e.cfg.APIVersion == "" {
+			e.cfg.APIVersion = defaultAzureAPIVersion
+		}
+		e.url = fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s", strings.TrimSuffix(e.cfg.BaseURL, "/"), e.cfg.Model, e.cfg.APIVersion)
+		e.name, e.azure = "Azure OpenAI", true
+	case OpenAICompatible:
+		if e.cfg.BaseURL == "" || e.cfg.Model == "" {
+			return nil, fmt.Errorf("an OpenAI-compatible server needs a base URL and a model")
+		}
+		e.url, e.name = strings.TrimSuffix(e.cfg.BaseURL, "/")+"/embeddings", e.cfg.BaseURL
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q, expected %s, %s, %s or %s", cfg.Provider, OpenAI, Azure, OpenAICompatible, NoProvider)
+	}
+
+	e.limiter = limiterFor(e.cfg)
+	switch cfg.Cache {
+	case "", CacheOn, CacheRefresh:
+		e.cache = OpenCache(cfg)
+	case CacheOff:
+	default:
+		return nil, fmt.Errorf("unknown LLM cache mode %q, expected %s, %s or %s", cfg.Cache, CacheOn, CacheOff, CacheRefresh)
+	}
+	return e, nil
+}
+
+// embeddingClient calls an OpenAI-style embeddings API.
+type embeddingClient struct {
+	cfg     Config
+	url     string   // The embeddings endpoint.
+	name    string   // The API in error messages.
+	azure   bool     // Whether to authenticate with an api-key header instead of a bearer token.
+	limiter *limiter // nil for no limits.
+	cache   *Cache   // nil if the cache is off.
+}
+
+func (e *embeddingClient) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	keys := make([]CacheKey, len(texts))
+	var missing []int
+	for i, text := range texts {
+		keys[i] = e.cacheKey(text)
+		if e.cache != nil && e.cfg.Cache != CacheRefresh {
+			if response, ok := e.cache.Get(keys[i]); ok && json.Unmarshal(response.JSON, &vectors[i]) == nil && len(vectors[i]) > 0 {
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+
+	for start := 0; start < len(missing); start += embeddingBatchSize {
+		batch := missing[start:min(start+embeddingBatchSize, len(missing))]
+		input := make([]string, len(batch))
+		tokens := 0
+		for j, i := range batch {
+			input[j] = texts[i]
+			tokens += EstimateTokens(e.cfg.Provider, texts[i])
+		}
+		var embeddings [][]float64
+		err := withRetries(ctx, e.limiter, tokens, e.cfg.MaxRetries, func() error {
+			var err error
+			embeddings, err = e.embed(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range batch {
+			vectors[i] = embeddings[j]
+			if e.cache == nil {
+				continue
+			}
+			data, _ := json.Marshal(embeddings[j])
+			if err := e.cache.Put(keys[i], Response{Model: e.cfg.Model, JSON: data}); err != nil {
+				// A failure to cache only costs a call next time.
+				log.Printf("Failed to cache embedding: %v", err)
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func (e *embeddingClient) cacheKey(text string) CacheKey {
+	hash := sha256.Sum256([]byte(text))
+	return CacheKey{
+		Kind:       KindEmbedding,
+		Provider:   e.cfg.Provider,
+		Model:      e.cfg.Model,
+		BaseURL:    e.cfg.BaseURL,
+		APIVersion: e.cfg.APIVersion,
+		PromptHash: hex.EncodeToString(hash[:]),
+	}
+}
+
+// embed sends one embeddings request.
+func (e *embeddingClient) embed(ctx context.Context, input []string) ([][]float64, error) {
+	payload := map[string]interface{}{"input": input}
+	if !e.azure {
+		// Azure takes the model from the deployment in the URL.
+		payload["model"] = e.cfg.Model
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	switch {
+	case e.azure:
+		httpReq.Header.Set("api-key", e.cfg.APIKey)
+	case e.cfg.APIKey != "":
+		httpReq.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s API: %w", e.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(e.name, resp)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", e.name, err)
+	}
+	if len(result.Data) != len(input) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", e.name, len(result.Data), len(input))
+	}
+	embeddings := make([][]float64, len(input))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(input) {
+			return nil, fmt.Errorf("%s returned an embedding for unknown input %d", e.name, d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// embeddingServer answers embeddings requests with the length of every input as its vector, and records the
+// inputs of every request.
+func embeddingServer(t *testing.T, requests *[][]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/embeddings") {
+			t.Errorf("request to %s, want an embeddings endpoint", r.URL.Path)
+		}
+		var payload struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		*requests = append(*requests, payload.Input)
+		var data []string
+		// Answer in reverse order to check that vectors are placed by index.
+		for i := len(payload.Input) - 1; i >= 0; i-- {
+			data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%d]}`, i, len(payload.Input[i])))
+		}
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(data, ","))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEmbedder(t *testing.T) {
+	var requests [][]string
+	server := embeddingServer(t, &requests)
+	t.Setenv("LLM_PROVIDER", OpenAICompatible)
+	t.Setenv("LLM_BASE_URL", server.URL)
+	t.Setenv("LLM_SPEC_RETRIEVAL_MODEL", "nomic-embed-text")
+	t.Setenv("LLM_CACHE_DIR", t.TempDir())
+
+	// The global provider takes the use off OpenAI, to the configured server.
+	cfg := Load(Config{Use: "SpecRetrieval", Provider: OpenAI, Model: defaultEmbeddingModel})
+	embedder, err := NewEmbedder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{1}, {2}, {3}}; !reflect.DeepEqual(vectors, want) {
+		t.Fatalf("vectors = %v, want %v", vectors, want)
+	}
+
+	// Cached texts are not sent again.
+	vectors, err = embedder.Embed(context.Background(), []string{"bb", "dddd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{2}, {4}}; !reflect.DeepEqual(vectors, want) {
+		t.Fatalf("vectors = %v, want %v", vectors, want)
+	}
+	if want := [][]string{{"a", "bb", "ccc"}, {"dddd"}}; !reflect.DeepEqual(requests, want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+}
+
+func TestNewEmbedderProviders(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "turned off", cfg: Config{Provider: NoProvider}, wantNil: true},
+		{name: "fake", cfg: Config{Provider: FakeProvider}, wantNil: true},
+		{name: "anthropic", cfg: Config{Provider: Anthropic}, wantErr: true},
+		{name: "openai without a key", cfg: Config{Provider: OpenAI}, wantErr: true},
+		{name: "openai", cfg: Config{Provider: OpenAI, APIKey: "k"}},
+		{name: "compatible without a model", cfg: Config{Provider: OpenAICompatible, BaseURL: "http://localhost"}, wantErr: true},
+		{name: "azure", cfg: Config{Provider: Azure, APIKey: "k", BaseURL: "https://x.openai.azure.com", Model: "embeddings"}},
+		{name: "unknown cache mode", cfg: Config{Provider: OpenAI, APIKey: "k", Cache: "sometimes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder, err := NewEmbedder(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (embedder == nil) != tt.wantNil {
+				t.Fatalf("embedder = %v, want nil %v", embedder, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is a response of an LLM API with an error status.
+type APIError struct {
+	API        string // The API that failed, for example "Claude".
+	StatusCode int
+	RetryAfter time.Duration // How long the API asked to wait before retrying, or 0.
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.API, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again: rate limits (429), overload (529),
+// timeouts, conflicts and server errors. Other client errors, such as an invalid API key (401) or a
+// malformed or too long request (400), fail again however often they are sent.
+func (e *APIError) Retryable() bool {
+	switch {
+	case e.StatusCode == http.StatusRequestTimeout, e.StatusCode == http.StatusConflict, e.StatusCode == http.StatusTooManyRequests:
+		return true
+	case e.StatusCode >= 500:
+		return true
+	}
+	return false
+}
+
+// newAPIError reads the error response of an API.
+func newAPIError(api string, resp *http.Response) *APIError {
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return &APIError{API: api, StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header), Body: string(bodyBytes)}
+}
+
+// retryAfter returns the wait a response asks for in its retry-after-ms header, which OpenAI and Azure
+// send, or its Retry-After header in seconds or as an HTTP date.
+func retryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
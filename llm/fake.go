@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Rule is a scripted answer of a Fake.
+type Rule struct {
+	Contains   string `json:"contains"`             // The rule answers prompts containing this text; "" matches every prompt.
+	Text       string `json:"text"`                 // The response text, the JSON object for a request with an Output.
+	StopReason string `json:"stopReason,omitempty"` // Defaults to "end_turn".
+	Error      string `json:"error,omitempty"`      // If set, the request fails with this error instead.
+}
+
+// Fake is a scripted Client for tests and offline runs. A request is answered by the first rule whose
+// Contains is part of the prompt, and fails if no rule matches. The requests are recorded.
+type Fake struct {
+	Rules []Rule
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewFake returns a fake that answers with the given rules.
+func NewFake(rules ...Rule) *Fake {
+	return &Fake{Rules: rules}
+}
+
+// LoadFake returns a fake that answers with the rules of a JSON file, an array of rules.
+func LoadFake(path string) (*Fake, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake LLM script: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse fake LLM script %s: %w", path, err)
+	}
+	return NewFake(rules...), nil
+}
+
+func (f *Fake) Complete(ctx context.Context, req Request) (Response, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	n := len(f.requests)
+	f.mu.Unlock()
+
+	for _, rule := range f.Rules {
+		if !strings.Contains(req.Prompt, rule.Contains) {
+			continue
+		}
+		if rule.Error != "" {
+			return Response{}, fmt.Errorf("fake LLM error: %s", rule.Error)
+		}
+		stopReason := rule.StopReason
+		if stopReason == "" {
+			stopReason = "end_turn"
+		}
+		response := Response{Text: rule.Text, Model: FakeProvider, ID: fmt.Sprintf("fake-%d", n), StopReason: stopReason}
+		if req.Output != nil {
+			response.JSON = jsonObject(rule.Text)
+		}
+		return response, nil
+	}
+	return Response{}, fmt.Errorf("no fake LLM rule matches the prompt")
+}
+
+// Requests returns the requests the fake received, in order.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
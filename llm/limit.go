@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter keeps the requests and tokens sent to an API within per minute limits, with a token bucket for
+// each that refills continuously. A limit of 0 is no limit.
+type limiter struct {
+	mu                sync.Mutex
+	requestsPerMinute float64
+	tokensPerMinute   float64
+	requests, tokens  float64 // What may be sent now.
+	updated           time.Time
+}
+
+// limiters are shared by every client of the process, so all activities of a worker stay within the limits
+// of an API together. They are keyed by provider, endpoint and model.
+var limiters = struct {
+	sync.Mutex
+	byKey map[string]*limiter
+}{byKey: map[string]*limiter{}}
+
+// limiterFor returns the shared limiter of a configuration, or nil if it sets no limits. The limits of the
+// first configuration for an API apply to all uses of it.
+func limiterFor(cfg Config) *limiter {
+	if cfg.RequestsPerMinute <= 0 && cfg.TokensPerMinute <= 0 {
+		return nil
+	}
+	key := cfg.Provider + "\x00" + cfg.BaseURL + "\x00" + cfg.Model
+	limiters.Lock()
+	defer limiters.Unlock()
+	l := limiters.byKey[key]
+	if l == nil {
+		l = &limiter{
+			requestsPerMinute: float64(cfg.RequestsPerMinute),
+			tokensPerMinute:   float64(cfg.TokensPerMinute),
+			requests:          float64(cfg.RequestsPerMinute),
+			tokens:            float64(cfg.TokensPerMinute),
+			updated:           time.Now(),
+		}
+		limiters.byKey[key] = l
+	}
+	return l
+}
+
+// wait blocks until a request of the given number of tokens may be sent, and takes it from the limits. A
+// request larger than the token limit waits for a full bucket and then goes alone.
+func (l *limiter) wait(ctx context.Context, tokens int) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		elapsed := now.Sub(l.updated).Minutes()
+		l.updated = now
+		l.requests = math.Min(l.requests+elapsed*l.requestsPerMinute, l.requestsPerMinute)
+		l.tokens = math.Min(l.tokens+elapsed*l.tokensPerMinute, l.tokensPerMinute)
+
+		need := math.Min(float64(tokens), l.tokensPerMinute)
+		var delay time.Duration
+		if l.requestsPerMinute > 0 && l.requests < 1 {
+			delay = time.Duration((1 - l.requests) / l.requestsPerMinute * float64(time.Minute))
+		}
+		if l.tokensPerMinute > 0 && l.tokens < need {
+			delay = max(delay, time.Duration((need-l.tokens)/l.tokensPerMinute*float64(time.Minute)))
+		}
+		if delay == 0 {
+			if l.requestsPerMinute > 0 {
+				l.requests--
+			}
+			if l.tokensPerMinute > 0 {
+				l.tokens -= need
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
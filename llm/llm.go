@@ -0,0 +1,295 @@
+// Package llm calls large language models through one Client interface, with implementations for the
+// Anthropic and OpenAI APIs, Azure OpenAI, OpenAI-compatible servers such as Ollama or llama.cpp, and a
+// scripted fake for tests and offline runs. Which provider and model a use of the LLM gets is configured
+// per use with environment variables, see Load.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Providers.
+const (
+	Anthropic        = "anthropic"         // The Anthropic messages API.
+	OpenAI           = "openai"            // The OpenAI chat completions API.
+	Azure            = "azure"             // An Azure OpenAI deployment.
+	OpenAICompatible = "openai-compatible" // A server with an OpenAI-style chat completions API, such as Ollama.
+	FakeProvider     = "fake"              // A scripted fake, see Fake.
+)
+
+// Request is a prompt to complete.
+type Request struct {
+	Prompt    string
+	MaxTokens int     // The most tokens to generate, or 0 for the configured limit.
+	Output    *Output // If set, the model answers with a JSON object filling the output's schema.
+}
+
+// Output asks for structured output: a JSON object filling a JSON Schema instead of free text. Anthropic
+// fills it as the input of a tool the model must call, and OpenAI-style APIs as a JSON schema response
+// format.
+type Output struct {
+	Name        string          // The name of the tool or response format, for example "annotate_apis".
+	Description string          // What the object describes.
+	Schema      json.RawMessage // The JSON Schema of the object.
+}
+
+// Response is the text an LLM returned together with what identifies the response in provenance.
+type Response struct {
+	Text  string
+	Model string // The model that produced the response, as reported by the API.
+	ID    string // The id the API assigned to the response.
+
+	// StopReason is why the model stopped, as reported by the API: for example "end_turn" or "max_tokens"
+	// from Claude and "stop" or "length" from OpenAI.
+	StopReason string
+
+	// JSON is the structured output of a request with an Output, empty if the model gave none.
+	JSON json.RawMessage `json:",omitempty"`
+}
+
+// Truncated reports whether the model stopped because it reached the output token limit, so the text is
+// incomplete.
+func (r Response) Truncated() bool {
+	return r.StopReason == "max_tokens" || r.StopReason == "length"
+}
+
+// Client completes prompts with an LLM.
+type Client interface {
+	Complete(ctx context.Context, req Request) (Response, error)
+}
+
+// Config selects and configures the LLM of one use, such as an activity.
+type Config struct {
+	Use        string // The name of the use, for example "BuildAstRdf"; it names its environment variables.
+	Provider   string
+	Model      string // The model, or the deployment for Azure.
+	BaseURL    string // The API endpoint; required for Azure and OpenAI-compatible servers.
+	APIKey     string // Defaults to the key variable of the provider, such as CLAUDE_API_KEY.
+	APIVersion string // The Azure OpenAI API version.
+	MaxTokens  int    // The most tokens a response may have unless a request says otherwise.
+
+	// ContextTokens is the context window of the model in tokens, prompt and response together; 0 for the
+	// provider's default, see PromptBudget. Set it lower to keep prompts short for a model that does worse
+	// on long ones.
+	ContextTokens int
+	Script        string // The JSON file of rules the fake answers with.
+
+	RequestsPerMinute int // The most requests per minute the workers' clients of the API send together, or 0.
+	TokensPerMinute   int // The most tokens per minute they send together, or 0.
+	MaxRetries        int // How often a request failing with a retryable error is retried; 0 for 4, negative for never.
+
+	Cache    string        // CacheOn (the default), CacheOff or CacheRefresh.
+	CacheDir string        // The directory of the response cache, "llm_cache" by default.
+	CacheTTL time.Duration // How long cached responses are used; 0 for the 30 day default, negative for ever.
+}
+
+// defaultModels are the models of a provider when a use changes the provider without naming a model.
+var defaultModels = map[string]string{
+	Anthropic: "claude-3-5-sonnet-20241022",
+	OpenAI:    "gpt-4o",
+}
+
+// defaultMaxTokens is the output token limit of a use that does not set one.
+const defaultMaxTokens = 2048
+
+// defaultAzureAPIVersion is the Azure OpenAI API version used unless one is configured, the first GA version
+// with structured outputs.
+const defaultAzureAPIVersion = "2024-10-21"
+
+// azureStructuredOutputsVersion is the first Azure OpenAI API version that accepts a json_schema response
+// format. Older versions are sent the schema in the prompt and asked for a json_object instead.
+const azureStructuredOutputsVersion = "2024-08-01"
+
+// Load returns the configuration of a use: its defaults overridden by the LLM_* environment variables,
+// which in turn are overridden by the LLM_<USE>_* variables of the use, where <USE> is the use in upper
+// snake case, for example LLM_BUILD_AST_RDF_PROVIDER. The variables are PROVIDER, MODEL, BASE_URL, API_KEY,
+// API_VERSION, MAX_TOKENS, CONTEXT_TOKENS, REQUESTS_PER_MINUTE, TOKENS_PER_MINUTE, MAX_RETRIES, SCRIPT, CACHE, CACHE_DIR and
+// CACHE_TTL, a Go duration such as 72h. A provider changed without a model gets the provider's default model.
+func Load(defaults Config) Config {
+	prefix := "LLM_" + envName(defaults.Use) + "_"
+	get := func(name string) string {
+		if value := os.Getenv(prefix + name); value != "" {
+			return value
+		}
+		return os.Getenv("LLM_" + name)
+	}
+
+	cfg := defaults
+	if provider := get("PROVIDER"); provider != "" && provider != cfg.Provider {
+		cfg.Provider = provider
+		cfg.Model = defaultModels[provider]
+	}
+	if model := get("MODEL"); model != "" {
+		cfg.Model = model
+	}
+	if baseURL := get("BASE_URL"); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	if apiKey := get("API_KEY"); apiKey != "" {
+		cfg.APIKey = apiKey
+	}
+	if apiVersion := get("API_VERSION"); apiVersion != "" {
+		cfg.APIVersion = apiVersion
+	}
+	if maxTokens, err := strconv.Atoi(get("MAX_TOKENS")); err == nil && maxTokens > 0 {
+		cfg.MaxTokens = maxTokens
+	}
+	if contextTokens, err := strconv.Atoi(get("CONTEXT_TOKENS")); err == nil && contextTokens > 0 {
+		cfg.ContextTokens = contextTokens
+	}
+	if rpm, err := strconv.Atoi(get("REQUESTS_PER_MINUTE")); err == nil && rpm >= 0 {
+		cfg.RequestsPerMinute = rpm
+	}
+	if tpm, err := strconv.Atoi(get("TOKENS_PER_MINUTE")); err == nil && tpm >= 0 {
+		cfg.TokensPerMinute = tpm
+	}
+	if retries, err := strconv.Atoi(get("MAX_RETRIES")); err == nil {
+		cfg.MaxRetries = retries
+		if retries == 0 {
+			cfg.MaxRetries = -1
+		}
+	}
+	if script := get("SCRIPT"); script != "" {
+		cfg.Script = script
+	}
+	if cache := get("CACHE"); cache != "" {
+		cfg.Cache = cache
+	}
+	if cacheDir := get("CACHE_DIR"); cacheDir != "" {
+		cfg.CacheDir = cacheDir
+	}
+	if ttl, err := time.ParseDuration(get("CACHE_TTL")); err == nil {
+		cfg.CacheTTL = ttl
+		if ttl == 0 {
+			cfg.CacheTTL = -1
+		}
+	}
+	return cfg
+}
+
+// OpenCache returns the response cache of a configuration.
+func OpenCache(cfg Config) *Cache {
+	cache := &Cache{Dir: cfg.CacheDir, TTL: cfg.CacheTTL}
+	if cache.Dir == "" {
+		cache.Dir = defaultCacheDir
+	}
+	switch {
+	case cache.TTL == 0:
+		cache.TTL = defaultCacheTTL
+	case cache.TTL < 0:
+		cache.TTL = 0
+	}
+	return cache
+}
+
+// New returns a client for a configuration. Its requests are kept within the configured rate limits, and
+// those failing with a retryable APIError are retried. Unless the cache is off, its responses are cached,
+// so a prompt sent again, for example by a rebuild of an unchanged repository or a retried activity, is
+// answered from the cache. Responses of the fake are never cached.
+func New(cfg Config) (Client, error) {
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = defaultMaxTokens
+	}
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = defaultMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Provider != FakeProvider {
+		client = &retryingClient{client: client, provider: cfg.Provider, limiter: limiterFor(cfg), maxTokens: cfg.MaxTokens, maxRetries: cfg.MaxRetries}
+	}
+	switch cfg.Cache {
+	case "", CacheOn, CacheRefresh:
+	case CacheOff:
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM cache mode %q, expected %s, %s or %s", cfg.Cache, CacheOn, CacheOff, CacheRefresh)
+	}
+	if cfg.Provider == FakeProvider {
+		return client, nil
+	}
+	return &cachedClient{client: client, cache: OpenCache(cfg), cfg: cfg}, nil
+}
+
+// newClient returns the client of the provider of a configuration.
+func newClient(cfg Config) (Client, error) {
+	switch cfg.Provider {
+	case Anthropic:
+		if cfg.APIKey == "" {
+			cfg.APIKey = os.Getenv("CLAUDE_API_KEY")
+		}
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("CLAUDE_API_KEY environment variable not set")
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.anthropic.com/v1"
+		}
+		return &anthropicClient{cfg: cfg}, nil
+	case OpenAI:
+		if cfg.APIKey == "" {
+			cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.openai.com/v1"
+		}
+		return &chatClient{cfg: cfg, url: cfg.BaseURL + "/chat/completions", name: "OpenAI"}, nil
+	case Azure:
+		if cfg.APIKey == "" {
+			cfg.APIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = os.Getenv("AZURE_OPENAI_ENDPOINT")
+		}
+		if cfg.APIKey == "" || cfg.BaseURL == "" || cfg.Model == "" {
+			return nil, fmt.Errorf("azure OpenAI needs an API key, an endpoint and a deployment as the model")
+		}
+		if cfg.APIVersion == "" {
+			cfg.APIVersion = defaultAzureAPIVersion
+		}
+		url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s", strings.TrimSuffix(cfg.BaseURL, "/"), cfg.Model, cfg.APIVersion)
+		return &chatClient{cfg: cfg, url: url, name: "Azure OpenAI", azure: true}, nil
+	case OpenAICompatible:
+		if cfg.BaseURL == "" || cfg.Model == "" {
+			return nil, fmt.Errorf("an OpenAI-compatible server needs a base URL and a model")
+		}
+		return &chatClient{cfg: cfg, url: strings.TrimSuffix(cfg.BaseURL, "/") + "/chat/completions", name: cfg.BaseURL}, nil
+	case FakeProvider:
+		if cfg.Script == "" {
+			return nil, fmt.Errorf("the fake LLM needs a script file")
+		}
+		return LoadFake(cfg.Script)
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q, expected %s, %s, %s, %s or %s", cfg.Provider, Anthropic, OpenAI, Azure, OpenAICompatible, FakeProvider)
+}
+
+// httpClient sends the requests of all clients.
+var httpClient = &http.Client{}
+
+// envName converts a use such as "GenerateRDFGraph" to upper snake case, "GENERATE_RDF_GRAPH".
+func envName(use string) string {
+	runes := []rune(use)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
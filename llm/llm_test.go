@@ -0,0 +1,290 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"BuildAstRdf":      "BUILD_AST_RDF",
+		"GenerateRDFGraph": "GENERATE_RDF_GRAPH",
+		"SpecToCode":       "SPEC_TO_CODE",
+		"SpecRetrieval":    "SPEC_RETRIEVAL",
+		"":                 "",
+	}
+	for use, want := range tests {
+		if got := envName(use); got != want {
+			t.Errorf("envName(%q) = %q, want %q", use, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defaults := Config{Use: "BuildAstRdf", Provider: Anthropic, Model: "claude", MaxTokens: 4096}
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Config
+	}{
+		{name: "defaults", want: defaults},
+		{
+			name: "global variables",
+			env:  map[string]string{"LLM_MODEL": "claude-next", "LLM_MAX_TOKENS": "1000", "LLM_CACHE": CacheOff},
+			want: Config{Use: "BuildAstRdf", Provider: Anthropic, Model: "claude-next", MaxTokens: 1000, Cache: CacheOff},
+		},
+		{
+			name: "use variables win",
+			env:  map[string]string{"LLM_MODEL": "global", "LLM_BUILD_AST_RDF_MODEL": "mine"},
+			want: Config{Use: "BuildAstRdf", Provider: Anthropic, Model: "mine", MaxTokens: 4096},
+		},
+		{
+			name: "provider change resets the model",
+			env:  map[string]string{"LLM_PROVIDER": OpenAI},
+			want: Config{Use: "BuildAstRdf", Provider: OpenAI, Model: defaultModels[OpenAI], MaxTokens: 4096},
+		},
+		{
+			name: "provider change with a model",
+			env:  map[string]string{"LLM_PROVIDER": OpenAICompatible, "LLM_BASE_URL": "http://localhost:11434/v1", "LLM_MODEL": "qwen"},
+			want: Config{Use: "BuildAstRdf", Provider: OpenAICompatible, Model: "qwen", BaseURL: "http://localhost:11434/v1", MaxTokens: 4096},
+		},
+		{
+			name: "same provider keeps the model",
+			env:  map[string]string{"LLM_PROVIDER": Anthropic},
+			want: defaults,
+		},
+		{
+			name: "zero retries and TTL mean never and forever",
+			env:  map[string]string{"LLM_MAX_RETRIES": "0", "LLM_CACHE_TTL": "0s"},
+			want: Config{Use: "BuildAstRdf", Provider: Anthropic, Model: "claude", MaxTokens: 4096, MaxRetries: -1, CacheTTL: -1},
+		},
+		{
+			name: "limits and TTL",
+			env:  map[string]string{"LLM_BUILD_AST_RDF_REQUESTS_PER_MINUTE": "50", "LLM_TOKENS_PER_MINUTE": "40000", "LLM_CACHE_TTL": "72h", "LLM_CONTEXT_TOKENS": "32000"},
+			want: Config{Use: "BuildAstRdf", Provider: Anthropic, Model: "claude", MaxTokens: 4096, RequestsPerMinute: 50, TokensPerMinute: 40000, CacheTTL: 72 * time.Hour, ContextTokens: 32000},
+		},
+		{
+			name: "invalid numbers are ignored",
+			env:  map[string]string{"LLM_MAX_TOKENS": "lots", "LLM_REQUESTS_PER_MINUTE": "-1", "LLM_CACHE_TTL": "a month"},
+			want: defaults,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"PROVIDER", "MODEL", "BASE_URL", "API_KEY", "API_VERSION", "MAX_TOKENS", "CONTEXT_TOKENS", "REQUESTS_PER_MINUTE", "TOKENS_PER_MINUTE", "MAX_RETRIES", "SCRIPT", "CACHE", "CACHE_DIR", "CACHE_TTL"} {
+				t.Setenv("LLM_"+name, "")
+				t.Setenv("LLM_BUILD_AST_RDF_"+name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if got := Load(defaults); got != tt.want {
+				t.Errorf("Load = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "none", header: http.Header{}, want: 0},
+		{name: "seconds", header: http.Header{"Retry-After": {"3"}}, want: 3 * time.Second},
+		{name: "milliseconds win", header: http.Header{"Retry-After": {"3"}, "Retry-After-Ms": {"250"}}, want: 250 * time.Millisecond},
+		{name: "past date", header: http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, want: 0},
+		{name: "garbage", header: http.Header{"Retry-After": {"soon"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.header); got != tt.want {
+				t.Errorf("retryAfter = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := retryAfter(http.Header{"Retry-After": {future}}); got < 58*time.Minute || got > time.Hour {
+		t.Errorf("retryAfter of a date an hour away = %s", got)
+	}
+}
+
+func TestRetryingClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int // The statuses of the successive responses; 200 answers.
+		maxRetries int
+		wantCalls  int
+		wantStatus int // The status of the returned APIError, or 0 for success.
+	}{
+		{name: "success", statuses: []int{200}, maxRetries: 2, wantCalls: 1},
+		{name: "rate limited then success", statuses: []int{429, 503, 200}, maxRetries: 2, wantCalls: 3},
+		{name: "retries exhausted", statuses: []int{529, 529, 529}, maxRetries: 2, wantCalls: 3, wantStatus: 529},
+		{name: "no retries", statuses: []int{500, 200}, maxRetries: 0, wantCalls: 1, wantStatus: 500},
+		{name: "bad request is not retried", statuses: []int{400, 200}, maxRetries: 2, wantCalls: 1, wantStatus: 400},
+		{name: "invalid key is not retried", statuses: []int{401, 200}, maxRetries: 2, wantCalls: 1, wantStatus: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(calls, len(tt.statuses)-1)]
+				calls++
+				if status != http.StatusOK {
+					// Ask for a short wait, so the test does not back off for seconds.
+					w.Header().Set("retry-after-ms", "1")
+					w.WriteHeader(status)
+					fmt.Fprint(w, `{"error":"try later"}`)
+					return
+				}
+				fmt.Fprint(w, `{"id":"1","model":"m","choices":[{"message":{"content":"done"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			inner, err := newClient(Config{Provider: OpenAICompatible, BaseURL: server.URL, Model: "m", MaxTokens: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &retryingClient{client: inner, provider: OpenAICompatible, maxTokens: 100, maxRetries: tt.maxRetries}
+			response, err := client.Complete(context.Background(), Request{Prompt: "p"})
+			if calls != tt.wantCalls {
+				t.Errorf("the API was called %d time(s), want %d", calls, tt.wantCalls)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil || response.Text != "done" {
+					t.Fatalf("Complete = %q, %v, want done", response.Text, err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+				t.Fatalf("error = %v, want an APIError with status %d", err, tt.wantStatus)
+			}
+			if apiErr.RetryAfter != time.Millisecond {
+				t.Errorf("RetryAfter = %s, want 1ms", apiErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestCacheKeyAndTTL(t *testing.T) {
+	base := CacheKey{Provider: OpenAI, Model: "m", MaxTokens: 100, PromptHash: "abc"}
+	variants := map[string]CacheKey{
+		"provider":   {Provider: Anthropic, Model: "m", MaxTokens: 100, PromptHash: "abc"},
+		"model":      {Provider: OpenAI, Model: "n", MaxTokens: 100, PromptHash: "abc"},
+		"base URL":   {Provider: OpenAI, Model: "m", BaseURL: "http://localhost", MaxTokens: 100, PromptHash: "abc"},
+		"max tokens": {Provider: OpenAI, Model: "m", MaxTokens: 200, PromptHash: "abc"},
+		"prompt":     {Provider: OpenAI, Model: "m", MaxTokens: 100, PromptHash: "abd"},
+		"output":     {Provider: OpenAI, Model: "m", MaxTokens: 100, PromptHash: "abc", OutputHash: "x"},
+		"kind":       {Kind: KindEmbedding, Provider: OpenAI, Model: "m", MaxTokens: 100, PromptHash: "abc"},
+	}
+	for name, key := range variants {
+		if key.Hash() == base.Hash() {
+			t.Errorf("keys differing by %s have the same hash", name)
+		}
+	}
+	if base.Hash() != (CacheKey{Provider: OpenAI, Model: "m", MaxTokens: 100, PromptHash: "abc"}).Hash() {
+		t.Error("equal keys have different hashes")
+	}
+
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	if _, ok := cache.Get(base); ok {
+		t.Fatal("Get of an empty cache found a response")
+	}
+	if err := cache.Put(base, Response{Text: "cached"}); err != nil {
+		t.Fatal(err)
+	}
+	if response, ok := cache.Get(base); !ok || response.Text != "cached" {
+		t.Fatalf("Get = %q, %v, want the cached response", response.Text, ok)
+	}
+	if _, ok := cache.Get(variants["model"]); ok {
+		t.Fatal("Get of another key found the response")
+	}
+	if stats, err := cache.Stats(); err != nil || stats.Entries != 1 || stats.Expired != 0 {
+		t.Fatalf("Stats = %+v, %v, want 1 fresh entry", stats, err)
+	}
+
+	// The same entry has expired for a cache with a shorter TTL.
+	expired := &Cache{Dir: cache.Dir, TTL: time.Nanosecond}
+	time.Sleep(time.Millisecond)
+	if _, ok := expired.Get(base); ok {
+		t.Fatal("Get found an expired response")
+	}
+	if removed, err := expired.Prune(); err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v, want 1 removed", removed, err)
+	}
+	if _, ok := cache.Get(base); ok {
+		t.Fatal("Get found a pruned response")
+	}
+
+	// A TTL of 0 keeps entries forever.
+	forever := OpenCache(Config{CacheDir: cache.Dir, CacheTTL: -1})
+	if forever.TTL != 0 {
+		t.Fatalf("OpenCache with a negative TTL has TTL %s, want 0", forever.TTL)
+	}
+	if defaults := OpenCache(Config{}); defaults.Dir != defaultCacheDir || defaults.TTL != defaultCacheTTL {
+		t.Fatalf("OpenCache defaults = %+v", defaults)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	if limiterFor(Config{Provider: OpenAI}) != nil {
+		t.Fatal("a configuration without limits has a limiter")
+	}
+	a := limiterFor(Config{Provider: OpenAI, BaseURL: "http://limits.test", Model: "m", RequestsPerMinute: 60})
+	b := limiterFor(Config{Provider: OpenAI, BaseURL: "http://limits.test", Model: "m", RequestsPerMinute: 1})
+	if a != b {
+		t.Fatal("configurations of the same API have different limiters")
+	}
+
+	// 6000 requests per minute is one every 10ms once the bucket of 6000 is spent.
+	l := &limiter{requestsPerMinute: 6000, requests: 2, updated: time.Now()}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.wait(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("4 requests with 2 available took %s, want at least 2 refills of 10ms", elapsed)
+	}
+
+	// A request larger than the token limit waits for a full bucket, not forever.
+	l = &limiter{tokensPerMinute: 60000, tokens: 60000, updated: time.Now()}
+	if err := l.wait(context.Background(), 100000); err != nil {
+		t.Fatal(err)
+	}
+	if l.tokens != 0 {
+		t.Errorf("tokens left = %v, want 0", l.tokens)
+	}
+
+	// A waiting request ends with its context.
+	l = &limiter{requestsPerMinute: 1, updated: time.Now()}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait error = %v, want the context's deadline", err)
+	}
+
+	// Concurrent waiters are served one at a time within the limit.
+	l = &limiter{requestsPerMinute: 60000, requests: 1, updated: time.Now()}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.wait(context.Background(), 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// chatClient calls an OpenAI-style chat completions API: OpenAI itself, an Azure OpenAI deployment or an
+// OpenAI-compatible server.
+type chatClient struct {
+	cfg   Config
+	url   string // The chat completions endpoint.
+	name  string // The API in error messages.
+	azure bool   // Whether to authenticate with an api-key header, as Azure expects, instead of a bearer token.
+}
+
+func (c *chatClient) Complete(ctx context.Context, req Request) (Response, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = c.cfg.MaxTokens
+	}
+	prompt := req.Prompt
+	if req.Output != nil && !c.structuredOutputs() {
+		prompt = fmt.Sprintf("%s\n\nAnswer with a single JSON object that follows this JSON schema:\n%s", prompt, req.Output.Schema)
+	}
+	payload := map[string]interface{}{
+		"messages": []map[string]string{
+			{
+				"role":    "user",
+				"content": prompt,
+			},
+		},
+		"max_tokens": maxTokens,
+	}
+	if !c.azure {
+		// Azure takes the model from the deployment in the URL.
+		payload["model"] = c.cfg.Model
+	}
+	if req.Output != nil && !c.structuredOutputs() {
+		payload["response_format"] = map[string]interface{}{"type": "json_object"}
+	} else if req.Output != nil {
+		payload["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":        req.Output.Name,
+				"description": req.Output.Description,
+				"schema":      req.Output.Schema,
+			},
+		}
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	switch {
+	case c.azure:
+		httpReq.Header.Set("api-key", c.cfg.APIKey)
+	case c.cfg.APIKey != "":
+		// Local servers usually need no key.
+		httpReq.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to call %s API: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(c.name, resp)
+	}
+
+	var result struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Response{}, fmt.Errorf("failed to decode %s response: %w", c.name, err)
+	}
+	if len(result.Choices) == 0 {
+		return Response{}, fmt.Errorf("no choices returned from %s", c.name)
+	}
+
+	model := result.Model
+	if model == "" {
+		model = c.cfg.Model
+	}
+	response := Response{
+		Text:       strings.TrimSpace(result.Choices[0].Message.Content),
+		Model:      model,
+		ID:         result.ID,
+		StopReason: result.Choices[0].FinishReason,
+	}
+	if req.Output != nil {
+		response.JSON = jsonObject(response.Text)
+	}
+	return response, nil
+}
+
+// structuredOutputs reports whether the API accepts a json_schema response format. Azure only does from
+// API version 2024-08-01; the dates of API versions sort as strings.
+func (c *chatClient) structuredOutputs() bool {
+	return !c.azure || c.cfg.APIVersion >= azureStructuredOutputsVersion
+}
+
+// jsonObject returns the JSON object a structured response consists of. Some OpenAI-compatible servers
+// fence it in a code block; the fence is removed. It returns nil if the text holds no JSON object.
+func jsonObject(text string) json.RawMessage {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start || !json.Valid([]byte(text[start:end+1])) {
+		return nil
+	}
+	return json.RawMessage(text[start : end+1])
+}
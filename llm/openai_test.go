@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatClientResponseFormat(t *testing.T) {
+	output := &Output{Name: "answer", Schema: json.RawMessage(`{"type":"object"}`)}
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantFormat string
+		wantSchema bool // Whether the schema is pasted into the prompt.
+	}{
+		{name: "openai", cfg: Config{Provider: OpenAI, Model: "gpt-4o", APIKey: "k"}, wantFormat: "json_schema"},
+		{name: "azure default version", cfg: Config{Provider: Azure, Model: "deployment", APIKey: "k"}, wantFormat: "json_schema"},
+		{name: "azure preview version", cfg: Config{Provider: Azure, Model: "deployment", APIKey: "k", APIVersion: "2024-08-01-preview"}, wantFormat: "json_schema"},
+		{name: "azure old version", cfg: Config{Provider: Azure, Model: "deployment", APIKey: "k", APIVersion: "2024-06-01"}, wantFormat: "json_object", wantSchema: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload struct {
+				Messages []struct {
+					Content string `json:"content"`
+				} `json:"messages"`
+				ResponseFormat struct {
+					Type string `json:"type"`
+				} `json:"response_format"`
+			}
+			var apiVersion string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				apiVersion = r.URL.Query().Get("api-version")
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				w.Write([]byte(`{"id":"1","model":"m","choices":[{"message":{"content":"{\"a\":1}"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			cfg := tt.cfg
+			cfg.BaseURL = server.URL
+			client, err := newClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := client.Complete(context.Background(), Request{Prompt: "Describe it.", Output: output})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(response.JSON) != `{"a":1}` {
+				t.Errorf("JSON = %s", response.JSON)
+			}
+			if payload.ResponseFormat.Type != tt.wantFormat {
+				t.Errorf("response_format type = %q, want %q", payload.ResponseFormat.Type, tt.wantFormat)
+			}
+			if got := strings.Contains(payload.Messages[0].Content, `{"type":"object"}`); got != tt.wantSchema {
+				t.Errorf("schema in prompt = %v, want %v", got, tt.wantSchema)
+			}
+			if tt.cfg.Provider == Azure && tt.cfg.APIVersion == "" && apiVersion != defaultAzureAPIVersion {
+				t.Errorf("api-version = %q, want %q", apiVersion, defaultAzureAPIVersion)
+			}
+		})
+	}
+}
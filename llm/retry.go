@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Retry defaults.
+const (
+	defaultMaxRetries = 4
+	initialBackoff    = 2 * time.Second
+	maxBackoff        = time.Minute
+)
+
+// retryingClient sends the requests of a client within the limits of its API and retries the ones that
+// fail with a retryable APIError, waiting as long as the API asks with Retry-After or else backing off
+// exponentially.
+type retryingClient struct {
+	client     Client
+	provider   string
+	limiter    *limiter // nil for no limits.
+	maxTokens  int
+	maxRetries int
+}
+
+func (c *retryingClient) Complete(ctx context.Context, req Request) (Response, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = c.maxTokens
+	}
+	var response Response
+	err := withRetries(ctx, c.limiter, EstimateTokens(c.provider, req.Prompt)+maxTokens, c.maxRetries, func() error {
+		var err error
+		response, err = c.client.Complete(ctx, req)
+		return err
+	})
+	return response, err
+}
+
+// withRetries calls send within the limits of a limiter, which may be nil, taking the given number of
+// tokens for every attempt, and calls it again while it fails with a retryable APIError, at most maxRetries
+// times.
+func withRetries(ctx context.Context, l *limiter, tokens, maxRetries int, send func() error) error {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		if l != nil {
+			if err := l.wait(ctx, tokens); err != nil {
+				return err
+			}
+		}
+		err := send()
+		var apiErr *APIError
+		if err == nil || !errors.As(err, &apiErr) || !apiErr.Retryable() || attempt >= maxRetries {
+			return err
+		}
+
+		delay := apiErr.RetryAfter
+		if delay == 0 {
+			delay = backoff
+			backoff = min(backoff*2, maxBackoff)
+		}
+		log.Printf("%s API returned status %d, retrying in %s (retry %d of %d)", apiErr.API, apiErr.StatusCode, delay.Round(time.Millisecond), attempt+1, maxRetries)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"math"
+	"strings"
+)
+
+// charsPerToken is about how many characters of code and prose one token of a provider's tokenizer spans.
+// The estimates err on the high side: a prompt estimated to fit does.
+var charsPerToken = map[string]float64{
+	Anthropic:        3.2,
+	OpenAI:           3.6,
+	Azure:            3.6,
+	OpenAICompatible: 3.2, // Llama and Qwen tokenizers are close to Claude's on code.
+}
+
+// contextWindows are the context windows of the providers' default models, in tokens. OpenAI-compatible
+// servers often run models with small windows or configure a small one, so their default is conservative.
+var contextWindows = map[string]int{
+	Anthropic:        200000,
+	OpenAI:           128000,
+	Azure:            128000,
+	OpenAICompatible: 8192,
+}
+
+// defaultContextWindow is the context window of a provider without a known one.
+const defaultContextWindow = 8192
+
+// EstimateTokens estimates the number of tokens a text is for the models of a provider, without a
+// tokenizer: by its characters, and by its words for text of many short words.
+func EstimateTokens(provider, text string) int {
+	ratio, ok := charsPerToken[provider]
+	if !ok {
+		ratio = 3.2
+	}
+	byChars := int(math.Ceil(float64(len(text)) / ratio))
+	byWords := int(math.Ceil(float64(len(strings.Fields(text))) * 1.3))
+	return max(byChars, byWords)
+}
+
+// PromptBudget returns the most tokens a prompt of the configuration may have: the context window, or
+// ContextTokens if set, less the tokens the response may have.
+func (cfg Config) PromptBudget() int {
+	window := cfg.ContextTokens
+	if window == 0 {
+		window = contextWindows[cfg.Provider]
+	}
+	if window == 0 {
+		window = defaultContextWindow
+	}
+	maxTokens := cfg.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+	return max(window-maxTokens, 0)
+}
+
+// Fits reports whether a prompt is estimated to fit the prompt budget of the configuration.
+func (cfg Config) Fits(prompt string) bool {
+	return EstimateTokens(cfg.Provider, prompt) <= cfg.PromptBudget()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+)
+
+// runLlmCache implements the llm-cache command, which shows how many LLM responses are cached, removes the
+// expired ones or clears the cache:
+//
+//	GraphMind llm-cache [-dir <dir>] [-ttl <duration>] [-prune | -clear]
+func runLlmCache(args []string) error {
+	cfg := llm.Load(llm.Config{})
+	flags := flag.NewFlagSet("llm-cache", flag.ContinueOnError)
+	dir := flags.String("dir", "", "cache directory; LLM_CACHE_DIR or llm_cache if empty")
+	ttl := flags.Duration("ttl", 0, "age after which cached responses expire; LLM_CACHE_TTL or 720h if 0")
+	prune := flags.Bool("prune", false, "remove the expired responses")
+	clear := flags.Bool("clear", false, "remove every cached response")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *prune && *clear {
+		return fmt.Errorf("-prune and -clear cannot be combined")
+	}
+	if *dir != "" {
+		cfg.CacheDir = *dir
+	}
+	if *ttl != 0 {
+		cfg.CacheTTL = *ttl
+	}
+	cache := llm.OpenCache(cfg)
+
+	switch {
+	case *clear:
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+		fmt.Printf("Cleared LLM cache %s\n", cache.Dir)
+	case *prune:
+		removed, err := cache.Prune()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d expired response(s) from LLM cache %s\n", removed, cache.Dir)
+	default:
+		stats, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+		expiry := "never expire"
+		if cache.TTL > 0 {
+			expiry = "expire after " + cache.TTL.Round(time.Second).String()
+		}
+		fmt.Printf("LLM cache %s: %d response(s), %d expired, %d bytes; responses %s\n", cache.Dir, stats.Entries, stats.Expired, stats.Bytes, expiry)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/SaiNageswarS/GraphMind/activities/buildcodegraph"
 	"github.com/SaiNageswarS/GraphMind/services"
+	"github.com/SaiNageswarS/GraphMind/store"
 	"github.com/SaiNageswarS/GraphMind/workflows"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
@@ -15,6 +16,32 @@ import (
 func main() {
 	loadEnv()
 
+	// Run a command instead of the worker when one is given.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				log.Fatalln("Export failed:", err)
+			}
+			return
+		case "diff":
+			if err := runDiff(os.Args[2:]); err != nil {
+				log.Fatalln("Diff failed:", err)
+			}
+			return
+		case "lint":
+			if err := runLint(os.Args[2:]); err != nil {
+				log.Fatalln("Lint failed:", err)
+			}
+			return
+		case "llm-cache":
+			if err := runLlmCache(os.Args[2:]); err != nil {
+				log.Fatalln("LLM cache command failed:", err)
+			}
+			return
+		}
+	}
+
 	// Create Temporal client.
 	c, err := client.Dial(client.Options{})
 	if err != nil {
@@ -22,16 +49,22 @@ func main() {
 	}
 	defer c.Close()
 
+	// Open the graph store builds are saved to.
+	graphStore, err := store.Open(graphStoreDir())
+	if err != nil {
+		log.Fatalln("Unable to open graph store", err)
+	}
+
 	// Create worker for the task queue.
 	w := worker.New(c, "GraphMind", worker.Options{})
 
 	// Register the workflow and activities.
 	w.RegisterWorkflow(workflows.BuildCodeGraphWorkflow)
 	w.RegisterWorkflow(workflows.BuildMultipleCodeGraphsWorkflow)
-	w.RegisterActivity(&buildcodegraph.Activities{})
+	w.RegisterActivity(&buildcodegraph.Activities{Store: graphStore})
 
 	// Start the HTTP server in a separate goroutine.
-	go services.StartHTTPServer()
+	go services.StartHTTPServer(graphStore)
 
 	// Start listening to the Task Queue.
 	err = w.Run(worker.InterruptCh())
@@ -40,6 +73,15 @@ func main() {
 	}
 }
 
+// graphStoreDir returns the directory of the graph store.
+func graphStoreDir() string {
+	dir := os.Getenv("GRAPH_STORE_DIR")
+	if dir == "" {
+		dir = "graph_store" // Default store directory if not set in environment.
+	}
+	return dir
+}
+
 func loadEnv(envPath ...string) error {
 	if len(envPath) == 0 {
 		_, err := os.Stat(".env")
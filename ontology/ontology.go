@@ -0,0 +1,131 @@
+// Package ontology ships the GraphMind vocabulary used by prompts and deterministic RDF emitters.
+package ontology
+
+import (
+	_ "embed"
+	"sync"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Namespace is the IRI namespace of the GraphMind ontology, bound to Prefix in generated graphs.
+const (
+	Namespace = "http://graphmind.io/ontology#"
+	Prefix    = "gm"
+)
+
+// Turtle is the GraphMind ontology in Turtle format.
+//
+//go:embed graphmind.ttl
+var Turtle string
+
+// Classes.
+const (
+	Repository          = rdf.IRI(Namespace + "Repository")
+	Service             = rdf.IRI(Namespace + "Service")
+	Api                 = rdf.IRI(Namespace + "Api")
+	Resource            = rdf.IRI(Namespace + "Resource")
+	Database            = rdf.IRI(Namespace + "Database")
+	Collection          = rdf.IRI(Namespace + "Collection")
+	CloudResource       = rdf.IRI(Namespace + "CloudResource")
+	Topic               = rdf.IRI(Namespace + "Topic")
+	ConfigKey           = rdf.IRI(Namespace + "ConfigKey")
+	ComposeProject      = rdf.IRI(Namespace + "ComposeProject")
+	ComposeService      = rdf.IRI(Namespace + "ComposeService")
+	Network             = rdf.IRI(Namespace + "Network")
+	EnvironmentVariable = rdf.IRI(Namespace + "EnvironmentVariable")
+	Source              = rdf.IRI(Namespace + "Source")
+)
+
+// Object properties.
+const (
+	HasService        = rdf.IRI(Namespace + "hasService")
+	HasApi            = rdf.IRI(Namespace + "hasApi")
+	HasCollection     = rdf.IRI(Namespace + "hasCollection")
+	PartOf            = rdf.IRI(Namespace + "partOf")
+	DependsOn         = rdf.IRI(Namespace + "dependsOn")
+	Calls             = rdf.IRI(Namespace + "calls")
+	ReadsFrom         = rdf.IRI(Namespace + "readsFrom")
+	WritesTo          = rdf.IRI(Namespace + "writesTo")
+	PublishesTo       = rdf.IRI(Namespace + "publishesTo")
+	SubscribesTo      = rdf.IRI(Namespace + "subscribesTo")
+	UsesResource      = rdf.IRI(Namespace + "usesResource")
+	UsesConfig        = rdf.IRI(Namespace + "usesConfig")
+	DeploysRepository = rdf.IRI(Namespace + "deploysRepository")
+	RunsService       = rdf.IRI(Namespace + "runsService")
+	AttachedTo        = rdf.IRI(Namespace + "attachedTo")
+	Environment       = rdf.IRI(Namespace + "environment")
+	HasSource         = rdf.IRI(Namespace + "hasSource")
+)
+
+// Datatype properties.
+const (
+	Name             = rdf.IRI(Namespace + "name")
+	Description      = rdf.IRI(Namespace + "description")
+	RepoURL          = rdf.IRI(Namespace + "repoUrl")
+	Language         = rdf.IRI(Namespace + "language")
+	Framework        = rdf.IRI(Namespace + "framework")
+	RepoType         = rdf.IRI(Namespace + "repoType")
+	DatabaseType     = rdf.IRI(Namespace + "databaseType")
+	ResourceType     = rdf.IRI(Namespace + "resourceType")
+	ConnectionString = rdf.IRI(Namespace + "connectionString")
+	Image            = rdf.IRI(Namespace + "image")
+	ContainerName    = rdf.IRI(Namespace + "containerName")
+	ExposesPort      = rdf.IRI(Namespace + "exposesPort")
+	Driver           = rdf.IRI(Namespace + "driver")
+	Key              = rdf.IRI(Namespace + "key")
+	Value            = rdf.IRI(Namespace + "value")
+)
+
+// Provenance properties of gm:Source nodes.
+const (
+	SourceRepository = rdf.IRI(Namespace + "sourceRepository")
+	SourceCommit     = rdf.IRI(Namespace + "sourceCommit")
+	SourceFile       = rdf.IRI(Namespace + "sourceFile")
+	StartLine        = rdf.IRI(Namespace + "startLine")
+	EndLine          = rdf.IRI(Namespace + "endLine")
+	ProducedBy       = rdf.IRI(Namespace + "producedBy")
+	Model            = rdf.IRI(Namespace + "model")
+	PromptVersion    = rdf.IRI(Namespace + "promptVersion")
+	ResponseID       = rdf.IRI(Namespace + "responseId")
+	Evidence         = rdf.IRI(Namespace + "evidence")
+	Confidence       = rdf.IRI(Namespace + "confidence")
+)
+
+var (
+	loadOnce sync.Once
+	graph    *rdf.Graph
+)
+
+// Graph returns a copy of the parsed ontology.
+func Graph() *rdf.Graph {
+	return load().Clone()
+}
+
+// IsDefined reports whether the IRI is a class or property declared by the ontology.
+func IsDefined(iri rdf.IRI) bool {
+	return len(load().Match(iri, rdf.RDFType, nil)) > 0
+}
+
+// load parses the embedded ontology once. The file ships inside the binary, so a parse failure is a
+// programming error and panics.
+func load() *rdf.Graph {
+	loadOnce.Do(func() {
+		g, err := rdf.ParseTurtle(Turtle, "")
+		if err != nil {
+			panic("ontology: invalid embedded graphmind.ttl: " + err.Error())
+		}
+		graph = g
+	})
+	return graph
+}
+
+// NewGraph returns an empty graph with the GraphMind and standard prefixes bound.
+func NewGraph() *rdf.Graph {
+	g := rdf.NewGraph()
+	g.BindPrefix(Prefix, Namespace)
+	g.BindPrefix("rdf", rdf.RDFNamespace)
+	g.BindPrefix("rdfs", rdf.RDFSNamespace)
+	g.BindPrefix("xsd", rdf.XSDNamespace)
+	return g
+}
@@ -0,0 +1,95 @@
+package ontology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Terms of the vocabularies the ontology is written in.
+const (
+	owlClass            = rdf.IRI(rdf.OWLNamespace + "Class")
+	owlObjectProperty   = rdf.IRI(rdf.OWLNamespace + "ObjectProperty")
+	owlDatatypeProperty = rdf.IRI(rdf.OWLNamespace + "DatatypeProperty")
+	rdfsSubClassOf      = rdf.IRI(rdf.RDFSNamespace + "subClassOf")
+	rdfsDomain          = rdf.IRI(rdf.RDFSNamespace + "domain")
+	rdfsRange           = rdf.IRI(rdf.RDFSNamespace + "range")
+)
+
+var (
+	summaryOnce sync.Once
+	summary     string
+)
+
+// Summary lists the classes and properties of the ontology one per line, with their superclass, domain,
+// range and comment, for prompts: it is a fraction of the size of the Turtle. The provenance vocabulary,
+// which GraphMind records itself, is left out.
+func Summary() string {
+	summaryOnce.Do(func() {
+		g := load()
+		var b strings.Builder
+		for _, section := range []struct {
+			title string
+			kind  rdf.IRI
+		}{{"Classes", owlClass}, {"Object properties", owlObjectProperty}, {"Datatype properties", owlDatatypeProperty}} {
+			var lines []string
+			for _, node := range g.Subjects(rdf.RDFType, section.kind) {
+				term, ok := node.(rdf.IRI)
+				if !ok || isProvenanceTerm(g, term) {
+					continue
+				}
+				line := curie(term)
+				if super := g.Object(term, rdfsSubClassOf); super != nil {
+					line += " (subclass of " + curie(super) + ")"
+				}
+				domain, rng := g.Object(term, rdfsDomain), g.Object(term, rdfsRange)
+				if domain != nil || rng != nil {
+					line += fmt.Sprintf(" %s -> %s", curieOr(domain, "any"), curieOr(rng, "any"))
+				}
+				if comment := g.Object(term, rdf.RDFSComment); comment != nil {
+					line += ": " + rdf.Value(comment)
+				}
+				lines = append(lines, line)
+			}
+			sort.Strings(lines)
+			fmt.Fprintf(&b, "%s:\n", section.title)
+			for _, line := range lines {
+				fmt.Fprintf(&b, "- %s\n", line)
+			}
+		}
+		summary = strings.TrimSuffix(b.String(), "\n")
+	})
+	return summary
+}
+
+// isProvenanceTerm reports whether a term belongs to the provenance vocabulary: gm:Source and the
+// properties of sources and of the rdf:Statement nodes linking triples to them.
+func isProvenanceTerm(g *rdf.Graph, term rdf.IRI) bool {
+	if term == Source {
+		return true
+	}
+	domain := g.Object(term, rdfsDomain)
+	return domain == Source || domain == rdf.RDFStatement
+}
+
+// curie abbreviates a term of the ontology or of XSD, for example "gm:Api" or "xsd:string".
+func curie(term rdf.Term) string {
+	value := rdf.Value(term)
+	switch {
+	case strings.HasPrefix(value, Namespace):
+		return Prefix + ":" + strings.TrimPrefix(value, Namespace)
+	case strings.HasPrefix(value, rdf.XSDNamespace):
+		return "xsd:" + strings.TrimPrefix(value, rdf.XSDNamespace)
+	}
+	return "<" + value + ">"
+}
+
+func curieOr(term rdf.Term, fallback string) string {
+	if term == nil {
+		return fallback
+	}
+	return curie(term)
+}
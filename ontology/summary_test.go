@@ -0,0 +1,50 @@
+package ontology
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+func TestSummary(t *testing.T) {
+	lines := map[string]bool{}
+	for _, line := range strings.Split(Summary(), "\n") {
+		lines[line] = true
+	}
+	for _, want := range []string{
+		"- gm:hasApi gm:Service -> gm:Api: The service exposes the API.",
+		"- gm:Database (subclass of gm:Resource): A database server or logical database, such as a MongoDB database or a PostgreSQL schema.",
+		"- gm:name any -> xsd:string: Human-friendly name of any GraphMind node.",
+	} {
+		if !lines[want] {
+			t.Errorf("the summary lacks %q", want)
+		}
+	}
+
+	tests := []struct {
+		term   rdf.IRI
+		listed bool
+	}{
+		{Repository, true},
+		{Service, true},
+		{Api, true},
+		{Calls, true},
+		{UsesConfig, true},
+		{ConnectionString, true},
+		{Source, false},
+		{HasSource, false},
+		{SourceFile, false},
+		{Evidence, false},
+		{Confidence, false},
+	}
+	for _, tt := range tests {
+		listed := strings.Contains(Summary(), "- "+curie(tt.term)+" ") || strings.Contains(Summary(), "- "+curie(tt.term)+":")
+		if listed != tt.listed {
+			t.Errorf("%s listed = %v, want %v", curie(tt.term), listed, tt.listed)
+		}
+	}
+	if len(Summary()) >= len(Turtle)/2 {
+		t.Errorf("the summary has %d bytes, the Turtle %d", len(Summary()), len(Turtle))
+	}
+}
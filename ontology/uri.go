@@ -0,0 +1,162 @@
+package ontology
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// IDNamespace is the namespace of canonical node URIs. Identical entities described by different
+// repositories get the same URI under it, so their nodes collapse into one when graphs are merged.
+const IDNamespace = "http://graphmind.io/id/"
+
+// owlSameAs links a node renamed to its canonical URI to the IRI it had before.
+const owlSameAs = rdf.IRI(rdf.OWLNamespace + "sameAs")
+
+// RepositoryURI returns the canonical URI of a git repository. HTTPS, SSH and scp-like URLs of the same
+// repository map to the same URI.
+func RepositoryURI(repoURL string) rdf.IRI {
+	return rdf.IRI(IDNamespace + "repo/" + normalizeRepoURL(repoURL))
+}
+
+// GraphNamespace is the namespace of the names of graphs in the graph store.
+const GraphNamespace = "http://graphmind.io/graph/"
+
+// ComposeGraphURI names the graph holding the docker-compose topology of the latest multi-repository build.
+const ComposeGraphURI = rdf.IRI(GraphNamespace + "compose")
+
+// RepositoryGraphURI returns the name of the graph holding the build of a git repository.
+func RepositoryGraphURI(repoURL string) rdf.IRI {
+	return rdf.IRI(GraphNamespace + "repo/" + normalizeRepoURL(repoURL))
+}
+
+// ServiceURI returns the canonical URI of a gRPC service from its fully qualified proto name, for example
+// "auth.Login". When the proto package is unknown the Go module path is used as the qualifier.
+func ServiceURI(protoService string) rdf.IRI {
+	return rdf.IRI(IDNamespace + "service/" + segment(protoService, false))
+}
+
+// QualifiedServiceName joins a proto package (or Go module path) and a service name.
+func QualifiedServiceName(protoPackage, service string) string {
+	if protoPackage == "" {
+		return service
+	}
+	return protoPackage + "." + service
+}
+
+// ApiURI returns the canonical URI of an RPC of a service.
+func ApiURI(protoService, rpc string) rdf.IRI {
+	return ServiceURI(protoService) + rdf.IRI("/"+segment(rpc, false))
+}
+
+// DatabaseURI returns the canonical URI of a database from its type (for example "MongoDB") and name.
+func DatabaseURI(databaseType, name string) rdf.IRI {
+	if databaseType == "" {
+		databaseType = "unknown"
+	}
+	return rdf.IRI(IDNamespace + "database/" + segment(databaseType, true) + "/" + segment(name, true))
+}
+
+// CollectionURI returns the canonical URI of a collection or table inside a database.
+func CollectionURI(database rdf.IRI, name string) rdf.IRI {
+	return database + rdf.IRI("/"+segment(name, true))
+}
+
+// CloudResourceURI returns the canonical URI of a cloud resource from its type (for example "KeyVault")
+// and name.
+func CloudResourceURI(resourceType, name string) rdf.IRI {
+	if resourceType == "" {
+		resourceType = "unknown"
+	}
+	return rdf.IRI(IDNamespace + "cloud/" + segment(resourceType, true) + "/" + segment(name, true))
+}
+
+// TopicURI returns the canonical URI of a message topic or queue.
+func TopicURI(name string) rdf.IRI {
+	return rdf.IRI(IDNamespace + "topic/" + segment(name, true))
+}
+
+// ConfigKeyURI returns the canonical URI of a configuration key. Keys are case-sensitive.
+func ConfigKeyURI(name string) rdf.IRI {
+	return rdf.IRI(IDNamespace + "config/" + segment(name, false))
+}
+
+// Canonicalize renames resource and repository nodes of the graph to their canonical URIs, computed from
+// their type and properties, so equal entities produced by different LLM calls merge. Nodes without the
+// properties a canonical URI needs are left unchanged. A renamed node is linked with owl:sameAs to the
+// IRI it had, so it can still be found by it.
+func Canonicalize(g *rdf.Graph) {
+	rename := func(class rdf.IRI, canonical func(node rdf.Term) (rdf.IRI, bool)) {
+		for _, node := range g.Subjects(rdf.RDFType, class) {
+			if uri, ok := canonical(node); ok && uri != node {
+				g.Rename(node, uri)
+				if former, ok := node.(rdf.IRI); ok {
+					g.AddTriple(uri, owlSameAs, former)
+				}
+			}
+		}
+	}
+
+	rename(Repository, func(node rdf.Term) (rdf.IRI, bool) {
+		url := rdf.Value(g.Object(node, RepoURL))
+		return RepositoryURI(url), url != ""
+	})
+	rename(Database, func(node rdf.Term) (rdf.IRI, bool) {
+		name := rdf.Value(g.Object(node, Name))
+		return DatabaseURI(rdf.Value(g.Object(node, DatabaseType)), name), name != ""
+	})
+	// Collections are renamed after databases so they are qualified by the canonical database URI.
+	rename(Collection, func(node rdf.Term) (rdf.IRI, bool) {
+		name := rdf.Value(g.Object(node, Name))
+		databases := g.Subjects(HasCollection, node)
+		if name == "" || len(databases) != 1 {
+			return "", false
+		}
+		database, ok := databases[0].(rdf.IRI)
+		return CollectionURI(database, name), ok && strings.HasPrefix(string(database), IDNamespace)
+	})
+	rename(CloudResource, func(node rdf.Term) (rdf.IRI, bool) {
+		name := rdf.Value(g.Object(node, Name))
+		return CloudResourceURI(rdf.Value(g.Object(node, ResourceType)), name), name != ""
+	})
+	rename(Topic, func(node rdf.Term) (rdf.IRI, bool) {
+		name := rdf.Value(g.Object(node, Name))
+		return TopicURI(name), name != ""
+	})
+	rename(ConfigKey, func(node rdf.Term) (rdf.IRI, bool) {
+		name := rdf.Value(g.Object(node, Name))
+		return ConfigKeyURI(name), name != ""
+	})
+}
+
+var (
+	scpLikeURL = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.*)$`)
+	urlScheme  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+	unsafeURI  = regexp.MustCompile(`[^A-Za-z0-9._~/-]+`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// normalizeRepoURL reduces a repository URL to "host/owner/name" in lower case.
+func normalizeRepoURL(repoURL string) string {
+	s := strings.TrimSpace(repoURL)
+	if m := scpLikeURL.FindStringSubmatch(s); m != nil && !urlScheme.MatchString(s) {
+		s = m[1] + "/" + m[2]
+	}
+	s = urlScheme.ReplaceAllString(s, "")
+	if at := strings.Index(s, "@"); at >= 0 && at < strings.Index(s+"/", "/") {
+		s = s[at+1:] // strip user info
+	}
+	s = strings.TrimSuffix(strings.TrimRight(s, "/"), ".git")
+	return strings.Trim(unsafeURI.ReplaceAllString(strings.ToLower(s), "_"), "/")
+}
+
+// segment makes a name safe to use in a canonical URI path.
+func segment(name string, lower bool) string {
+	s := strings.TrimSpace(name)
+	if lower {
+		s = strings.ToLower(s)
+	}
+	s = whitespace.ReplaceAllString(s, "-")
+	return unsafeURI.ReplaceAllString(strings.ReplaceAll(s, "/", "_"), "_")
+}
@@ -0,0 +1,64 @@
+package ontology
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/shacl"
+)
+
+// Shapes are the SHACL shapes for the GraphMind ontology in Turtle format.
+//
+//go:embed shapes.ttl
+var Shapes string
+
+var (
+	shapesOnce  sync.Once
+	shapesGraph *rdf.Graph
+)
+
+// Validate checks a graph against the GraphMind SHACL shapes and reports every class or predicate in the
+// GraphMind namespace that the ontology does not declare.
+func Validate(g *rdf.Graph) shacl.Report {
+	report := shacl.Validate(g, loadShapes())
+
+	reported := map[rdf.IRI]bool{}
+	undefined := func(t rdf.Triple, iri rdf.IRI, kind string) {
+		if !strings.HasPrefix(string(iri), Namespace) || IsDefined(iri) || reported[iri] {
+			return
+		}
+		reported[iri] = true
+		report.Conforms = false
+		report.Results = append(report.Results, shacl.Result{
+			FocusNode:  t.Subject,
+			Path:       t.Predicate,
+			Constraint: "gm:vocabulary",
+			Severity:   shacl.Violation,
+			Message:    fmt.Sprintf("%s %s is not defined by the GraphMind ontology", kind, iri),
+		})
+	}
+	for _, t := range g.Triples() {
+		undefined(t, t.Predicate, "predicate")
+		if class, ok := t.Object.(rdf.IRI); ok && t.Predicate == rdf.RDFType {
+			undefined(t, class, "class")
+		}
+	}
+	return report
+}
+
+// loadShapes parses the embedded shapes together with the ontology, whose rdfs:subClassOf statements
+// make sh:class accept subclasses.
+func loadShapes() *rdf.Graph {
+	shapesOnce.Do(func() {
+		g, err := rdf.ParseTurtle(Shapes, "")
+		if err != nil {
+			panic("ontology: invalid embedded shapes.ttl: " + err.Error())
+		}
+		g.Merge(load())
+		shapesGraph = g
+	})
+	return shapesGraph
+}
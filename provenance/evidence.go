@@ -0,0 +1,63 @@
+package provenance
+
+// Evidence is the kind of analysis a triple was derived from.
+type Evidence string
+
+const (
+	StaticAnalysis Evidence = "static-analysis" // Code parsed deterministically, for example a pb.NewAuthClient call.
+	Manifest       Evidence = "manifest"        // A declaration such as a docker-compose file.
+	RuntimeTrace   Evidence = "runtime-trace"   // Behaviour observed at runtime, for example in distributed traces.
+	LLMInference   Evidence = "llm-inference"   // An LLM's reading of code or file names.
+)
+
+// defaultConfidence is the confidence of a source that states its evidence but no confidence of its own.
+// Observed and parsed facts are nearly certain; an LLM's inference is a well-founded guess.
+var defaultConfidence = map[Evidence]float64{
+	RuntimeTrace:   1.0,
+	StaticAnalysis: 0.95,
+	Manifest:       0.9,
+	LLMInference:   0.6,
+}
+
+// activityEvidence is the evidence of the activities that produced triples before sources recorded it.
+var activityEvidence = map[string]Evidence{
+	"GenerateRDFGraph":      LLMInference,
+	"BuildAstRdf":           LLMInference,
+	"LinkServiceCalls":      StaticAnalysis,
+	"ImportComposeTopology": Manifest,
+}
+
+// filled returns the source with the evidence its activity implies and the default confidence of its
+// evidence where it states none.
+func (s Source) filled() Source {
+	if s.Evidence == "" {
+		s.Evidence = activityEvidence[s.Activity]
+	}
+	if s.Confidence == 0 {
+		s.Confidence = DefaultConfidence(s.Evidence)
+	}
+	return s
+}
+
+// inferred returns the source with only the evidence and confidence its activity implies.
+func (s Source) inferred() Source {
+	s.Evidence, s.Confidence = "", 0
+	return s.filled()
+}
+
+// DefaultConfidence returns the confidence of evidence of a kind, or 0 if the kind is unknown.
+func DefaultConfidence(evidence Evidence) float64 {
+	return defaultConfidence[evidence]
+}
+
+// Strongest returns the source that supports a triple best: the one with the highest confidence. Sources
+// of unknown confidence, such as entity resolution, count only when there is nothing else. ok is false
+// without sources.
+func Strongest(sources []Source) (strongest Source, ok bool) {
+	for i, source := range sources {
+		if i == 0 || source.Confidence > strongest.Confidence {
+			strongest = source
+		}
+	}
+	return strongest, len(sources) > 0
+}
@@ -0,0 +1,344 @@
+// Package provenance records where the triples of a GraphMind graph come from. Every triple can have any
+// number of sources; a source names the repository, commit, file and lines that justify the triple, the
+// activity that produced it and, for LLM output, the model, prompt template and response. It also states
+// the kind of evidence behind the triple and how confident it is, so a parsed gRPC client call can be told
+// apart from an LLM's guess.
+//
+// Provenance is stored in the graph it describes, using RDF reification:
+//
+//	_:s a rdf:Statement ; rdf:subject S ; rdf:predicate P ; rdf:object O ; gm:hasSource <source> .
+//	<source> a gm:Source ; gm:sourceFile "service.go" ; gm:startLine 10 ; gm:endLine 42 ; ...
+//
+// so it survives Turtle files, merges, renames and the graph store, and can be queried with SPARQL. Source
+// nodes get IRIs derived from their fields, which makes equal sources collapse into one node.
+package provenance
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Namespace is the namespace of source node IRIs.
+const Namespace = "http://graphmind.io/source/"
+
+// Source describes where a triple comes from. Fields that do not apply are left empty.
+type Source struct {
+	Repository    string `json:"repository,omitempty"`    // The URL of the repository.
+	Commit        string `json:"commit,omitempty"`        // The commit the repository was read at.
+	File          string `json:"file,omitempty"`          // The path relative to the repository root, with forward slashes.
+	StartLine     int    `json:"startLine,omitempty"`     // The first line, counted from 1, or 0 for the whole file.
+	EndLine       int    `json:"endLine,omitempty"`       // The last line.
+	Activity      string `json:"activity"`                // The activity that produced the triple, for example "BuildAstRdf".
+	Model         string `json:"model,omitempty"`         // The LLM that produced the triple.
+	PromptVersion string `json:"promptVersion,omitempty"` // The prompt template the LLM was called with, see PromptVersion.
+	ResponseID    string `json:"responseId,omitempty"`    // The id the LLM API assigned to the response.
+
+	Evidence   Evidence `json:"evidence,omitempty"`   // The kind of analysis the triple was derived from.
+	Confidence float64  `json:"confidence,omitempty"` // From 0 to 1; DefaultConfidence of the evidence if 0.
+}
+
+// PromptVersion identifies a prompt template by its name and a hash of its content, so a change of the
+// template shows in the provenance of every triple generated with it.
+func PromptVersion(name, template string) string {
+	sum := sha1.Sum([]byte(template))
+	return name + "@" + hex.EncodeToString(sum[:])[:12]
+}
+
+// URI returns the IRI of the source node, which is the same for equal sources.
+func (s Source) URI() rdf.IRI {
+	fields := []string{s.Repository, s.Commit, s.File, strconv.Itoa(s.StartLine), strconv.Itoa(s.EndLine),
+		s.Activity, s.Model, s.PromptVersion, s.ResponseID}
+	if filled := s.filled(); filled != s.inferred() {
+		// Only evidence that differs from what the activity implies is part of the key, so sources recorded
+		// before evidence existed keep their IRIs.
+		fields = append(fields, string(filled.Evidence), strconv.FormatFloat(filled.Confidence, 'f', -1, 64))
+	}
+	key := strings.Join(fields, "\x00")
+	sum := sha1.Sum([]byte(key))
+	return rdf.IRI(Namespace + hex.EncodeToString(sum[:])[:16])
+}
+
+// Lines returns the line range in the "file:10-42" form, or just the file when no lines are known.
+func (s Source) Lines() string {
+	switch {
+	case s.File == "" || s.StartLine == 0:
+		return s.File
+	case s.EndLine <= s.StartLine:
+		return fmt.Sprintf("%s:%d", s.File, s.StartLine)
+	}
+	return fmt.Sprintf("%s:%d-%d", s.File, s.StartLine, s.EndLine)
+}
+
+// Link returns a URL showing the source lines in the repository's web interface, or "" if the source has
+// no repository file. GitHub's URL layout is assumed unless the host is a GitLab or Bitbucket server.
+func (s Source) Link() string {
+	if s.Repository == "" || s.File == "" {
+		return ""
+	}
+	base := webURL(s.Repository)
+	commit := s.Commit
+	if commit == "" {
+		commit = "HEAD"
+	}
+
+	host := strings.ToLower(base[len("https://"):])
+	start, end := s.StartLine, max(s.StartLine, s.EndLine)
+	switch {
+	case strings.Contains(host, "gitlab"):
+		link := fmt.Sprintf("%s/-/blob/%s/%s", base, commit, s.File)
+		if start > 0 {
+			link += fmt.Sprintf("#L%d-%d", start, end)
+		}
+		return link
+	case strings.Contains(host, "bitbucket"):
+		link := fmt.Sprintf("%s/src/%s/%s", base, commit, s.File)
+		if start > 0 {
+			link += fmt.Sprintf("#lines-%d:%d", start, end)
+		}
+		return link
+	}
+	link := fmt.Sprintf("%s/blob/%s/%s", base, commit, s.File)
+	switch {
+	case start > 0 && end > start:
+		link += fmt.Sprintf("#L%d-L%d", start, end)
+	case start > 0:
+		link += fmt.Sprintf("#L%d", start)
+	}
+	return link
+}
+
+// webURL turns an HTTPS, SSH or scp-like clone URL into the https URL of the repository's web page.
+func webURL(repoURL string) string {
+	s := strings.TrimSpace(repoURL)
+	if scheme := strings.Index(s, "://"); scheme >= 0 {
+		s = s[scheme+3:]
+		if at := strings.Index(s, "@"); at >= 0 && at < strings.Index(s+"/", "/") {
+			s = s[at+1:]
+		}
+	} else if at := strings.Index(s, "@"); at >= 0 {
+		// scp-like syntax: git@github.com:org/repo.git
+		s = strings.Replace(s[at+1:], ":", "/", 1)
+	}
+	if slash := strings.Index(s, "/"); slash >= 0 {
+		if colon := strings.Index(s[:slash], ":"); colon >= 0 {
+			s = s[:colon] + s[slash:] // drop the SSH port
+		}
+	}
+	return "https://" + strings.TrimSuffix(strings.TrimRight(s, "/"), ".git")
+}
+
+// Record records that the triple, which should be in g, comes from the source. Recording the same source
+// twice has no effect.
+func Record(g *rdf.Graph, t rdf.Triple, source Source) {
+	node := source.URI()
+	for _, statement := range statements(g, t) {
+		if g.Contains(rdf.Triple{Subject: statement, Predicate: ontology.HasSource, Object: node}) {
+			return
+		}
+	}
+	addStatement(g, t, node)
+	addSource(g, node, source)
+}
+
+// Add adds a triple to g and records its source.
+func Add(g *rdf.Graph, s rdf.Term, p rdf.IRI, o rdf.Term, source Source) {
+	t := rdf.Triple{Subject: s, Predicate: p, Object: o}
+	g.Add(t)
+	Record(g, t, source)
+}
+
+// RecordRemaining records the source for every triple of g that has no provenance yet. The triples with
+// provenance are looked up once, rather than per triple as Record does, so graphs with many triples about
+// a node are not slow to record.
+func RecordRemaining(g *rdf.Graph, source Source) {
+	recorded := All(g)
+	node := source.URI()
+	added := false
+	for _, t := range Strip(g).Triples() {
+		if len(recorded[t]) == 0 {
+			addStatement(g, t, node)
+			added = true
+		}
+	}
+	if added {
+		addSource(g, node, source)
+	}
+}
+
+// addStatement adds an rdf:Statement that links the triple to a source node.
+func addStatement(g *rdf.Graph, t rdf.Triple, node rdf.IRI) {
+	g.BindPrefix(ontology.Prefix, ontology.Namespace)
+	g.BindPrefix("rdf", rdf.RDFNamespace)
+	statement := g.NewBlankNode()
+	g.AddTriple(statement, rdf.RDFType, rdf.RDFStatement)
+	g.AddTriple(statement, rdf.RDFSubject, t.Subject)
+	g.AddTriple(statement, rdf.RDFPredicate, t.Predicate)
+	g.AddTriple(statement, rdf.RDFObject, t.Object)
+	g.AddTriple(statement, ontology.HasSource, node)
+}
+
+func addSource(g *rdf.Graph, node rdf.IRI, s Source) {
+	if g.Contains(rdf.Triple{Subject: node, Predicate: rdf.RDFType, Object: ontology.Source}) {
+		return
+	}
+	g.AddTriple(node, rdf.RDFType, ontology.Source)
+	s = s.filled()
+	text := map[rdf.IRI]string{
+		ontology.SourceRepository: s.Repository,
+		ontology.SourceCommit:     s.Commit,
+		ontology.SourceFile:       s.File,
+		ontology.ProducedBy:       s.Activity,
+		ontology.Model:            s.Model,
+		ontology.PromptVersion:    s.PromptVersion,
+		ontology.ResponseID:       s.ResponseID,
+		ontology.Evidence:         string(s.Evidence),
+	}
+	for p, value := range text {
+		if value != "" {
+			g.AddTriple(node, p, rdf.NewLiteral(value))
+		}
+	}
+	if s.StartLine > 0 {
+		g.AddTriple(node, ontology.StartLine, rdf.NewTypedLiteral(strconv.Itoa(s.StartLine), rdf.XSDInteger))
+		g.AddTriple(node, ontology.EndLine, rdf.NewTypedLiteral(strconv.Itoa(max(s.StartLine, s.EndLine)), rdf.XSDInteger))
+	}
+	if s.Confidence > 0 {
+		g.AddTriple(node, ontology.Confidence, rdf.NewTypedLiteral(strconv.FormatFloat(s.Confidence, 'f', -1, 64), rdf.XSDDecimal))
+	}
+}
+
+// statements returns the rdf:Statement nodes describing the triple.
+func statements(g *rdf.Graph, t rdf.Triple) []rdf.Term {
+	var result []rdf.Term
+	for _, m := range g.Match(nil, rdf.RDFSubject, t.Subject) {
+		if g.Contains(rdf.Triple{Subject: m.Subject, Predicate: rdf.RDFPredicate, Object: t.Predicate}) &&
+			g.Contains(rdf.Triple{Subject: m.Subject, Predicate: rdf.RDFObject, Object: t.Object}) {
+			result = append(result, m.Subject)
+		}
+	}
+	return result
+}
+
+// Sources returns the sources recorded for a triple, sorted by file and line.
+func Sources(g *rdf.Graph, t rdf.Triple) []Source {
+	var sources []Source
+	for _, statement := range statements(g, t) {
+		for _, node := range g.Objects(statement, ontology.HasSource) {
+			sources = append(sources, readSource(g, node))
+		}
+	}
+	return sortSources(sources)
+}
+
+// All returns the sources recorded for every triple of g.
+func All(g *rdf.Graph) map[rdf.Triple][]Source {
+	result := map[rdf.Triple][]Source{}
+	for _, statement := range g.Subjects(rdf.RDFType, rdf.RDFStatement) {
+		s, p, o := g.Object(statement, rdf.RDFSubject), g.Object(statement, rdf.RDFPredicate), g.Object(statement, rdf.RDFObject)
+		predicate, ok := p.(rdf.IRI)
+		if s == nil || !ok || o == nil {
+			continue
+		}
+		t := rdf.Triple{Subject: s, Predicate: predicate, Object: o}
+		for _, node := range g.Objects(statement, ontology.HasSource) {
+			result[t] = append(result[t], readSource(g, node))
+		}
+	}
+	for t, sources := range result {
+		result[t] = sortSources(sources)
+	}
+	return result
+}
+
+func readSource(g *rdf.Graph, node rdf.Term) Source {
+	line := func(p rdf.IRI) int {
+		n, _ := strconv.Atoi(rdf.Value(g.Object(node, p)))
+		return n
+	}
+	source := Source{
+		Repository:    rdf.Value(g.Object(node, ontology.SourceRepository)),
+		Commit:        rdf.Value(g.Object(node, ontology.SourceCommit)),
+		File:          rdf.Value(g.Object(node, ontology.SourceFile)),
+		StartLine:     line(ontology.StartLine),
+		EndLine:       line(ontology.EndLine),
+		Activity:      rdf.Value(g.Object(node, ontology.ProducedBy)),
+		Model:         rdf.Value(g.Object(node, ontology.Model)),
+		PromptVersion: rdf.Value(g.Object(node, ontology.PromptVersion)),
+		ResponseID:    rdf.Value(g.Object(node, ontology.ResponseID)),
+		Evidence:      Evidence(rdf.Value(g.Object(node, ontology.Evidence))),
+	}
+	source.Confidence, _ = strconv.ParseFloat(rdf.Value(g.Object(node, ontology.Confidence)), 64)
+	return source.filled()
+}
+
+// sortSources sorts sources by repository, file and line and drops duplicates, which appear when entity
+// resolution renames two nodes with equal triples into one.
+func sortSources(sources []Source) []Source {
+	sort.Slice(sources, func(i, j int) bool {
+		a, b := sources[i], sources[j]
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.URI() < b.URI()
+	})
+	return slices.Compact(sources)
+}
+
+// isProvenance reports whether a triple belongs to the provenance of other triples: it describes an
+// rdf:Statement or a gm:Source node.
+func isProvenance(g *rdf.Graph, t rdf.Triple) bool {
+	return g.Contains(rdf.Triple{Subject: t.Subject, Predicate: rdf.RDFType, Object: rdf.RDFStatement}) ||
+		g.Contains(rdf.Triple{Subject: t.Subject, Predicate: rdf.RDFType, Object: ontology.Source})
+}
+
+// Strip returns a copy of g without provenance. Prompts get stripped graphs, which are a fraction of the
+// size.
+func Strip(g *rdf.Graph) *rdf.Graph {
+	stripped := rdf.NewGraph()
+	for prefix, namespace := range g.Prefixes {
+		stripped.BindPrefix(prefix, namespace)
+	}
+	for _, t := range g.Triples() {
+		if !isProvenance(g, t) {
+			stripped.Add(t)
+		}
+	}
+	return stripped
+}
+
+// Prune removes the provenance of triples that are no longer in g, for example after entity resolution
+// rewrote them, and source nodes no statement refers to any more.
+func Prune(g *rdf.Graph) {
+	for _, statement := range g.Subjects(rdf.RDFType, rdf.RDFStatement) {
+		s, p, o := g.Object(statement, rdf.RDFSubject), g.Object(statement, rdf.RDFPredicate), g.Object(statement, rdf.RDFObject)
+		predicate, ok := p.(rdf.IRI)
+		if ok && s != nil && o != nil && g.Contains(rdf.Triple{Subject: s, Predicate: predicate, Object: o}) {
+			continue
+		}
+		for _, t := range g.Match(statement, "", nil) {
+			g.Remove(t)
+		}
+	}
+	for _, node := range g.Subjects(rdf.RDFType, ontology.Source) {
+		if len(g.Match(nil, ontology.HasSource, node)) > 0 {
+			continue
+		}
+		for _, t := range g.Match(node, "", nil) {
+			g.Remove(t)
+		}
+	}
+}
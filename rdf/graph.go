@@ -0,0 +1,319 @@
+package rdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is an in-memory set of triples together with the prefixes used to abbreviate its IRIs.
+type Graph struct {
+	Prefixes map[string]string // Prefix label to namespace IRI.
+
+	triples     map[Triple]struct{}
+	bySubject   map[Term]map[Triple]struct{}
+	byPredicate map[IRI]map[Triple]struct{}
+	byObject    map[Term]map[Triple]struct{}
+	blankCount  int
+}
+
+// NewGraph returns an empty graph.
+func NewGraph() *Graph {
+	return &Graph{
+		Prefixes:    map[string]string{},
+		triples:     map[Triple]struct{}{},
+		bySubject:   map[Term]map[Triple]struct{}{},
+		byPredicate: map[IRI]map[Triple]struct{}{},
+		byObject:    map[Term]map[Triple]struct{}{},
+	}
+}
+
+// Len returns the number of triples in the graph.
+func (g *Graph) Len() int {
+	return len(g.triples)
+}
+
+// Add inserts a triple and reports whether it was not already present.
+func (g *Graph) Add(t Triple) bool {
+	if _, ok := g.triples[t]; ok {
+		return false
+	}
+	g.triples[t] = struct{}{}
+	addToIndex(g.bySubject, t.Subject, t)
+	addToIndex(g.byPredicate, t.Predicate, t)
+	addToIndex(g.byObject, t.Object, t)
+	return true
+}
+
+// AddTriple is a shorthand for Add(Triple{s, p, o}).
+func (g *Graph) AddTriple(s Term, p IRI, o Term) bool {
+	return g.Add(Triple{Subject: s, Predicate: p, Object: o})
+}
+
+// Remove deletes a triple and reports whether it was present.
+func (g *Graph) Remove(t Triple) bool {
+	if _, ok := g.triples[t]; !ok {
+		return false
+	}
+	delete(g.triples, t)
+	removeFromIndex(g.bySubject, t.Subject, t)
+	removeFromIndex(g.byPredicate, t.Predicate, t)
+	removeFromIndex(g.byObject, t.Object, t)
+	return true
+}
+
+// Contains reports whether the triple is in the graph.
+func (g *Graph) Contains(t Triple) bool {
+	_, ok := g.triples[t]
+	return ok
+}
+
+// Triples returns all triples sorted by subject, predicate and object.
+func (g *Graph) Triples() []Triple {
+	return sortedTriples(g.triples)
+}
+
+// Match returns the triples matching the pattern, sorted. A nil term (or empty predicate) is a wildcard.
+func (g *Graph) Match(s Term, p IRI, o Term) []Triple {
+	candidates := g.triples
+	if s != nil {
+		candidates = smaller(candidates, g.bySubject[s])
+	}
+	if p != "" {
+		candidates = smaller(candidates, g.byPredicate[p])
+	}
+	if o != nil {
+		candidates = smaller(candidates, g.byObject[o])
+	}
+
+	matches := map[Triple]struct{}{}
+	for t := range candidates {
+		if (s == nil || t.Subject == s) && (p == "" || t.Predicate == p) && (o == nil || t.Object == o) {
+			matches[t] = struct{}{}
+		}
+	}
+	return sortedTriples(matches)
+}
+
+// Objects returns the objects of all triples with the given subject and predicate.
+func (g *Graph) Objects(s Term, p IRI) []Term {
+	var objects []Term
+	for _, t := range g.Match(s, p, nil) {
+		objects = append(objects, t.Object)
+	}
+	return objects
+}
+
+// Object returns the first object for the subject and predicate, or nil.
+func (g *Graph) Object(s Term, p IRI) Term {
+	if objects := g.Objects(s, p); len(objects) > 0 {
+		return objects[0]
+	}
+	return nil
+}
+
+// Subjects returns the distinct subjects of all triples with the given predicate and object.
+func (g *Graph) Subjects(p IRI, o Term) []Term {
+	seen := map[Term]bool{}
+	var subjects []Term
+	for _, t := range g.Match(nil, p, o) {
+		if !seen[t.Subject] {
+			seen[t.Subject] = true
+			subjects = append(subjects, t.Subject)
+		}
+	}
+	return subjects
+}
+
+// SubjectTerms returns every distinct subject in the graph, sorted.
+func (g *Graph) SubjectTerms() []Term {
+	subjects := make([]Term, 0, len(g.bySubject))
+	for s := range g.bySubject {
+		subjects = append(subjects, s)
+	}
+	sortTerms(subjects)
+	return subjects
+}
+
+// NewBlankNode returns a blank node whose label is not used in the graph.
+func (g *Graph) NewBlankNode() BlankNode {
+	for {
+		g.blankCount++
+		b := BlankNode(fmt.Sprintf("b%d", g.blankCount))
+		if g.bySubject[b] == nil && g.byObject[b] == nil {
+			return b
+		}
+	}
+}
+
+// BindPrefix registers a prefix label for a namespace. An existing binding for the label is replaced.
+func (g *Graph) BindPrefix(prefix, namespace string) {
+	g.Prefixes[prefix] = namespace
+}
+
+// Merge adds every triple of other to g. Blank nodes of other are relabelled so they never collide with
+// blank nodes already in g. Prefixes of other are added unless the label is already bound to a different
+// namespace, in which case the namespace gets a fresh label.
+func (g *Graph) Merge(other *Graph) {
+	g.mergePrefixes(other.Prefixes)
+
+	blanks := map[BlankNode]BlankNode{}
+	relabel := func(t Term) Term {
+		b, ok := t.(BlankNode)
+		if !ok {
+			return t
+		}
+		if mapped, ok := blanks[b]; ok {
+			return mapped
+		}
+		mapped := g.NewBlankNode()
+		blanks[b] = mapped
+		return mapped
+	}
+
+	for _, t := range other.Triples() {
+		g.Add(Triple{Subject: relabel(t.Subject), Predicate: t.Predicate, Object: relabel(t.Object)})
+	}
+}
+
+// Rename replaces every occurrence of a subject or object term with another term. Triples that become
+// duplicates collapse into one.
+func (g *Graph) Rename(from, to Term) {
+	if from == to {
+		return
+	}
+	var affected []Triple
+	for t := range g.bySubject[from] {
+		affected = append(affected, t)
+	}
+	for t := range g.byObject[from] {
+		affected = append(affected, t)
+	}
+	for _, t := range affected {
+		if !g.Remove(t) {
+			continue
+		}
+		if t.Subject == from {
+			t.Subject = to
+		}
+		if t.Object == from {
+			t.Object = to
+		}
+		g.Add(t)
+	}
+}
+
+// Clone returns a copy of the graph including its prefixes.
+func (g *Graph) Clone() *Graph {
+	clone := NewGraph()
+	for prefix, namespace := range g.Prefixes {
+		clone.Prefixes[prefix] = namespace
+	}
+	for t := range g.triples {
+		clone.Add(t)
+	}
+	clone.blankCount = g.blankCount
+	return clone
+}
+
+func (g *Graph) mergePrefixes(prefixes map[string]string) {
+	bound := map[string]bool{}
+	for _, namespace := range g.Prefixes {
+		bound[namespace] = true
+	}
+
+	labels := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		labels = append(labels, prefix)
+	}
+	sort.Strings(labels)
+
+	for _, prefix := range labels {
+		namespace := prefixes[prefix]
+		if bound[namespace] {
+			continue
+		}
+		label := prefix
+		for i := 1; ; i++ {
+			if _, taken := g.Prefixes[label]; !taken {
+				break
+			}
+			label = fmt.Sprintf("%s%d", prefix, i)
+		}
+		g.Prefixes[label] = namespace
+		bound[namespace] = true
+	}
+}
+
+func addToIndex[K comparable](index map[K]map[Triple]struct{}, key K, t Triple) {
+	set, ok := index[key]
+	if !ok {
+		set = map[Triple]struct{}{}
+		index[key] = set
+	}
+	set[t] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[Triple]struct{}, key K, t Triple) {
+	if set, ok := index[key]; ok {
+		delete(set, t)
+		if len(set) == 0 {
+			delete(index, key)
+		}
+	}
+}
+
+func smaller(a, b map[Triple]struct{}) map[Triple]struct{} {
+	if len(b) < len(a) {
+		return b
+	}
+	return a
+}
+
+func sortedTriples(set map[Triple]struct{}) []Triple {
+	triples := make([]Triple, 0, len(set))
+	for t := range set {
+		triples = append(triples, t)
+	}
+	sort.Slice(triples, func(i, j int) bool {
+		return CompareTriples(triples[i], triples[j]) < 0
+	})
+	return triples
+}
+
+// CompareTriples orders triples by subject, predicate and object.
+func CompareTriples(a, b Triple) int {
+	if c := CompareTerms(a.Subject, b.Subject); c != 0 {
+		return c
+	}
+	if c := strings.Compare(string(a.Predicate), string(b.Predicate)); c != 0 {
+		return c
+	}
+	return CompareTerms(a.Object, b.Object)
+}
+
+// CompareTerms orders IRIs before blank nodes before literals, then lexically.
+func CompareTerms(a, b Term) int {
+	if ka, kb := termKind(a), termKind(b); ka != kb {
+		return ka - kb
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+func sortTerms(terms []Term) {
+	sort.Slice(terms, func(i, j int) bool {
+		return CompareTerms(terms[i], terms[j]) < 0
+	})
+}
+
+func termKind(t Term) int {
+	switch t.(type) {
+	case IRI:
+		return 0
+	case BlankNode:
+		return 1
+	case Literal:
+		return 2
+	}
+	return 3
+}
@@ -0,0 +1,83 @@
+package rdf
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// WriteJSONLD writes the graph as compacted JSON-LD: a @context holding the graph prefixes and a @graph
+// with one node object per subject. IRIs are abbreviated to compact IRIs where a prefix applies, and every
+// property value is an array so consumers do not need to special-case single values. The empty prefix has
+// no JSON-LD equivalent, so IRIs in its namespace are written in full.
+func WriteJSONLD(w io.Writer, g *Graph) error {
+	var prefixes []prefixBinding
+	context := map[string]string{}
+	for _, binding := range sortedPrefixes(g.Prefixes) {
+		if binding.prefix != "" {
+			prefixes = append(prefixes, binding)
+			context[binding.prefix] = binding.namespace
+		}
+	}
+	compact := func(i IRI) string {
+		s := string(i)
+		for _, binding := range prefixes {
+			if strings.HasPrefix(s, binding.namespace) {
+				if local := s[len(binding.namespace):]; local != "" && localNamePattern.MatchString(local) {
+					return binding.prefix + ":" + local
+				}
+			}
+		}
+		return s
+	}
+	reference := func(t Term) string {
+		if iri, ok := t.(IRI); ok {
+			return compact(iri)
+		}
+		return t.String()
+	}
+
+	nodes := []map[string]interface{}{}
+	for _, subject := range g.SubjectTerms() {
+		node := map[string]interface{}{"@id": reference(subject)}
+		for _, t := range g.Match(subject, "", nil) {
+			if t.Predicate == RDFType {
+				if _, ok := t.Object.(Literal); !ok {
+					types, _ := node["@type"].([]string)
+					node["@type"] = append(types, reference(t.Object))
+					continue
+				}
+			}
+			key := compact(t.Predicate)
+			values, _ := node[key].([]interface{})
+			node[key] = append(values, jsonLDValue(t.Object, compact, reference))
+		}
+		nodes = append(nodes, node)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{"@context": context, "@graph": nodes})
+}
+
+func jsonLDValue(t Term, compact func(IRI) string, reference func(Term) string) interface{} {
+	l, ok := t.(Literal)
+	if !ok {
+		return map[string]string{"@id": reference(t)}
+	}
+	switch {
+	case l.Language != "":
+		return map[string]string{"@value": l.Value, "@language": l.Language}
+	case l.Datatype != "" && l.Datatype != XSDString:
+		return map[string]string{"@value": l.Value, "@type": compact(l.Datatype)}
+	}
+	return l.Value
+}
+
+// ToJSONLD returns the graph serialised as JSON-LD.
+func ToJSONLD(g *Graph) string {
+	var b strings.Builder
+	_ = WriteJSONLD(&b, g)
+	return b.String()
+}
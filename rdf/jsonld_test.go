@@ -0,0 +1,77 @@
+package rdf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWriteJSONLD(t *testing.T) {
+	g, err := ParseTurtle(`
+		@prefix : <http://example.com/local/> .
+		@prefix ex: <http://example.com/> .
+		@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+		:a a ex:Service ; ex:name "a", "A"@en ; ex:port 8080 ; ex:calls ex:b, :c ; <http://example.com/weird-local/path> "x" .
+		ex:b ex:owner [ ex:name "team" ] .
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document struct {
+		Context map[string]string            `json:"@context"`
+		Graph   []map[string]json.RawMessage `json:"@graph"`
+	}
+	if err := json.Unmarshal([]byte(ToJSONLD(g)), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	// The empty prefix has no JSON-LD equivalent, so it is left out and its IRIs are written in full.
+	wantContext := map[string]string{"ex": "http://example.com/", "xsd": "http://www.w3.org/2001/XMLSchema#"}
+	if !reflect.DeepEqual(document.Context, wantContext) {
+		t.Errorf("@context = %v, want %v", document.Context, wantContext)
+	}
+
+	nodes := map[string]map[string]json.RawMessage{}
+	for _, node := range document.Graph {
+		var id string
+		if err := json.Unmarshal(node["@id"], &id); err != nil {
+			t.Fatal(err)
+		}
+		nodes[id] = node
+	}
+	a, ok := nodes["http://example.com/local/a"]
+	if !ok {
+		t.Fatalf("no node with the full IRI of :a among %v", document.Graph)
+	}
+	for key, want := range map[string]string{
+		"@type":                               `["ex:Service"]`,
+		"ex:name":                             `[{"@language":"en","@value":"A"},"a"]`,
+		"ex:port":                             `[{"@type":"xsd:integer","@value":"8080"}]`,
+		"ex:calls":                            `[{"@id":"ex:b"},{"@id":"http://example.com/local/c"}]`,
+		"http://example.com/weird-local/path": `["x"]`,
+	} {
+		if got := compactJSON(t, a[key]); got != want {
+			t.Errorf("%s = %s, want %s", key, got, want)
+		}
+	}
+	if _, ok := nodes["ex:b"]; !ok {
+		t.Errorf("no node ex:b among %v", document.Graph)
+	}
+	if len(nodes) != 3 {
+		t.Errorf("%d nodes, want :a, ex:b and the blank node", len(nodes))
+	}
+}
+
+func compactJSON(t *testing.T, raw json.RawMessage) string {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
@@ -0,0 +1,258 @@
+package rdf
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// WriteNTriples writes the graph as N-Triples, one sorted triple per line.
+func WriteNTriples(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range g.Triples() {
+		if _, err := bw.WriteString(t.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteTurtle writes the graph as Turtle. IRIs are abbreviated using the graph prefixes, triples are grouped
+// by subject and blank nodes referenced exactly once are written inline.
+func WriteTurtle(w io.Writer, g *Graph) error {
+	tw := &turtleWriter{
+		w:        bufio.NewWriter(w),
+		graph:    g,
+		prefixes: sortedPrefixes(g.Prefixes),
+		refs:     map[BlankNode]int{},
+		done:     map[Term]bool{},
+	}
+	return tw.write()
+}
+
+// ToTurtle returns the graph serialised as Turtle.
+func ToTurtle(g *Graph) string {
+	var b strings.Builder
+	_ = WriteTurtle(&b, g)
+	return b.String()
+}
+
+// ToNTriples returns the graph serialised as N-Triples.
+func ToNTriples(g *Graph) string {
+	var b strings.Builder
+	_ = WriteNTriples(&b, g)
+	return b.String()
+}
+
+// WriteTurtleFile writes the graph as Turtle to the given path.
+func WriteTurtleFile(g *Graph, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteTurtle(f, g); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type prefixBinding struct {
+	prefix    string
+	namespace string
+}
+
+// sortedPrefixes orders bindings by descending namespace length so the most specific namespace wins.
+func sortedPrefixes(prefixes map[string]string) []prefixBinding {
+	bindings := make([]prefixBinding, 0, len(prefixes))
+	for prefix, namespace := range prefixes {
+		bindings = append(bindings, prefixBinding{prefix, namespace})
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		if len(bindings[i].namespace) != len(bindings[j].namespace) {
+			return len(bindings[i].namespace) > len(bindings[j].namespace)
+		}
+		return bindings[i].prefix < bindings[j].prefix
+	})
+	return bindings
+}
+
+var localNamePattern = regexp.MustCompile(`^([\pL\pN_]([\pL\pN_.-]*[\pL\pN_-])?)?$`)
+
+type turtleWriter struct {
+	w        *bufio.Writer
+	graph    *Graph
+	prefixes []prefixBinding
+	refs     map[BlankNode]int
+	done     map[Term]bool
+}
+
+func (tw *turtleWriter) write() error {
+	triples := tw.graph.Triples()
+	for _, t := range triples {
+		if b, ok := t.Object.(BlankNode); ok {
+			tw.refs[b]++
+		}
+	}
+
+	byPrefix := make([]prefixBinding, len(tw.prefixes))
+	copy(byPrefix, tw.prefixes)
+	sort.Slice(byPrefix, func(i, j int) bool { return byPrefix[i].prefix < byPrefix[j].prefix })
+	for _, binding := range byPrefix {
+		tw.w.WriteString("@prefix " + binding.prefix + ": " + IRI(binding.namespace).String() + " .\n")
+	}
+	if len(byPrefix) > 0 {
+		tw.w.WriteString("\n")
+	}
+
+	// Subjects that can be written inline are skipped at the top level.
+	for _, subject := range tw.graph.SubjectTerms() {
+		if b, ok := subject.(BlankNode); ok && tw.refs[b] == 1 {
+			continue
+		}
+		tw.writeSubject(subject)
+	}
+	// Blank nodes only reachable through a cycle of single references are written with their label.
+	for _, subject := range tw.graph.SubjectTerms() {
+		if !tw.done[subject] {
+			tw.writeSubject(subject)
+		}
+	}
+	return tw.w.Flush()
+}
+
+func (tw *turtleWriter) writeSubject(subject Term) {
+	tw.done[subject] = true
+	var b strings.Builder
+	b.WriteString(tw.term(subject, 0))
+	tw.writePredicates(&b, subject, 1)
+	b.WriteString(" .\n\n")
+	tw.w.WriteString(b.String())
+}
+
+// writePredicates writes the predicate-object list of a subject with rdf:type first.
+func (tw *turtleWriter) writePredicates(b *strings.Builder, subject Term, depth int) {
+	triples := tw.graph.Match(subject, "", nil)
+	sort.SliceStable(triples, func(i, j int) bool {
+		return triples[i].Predicate == RDFType && triples[j].Predicate != RDFType
+	})
+
+	indent := strings.Repeat("    ", depth)
+	var previous IRI
+	for i, t := range triples {
+		switch {
+		case i == 0:
+			b.WriteString(" " + tw.predicate(t.Predicate) + " ")
+		case t.Predicate == previous:
+			b.WriteString(", ")
+		default:
+			b.WriteString(" ;\n" + indent + tw.predicate(t.Predicate) + " ")
+		}
+		b.WriteString(tw.term(t.Object, depth))
+		previous = t.Predicate
+	}
+}
+
+func (tw *turtleWriter) predicate(p IRI) string {
+	if p == RDFType {
+		return "a"
+	}
+	return tw.iri(p)
+}
+
+func (tw *turtleWriter) term(t Term, depth int) string {
+	switch v := t.(type) {
+	case IRI:
+		return tw.iri(v)
+	case BlankNode:
+		if tw.refs[v] == 1 && !tw.done[v] && depth > 0 {
+			tw.done[v] = true
+			if len(tw.graph.Match(v, "", nil)) == 0 {
+				return "[]"
+			}
+			if items, ok := tw.collection(v); ok {
+				parts := make([]string, len(items))
+				for i, item := range items {
+					parts[i] = tw.term(item, depth+1)
+				}
+				return "( " + strings.Join(parts, " ") + " )"
+			}
+			var b strings.Builder
+			b.WriteString("[")
+			tw.writePredicates(&b, v, depth+1)
+			b.WriteString(" ]")
+			return b.String()
+		}
+		return v.String()
+	case Literal:
+		return tw.literal(v)
+	}
+	return ""
+}
+
+// collection returns the items of a well-formed RDF list whose nodes are only referenced by the list itself.
+func (tw *turtleWriter) collection(head BlankNode) ([]Term, bool) {
+	var items []Term
+	var node Term = head
+	seen := map[Term]bool{}
+	for node != RDFNil {
+		b, ok := node.(BlankNode)
+		if !ok || seen[b] || (b != head && (tw.refs[b] != 1 || tw.done[b])) {
+			return nil, false
+		}
+		seen[b] = true
+		triples := tw.graph.Match(b, "", nil)
+		if len(triples) != 2 || triples[0].Predicate != RDFFirst || triples[1].Predicate != RDFRest {
+			return nil, false
+		}
+		items = append(items, triples[0].Object)
+		node = triples[1].Object
+	}
+
+	// Mark the list nodes as written only once the whole list is known to be well-formed.
+	for node = head; node != RDFNil; node = tw.graph.Object(node, RDFRest) {
+		tw.done[node] = true
+	}
+	return items, true
+}
+
+func (tw *turtleWriter) iri(i IRI) string {
+	s := string(i)
+	for _, binding := range tw.prefixes {
+		if strings.HasPrefix(s, binding.namespace) {
+			local := s[len(binding.namespace):]
+			if localNamePattern.MatchString(local) {
+				return binding.prefix + ":" + local
+			}
+		}
+	}
+	return i.String()
+}
+
+var (
+	integerPattern = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	decimalPattern = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+	doublePattern  = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)[eE][+-]?[0-9]+$`)
+)
+
+func (tw *turtleWriter) literal(l Literal) string {
+	switch {
+	case l.Datatype == XSDInteger && integerPattern.MatchString(l.Value),
+		l.Datatype == XSDDecimal && decimalPattern.MatchString(l.Value),
+		l.Datatype == XSDDouble && doublePattern.MatchString(l.Value),
+		l.Datatype == XSDBoolean && (l.Value == "true" || l.Value == "false"):
+		return l.Value
+	}
+
+	s := `"` + escapeString(l.Value) + `"`
+	switch {
+	case l.Language != "":
+		s += "@" + l.Language
+	case l.Datatype != "":
+		s += "^^" + tw.iri(l.Datatype)
+	}
+	return s
+}
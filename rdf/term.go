@@ -0,0 +1,169 @@
+// Package rdf implements RDF terms, in-memory graphs and Turtle/N-Triples parsing and serialisation.
+package rdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Well-known namespaces.
+const (
+	RDFNamespace  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	RDFSNamespace = "http://www.w3.org/2000/01/rdf-schema#"
+	XSDNamespace  = "http://www.w3.org/2001/XMLSchema#"
+	OWLNamespace  = "http://www.w3.org/2002/07/owl#"
+)
+
+// Well-known IRIs.
+const (
+	RDFType       = IRI(RDFNamespace + "type")
+	RDFFirst      = IRI(RDFNamespace + "first")
+	RDFRest       = IRI(RDFNamespace + "rest")
+	RDFNil        = IRI(RDFNamespace + "nil")
+	RDFLangString = IRI(RDFNamespace + "langString")
+	RDFStatement  = IRI(RDFNamespace + "Statement")
+	RDFSubject    = IRI(RDFNamespace + "subject")
+	RDFPredicate  = IRI(RDFNamespace + "predicate")
+	RDFObject     = IRI(RDFNamespace + "object")
+	RDFSLabel     = IRI(RDFSNamespace + "label")
+	RDFSComment   = IRI(RDFSNamespace + "comment")
+	XSDString     = IRI(XSDNamespace + "string")
+	XSDBoolean    = IRI(XSDNamespace + "boolean")
+	XSDInteger    = IRI(XSDNamespace + "integer")
+	XSDDecimal    = IRI(XSDNamespace + "decimal")
+	XSDDouble     = IRI(XSDNamespace + "double")
+	XSDDateTime   = IRI(XSDNamespace + "dateTime")
+)
+
+// Term is an RDF term: an IRI, a blank node or a literal.
+// All implementations are comparable so terms and triples can be used as map keys.
+type Term interface {
+	// String returns the term in N-Triples syntax.
+	String() string
+	isTerm()
+}
+
+// IRI is an absolute IRI reference.
+type IRI string
+
+func (i IRI) String() string { return "<" + escapeIRI(string(i)) + ">" }
+func (IRI) isTerm()          {}
+
+// BlankNode is a blank node identified by a label local to its graph.
+type BlankNode string
+
+func (b BlankNode) String() string { return "_:" + string(b) }
+func (BlankNode) isTerm()          {}
+
+// Literal is an RDF literal. Plain literals have an empty Datatype and Language.
+type Literal struct {
+	Value    string
+	Language string
+	Datatype IRI
+}
+
+// NewLiteral returns a plain string literal.
+func NewLiteral(value string) Literal {
+	return Literal{Value: value}
+}
+
+// NewTypedLiteral returns a literal with the given datatype.
+func NewTypedLiteral(value string, datatype IRI) Literal {
+	if datatype == XSDString {
+		datatype = ""
+	}
+	return Literal{Value: value, Datatype: datatype}
+}
+
+// NewLangLiteral returns a language-tagged literal.
+func NewLangLiteral(value, language string) Literal {
+	return Literal{Value: value, Language: strings.ToLower(language)}
+}
+
+func (l Literal) String() string {
+	s := `"` + escapeString(l.Value) + `"`
+	switch {
+	case l.Language != "":
+		s += "@" + l.Language
+	case l.Datatype != "":
+		s += "^^" + l.Datatype.String()
+	}
+	return s
+}
+func (Literal) isTerm() {}
+
+// Triple is a single RDF statement.
+type Triple struct {
+	Subject   Term
+	Predicate IRI
+	Object    Term
+}
+
+// String returns the triple as an N-Triples line without the trailing newline.
+func (t Triple) String() string {
+	return fmt.Sprintf("%s %s %s .", t.Subject, t.Predicate, t.Object)
+}
+
+// Value returns the lexical value of a term: the IRI, the blank node label or the literal value.
+func Value(t Term) string {
+	switch v := t.(type) {
+	case IRI:
+		return string(v)
+	case BlankNode:
+		return string(v)
+	case Literal:
+		return v.Value
+	}
+	return ""
+}
+
+// LocalName returns the part of an IRI after the last '#', '/' or ':'.
+func LocalName(i IRI) string {
+	s := string(i)
+	if idx := strings.LastIndexAny(s, "#/:"); idx >= 0 && idx < len(s)-1 {
+		return s[idx+1:]
+	}
+	return s
+}
+
+func escapeString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+func escapeIRI(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r <= 0x20, strings.ContainsRune("<>\"{}|^`\\", r):
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
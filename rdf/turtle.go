@@ -0,0 +1,766 @@
+package rdf
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseTurtle parses a Turtle document into a new graph. Relative IRIs are resolved against base, which may
+// be empty. Since N-Triples is a subset of Turtle, N-Triples documents are accepted as well.
+func ParseTurtle(document, base string) (*Graph, error) {
+	g := NewGraph()
+	if err := ParseTurtleInto(g, document, base); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// ParseTurtleInto parses a Turtle document and adds its triples and prefixes to g. Blank node labels of
+// the document are relabelled so they do not collide with blank nodes already in g.
+func ParseTurtleInto(g *Graph, document, base string) error {
+	p := &turtleParser{
+		input:    document,
+		line:     1,
+		base:     base,
+		prefixes: map[string]string{},
+		graph:    g,
+		blanks:   map[string]BlankNode{},
+	}
+	if err := p.parseDocument(); err != nil {
+		return err
+	}
+	g.mergePrefixes(p.prefixes)
+	return nil
+}
+
+// ReadTurtle parses a Turtle document from a reader.
+func ReadTurtle(r io.Reader, base string) (*Graph, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTurtle(string(content), base)
+}
+
+// ParseTurtleFile parses a Turtle file. The file URL is used as base IRI.
+func ParseTurtleFile(path string) (*Graph, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := ParseTurtle(string(content), "file://"+path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// ParseNTriples parses an N-Triples document.
+func ParseNTriples(document string) (*Graph, error) {
+	return ParseTurtle(document, "")
+}
+
+// SyntaxError describes a Turtle syntax error and where it occurred.
+type SyntaxError struct {
+	Line    int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("turtle syntax error on line %d: %s", e.Line, e.Message)
+}
+
+type turtleParser struct {
+	input    string
+	pos      int
+	line     int
+	base     string
+	prefixes map[string]string
+	graph    *Graph
+	blanks   map[string]BlankNode
+}
+
+func (p *turtleParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: p.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *turtleParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *turtleParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(p.input[p.pos:])
+	return r
+}
+
+func (p *turtleParser) peekAt(offset int) byte {
+	if p.pos+offset >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos+offset]
+}
+
+func (p *turtleParser) next() rune {
+	r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+	p.pos += size
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func (p *turtleParser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.input[p.pos:], s)
+}
+
+// hasKeyword reports whether the input continues with the case-insensitive keyword followed by a delimiter.
+func (p *turtleParser) hasKeyword(keyword string) bool {
+	end := p.pos + len(keyword)
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], keyword) {
+		return false
+	}
+	if end == len(p.input) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(p.input[end:])
+	return unicode.IsSpace(r) || r == '<' || r == '#'
+}
+
+// hasBareWord reports whether the input continues with the word not followed by a name character, so
+// that for example "a" is not confused with the prefixed name "a:b" or "ab:c".
+func (p *turtleParser) hasBareWord(word string) bool {
+	if !p.hasPrefix(word) {
+		return false
+	}
+	rest := p.input[p.pos+len(word):]
+	r, size := utf8.DecodeRuneInString(rest)
+	if r == '.' {
+		// "true." ends a statement while "a.b:c" continues a name.
+		r, _ = utf8.DecodeRuneInString(rest[size:])
+		return !isNameChar(r)
+	}
+	return !isNameChar(r) && r != ':'
+}
+
+func (p *turtleParser) expect(r rune) error {
+	p.skipWhitespace()
+	if p.eof() {
+		return p.errorf("expected %q but reached end of input", r)
+	}
+	if got := p.peek(); got != r {
+		return p.errorf("expected %q but found %q", r, got)
+	}
+	p.next()
+	return nil
+}
+
+func (p *turtleParser) skipWhitespace() {
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case r == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		case unicode.IsSpace(r):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *turtleParser) parseDocument() error {
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			return nil
+		}
+		if err := p.parseStatement(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *turtleParser) parseStatement() error {
+	switch {
+	case p.hasPrefix("@prefix"):
+		p.pos += len("@prefix")
+		if err := p.parsePrefixDirective(); err != nil {
+			return err
+		}
+		return p.expect('.')
+	case p.hasPrefix("@base"):
+		p.pos += len("@base")
+		if err := p.parseBaseDirective(); err != nil {
+			return err
+		}
+		return p.expect('.')
+	case p.hasKeyword("PREFIX"):
+		p.pos += len("PREFIX")
+		return p.parsePrefixDirective()
+	case p.hasKeyword("BASE"):
+		p.pos += len("BASE")
+		return p.parseBaseDirective()
+	}
+
+	if err := p.parseTriples(); err != nil {
+		return err
+	}
+	return p.expect('.')
+}
+
+func (p *turtleParser) parsePrefixDirective() error {
+	p.skipWhitespace()
+	start := p.pos
+	for !p.eof() && p.peek() != ':' {
+		r := p.peek()
+		if unicode.IsSpace(r) {
+			return p.errorf("invalid prefix name %q", p.input[start:p.pos])
+		}
+		p.next()
+	}
+	prefix := p.input[start:p.pos]
+	if err := p.expect(':'); err != nil {
+		return err
+	}
+	p.skipWhitespace()
+	iri, err := p.parseIRIRef()
+	if err != nil {
+		return err
+	}
+	p.prefixes[prefix] = string(iri)
+	return nil
+}
+
+func (p *turtleParser) parseBaseDirective() error {
+	p.skipWhitespace()
+	iri, err := p.parseIRIRef()
+	if err != nil {
+		return err
+	}
+	p.base = string(iri)
+	return nil
+}
+
+func (p *turtleParser) parseTriples() error {
+	p.skipWhitespace()
+	if p.peek() == '[' {
+		subject, propertyList, err := p.parseBlankNodeOrPropertyList()
+		if err != nil {
+			return err
+		}
+		p.skipWhitespace()
+		// A blank node property list may stand on its own as a statement.
+		if propertyList && p.peek() == '.' {
+			return nil
+		}
+		return p.parsePredicateObjectList(subject)
+	}
+
+	subject, err := p.parseSubject()
+	if err != nil {
+		return err
+	}
+	return p.parsePredicateObjectList(subject)
+}
+
+func (p *turtleParser) parseSubject() (Term, error) {
+	p.skipWhitespace()
+	switch p.peek() {
+	case '<':
+		return p.parseIRIRef()
+	case '(':
+		return p.parseCollection()
+	case '_':
+		return p.parseBlankNodeLabel()
+	}
+	return p.parsePrefixedName()
+}
+
+func (p *turtleParser) parsePredicateObjectList(subject Term) error {
+	for {
+		p.skipWhitespace()
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return err
+		}
+		if err := p.parseObjectList(subject, predicate); err != nil {
+			return err
+		}
+
+		p.skipWhitespace()
+		if p.peek() != ';' {
+			return nil
+		}
+		for p.peek() == ';' {
+			p.next()
+			p.skipWhitespace()
+		}
+		// A trailing ';' may be followed directly by the end of the statement or property list.
+		if r := p.peek(); r == '.' || r == ']' || p.eof() {
+			return nil
+		}
+	}
+}
+
+func (p *turtleParser) parsePredicate() (IRI, error) {
+	if p.hasBareWord("a") {
+		p.next()
+		return RDFType, nil
+	}
+	if p.peek() == '<' {
+		return p.parseIRIRef()
+	}
+	term, err := p.parsePrefixedName()
+	if err != nil {
+		return "", err
+	}
+	iri, ok := term.(IRI)
+	if !ok {
+		return "", p.errorf("predicate must be an IRI")
+	}
+	return iri, nil
+}
+
+func (p *turtleParser) parseObjectList(subject Term, predicate IRI) error {
+	for {
+		object, err := p.parseObject()
+		if err != nil {
+			return err
+		}
+		p.graph.AddTriple(subject, predicate, object)
+
+		p.skipWhitespace()
+		if p.peek() != ',' {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *turtleParser) parseObject() (Term, error) {
+	p.skipWhitespace()
+	if p.eof() {
+		return nil, p.errorf("expected object but reached end of input")
+	}
+	r := p.peek()
+	switch {
+	case r == '<':
+		return p.parseIRIRef()
+	case r == '_':
+		return p.parseBlankNodeLabel()
+	case r == '(':
+		return p.parseCollection()
+	case r == '[':
+		node, _, err := p.parseBlankNodeOrPropertyList()
+		return node, err
+	case r == '"' || r == '\'':
+		return p.parseLiteral()
+	case r == '+' || r == '-' || r == '.' || (r >= '0' && r <= '9'):
+		return p.parseNumber()
+	case p.hasBareWord("true"):
+		p.pos += 4
+		return NewTypedLiteral("true", XSDBoolean), nil
+	case p.hasBareWord("false"):
+		p.pos += 5
+		return NewTypedLiteral("false", XSDBoolean), nil
+	}
+	return p.parsePrefixedName()
+}
+
+// parseBlankNodeOrPropertyList parses either an anonymous blank node "[]" or a blank node property list
+// "[ p o ]". The boolean result reports whether a property list was parsed.
+func (p *turtleParser) parseBlankNodeOrPropertyList() (Term, bool, error) {
+	save, line := p.pos, p.line
+	p.next()
+	p.skipWhitespace()
+	if p.peek() == ']' {
+		p.next()
+		return p.graph.NewBlankNode(), false, nil
+	}
+	p.pos, p.line = save, line
+	node, err := p.parseBlankNodePropertyList()
+	return node, true, err
+}
+
+func (p *turtleParser) parseBlankNodePropertyList() (Term, error) {
+	if err := p.expect('['); err != nil {
+		return nil, err
+	}
+	node := p.graph.NewBlankNode()
+	if err := p.parsePredicateObjectList(node); err != nil {
+		return nil, err
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *turtleParser) parseCollection() (Term, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var items []Term
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			return nil, p.errorf("unterminated collection")
+		}
+		if p.peek() == ')' {
+			p.next()
+			break
+		}
+		item, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return RDFNil, nil
+	}
+	head := p.graph.NewBlankNode()
+	current := head
+	for i, item := range items {
+		p.graph.AddTriple(current, RDFFirst, item)
+		if i == len(items)-1 {
+			p.graph.AddTriple(current, RDFRest, RDFNil)
+		} else {
+			next := p.graph.NewBlankNode()
+			p.graph.AddTriple(current, RDFRest, next)
+			current = next
+		}
+	}
+	return head, nil
+}
+
+func (p *turtleParser) parseIRIRef() (IRI, error) {
+	if err := p.expect('<'); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated IRI")
+		}
+		r := p.next()
+		switch {
+		case r == '>':
+			return p.resolve(b.String()), nil
+		case r == '\\':
+			decoded, err := p.parseUnicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(decoded)
+		case r <= 0x20 || strings.ContainsRune("<\"{}|^`", r):
+			return "", p.errorf("invalid character %q in IRI", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *turtleParser) resolve(ref string) IRI {
+	if p.base == "" {
+		return IRI(ref)
+	}
+	if u, err := url.Parse(ref); err == nil && u.IsAbs() {
+		return IRI(ref)
+	}
+	base, err := url.Parse(p.base)
+	if err != nil {
+		return IRI(ref)
+	}
+	relative, err := url.Parse(ref)
+	if err != nil {
+		return IRI(p.base + ref)
+	}
+	return IRI(base.ResolveReference(relative).String())
+}
+
+func (p *turtleParser) parseUnicodeEscape() (rune, error) {
+	if p.eof() {
+		return 0, p.errorf("incomplete escape sequence")
+	}
+	var digits int
+	switch p.next() {
+	case 'u':
+		digits = 4
+	case 'U':
+		digits = 8
+	default:
+		return 0, p.errorf("invalid escape sequence in IRI")
+	}
+	if p.pos+digits > len(p.input) {
+		return 0, p.errorf("incomplete unicode escape")
+	}
+	value, err := strconv.ParseUint(p.input[p.pos:p.pos+digits], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape %q", p.input[p.pos:p.pos+digits])
+	}
+	p.pos += digits
+	return rune(value), nil
+}
+
+func (p *turtleParser) parseBlankNodeLabel() (Term, error) {
+	if !p.hasPrefix("_:") {
+		return nil, p.errorf("expected blank node label")
+	}
+	p.pos += 2
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if isNameChar(r) || r == '.' {
+			p.next()
+			continue
+		}
+		break
+	}
+	// A label cannot end with '.', which then terminates the statement instead.
+	for p.pos > start && p.input[p.pos-1] == '.' {
+		p.pos--
+	}
+	label := p.input[start:p.pos]
+	if label == "" {
+		return nil, p.errorf("empty blank node label")
+	}
+	node, ok := p.blanks[label]
+	if !ok {
+		node = p.graph.NewBlankNode()
+		p.blanks[label] = node
+	}
+	return node, nil
+}
+
+func (p *turtleParser) parsePrefixedName() (Term, error) {
+	start := p.pos
+	for !p.eof() && p.peek() != ':' {
+		r := p.peek()
+		if !isNameChar(r) && r != '.' {
+			break
+		}
+		p.next()
+	}
+	if p.eof() || p.peek() != ':' {
+		if p.pos == start {
+			if p.eof() {
+				return nil, p.errorf("unexpected end of input")
+			}
+			return nil, p.errorf("unexpected character %q", p.peek())
+		}
+		return nil, p.errorf("unknown term %q", p.input[start:p.pos])
+	}
+	prefix := p.input[start:p.pos]
+	p.next() // ':'
+
+	namespace, ok := p.prefixes[prefix]
+	if !ok {
+		return nil, p.errorf("undefined prefix %q", prefix)
+	}
+
+	var local strings.Builder
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case isNameChar(r) || r == ':':
+			local.WriteRune(p.next())
+		case r == '.':
+			// Dots are allowed inside local names but not at the end.
+			next, _ := utf8.DecodeRuneInString(p.input[p.pos+1:])
+			if p.pos+1 < len(p.input) && (isNameChar(next) || next == ':' || next == '%' || next == '\\' || next == '.') {
+				local.WriteRune(p.next())
+			} else {
+				return IRI(namespace + local.String()), nil
+			}
+		case r == '%':
+			if p.pos+2 >= len(p.input) {
+				return nil, p.errorf("incomplete percent encoding")
+			}
+			local.WriteString(p.input[p.pos : p.pos+3])
+			p.pos += 3
+		case r == '\\':
+			p.next()
+			if p.eof() {
+				return nil, p.errorf("incomplete escape in local name")
+			}
+			escaped := p.next()
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", escaped) {
+				return nil, p.errorf("invalid escape %q in local name", escaped)
+			}
+			local.WriteRune(escaped)
+		default:
+			return IRI(namespace + local.String()), nil
+		}
+	}
+	return IRI(namespace + local.String()), nil
+}
+
+func (p *turtleParser) parseLiteral() (Term, error) {
+	value, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() == '@' {
+		p.next()
+		start := p.pos
+		for !p.eof() {
+			r := p.peek()
+			if r == '-' || (r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
+				p.next()
+				continue
+			}
+			break
+		}
+		if p.pos == start {
+			return nil, p.errorf("empty language tag")
+		}
+		return NewLangLiteral(value, p.input[start:p.pos]), nil
+	}
+
+	if p.hasPrefix("^^") {
+		p.pos += 2
+		var datatype IRI
+		if p.peek() == '<' {
+			datatype, err = p.parseIRIRef()
+		} else {
+			var term Term
+			term, err = p.parsePrefixedName()
+			if err == nil {
+				datatype = term.(IRI)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewTypedLiteral(value, datatype), nil
+	}
+
+	return NewLiteral(value), nil
+}
+
+func (p *turtleParser) parseString() (string, error) {
+	quote := p.input[p.pos]
+	long := p.hasPrefix(strings.Repeat(string(quote), 3))
+	if long {
+		p.pos += 3
+	} else {
+		p.pos++
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string literal")
+		}
+		if long {
+			if p.hasPrefix(strings.Repeat(string(quote), 3)) {
+				// Quotes directly before the closing delimiter belong to the string.
+				for p.pos+3 < len(p.input) && p.input[p.pos+3] == quote {
+					b.WriteByte(quote)
+					p.pos++
+				}
+				p.pos += 3
+				return b.String(), nil
+			}
+		} else if p.input[p.pos] == quote {
+			p.pos++
+			return b.String(), nil
+		} else if c := p.input[p.pos]; c == '\n' || c == '\r' {
+			// Checked before the line break is read, so the error is on the line of the string.
+			return "", p.errorf("line break in single-line string literal")
+		}
+
+		r := p.next()
+		switch {
+		case r == '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated escape sequence")
+			}
+			switch e := p.peek(); e {
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 'f':
+				b.WriteByte('\f')
+			case '"', '\'', '\\':
+				b.WriteRune(e)
+			case 'u', 'U':
+				decoded, err := p.parseUnicodeEscape()
+				if err != nil {
+					return "", err
+				}
+				b.WriteRune(decoded)
+				continue
+			default:
+				return "", p.errorf("invalid escape sequence \\%c", e)
+			}
+			p.next()
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *turtleParser) parseNumber() (Term, error) {
+	start := p.pos
+	if r := p.peek(); r == '+' || r == '-' {
+		p.next()
+	}
+	digits := func() int {
+		n := 0
+		for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+			p.next()
+			n++
+		}
+		return n
+	}
+
+	datatype := XSDInteger
+	intDigits := digits()
+	fracDigits := 0
+	if p.peek() == '.' {
+		if next := p.peekAt(1); next >= '0' && next <= '9' {
+			p.next()
+			fracDigits = digits()
+			datatype = XSDDecimal
+		}
+	}
+	if r := p.peek(); r == 'e' || r == 'E' {
+		p.next()
+		if r := p.peek(); r == '+' || r == '-' {
+			p.next()
+		}
+		if digits() == 0 {
+			return nil, p.errorf("invalid exponent in number %q", p.input[start:p.pos])
+		}
+		datatype = XSDDouble
+	}
+	if intDigits == 0 && fracDigits == 0 {
+		return nil, p.errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return NewTypedLiteral(p.input[start:p.pos], datatype), nil
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r) || r == 0xB7 ||
+		(r >= 0x0300 && r <= 0x036F) || (r >= 0x203F && r <= 0x2040)
+}
@@ -0,0 +1,293 @@
+package rdf
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const ex = "http://example.com/"
+
+func TestParseTurtle(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     []Triple // Every triple the document holds; blank nodes are tested separately.
+	}{
+		{
+			name:     "predicate and object lists",
+			document: `@prefix ex: <http://example.com/> . ex:a ex:p ex:b, ex:c ; ex:q "x" ;.`,
+			want: []Triple{
+				{IRI(ex + "a"), IRI(ex + "p"), IRI(ex + "b")},
+				{IRI(ex + "a"), IRI(ex + "p"), IRI(ex + "c")},
+				{IRI(ex + "a"), IRI(ex + "q"), NewLiteral("x")},
+			},
+		},
+		{
+			name:     "dotted local names",
+			document: "@prefix ex: <http://example.com/> .\nex:com.example.Orders ex:p ex:v1.2 .",
+			want:     []Triple{{IRI(ex + "com.example.Orders"), IRI(ex + "p"), IRI(ex + "v1.2")}},
+		},
+		{
+			name:     "local name ending before a dot",
+			document: "@prefix ex: <http://example.com/> .\nex:a ex:p ex:b.",
+			want:     []Triple{{IRI(ex + "a"), IRI(ex + "p"), IRI(ex + "b")}},
+		},
+		{
+			name:     "escaped local names",
+			document: `@prefix ex: <http://example.com/> . ex:a\/b ex:p ex:c\#d .`,
+			want:     []Triple{{IRI(ex + "a/b"), IRI(ex + "p"), IRI(ex + "c#d")}},
+		},
+		{
+			name:     "sparql style directives and base",
+			document: "BASE <http://example.com/>\nPREFIX ex: <http://example.com/ns#>\n<a> ex:p <../b> .",
+			want:     []Triple{{IRI(ex + "a"), IRI(ex + "ns#p"), IRI("http://example.com/b")}},
+		},
+		{
+			name:     "a as rdf:type",
+			document: `<http://example.com/a> a <http://example.com/T> .`,
+			want:     []Triple{{IRI(ex + "a"), RDFType, IRI(ex + "T")}},
+		},
+		{
+			name:     "language tags",
+			document: `<http://example.com/a> <http://example.com/p> "chat"@fr, "colour"@en-GB .`,
+			want: []Triple{
+				{IRI(ex + "a"), IRI(ex + "p"), NewLangLiteral("chat", "fr")},
+				{IRI(ex + "a"), IRI(ex + "p"), NewLangLiteral("colour", "en-gb")},
+			},
+		},
+		{
+			name:     "typed literals and numbers",
+			document: `@prefix xsd: <http://www.w3.org/2001/XMLSchema#> . <http://example.com/a> <http://example.com/p> "5"^^xsd:int, 42, -1.5, 1e3, true .`,
+			want: []Triple{
+				{IRI(ex + "a"), IRI(ex + "p"), NewTypedLiteral("5", XSDNamespace+"int")},
+				{IRI(ex + "a"), IRI(ex + "p"), NewTypedLiteral("42", XSDInteger)},
+				{IRI(ex + "a"), IRI(ex + "p"), NewTypedLiteral("-1.5", XSDDecimal)},
+				{IRI(ex + "a"), IRI(ex + "p"), NewTypedLiteral("1e3", XSDDouble)},
+				{IRI(ex + "a"), IRI(ex + "p"), NewTypedLiteral("true", XSDBoolean)},
+			},
+		},
+		{
+			name:     "string escapes",
+			document: `<http://example.com/a> <http://example.com/p> "tab\tquote\" newline\n \u00e9 \U0001F600 back\\slash" .`,
+			want:     []Triple{{IRI(ex + "a"), IRI(ex + "p"), NewLiteral("tab\tquote\" newline\n é 😀 back\\slash")}},
+		},
+		{
+			name:     "long strings",
+			document: "<http://example.com/a> <http://example.com/p> \"\"\"first \"line\"\nsecond ''line''\"\"\", '''single \"\"\"quoted\"\"\" '''.",
+			want: []Triple{
+				{IRI(ex + "a"), IRI(ex + "p"), NewLiteral("first \"line\"\nsecond ''line''")},
+				{IRI(ex + "a"), IRI(ex + "p"), NewLiteral("single \"\"\"quoted\"\"\" ")},
+			},
+		},
+		{
+			name:     "comments",
+			document: "# a comment\n<http://example.com/a> <http://example.com/p> \"# not a comment\" . # trailing\n",
+			want:     []Triple{{IRI(ex + "a"), IRI(ex + "p"), NewLiteral("# not a comment")}},
+		},
+		{
+			name:     "empty collection",
+			document: `<http://example.com/a> <http://example.com/p> () .`,
+			want:     []Triple{{IRI(ex + "a"), IRI(ex + "p"), RDFNil}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := ParseTurtle(tt.document, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := g.Triples()
+			if len(got) != len(tt.want) {
+				t.Fatalf("parsed %d triples, want %d:\n%s", len(got), len(tt.want), ToNTriples(g))
+			}
+			for _, triple := range tt.want {
+				if !g.Contains(triple) {
+					t.Errorf("missing %s %s %s in:\n%s", triple.Subject, triple.Predicate, triple.Object, ToNTriples(g))
+				}
+			}
+		})
+	}
+}
+
+func TestParseTurtleBlankNodes(t *testing.T) {
+	g, err := ParseTurtle(`
+		@prefix ex: <http://example.com/> .
+		[] ex:name "anonymous" .
+		ex:a ex:knows [ ex:name "b" ; ex:knows [ ex:name "c" ] ] .
+		ex:a ex:list ( ex:x "y" ( 1 ) ) .
+		_:n1 ex:p _:n1 .
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The [] subject is a fresh blank node.
+	subjects := g.Subjects(IRI(ex+"name"), NewLiteral("anonymous"))
+	if len(subjects) != 1 {
+		t.Fatalf("subjects of the [] statement = %v", subjects)
+	}
+	if _, ok := subjects[0].(BlankNode); !ok {
+		t.Fatalf("the [] subject is %s, want a blank node", subjects[0])
+	}
+
+	// Nested property lists.
+	b := g.Object(IRI(ex+"a"), IRI(ex+"knows"))
+	c := g.Object(b, IRI(ex+"knows"))
+	if g.Object(b, IRI(ex+"name")) != NewLiteral("b") || g.Object(c, IRI(ex+"name")) != NewLiteral("c") {
+		t.Fatalf("nested property lists parsed as:\n%s", ToNTriples(g))
+	}
+
+	// Collections are rdf:first/rdf:rest lists, and may nest.
+	var items []Term
+	for node := g.Object(IRI(ex+"a"), IRI(ex+"list")); node != RDFNil; node = g.Object(node, RDFRest) {
+		if node == nil || len(items) > 3 {
+			t.Fatalf("the collection is not a proper list:\n%s", ToNTriples(g))
+		}
+		items = append(items, g.Object(node, RDFFirst))
+	}
+	if len(items) != 3 || items[0] != IRI(ex+"x") || items[1] != NewLiteral("y") {
+		t.Fatalf("collection items = %v", items)
+	}
+	if g.Object(items[2], RDFFirst) != NewTypedLiteral("1", XSDInteger) || g.Object(items[2], RDFRest) != RDFNil {
+		t.Fatalf("nested collection parsed as:\n%s", ToNTriples(g))
+	}
+
+	// A label names the same node within the document.
+	if len(g.Match(nil, IRI(ex+"p"), nil)) != 1 {
+		t.Fatalf("_:n1 ex:p _:n1 parsed as:\n%s", ToNTriples(g))
+	}
+	self := g.Match(nil, IRI(ex+"p"), nil)[0]
+	if self.Subject != self.Object {
+		t.Fatalf("_:n1 is two nodes: %s and %s", self.Subject, self.Object)
+	}
+}
+
+func TestTurtleRoundTrip(t *testing.T) {
+	documents := map[string]string{
+		"literals": `
+			@prefix ex: <http://example.com/> .
+			@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+			ex:a ex:text "line one\nline \"two\"\ttabbed \\ back" ;
+				ex:long """has ""quotes"" and
+newlines""" ;
+				ex:lang "bonjour"@fr ;
+				ex:typed "2024-05-31"^^xsd:date ;
+				ex:number 42, 4.2, 4.2e1, false ;
+				ex:unicode "é 😀" .`,
+		"names": `
+			@prefix ex: <http://example.com/> .
+			ex:com.example.Orders ex:calls ex:com.example.Billing ;
+				ex:path <http://example.com/a/b#c> ;
+				ex:odd <http://example.com/has%20space> .`,
+		"blank nodes and collections": `
+			@prefix ex: <http://example.com/> .
+			[] ex:name "anonymous" .
+			ex:a ex:knows [ ex:name "b" ; ex:knows [ ex:name "c" ] ] ;
+				ex:list ( ex:x "y" ( 1 2 ) ) ;
+				ex:empty () .
+			_:shared ex:p ex:a .
+			ex:b ex:q _:shared .
+			ex:c ex:r _:shared .`,
+	}
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			g, err := ParseTurtle(document, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			turtle := ToTurtle(g)
+			again, err := ParseTurtle(turtle, "")
+			if err != nil {
+				t.Fatalf("the written Turtle does not parse: %v\n%s", err, turtle)
+			}
+			if again.Len() != g.Len() {
+				t.Fatalf("round trip has %d triples, want %d:\n%s", again.Len(), g.Len(), turtle)
+			}
+			for _, triple := range g.Triples() {
+				if isBlank(triple.Subject) || isBlank(triple.Object) {
+					continue
+				}
+				if !again.Contains(triple) {
+					t.Errorf("round trip lost %s %s %s:\n%s", triple.Subject, triple.Predicate, triple.Object, turtle)
+				}
+			}
+			if got, want := shape(again), shape(g); got != want {
+				t.Errorf("round trip changed the graph:\n%s\nwant:\n%s", got, want)
+			}
+
+			nt, err := ParseNTriples(ToNTriples(g))
+			if err != nil || nt.Len() != g.Len() {
+				t.Errorf("N-Triples round trip: %d triples, %v, want %d", nt.Len(), err, g.Len())
+			}
+		})
+	}
+}
+
+// shape lists the triples of a graph with every blank node written as _:, which tells graphs apart well
+// enough for the tests without comparing them for isomorphism.
+func shape(g *Graph) string {
+	var lines []string
+	for _, triple := range g.Triples() {
+		line := make([]string, 3)
+		for i, term := range []Term{triple.Subject, triple.Predicate, triple.Object} {
+			line[i] = term.String()
+			if isBlank(term) {
+				line[i] = "_:"
+			}
+		}
+		lines = append(lines, strings.Join(line, " "))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func isBlank(term Term) bool {
+	_, ok := term.(BlankNode)
+	return ok
+}
+
+func TestParseTurtleErrors(t *testing.T) {
+	tests := map[string]struct {
+		document string
+		line     int
+	}{
+		"missing dot":         {"<http://example.com/a> <http://example.com/p> <http://example.com/b>", 1},
+		"missing object":      {"<http://example.com/a> <http://example.com/p> .", 1},
+		"undeclared prefix":   {"<http://example.com/a> ex:p <http://example.com/b> .", 1},
+		"unterminated string": {"<http://example.com/a> <http://example.com/p> \"open .\n", 1},
+		"unterminated long":   {"<http://example.com/a> <http://example.com/p> \"\"\"open\n\n.", 3},
+		"bad escape":          {`<http://example.com/a> <http://example.com/p> "\q" .`, 1},
+		"bad unicode escape":  {`<http://example.com/a> <http://example.com/p> "\u00zz" .`, 1},
+		"space in IRI":        {"<http://example.com/a b> <http://example.com/p> <http://example.com/b> .", 1},
+		"unclosed list":       {"\n<http://example.com/a> <http://example.com/p> ( 1 2 .", 2},
+		"unclosed brackets":   {"<http://example.com/a> <http://example.com/p> [ <http://example.com/q> 1 .", 1},
+		"literal subject":     {`"a" <http://example.com/p> <http://example.com/b> .`, 1},
+		"literal predicate":   {`<http://example.com/a> "p" <http://example.com/b> .`, 1},
+		"missing language":    {`<http://example.com/a> <http://example.com/p> "x"@ .`, 1},
+		"error on later line": {"@prefix ex: <http://example.com/> .\nex:a ex:p ex:b .\nex:a ex:p ;", 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g, err := ParseTurtle(tt.document, "")
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("error = %v, want a SyntaxError; parsed:\n%s", err, ToNTriples(orEmpty(g)))
+			}
+			if syntaxErr.Line != tt.line {
+				t.Errorf("error on line %d, want %d: %v", syntaxErr.Line, tt.line, err)
+			}
+			if !strings.Contains(err.Error(), "line") {
+				t.Errorf("error %q does not say where", err)
+			}
+		})
+	}
+}
+
+func orEmpty(g *Graph) *Graph {
+	if g == nil {
+		return NewGraph()
+	}
+	return g
+}
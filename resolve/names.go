@@ -0,0 +1,131 @@
+package resolve
+
+import (
+	"strings"
+	"unicode"
+)
+
+// genericWords say what kind of thing a name refers to rather than which one, so they are ignored when
+// comparing names: "users DB", "mongo/users" and "UserStore" all reduce to "user".
+var genericWords = map[string]bool{
+	"api": true, "client": true, "collection": true, "data": true, "database": true, "db": true,
+	"mongo": true, "mongodb": true, "mysql": true, "pg": true, "postgres": true, "postgresql": true,
+	"redis": true, "repo": true, "repository": true, "server": true, "service": true, "sql": true,
+	"storage": true, "store": true, "svc": true, "table": true, "the": true,
+}
+
+// nameSimilarity scores two names between 0 and 1 by comparing their significant words, both as sets and
+// by edit distance to tolerate typos and abbreviations.
+func nameSimilarity(a, b string) float64 {
+	ta, tb := nameTokens(a), nameTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	set := map[string]bool{}
+	for _, t := range ta {
+		set[t] = true
+	}
+	common := 0
+	union := len(set)
+	for _, t := range tb {
+		if set[t] {
+			common++
+			delete(set, t)
+		} else {
+			union++
+		}
+	}
+	jaccard := float64(common) / float64(union)
+
+	ja, jb := strings.Join(ta, ""), strings.Join(tb, "")
+	longest := len(ja)
+	if len(jb) > longest {
+		longest = len(jb)
+	}
+	edit := 1 - float64(levenshtein(ja, jb))/float64(longest)
+
+	if jaccard > edit {
+		return jaccard
+	}
+	return edit
+}
+
+// nameTokens splits a name into lower-case singular words, dropping generic words unless nothing else
+// is left.
+func nameTokens(name string) []string {
+	var words, significant []string
+	for _, word := range splitWords(name) {
+		word = singular(strings.ToLower(word))
+		words = append(words, word)
+		if !genericWords[word] {
+			significant = append(significant, word)
+		}
+	}
+	if len(significant) == 0 {
+		return words
+	}
+	return significant
+}
+
+// splitWords splits on non-alphanumeric characters and camel case boundaries, keeping acronyms such as
+// "HTTP" in "HTTPServer" together.
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func singular(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,351 @@
+// Package resolve finds nodes of a merged GraphMind graph that describe the same entity under different
+// URIs, for example "users DB", "mongo/users" and "UserStore", and merges them.
+package resolve
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// OWLSameAs links a merged node to the URIs it was known by before the merge.
+const OWLSameAs = rdf.IRI(rdf.OWLNamespace + "sameAs")
+
+// Options controls which candidate pairs are merged automatically and which are only reported.
+type Options struct {
+	AutoMergeThreshold float64 // Pairs scoring at least this much are merged.
+	ReviewThreshold    float64 // Pairs scoring at least this much, but below AutoMergeThreshold, are reported for review.
+}
+
+// DefaultOptions merges pairs whose names match and whose types agree, and reports pairs whose names
+// match but whose types are unknown.
+func DefaultOptions() Options {
+	return Options{AutoMergeThreshold: 0.9, ReviewThreshold: 0.6}
+}
+
+// Candidate is a pair of nodes that may describe the same entity.
+type Candidate struct {
+	Nodes   [2]string `json:"nodes"` // For applied merges the first node is the one that was kept.
+	Names   [2]string `json:"names"`
+	Class   string    `json:"class"`
+	Score   float64   `json:"score"`
+	Reasons []string  `json:"reasons"`
+}
+
+// Result lists the merges applied to the graph and the candidates left for review.
+type Result struct {
+	Merged []Candidate `json:"merged"`
+	Review []Candidate `json:"review"`
+}
+
+// resolvedClasses are compared in order. Collections come after databases so collections of merged
+// databases share a parent.
+var resolvedClasses = []rdf.IRI{
+	ontology.Database,
+	ontology.Collection,
+	ontology.CloudResource,
+	ontology.Topic,
+	ontology.ConfigKey,
+	ontology.Service,
+}
+
+// Resolve compares nodes of the same class pairwise. Pairs scoring above opts.AutoMergeThreshold are
+// merged into one node, which keeps the other URIs through owl:sameAs. Lower scoring pairs above
+// opts.ReviewThreshold are returned for review and the graph is left unchanged for them.
+func Resolve(g *rdf.Graph, opts Options) Result {
+	g.BindPrefix("rdfs", rdf.RDFSNamespace)
+	g.BindPrefix("owl", rdf.OWLNamespace)
+
+	var result Result
+	for _, class := range resolvedClasses {
+		entities := collectEntities(g, class)
+
+		var candidates []Candidate
+		for i := range entities {
+			for j := i + 1; j < len(entities); j++ {
+				if c, ok := compare(entities[i], entities[j], class); ok && c.Score >= opts.ReviewThreshold {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+		// Group high-confidence pairs transitively, then merge each group into one node.
+		groups := newUnionFind()
+		for _, c := range candidates {
+			if c.Score >= opts.AutoMergeThreshold {
+				groups.union(c.Nodes[0], c.Nodes[1])
+			}
+		}
+		kept := mergeGroups(g, entities, groups)
+
+		for _, c := range candidates {
+			if c.Score >= opts.AutoMergeThreshold {
+				if kept[c.Nodes[1]] == c.Nodes[1] {
+					c.Nodes[0], c.Nodes[1] = c.Nodes[1], c.Nodes[0]
+					c.Names[0], c.Names[1] = c.Names[1], c.Names[0]
+				}
+				result.Merged = append(result.Merged, c)
+			} else if groups.find(c.Nodes[0]) != groups.find(c.Nodes[1]) {
+				result.Review = append(result.Review, c)
+			}
+		}
+	}
+	return result
+}
+
+// Apply repeats merges returned by Resolve on another graph, typically one of the graphs that were merged
+// into the resolved graph, so it uses the same nodes. Merges of blank nodes are skipped because blank node
+// labels are local to a graph.
+func Apply(g *rdf.Graph, merged []Candidate) {
+	groups := newUnionFind()
+	names := map[string]string{}
+	for _, c := range merged {
+		groups.union(c.Nodes[0], c.Nodes[1])
+		names[c.Nodes[0]], names[c.Nodes[1]] = c.Names[0], c.Names[1]
+	}
+
+	members := map[string][]rdf.IRI{}
+	for node := range names {
+		if strings.HasPrefix(node, "_:") {
+			continue
+		}
+		root := groups.find(node)
+		members[root] = append(members[root], rdf.IRI(node))
+	}
+	for _, group := range members {
+		sort.Slice(group, func(i, j int) bool { return preferred(group[i], group[j]) })
+		keep := group[0]
+		renamed := false
+		for _, node := range group[1:] {
+			if len(g.Match(node, "", nil)) == 0 && len(g.Match(nil, "", node)) == 0 {
+				continue
+			}
+			g.Rename(node, keep)
+			g.AddTriple(keep, OWLSameAs, node)
+			renamed = true
+		}
+		if renamed {
+			tidyMergedNode(g, keep, names[string(keep)])
+		}
+	}
+}
+
+// entity holds the features of a node used for comparison.
+type entity struct {
+	node   rdf.Term
+	name   string
+	names  []string
+	kind   string   // Lower-cased gm:databaseType or gm:resourceType, "" if unknown.
+	parent rdf.Term // The database of a collection.
+	conns  []string // Normalized connection strings.
+}
+
+func collectEntities(g *rdf.Graph, class rdf.IRI) []*entity {
+	var entities []*entity
+	for _, node := range g.Subjects(rdf.RDFType, class) {
+		e := &entity{node: node}
+		for _, name := range g.Objects(node, ontology.Name) {
+			e.names = append(e.names, rdf.Value(name))
+		}
+		for _, label := range g.Objects(node, rdf.RDFSLabel) {
+			e.names = append(e.names, rdf.Value(label))
+		}
+		if iri, ok := node.(rdf.IRI); ok {
+			e.names = append(e.names, rdf.LocalName(iri))
+		}
+		if len(e.names) > 0 {
+			e.name = e.names[0]
+		}
+
+		for _, p := range []rdf.IRI{ontology.DatabaseType, ontology.ResourceType} {
+			if kind := strings.ToLower(rdf.Value(g.Object(node, p))); kind != "" && kind != "unknown" {
+				e.kind = kind
+			}
+		}
+		if databases := g.Subjects(ontology.HasCollection, node); len(databases) == 1 {
+			e.parent = databases[0]
+		}
+		for _, conn := range g.Objects(node, ontology.ConnectionString) {
+			if normalized := normalizeConnectionString(rdf.Value(conn)); normalized != "" {
+				e.conns = append(e.conns, normalized)
+			}
+		}
+		entities = append(entities, e)
+	}
+	return entities
+}
+
+// compare scores how likely two nodes describe the same entity. It reports false if the pair can be ruled
+// out, for example because the nodes have different database types.
+func compare(a, b *entity, class rdf.IRI) (Candidate, bool) {
+	c := Candidate{
+		Nodes: [2]string{termKey(a.node), termKey(b.node)},
+		Names: [2]string{a.name, b.name},
+		Class: rdf.LocalName(class),
+	}
+
+	// Services with canonical URIs were minted from proto definitions, so different URIs are different services.
+	servicePrefix := ontology.IDNamespace + "service/"
+	if class == ontology.Service && strings.HasPrefix(c.Nodes[0], servicePrefix) && strings.HasPrefix(c.Nodes[1], servicePrefix) {
+		return c, false
+	}
+
+	nameScore := 0.0
+	for _, x := range a.names {
+		for _, y := range b.names {
+			if s := nameSimilarity(x, y); s > nameScore {
+				nameScore = s
+			}
+		}
+	}
+	if nameScore > 0 {
+		c.Reasons = append(c.Reasons, fmt.Sprintf("name similarity %.2f", nameScore))
+	}
+
+	typeScore := 0.5
+	switch {
+	case a.kind != "" && b.kind != "" && a.kind != b.kind:
+		return c, false
+	case a.kind != "" && a.kind == b.kind:
+		typeScore = 1
+		c.Reasons = append(c.Reasons, "same type "+a.kind)
+	}
+	if a.parent != nil && b.parent != nil {
+		if a.parent != b.parent {
+			return c, false
+		}
+		typeScore = 1
+		c.Reasons = append(c.Reasons, "same database")
+	}
+
+	c.Score = 0.7*nameScore + 0.3*typeScore
+	if conn := sharedValue(a.conns, b.conns); conn != "" {
+		c.Reasons = append(c.Reasons, "shared connection string "+conn)
+		if c.Score < 0.95 {
+			c.Score = 0.95
+		}
+	}
+	c.Score = float64(int(c.Score*100+0.5)) / 100
+	return c, true
+}
+
+// mergeGroups renames every node of a group to the group's preferred node and returns the node each
+// entity was merged into, keyed by the entity's original node.
+func mergeGroups(g *rdf.Graph, entities []*entity, groups *unionFind) map[string]string {
+	members := map[string][]*entity{}
+	for _, e := range entities {
+		root := groups.find(termKey(e.node))
+		members[root] = append(members[root], e)
+	}
+
+	kept := map[string]string{}
+	for _, group := range members {
+		sort.Slice(group, func(i, j int) bool { return preferred(group[i].node, group[j].node) })
+		keep := group[0]
+		for _, e := range group {
+			kept[termKey(e.node)] = termKey(keep.node)
+		}
+		for _, e := range group[1:] {
+			g.Rename(e.node, keep.node)
+			if iri, ok := e.node.(rdf.IRI); ok {
+				g.AddTriple(keep.node, OWLSameAs, iri)
+			}
+		}
+		if len(group) > 1 {
+			tidyMergedNode(g, keep.node, keep.name)
+		}
+	}
+	return kept
+}
+
+// tidyMergedNode keeps one gm:name on a merged node, turning the others into rdfs:label aliases, and one
+// type, preferring a concrete type over "unknown".
+func tidyMergedNode(g *rdf.Graph, node rdf.Term, name string) {
+	for _, t := range g.Match(node, ontology.Name, nil) {
+		if rdf.Value(t.Object) != name {
+			g.Remove(t)
+			g.AddTriple(node, rdf.RDFSLabel, t.Object)
+		}
+	}
+	for _, p := range []rdf.IRI{ontology.DatabaseType, ontology.ResourceType} {
+		values := g.Match(node, p, nil)
+		sort.SliceStable(values, func(i, j int) bool {
+			return !strings.EqualFold(rdf.Value(values[i].Object), "unknown") &&
+				strings.EqualFold(rdf.Value(values[j].Object), "unknown")
+		})
+		for _, t := range values[min(1, len(values)):] {
+			g.Remove(t)
+		}
+	}
+}
+
+// preferred orders canonical URIs first, then other IRIs, then blank nodes.
+func preferred(a, b rdf.Term) bool {
+	canonical := func(t rdf.Term) bool {
+		iri, ok := t.(rdf.IRI)
+		return ok && strings.HasPrefix(string(iri), ontology.IDNamespace)
+	}
+	if canonical(a) != canonical(b) {
+		return canonical(a)
+	}
+	return rdf.CompareTerms(a, b) < 0
+}
+
+// normalizeConnectionString reduces a connection string to scheme, host, port and path so the same
+// endpoint written with different credentials or options compares equal.
+func normalizeConnectionString(s string) string {
+	s = strings.TrimSpace(s)
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(s)
+	}
+	scheme := strings.TrimSuffix(strings.ToLower(u.Scheme), "+srv")
+	return scheme + "://" + strings.ToLower(u.Host) + strings.TrimRight(u.Path, "/")
+}
+
+func sharedValue(a, b []string) string {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return x
+			}
+		}
+	}
+	return ""
+}
+
+func termKey(t rdf.Term) string {
+	if iri, ok := t.(rdf.IRI); ok {
+		return string(iri)
+	}
+	return t.String()
+}
+
+type unionFind struct {
+	parent map[string]string
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: map[string]string{}}
+}
+
+func (u *unionFind) find(x string) string {
+	for {
+		p, ok := u.parent[x]
+		if !ok || p == x {
+			return x
+		}
+		x = p
+	}
+}
+
+func (u *unionFind) union(a, b string) {
+	if ra, rb := u.find(a), u.find(b); ra != rb {
+		u.parent[rb] = ra
+	}
+}
@@ -0,0 +1,70 @@
+package retrieve
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are common words of specs and graph literals that say nothing about which node is meant.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true, "from": true,
+	"into": true, "when": true, "should": true, "must": true, "will": true, "are": true, "all": true,
+	"new": true, "add": true, "any": true, "can": true, "has": true, "have": true, "not": true,
+	"its": true, "also": true, "each": true, "which": true, "who": true, "use": true, "used": true,
+	"http": true, "https": true, "www": true, "com": true, "graphmind": true, "api": true, "rpc": true,
+}
+
+// keywords returns the keywords of a text: its words split at camel case and punctuation, lower cased and
+// reduced to a crude singular, without stop words and words shorter than three letters.
+func keywords(text string) map[string]bool {
+	tokens := map[string]bool{}
+	for _, word := range splitWords(text) {
+		word = singular(strings.ToLower(word))
+		if len(word) >= 3 && !stopWords[word] {
+			tokens[word] = true
+		}
+	}
+	return tokens
+}
+
+// splitWords splits a text into words at every character that is not a letter or digit, and at lower to
+// upper case changes, so "CreateOrder" and "create_order" both give "Create" and "Order".
+func splitWords(text string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = current[:0]
+		}
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			// A new word starts at "Order" in "createOrder" and at "Parser" in "HTTPParser".
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+	return words
+}
+
+// singular strips the plural endings of regular English nouns, so "orders" matches "order".
+func singular(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 4 && strings.HasSuffix(word, "sses"):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us"):
+		return word[:len(word)-1]
+	}
+	return word
+}
@@ -0,0 +1,126 @@
+package retrieve
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// maxOverviewNames is the number of names listed per kind of resource before the rest is only counted.
+const maxOverviewNames = 40
+
+// Overview summarizes a whole graph in a few lines per repository: its services with their number of
+// APIs, the resources of every kind and which services call which. It gives a prompt the shape of the
+// system that a retrieved subgraph leaves out.
+func Overview(g *rdf.Graph) string {
+	g = provenance.Strip(g)
+	var b strings.Builder
+
+	// 1. Repositories and their services.
+	repositories := sortedIRIs(g.Subjects(rdf.RDFType, ontology.Repository))
+	listed := map[rdf.IRI]bool{}
+	fmt.Fprintf(&b, "Repositories (%d):\n", len(repositories))
+	for _, repository := range repositories {
+		label := nodeName(g, repository)
+		if url := g.Object(repository, ontology.RepoURL); url != nil {
+			label = rdf.Value(url)
+		}
+		var services []string
+		for _, service := range sortedIRIs(g.Objects(repository, ontology.HasService)) {
+			listed[service] = true
+			services = append(services, serviceSummary(g, service))
+		}
+		if len(services) == 0 {
+			fmt.Fprintf(&b, "- %s\n", label)
+		} else {
+			fmt.Fprintf(&b, "- %s: %s\n", label, strings.Join(services, ", "))
+		}
+	}
+	var orphans []string
+	for _, service := range sortedIRIs(g.Subjects(rdf.RDFType, ontology.Service)) {
+		if !listed[service] {
+			orphans = append(orphans, serviceSummary(g, service))
+		}
+	}
+	if len(orphans) > 0 {
+		fmt.Fprintf(&b, "Services outside known repositories: %s\n", strings.Join(orphans, ", "))
+	}
+
+	// 2. Resources by kind.
+	b.WriteString("Resources:\n")
+	for _, class := range []rdf.IRI{ontology.Database, ontology.Collection, ontology.Topic, ontology.CloudResource, ontology.ConfigKey} {
+		nodes := sortedIRIs(g.Subjects(rdf.RDFType, class))
+		if len(nodes) == 0 {
+			continue
+		}
+		var names []string
+		for _, node := range nodes[:min(len(nodes), maxOverviewNames)] {
+			names = append(names, nodeName(g, node))
+		}
+		if len(nodes) > maxOverviewNames {
+			names = append(names, fmt.Sprintf("and %d more", len(nodes)-maxOverviewNames))
+		}
+		fmt.Fprintf(&b, "- %s (%d): %s\n", rdf.LocalName(class), len(nodes), strings.Join(names, ", "))
+	}
+
+	// 3. Calls between services, counted by the API calls that make them up.
+	serviceOf := map[rdf.Term]rdf.Term{}
+	for _, t := range g.Match(nil, ontology.HasApi, nil) {
+		serviceOf[t.Object] = t.Subject
+	}
+	calls := map[[2]string]int{}
+	for _, t := range g.Match(nil, ontology.Calls, nil) {
+		from, to := serviceOf[t.Subject], serviceOf[t.Object]
+		if from != nil && to != nil && from != to {
+			calls[[2]string{nodeName(g, from), nodeName(g, to)}]++
+		}
+	}
+	if len(calls) > 0 {
+		pairs := make([][2]string, 0, len(calls))
+		for pair := range calls {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			if pairs[i][0] != pairs[j][0] {
+				return pairs[i][0] < pairs[j][0]
+			}
+			return pairs[i][1] < pairs[j][1]
+		})
+		b.WriteString("Service calls:\n")
+		for _, pair := range pairs {
+			fmt.Fprintf(&b, "- %s -> %s (%d API calls)\n", pair[0], pair[1], calls[pair])
+		}
+	}
+	return b.String()
+}
+
+// serviceSummary names a service with its number of APIs, for example "OrderService (5 APIs)".
+func serviceSummary(g *rdf.Graph, service rdf.IRI) string {
+	return fmt.Sprintf("%s (%d APIs)", nodeName(g, service), len(g.Objects(service, ontology.HasApi)))
+}
+
+func nodeName(g *rdf.Graph, node rdf.Term) string {
+	if name := g.Object(node, ontology.Name); name != nil {
+		return rdf.Value(name)
+	}
+	if iri, ok := node.(rdf.IRI); ok {
+		return rdf.LocalName(iri)
+	}
+	return node.String()
+}
+
+// sortedIRIs returns the IRIs among terms, sorted.
+func sortedIRIs(terms []rdf.Term) []rdf.IRI {
+	var iris []rdf.IRI
+	for _, term := range terms {
+		if iri, ok := term.(rdf.IRI); ok {
+			iris = append(iris, iri)
+		}
+	}
+	sort.Slice(iris, func(i, j int) bool { return iris[i] < iris[j] })
+	return iris
+}
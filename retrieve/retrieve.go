@@ -0,0 +1,348 @@
+// Package retrieve selects the part of a GraphMind graph that is relevant to a specification, so spec
+// prompts stay within the model's context however many repositories the graph covers. Nodes are ranked by
+// keyword and, when an embedder is available, embedding similarity to the spec; the best ones are expanded
+// with their neighbourhood and returned as a subgraph.
+package retrieve
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Embedder returns an embedding vector for every text, in order. An embedder that returns no vectors
+// disables the embedding search.
+type Embedder func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Options bounds the retrieved subgraph.
+type Options struct {
+	MaxSeeds int // The number of best ranked nodes the subgraph is grown from.
+	Hops     int // How many edges away from a seed neighbours are included.
+	MaxNodes int // The most nodes the subgraph describes.
+}
+
+// DefaultOptions keeps the subgraph to a few hundred triples.
+func DefaultOptions() Options {
+	return Options{MaxSeeds: 15, Hops: 1, MaxNodes: 150}
+}
+
+// Seed is a node ranked relevant to the spec.
+type Seed struct {
+	Node    rdf.IRI  `json:"node"`
+	Name    string   `json:"name"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"` // For example "keywords: order, discount" or "embedding similarity 0.61".
+}
+
+// Result is the subgraph retrieved for a spec.
+type Result struct {
+	Seeds    []Seed
+	Nodes    []rdf.IRI  // The seeds and their neighbours, in the order they were added.
+	Subgraph *rdf.Graph // The triples about Nodes, and the type and name of the nodes they point to.
+}
+
+// document is the searchable text of a node.
+type document struct {
+	node   rdf.IRI
+	name   string
+	text   string          // Sent to the embedder.
+	tokens map[string]bool // Keywords of the text.
+	names  map[string]bool // Keywords of the name, which weigh more.
+}
+
+// Retriever searches one graph. Build it once with New; it keeps the embeddings of the graph's nodes
+// between searches. It is safe for concurrent use.
+type Retriever struct {
+	graph     *rdf.Graph
+	documents []document
+	idf       map[string]float64
+	embed     Embedder
+
+	mu      sync.Mutex
+	vectors [][]float64 // The embeddings of documents, nil until the first search with an embedder.
+}
+
+// New indexes the named nodes of a graph. embed may be nil for keyword search only.
+func New(g *rdf.Graph, embed Embedder) *Retriever {
+	g = provenance.Strip(g)
+	r := &Retriever{graph: g, idf: map[string]float64{}, embed: embed}
+
+	seen := map[rdf.IRI]bool{}
+	for _, t := range g.Match(nil, rdf.RDFType, nil) {
+		node, ok := t.Subject.(rdf.IRI)
+		if !ok || seen[node] {
+			continue
+		}
+		seen[node] = true
+		r.documents = append(r.documents, r.document(node))
+	}
+	sort.Slice(r.documents, func(i, j int) bool { return r.documents[i].node < r.documents[j].node })
+
+	df := map[string]int{}
+	for _, d := range r.documents {
+		for token := range d.tokens {
+			df[token]++
+		}
+	}
+	for token, count := range df {
+		r.idf[token] = math.Log(1 + float64(len(r.documents))/float64(count))
+	}
+	return r
+}
+
+// document describes a node by its classes, name, URI and descriptive literals.
+func (r *Retriever) document(node rdf.IRI) document {
+	name := rdf.LocalName(node)
+	if n := r.graph.Object(node, ontology.Name); n != nil {
+		name = rdf.Value(n)
+	}
+	var classes, literals []string
+	for _, class := range r.graph.Objects(node, rdf.RDFType) {
+		if iri, ok := class.(rdf.IRI); ok {
+			classes = append(classes, rdf.LocalName(iri))
+		}
+	}
+	for _, t := range r.graph.Match(node, "", nil) {
+		if literal, ok := t.Object.(rdf.Literal); ok && t.Predicate != ontology.Name {
+			literals = append(literals, rdf.LocalName(t.Predicate)+": "+literal.Value)
+		}
+	}
+	sort.Strings(classes)
+	sort.Strings(literals)
+
+	text := fmt.Sprintf("%s %s (%s)", strings.Join(classes, ", "), name, node)
+	if len(literals) > 0 {
+		text += "\n" + strings.Join(literals, "\n")
+	}
+	return document{
+		node:   node,
+		name:   name,
+		text:   text,
+		tokens: keywords(text),
+		names:  keywords(name + " " + rdf.LocalName(node)),
+	}
+}
+
+// Retrieve returns the subgraph relevant to a spec.
+func (r *Retriever) Retrieve(ctx context.Context, spec string, opts Options) (*Result, error) {
+	// 1. Rank the nodes by keywords and by embedding similarity, and fuse the rankings.
+	scores := map[rdf.IRI]float64{}
+	reasons := map[rdf.IRI][]string{}
+	for rank, match := range r.keywordMatches(spec) {
+		scores[match.node] += fusedScore(rank)
+		reasons[match.node] = append(reasons[match.node], "keywords: "+strings.Join(match.tokens, ", "))
+	}
+	similar, err := r.embeddingMatches(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	for rank, match := range similar {
+		scores[match.node] += fusedScore(rank)
+		reasons[match.node] = append(reasons[match.node], fmt.Sprintf("embedding similarity %.2f", match.similarity))
+	}
+
+	result := &Result{Seeds: []Seed{}, Subgraph: rdf.NewGraph()}
+	for node, score := range scores {
+		result.Seeds = append(result.Seeds, Seed{Node: node, Name: r.name(node), Score: score, Reasons: reasons[node]})
+	}
+	sort.Slice(result.Seeds, func(i, j int) bool {
+		if result.Seeds[i].Score != result.Seeds[j].Score {
+			return result.Seeds[i].Score > result.Seeds[j].Score
+		}
+		return result.Seeds[i].Node < result.Seeds[j].Node
+	})
+	if len(result.Seeds) > opts.MaxSeeds {
+		result.Seeds = result.Seeds[:opts.MaxSeeds]
+	}
+
+	// 2. Grow the seeds into their neighbourhood, nearest first, up to the node limit.
+	included := map[rdf.IRI]bool{}
+	var frontier []rdf.IRI
+	for _, seed := range result.Seeds {
+		if len(result.Nodes) < opts.MaxNodes {
+			included[seed.Node] = true
+			result.Nodes = append(result.Nodes, seed.Node)
+			frontier = append(frontier, seed.Node)
+		}
+	}
+	for hop := 0; hop < opts.Hops && len(result.Nodes) < opts.MaxNodes; hop++ {
+		var next []rdf.IRI
+		for _, node := range frontier {
+			for _, neighbour := range r.neighbours(node) {
+				if included[neighbour] || len(result.Nodes) >= opts.MaxNodes {
+					continue
+				}
+				included[neighbour] = true
+				result.Nodes = append(result.Nodes, neighbour)
+				next = append(next, neighbour)
+			}
+		}
+		frontier = next
+	}
+
+	// 3. Collect the triples about the included nodes, naming the nodes they point to.
+	for prefix, namespace := range r.graph.Prefixes {
+		result.Subgraph.BindPrefix(prefix, namespace)
+	}
+	for _, node := range result.Nodes {
+		for _, t := range r.graph.Match(node, "", nil) {
+			result.Subgraph.Add(t)
+			if object, ok := t.Object.(rdf.IRI); ok && !included[object] && t.Predicate != rdf.RDFType {
+				for _, p := range []rdf.IRI{rdf.RDFType, ontology.Name} {
+					for _, described := range r.graph.Match(object, p, nil) {
+						result.Subgraph.Add(described)
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+type keywordMatch struct {
+	node   rdf.IRI
+	score  float64
+	tokens []string
+}
+
+// keywordMatches returns the nodes sharing keywords with the text, best first. Keywords are weighted by
+// their rarity in the graph, and keywords of a node's name count double.
+func (r *Retriever) keywordMatches(text string) []keywordMatch {
+	query := keywords(text)
+	var matches []keywordMatch
+	for _, d := range r.documents {
+		match := keywordMatch{node: d.node}
+		for token := range query {
+			if !d.tokens[token] {
+				continue
+			}
+			weight := r.idf[token]
+			if d.names[token] {
+				weight *= 2
+			}
+			match.score += weight
+			match.tokens = append(match.tokens, token)
+		}
+		if match.score > 0 {
+			sort.Strings(match.tokens)
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	return matches
+}
+
+type embeddingMatch struct {
+	node       rdf.IRI
+	similarity float64
+}
+
+// minSimilarity leaves out nodes that are only as similar to the spec as unrelated text tends to be.
+const minSimilarity = 0.3
+
+// maxEmbeddingMatches is the number of most similar nodes that take part in the ranking.
+const maxEmbeddingMatches = 50
+
+// embeddingMatches returns the nodes most similar to the text, best first, embedding the nodes on first
+// use.
+func (r *Retriever) embeddingMatches(ctx context.Context, text string) ([]embeddingMatch, error) {
+	if r.embed == nil || len(r.documents) == 0 {
+		return nil, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.vectors == nil {
+		texts := make([]string, len(r.documents))
+		for i, d := range r.documents {
+			texts[i] = d.text
+		}
+		vectors, err := r.embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed graph nodes: %w", err)
+		}
+		if len(vectors) == 0 {
+			return nil, nil
+		}
+		if len(vectors) != len(texts) {
+			return nil, fmt.Errorf("embedder returned %d vectors for %d nodes", len(vectors), len(texts))
+		}
+		r.vectors = vectors
+	}
+	query, err := r.embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed spec: %w", err)
+	}
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	var matches []embeddingMatch
+	for i, vector := range r.vectors {
+		if similarity := cosine(query[0], vector); similarity >= minSimilarity {
+			matches = append(matches, embeddingMatch{node: r.documents[i].node, similarity: similarity})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].similarity > matches[j].similarity })
+	if len(matches) > maxEmbeddingMatches {
+		matches = matches[:maxEmbeddingMatches]
+	}
+	return matches, nil
+}
+
+// neighbours returns the nodes linked to a node in either direction, sorted.
+func (r *Retriever) neighbours(node rdf.IRI) []rdf.IRI {
+	seen := map[rdf.IRI]bool{}
+	for _, t := range r.graph.Match(node, "", nil) {
+		if object, ok := t.Object.(rdf.IRI); ok && t.Predicate != rdf.RDFType {
+			seen[object] = true
+		}
+	}
+	for _, t := range r.graph.Match(nil, "", node) {
+		if subject, ok := t.Subject.(rdf.IRI); ok {
+			seen[subject] = true
+		}
+	}
+	delete(seen, node)
+	neighbours := make([]rdf.IRI, 0, len(seen))
+	for n := range seen {
+		neighbours = append(neighbours, n)
+	}
+	sort.Slice(neighbours, func(i, j int) bool { return neighbours[i] < neighbours[j] })
+	return neighbours
+}
+
+func (r *Retriever) name(node rdf.IRI) string {
+	if n := r.graph.Object(node, ontology.Name); n != nil {
+		return rdf.Value(n)
+	}
+	return rdf.LocalName(node)
+}
+
+// fusedScore is the reciprocal rank fusion score of a rank, which lets rankings with incomparable scores
+// be combined.
+func fusedScore(rank int) float64 {
+	return 1 / float64(60+rank)
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
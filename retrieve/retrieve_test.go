@@ -0,0 +1,272 @@
+package retrieve
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// testGraph has an order service whose CreateOrder API writes orders and calls the Charge API of a payment
+// service, which publishes payment events.
+func testGraph(t *testing.T) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(`
+		@prefix gm: <http://graphmind.io/ontology#> .
+		@prefix ex: <http://example.com/> .
+		ex:ordersRepo a gm:Repository ; gm:repoUrl "https://github.com/org/orders" ; gm:hasService ex:orderService .
+		ex:orderService a gm:Service ; gm:name "OrderService" ; gm:hasApi ex:createOrder, ex:getOrder .
+		ex:createOrder a gm:Api ; gm:name "CreateOrder" ; gm:description "Creates an order and charges the customer." ;
+			gm:writesTo ex:ordersCollection ; gm:calls ex:charge .
+		ex:getOrder a gm:Api ; gm:name "GetOrder" ; gm:readsFrom ex:ordersCollection .
+		ex:paymentService a gm:Service ; gm:name "PaymentService" ; gm:hasApi ex:charge .
+		ex:charge a gm:Api ; gm:name "Charge" ; gm:description "Charges a card." ; gm:publishesTo ex:paymentEvents .
+		ex:shopDb a gm:Database ; gm:name "shop" ; gm:databaseType "mongodb" ; gm:hasCollection ex:ordersCollection .
+		ex:ordersCollection a gm:Collection ; gm:name "orders" .
+		ex:paymentEvents a gm:Topic ; gm:name "payment-events" .
+	`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Provenance is not searched.
+	provenance.Record(g, rdf.Triple{Subject: ex("createOrder"), Predicate: ontology.Calls, Object: ex("charge")},
+		provenance.Source{Activity: "LinkServiceCalls", File: "customer_orders.go"})
+	return g
+}
+
+func ex(name string) rdf.IRI {
+	return rdf.IRI("http://example.com/" + name)
+}
+
+func seedNames(seeds []Seed) string {
+	var names []string
+	for _, seed := range seeds {
+		names = append(names, seed.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func localNames(nodes []rdf.IRI) string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, rdf.LocalName(node))
+	}
+	return strings.Join(names, " ")
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"CreateOrder", []string{"create", "order"}},
+		{"create_order orders", []string{"create", "order"}},
+		{"HTTPParser parses the Addresses", []string{"address", "parse", "parser"}},
+		{"Categories of status classes", []string{"category", "class", "status"}},
+		{"Add an API to the new service", []string{"service"}},
+		{"payment-events v2", []string{"event", "payment"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for token := range keywords(tt.text) {
+			got = append(got, token)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("keywords(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRetrieveRanking(t *testing.T) {
+	r := New(testGraph(t), nil)
+	result, err := r.Retrieve(context.Background(), "Customers want to cancel their orders.", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CreateOrder also matches the rarer "customer"; the nodes matching only "order" in their name follow in
+	// URI order.
+	if got, want := seedNames(result.Seeds), "CreateOrder, GetOrder, OrderService, orders, ordersRepo"; got != want {
+		t.Errorf("seeds = %s, want %s", got, want)
+	}
+	if got := result.Seeds[0].Reasons; !reflect.DeepEqual(got, []string{"keywords: customer, order"}) {
+		t.Errorf("reasons = %v", got)
+	}
+	if result.Seeds[0].Score <= result.Seeds[1].Score {
+		t.Errorf("scores = %v and %v, want the first higher", result.Seeds[0].Score, result.Seeds[1].Score)
+	}
+
+	none, err := r.Retrieve(context.Background(), "Nothing in common", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none.Seeds) != 0 || len(none.Nodes) != 0 || none.Subgraph.Len() != 0 || none.Seeds == nil {
+		t.Errorf("result without matches = %+v", none)
+	}
+}
+
+func TestRetrieveSubgraph(t *testing.T) {
+	r := New(testGraph(t), nil)
+	result, err := r.Retrieve(context.Background(), "Charge a card", Options{MaxSeeds: 1, Hops: 1, MaxNodes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Charge matches both keywords, and in its name; its neighbours follow in URI order.
+	if got, want := localNames(result.Nodes), "charge createOrder paymentEvents paymentService"; got != want {
+		t.Errorf("nodes = %s, want %s", got, want)
+	}
+
+	g := result.Subgraph
+	for _, want := range []rdf.Triple{
+		{Subject: ex("charge"), Predicate: ontology.Description, Object: rdf.NewLiteral("Charges a card.")},
+		{Subject: ex("createOrder"), Predicate: ontology.WritesTo, Object: ex("ordersCollection")},
+		{Subject: ex("paymentService"), Predicate: ontology.HasApi, Object: ex("charge")},
+		// Nodes outside the subgraph that it points to are named and typed.
+		{Subject: ex("ordersCollection"), Predicate: rdf.RDFType, Object: ontology.Collection},
+		{Subject: ex("ordersCollection"), Predicate: ontology.Name, Object: rdf.NewLiteral("orders")},
+	} {
+		if !g.Contains(want) {
+			t.Errorf("the subgraph lacks %s", want)
+		}
+	}
+	for _, unwanted := range []rdf.Triple{
+		{Subject: ex("orderService"), Predicate: ontology.HasApi, Object: ex("createOrder")},
+		{Subject: ex("shopDb"), Predicate: ontology.HasCollection, Object: ex("ordersCollection")},
+	} {
+		if g.Contains(unwanted) {
+			t.Errorf("the subgraph has %s about a node outside it", unwanted)
+		}
+	}
+	if len(g.Subjects(rdf.RDFType, rdf.RDFStatement)) != 0 {
+		t.Error("the subgraph has provenance")
+	}
+	if g.Prefixes["ex"] != "http://example.com/" {
+		t.Errorf("prefixes = %v", g.Prefixes)
+	}
+}
+
+func TestRetrieveLimits(t *testing.T) {
+	r := New(testGraph(t), nil)
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"seeds only", Options{MaxSeeds: 2, MaxNodes: 10}, "createOrder getOrder"},
+		{"node limit", Options{MaxSeeds: 5, Hops: 2, MaxNodes: 3}, "createOrder getOrder orderService"},
+		{"two hops", Options{MaxSeeds: 1, Hops: 2, MaxNodes: 10}, "createOrder charge orderService ordersCollection paymentEvents paymentService getOrder ordersRepo shopDb"},
+	}
+	for _, tt := range tests {
+		result, err := r.Retrieve(context.Background(), "Customers want to cancel their orders.", tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := localNames(result.Nodes); got != tt.want {
+			t.Errorf("%s: nodes = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// topicEmbedder embeds a text as how much it is about payments, orders and storage.
+type topicEmbedder struct {
+	calls [][]string
+	err   error
+}
+
+func (e *topicEmbedder) embed(ctx context.Context, texts []string) ([][]float64, error) {
+	e.calls = append(e.calls, texts)
+	if e.err != nil {
+		return nil, e.err
+	}
+	var vectors [][]float64
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		vector := []float64{0, 0, 0}
+		for i, words := range [][]string{{"payment", "charge", "refund", "card"}, {"order"}, {"database", "collection", "mongodb"}} {
+			for _, word := range words {
+				if strings.Contains(text, word) {
+					vector[i]++
+				}
+			}
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func TestRetrieveEmbeddings(t *testing.T) {
+	embedder := &topicEmbedder{}
+	r := New(testGraph(t), embedder.embed)
+	// No keyword of the spec is in the graph, so only the embeddings find the payment nodes. They are all
+	// about payments alone, and equally similar nodes keep URI order.
+	result, err := r.Retrieve(context.Background(), "Refunds", Options{MaxSeeds: 3, MaxNodes: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := seedNames(result.Seeds), "Charge, payment-events, PaymentService"; got != want {
+		t.Errorf("seeds = %s, want %s", got, want)
+	}
+	if got := result.Seeds[0].Reasons; !reflect.DeepEqual(got, []string{"embedding similarity 1.00"}) {
+		t.Errorf("reasons = %v", got)
+	}
+
+	// Keyword and embedding rankings add up.
+	result, err = r.Retrieve(context.Background(), "Store the order in the database", Options{MaxSeeds: 1, MaxNodes: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Seeds) != 1 || len(result.Seeds[0].Reasons) != 2 || !strings.HasPrefix(result.Seeds[0].Reasons[0], "keywords: ") ||
+		!strings.HasPrefix(result.Seeds[0].Reasons[1], "embedding similarity ") {
+		t.Errorf("seeds = %+v, want a node found both ways", result.Seeds)
+	}
+
+	// The nodes are embedded once; later searches only embed the spec.
+	if len(embedder.calls) != 3 || len(embedder.calls[0]) != 9 || len(embedder.calls[2]) != 1 {
+		t.Errorf("embedder calls = %d, want the 9 nodes once and the 2 specs", len(embedder.calls))
+	}
+}
+
+func TestRetrieveEmbeddingErrors(t *testing.T) {
+	failing := &topicEmbedder{err: errors.New("quota exceeded")}
+	if _, err := New(testGraph(t), failing.embed).Retrieve(context.Background(), "orders", DefaultOptions()); err == nil ||
+		!strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("err = %v, want the embedder error", err)
+	}
+
+	short := func(ctx context.Context, texts []string) ([][]float64, error) { return [][]float64{{1}}, nil }
+	if _, err := New(testGraph(t), short).Retrieve(context.Background(), "orders", DefaultOptions()); err == nil ||
+		!strings.Contains(err.Error(), "1 vectors for 9 nodes") {
+		t.Errorf("err = %v, want a vector count error", err)
+	}
+
+	// An embedder without vectors leaves the keyword search.
+	disabled := func(ctx context.Context, texts []string) ([][]float64, error) { return nil, nil }
+	result, err := New(testGraph(t), disabled).Retrieve(context.Background(), "orders", Options{MaxSeeds: 1, MaxNodes: 1})
+	if err != nil || len(result.Seeds) != 1 || len(result.Seeds[0].Reasons) != 1 {
+		t.Errorf("result = %+v, %v, want a keyword match", result, err)
+	}
+}
+
+func TestOverview(t *testing.T) {
+	g := testGraph(t)
+	g.AddTriple(ex("legacy"), rdf.RDFType, ontology.Service)
+	want := `Repositories (1):
+- https://github.com/org/orders: OrderService (2 APIs)
+Services outside known repositories: legacy (0 APIs), PaymentService (1 APIs)
+Resources:
+- Database (1): shop
+- Collection (1): orders
+- Topic (1): payment-events
+Service calls:
+- OrderService -> PaymentService (1 API calls)
+`
+	if got := Overview(g); got != want {
+		t.Errorf("Overview:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/GraphMind/activities/buildcodegraph"
+	"github.com/SaiNageswarS/GraphMind/diagram"
+	"github.com/SaiNageswarS/GraphMind/impact"
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// diagramHandler draws a diagram straight from the graph. view is system (the default) for all services
+// and the resources they use, service for a service and its neighbourhood, or impact for a node and
+// everything that depends on it or it depends on, up to depth; node names the service or node by URI or
+// name. highlight lists node URIs to highlight, separated by commas. format is mermaid (the default), dot or
+// svg, which needs Graphviz. graph, commit and asOf select the graphs as for the SPARQL endpoint.
+func diagramHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	view := params.Get("view")
+	if view == "" {
+		view = "system"
+	}
+	if view != "system" && view != "service" && view != "impact" {
+		http.Error(w, "view must be system, service or impact", http.StatusBadRequest)
+		return
+	}
+	format := params.Get("format")
+	if format == "" {
+		format = "mermaid"
+	}
+	if format != "mermaid" && format != "dot" && format != "svg" {
+		http.Error(w, "format must be mermaid, dot or svg", http.StatusBadRequest)
+		return
+	}
+	opts := impact.DefaultOptions()
+	if depth := params.Get("depth"); depth != "" {
+		var err error
+		if opts.MaxDepth, err = strconv.Atoi(depth); err != nil || opts.MaxDepth < 0 {
+			http.Error(w, "depth must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	snapshot, err := snapshotParam(params)
+	var graph *rdf.Graph
+	if err == nil {
+		graph, err = loadSnapshotGraph(params.Get("graph"), snapshot)
+	}
+	if err != nil {
+		writeStoreError(w, err, "Failed to load graph for diagram")
+		return
+	}
+
+	// 1. Draw the view.
+	var d *diagram.Diagram
+	if view == "system" {
+		d = diagram.System(graph)
+	} else {
+		analyzer := impact.New(graph)
+		ref := params.Get("node")
+		if ref == "" {
+			http.Error(w, "Missing node", http.StatusBadRequest)
+			return
+		}
+		node, ok := findNode(w, analyzer, ref)
+		if !ok {
+			return
+		}
+		if view == "service" {
+			d = diagram.Service(graph, node)
+		} else {
+			result, err := analyzer.Analyze(node, opts)
+			if err != nil {
+				log.Printf("Impact analysis failed: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			d = diagram.Impact(graph, result)
+		}
+	}
+	if highlight := params.Get("highlight"); highlight != "" {
+		var uris []rdf.IRI
+		for _, uri := range strings.Split(highlight, ",") {
+			uris = append(uris, rdf.IRI(strings.TrimSpace(uri)))
+		}
+		d.Highlight(uris...)
+	}
+
+	// 2. Render it.
+	switch format {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Write([]byte(d.DOT()))
+	case "svg":
+		svg, err := d.SVG(r.Context())
+		if errors.Is(err, diagram.ErrNoGraphviz) {
+			http.Error(w, "SVG output needs Graphviz, which is not installed; use format=dot", http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to render diagram: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(svg)
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(d.Mermaid()))
+	}
+}
+
+// specDiagram draws the nodes a spec is about: the impact set of the nodes it mentions, or else the nodes
+// retrieved for it. The LLM only picks which of the drawn nodes the proposed change touches; they are
+// highlighted.
+func specDiagram(ctx context.Context, graph *rdf.Graph, results []*impact.Result, specCtx specContext, spec, codeChange string) string {
+	var d *diagram.Diagram
+	if len(results) > 0 {
+		d = diagram.Impact(graph, results...)
+	} else {
+		d = diagram.Nodes(graph, specCtx.Seeds)
+	}
+	if len(d.Nodes) == 0 {
+		return ""
+	}
+	highlighted := d.Highlight(changedNodes(ctx, d, spec, codeChange)...)
+	log.Printf("Spec diagram has %d nodes and %d edges, %d highlighted", len(d.Nodes), len(d.Edges), highlighted)
+	return d.Mermaid()
+}
+
+// highlightDiagramLLM is the LLM that picks the nodes a spec changes unless configured otherwise.
+var highlightDiagramLLM = llm.Config{Use: "HighlightDiagram", Provider: llm.Anthropic, Model: "claude-3-5-sonnet-20241022", MaxTokens: 2048}
+
+// changedNodes asks the LLM which nodes of a diagram a proposed code change touches. Only URIs of the
+// diagram's nodes are taken from the answer, so the LLM cannot add anything to the diagram.
+func changedNodes(ctx context.Context, d *diagram.Diagram, spec, codeChange string) []rdf.IRI {
+	promptTemplate, err := buildcodegraph.ReadFileToString("prompts/highlight_diagram.txt")
+	if err != nil {
+		log.Printf("Failed to read prompt file: %v", err)
+		return nil
+	}
+	var nodes strings.Builder
+	for _, n := range d.Nodes {
+		fmt.Fprintf(&nodes, "%s | %s | %s\n", n.URI, n.Class, n.Name)
+	}
+	prompt := strings.ReplaceAll(promptTemplate, "{{.Spec}}", spec)
+	prompt = strings.ReplaceAll(prompt, "{{.CodeChangeApproach}}", codeChange)
+	prompt = strings.ReplaceAll(prompt, "{{.Nodes}}", nodes.String())
+
+	response, err := complete(ctx, highlightDiagramLLM, prompt)
+	if err != nil {
+		log.Printf("LLM call failed: %v", err)
+		return nil
+	}
+	named := responseURIs(response)
+	var changed []rdf.IRI
+	for _, n := range d.Nodes {
+		if named[n.URI] {
+			changed = append(changed, n.URI)
+		}
+	}
+	return changed
+}
+
+// responseURIs returns the whole tokens of an LLM answer that can be URIs, one per line or inside <...>,
+// without the list markers, quotes and punctuation around them. Comparing them exactly keeps a URI from
+// matching the longer URIs it is a prefix of, such as .../Get of .../GetAll.
+func responseURIs(response string) map[rdf.IRI]bool {
+	tokens := strings.FieldsFunc(response, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '|' || r == ','
+	})
+	uris := map[rdf.IRI]bool{}
+	for _, token := range tokens {
+		token = strings.Trim(token, "<>`'\"()[]*")
+		token = strings.TrimRight(token, ".;:")
+		if token != "" {
+			uris[rdf.IRI(token)] = true
+		}
+	}
+	return uris
+}
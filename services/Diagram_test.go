@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+func TestResponseURIs(t *testing.T) {
+	const get = "http://graphmind.io/id/service/shop.Orders/Get"
+	const getAll = "http://graphmind.io/id/service/shop.Orders/GetAll"
+	tests := []struct {
+		name     string
+		response string
+		want     []string
+		notWant  []string
+	}{
+		{name: "one per line", response: getAll + "\n", want: []string{getAll}, notWant: []string{get}},
+		{name: "bracketed", response: "The change touches <" + get + "> and nothing else.", want: []string{get}, notWant: []string{getAll}},
+		{name: "list markers", response: "- `" + get + "`\n* " + getAll + ".", want: []string{get, getAll}},
+		{name: "copied node line", response: get + " | Api | Get", want: []string{get}},
+		{name: "prose", response: "No node is changed.", notWant: []string{get, getAll}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uris := responseURIs(tt.response)
+			for _, uri := range tt.want {
+				if !uris[rdf.IRI(uri)] {
+					t.Errorf("%s is missing from %v", uri, uris)
+				}
+			}
+			for _, uri := range tt.notWant {
+				if uris[rdf.IRI(uri)] {
+					t.Errorf("%s is in %v", uri, uris)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaiNageswarS/GraphMind/diff"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// diffHandler compares two builds. With graph it compares the versions from and to of that graph (the
+// latest version and the one before it by default), or the versions built from the commits fromCommit and
+// toCommit; with build it compares the store as that build and fromBuild (the build before it by default)
+// left it. The report is JSON, or Markdown with format=markdown.
+func diffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "markdown" {
+		http.Error(w, "format must be json or markdown", http.StatusBadRequest)
+		return
+	}
+
+	var report *diff.Report
+	var err error
+	switch graphName, build := query.Get("graph"), query.Get("build"); {
+	case graphName != "" && query.Get("toCommit") != "":
+		report, err = diff.Commits(graphStore, rdf.IRI(graphName), query.Get("fromCommit"), query.Get("toCommit"))
+	case graphName != "":
+		var from, to int
+		if from, err = versionParam(query.Get("from")); err == nil {
+			to, err = versionParam(query.Get("to"))
+		}
+		if err != nil {
+			http.Error(w, "from and to must be positive numbers", http.StatusBadRequest)
+			return
+		}
+		report, err = diff.Versions(graphStore, rdf.IRI(graphName), from, to)
+	case build != "":
+		report, err = diff.Builds(graphStore, query.Get("fromBuild"), build)
+	default:
+		http.Error(w, "graph or build is required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err, "Failed to diff graphs")
+		return
+	}
+
+	if format == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(report.Markdown()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+// versionParam parses an optional positive version number; an empty value is 0.
+func versionParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err == nil && version < 1 {
+		err = errors.New("version must be positive")
+	}
+	return version, err
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/SaiNageswarS/GraphMind/export"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// exportHandler serves a graph of the store for download. The format parameter selects the export format
+// (turtle by default), graph names a stored graph (the union of all graphs by default) and version picks
+// an earlier version of it. commit or asOf exports the graphs as they were at a commit or date.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "turtle"
+	}
+	format, err := export.FormatByName(formatName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	graphName := r.URL.Query().Get("graph")
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		if version, err = strconv.Atoi(v); err != nil || version < 1 || graphName == "" {
+			http.Error(w, "version must be a positive number and requires graph", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var graph *rdf.Graph
+	snapshot, err := snapshotParam(r.URL.Query())
+	switch {
+	case err != nil:
+	case snapshot != nil && version != 0:
+		err = fmt.Errorf("%w: version cannot be combined with commit or asOf", errBadParameter)
+	case version != 0:
+		graph, err = graphStore.GraphVersion(rdf.IRI(graphName), version)
+	default:
+		graph, err = loadSnapshotGraph(graphName, snapshot)
+	}
+	if err != nil {
+		writeStoreError(w, err, "Failed to load graph for export")
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported with an error status.
+	var buf bytes.Buffer
+	if err := format.Write(&buf, graph); err != nil {
+		log.Printf("Failed to export graph: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := "graphmind"
+	if graphName != "" {
+		fileName = path.Base(graphName)
+	}
+	w.Header().Set("Content-Type", format.ContentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+format.Extension))
+	w.Write(buf.Bytes())
+}
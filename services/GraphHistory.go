@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/SaiNageswarS/GraphMind/diff"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// historyHandler returns, as JSON, the graph versions in which the triples matching the subject, predicate
+// and object parameters appeared or disappeared, oldest commit first. At least one of them is required;
+// graph and branch narrow the history down to one stored graph or branch. For example, the first change
+// with an API of service A as subject, gm:calls as predicate and an API of service B as object tells when
+// A started to call B.
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	query := diff.HistoryQuery{
+		Predicate: rdf.IRI(params.Get("predicate")),
+		Graph:     rdf.IRI(params.Get("graph")),
+		Branch:    params.Get("branch"),
+	}
+	if s := params.Get("subject"); s != "" {
+		query.Subject = rdf.IRI(s)
+	}
+	if o := params.Get("object"); o != "" {
+		query.Object = rdf.IRI(o)
+	}
+	if query.Subject == nil && query.Predicate == "" && query.Object == nil {
+		http.Error(w, "subject, predicate or object is required", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := diff.History(graphStore, query)
+	if err != nil {
+		writeStoreError(w, err, "Failed to trace graph history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) // keep the angle brackets of IRIs readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(changes); err != nil {
+		log.Printf("Failed to write graph history: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/impact"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// specImpactDepth limits the impact analysis that seeds the spec prompt, so a spec naming a widely used
+// node does not pull in the whole graph.
+const specImpactDepth = 4
+
+// specImpactConfidence leaves paths out of the spec prompt that are more guess than fact, such as three
+// LLM-inferred edges in a row.
+const specImpactConfidence = 0.25
+
+// impactHandler returns, as JSON, everything that transitively depends on a node and everything it depends
+// on, with the path that explains each. The node parameter is a node URI or name; direction is dependents,
+// dependencies or both (the default); depth limits the length of the paths; minConfidence leaves out paths
+// less confident than it; graph, commit and asOf select the graphs as for the SPARQL endpoint. With
+// format=text the result is plain text.
+func impactHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	ref := params.Get("node")
+	if ref == "" {
+		http.Error(w, "Missing node", http.StatusBadRequest)
+		return
+	}
+	opts := impact.DefaultOptions()
+	switch direction := impact.Direction(params.Get("direction")); direction {
+	case "":
+	case impact.Dependents, impact.Dependencies, impact.Both:
+		opts.Direction = direction
+	default:
+		http.Error(w, "direction must be dependents, dependencies or both", http.StatusBadRequest)
+		return
+	}
+	if depth := params.Get("depth"); depth != "" {
+		var err error
+		if opts.MaxDepth, err = strconv.Atoi(depth); err != nil || opts.MaxDepth < 0 {
+			http.Error(w, "depth must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+	}
+	if minConfidence := params.Get("minConfidence"); minConfidence != "" {
+		var err error
+		if opts.MinConfidence, err = strconv.ParseFloat(minConfidence, 64); err != nil || opts.MinConfidence < 0 || opts.MinConfidence > 1 {
+			http.Error(w, "minConfidence must be a number from 0 to 1", http.StatusBadRequest)
+			return
+		}
+	}
+
+	snapshot, err := snapshotParam(params)
+	var graph *rdf.Graph
+	if err == nil {
+		graph, err = loadSnapshotGraph(params.Get("graph"), snapshot)
+	}
+	if err != nil {
+		writeStoreError(w, err, "Failed to load graph for impact analysis")
+		return
+	}
+
+	analyzer := impact.New(graph)
+	node, ok := findNode(w, analyzer, ref)
+	if !ok {
+		return
+	}
+	result, err := analyzer.Analyze(node, opts)
+	if err != nil {
+		log.Printf("Impact analysis failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if params.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(result.Text()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Printf("Failed to write impact analysis: %v", err)
+	}
+}
+
+// findNode returns the node a node parameter names by URI or name. If it names none or several, it writes
+// the error response and returns false.
+func findNode(w http.ResponseWriter, analyzer *impact.Analyzer, ref string) (rdf.IRI, bool) {
+	nodes := analyzer.Find(ref)
+	switch {
+	case len(nodes) == 0:
+		http.Error(w, fmt.Sprintf("No node is named %s", ref), http.StatusNotFound)
+		return "", false
+	case len(nodes) > 1:
+		names := make([]string, len(nodes))
+		for i, node := range nodes {
+			names[i] = string(node)
+		}
+		http.Error(w, fmt.Sprintf("%s names several nodes, use one of their URIs: %s", ref, strings.Join(names, ", ")), http.StatusBadRequest)
+		return "", false
+	}
+	return nodes[0], true
+}
+
+// specImpact analyzes the impact of every node a spec mentions by name. It returns the analyses and their
+// text for the spec prompt, which is empty if the spec mentions no node.
+func specImpact(graph *rdf.Graph, spec string) (string, []*impact.Result) {
+	analyzer := impact.New(graph)
+	opts := impact.Options{Direction: impact.Both, MaxDepth: specImpactDepth, MinConfidence: specImpactConfidence}
+	var parts []string
+	var results []*impact.Result
+	for _, node := range analyzer.Mentions(spec) {
+		result, err := analyzer.Analyze(node, opts)
+		if err != nil {
+			log.Printf("Impact analysis of %s failed: %v", node, err)
+			continue
+		}
+		parts = append(parts, result.Text())
+		results = append(results, result)
+	}
+	return strings.Join(parts, "\n"), results
+}
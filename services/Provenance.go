@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/SaiNageswarS/GraphMind/provenance"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// sourcedTriple is a triple with its sources in the response of the provenance endpoint. Terms are in
+// N-Triples syntax.
+type sourcedTriple struct {
+	Subject   string         `json:"subject"`
+	Predicate string         `json:"predicate"`
+	Object    string         `json:"object"`
+	Sources   []sourceRecord `json:"sources"`
+}
+
+// sourceRecord is a source with the line range and the link to the code spelled out for display.
+type sourceRecord struct {
+	provenance.Source
+	Lines string `json:"lines,omitempty"`
+	Link  string `json:"link,omitempty"`
+}
+
+// provenanceHandler returns the sources of the triples about a node as JSON, so a reviewer can go from an
+// edge of the graph to the code that justifies it. The subject parameter names the node; predicate and
+// object narrow the triples down to one edge, and graph selects a stored graph instead of the union.
+func provenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	subject := params.Get("subject")
+	if subject == "" {
+		http.Error(w, "Missing subject", http.StatusBadRequest)
+		return
+	}
+
+	graph, err := loadGraph(params.Get("graph"))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load graph for provenance: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var object rdf.Term
+	if o := params.Get("object"); o != "" {
+		object = rdf.IRI(o)
+	}
+	result := []sourcedTriple{}
+	for _, t := range graph.Match(rdf.IRI(subject), rdf.IRI(params.Get("predicate")), object) {
+		entry := sourcedTriple{Subject: t.Subject.String(), Predicate: t.Predicate.String(), Object: t.Object.String(), Sources: []sourceRecord{}}
+		for _, source := range provenance.Sources(graph, t) {
+			entry.Sources = append(entry.Sources, sourceRecord{Source: source, Lines: source.Lines(), Link: source.Link()})
+		}
+		result = append(result, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) // keep the angle brackets of IRIs readable
+	if err := encoder.Encode(result); err != nil {
+		log.Printf("Failed to write provenance: %v", err)
+	}
+}
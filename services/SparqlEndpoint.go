@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/sparql"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// defaultPrefixes are available in every query without PREFIX declarations.
+var defaultPrefixes = map[string]string{
+	"gm":   ontology.Namespace,
+	"rdf":  rdf.RDFNamespace,
+	"rdfs": rdf.RDFSNamespace,
+	"xsd":  rdf.XSDNamespace,
+	"owl":  rdf.OWLNamespace,
+}
+
+// sparqlTimeout is how long a SPARQL query may run before it is stopped.
+var sparqlTimeout = 30 * time.Second
+
+// sparqlHandler implements the SPARQL 1.1 protocol for queries over the graph store. The query is passed
+// as the query parameter of a GET or form POST, or as the body of an application/sparql-query POST. The
+// default graph is the union of all stored graphs, and every stored graph is also a named graph. The
+// default-graph-uri and named-graph-uri parameters restrict the dataset to the given graphs, and the commit
+// or asOf parameter queries the graphs as they were at a commit or date instead of the latest versions.
+// Queries are stopped when the client goes away or after sparqlTimeout.
+func sparqlHandler(w http.ResponseWriter, r *http.Request) {
+	var query string
+	switch r.Method {
+	case http.MethodGet:
+		query = r.URL.Query().Get("query")
+	case http.MethodPost:
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/sparql-query") {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Unable to read query", http.StatusBadRequest)
+				return
+			}
+			query = string(body)
+		} else {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, "Unable to parse form", http.StatusBadRequest)
+				return
+			}
+			query = r.FormValue("query")
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+
+	params := r.URL.Query()
+	if r.Method == http.MethodPost && r.PostForm != nil {
+		for key, values := range r.PostForm {
+			params[key] = append(params[key], values...)
+		}
+	}
+
+	parsed, err := sparql.Parse(query, defaultPrefixes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var dataset *sparql.Dataset
+	snapshot, err := snapshotParam(params)
+	if err == nil {
+		dataset, err = loadDataset(params["default-graph-uri"], params["named-graph-uri"], snapshot)
+	}
+	if err != nil {
+		writeStoreError(w, err, "Failed to load graphs for SPARQL query")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), sparqlTimeout)
+	defer cancel()
+	start := time.Now()
+	result, err := sparql.Execute(ctx, parsed, dataset)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("SPARQL %s query stopped after %v", parsed.Form, time.Since(start))
+		http.Error(w, "Query timed out", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Printf("SPARQL %s query canceled by the client after %v", parsed.Form, time.Since(start))
+		return
+	}
+	if err != nil {
+		log.Printf("SPARQL query failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("SPARQL %s query over %d graph(s) took %v", parsed.Form, len(dataset.Named), time.Since(start))
+
+	// Render into a buffer so a failure can still be reported with an error status.
+	var buf bytes.Buffer
+	var contentType string
+	accept := r.Header.Get("Accept")
+	switch {
+	case result.Graph == nil:
+		contentType = "application/sparql-results+json"
+		err = result.WriteJSON(&buf)
+	case strings.Contains(accept, "application/n-triples"):
+		contentType = "application/n-triples"
+		err = result.WriteNTriples(&buf)
+	default:
+		contentType = "text/turtle"
+		err = result.WriteTurtle(&buf)
+	}
+	if err != nil {
+		log.Printf("Failed to write SPARQL results: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// loadDataset builds the dataset of a query from the graph store, using the versions of snapshot or the
+// latest versions if it is nil. Without graph names the default graph is the union of all graphs and every
+// graph is a named graph.
+func loadDataset(defaultGraphs, namedGraphs []string, snapshot []store.GraphInfo) (*sparql.Dataset, error) {
+	dataset := &sparql.Dataset{Named: map[rdf.IRI]*rdf.Graph{}}
+
+	if len(namedGraphs) == 0 && len(defaultGraphs) == 0 {
+		graphs := snapshot
+		if graphs == nil {
+			var err error
+			if graphs, err = graphStore.Graphs(); err != nil {
+				return nil, err
+			}
+		}
+		for _, info := range graphs {
+			namedGraphs = append(namedGraphs, string(info.Name))
+		}
+	}
+	for _, name := range namedGraphs {
+		graph, err := loadSnapshotGraph(name, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		dataset.Named[rdf.IRI(name)] = graph
+	}
+
+	if len(defaultGraphs) == 0 {
+		union, err := loadSnapshotGraph("", snapshot)
+		if err != nil {
+			return nil, err
+		}
+		dataset.Default = union
+		return dataset, nil
+	}
+	dataset.Default = rdf.NewGraph()
+	for _, name := range defaultGraphs {
+		graph, err := loadSnapshotGraph(name, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		dataset.Default.Merge(graph)
+	}
+	return dataset, nil
+}
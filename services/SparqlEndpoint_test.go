@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
+)
+
+// useTestStore makes the handlers serve a store with two versions of a repository graph, built from
+// commits on the 1st and 3rd of May, and a compose graph written on the 2nd.
+func useTestStore(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []struct {
+		name    rdf.IRI
+		service string
+		info    store.GraphInfo
+	}{
+		{"http://graphmind.io/graph/repo/orders", "orders", store.GraphInfo{Commit: "1111111aaaa", CommitTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}},
+		{"http://graphmind.io/graph/compose", "gateway", store.GraphInfo{CommitTime: time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)}},
+		{"http://graphmind.io/graph/repo/orders", "billing", store.GraphInfo{Commit: "2222222bbbb", CommitTime: time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)}},
+	}
+	for _, v := range versions {
+		g := rdf.NewGraph()
+		g.AddTriple(rdf.IRI("http://example.com/"+v.service), rdf.RDFNamespace+"type", rdf.IRI("http://graphmind.io/ontology#Service"))
+		if _, err := s.Put(v.name, g, v.info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := graphStore
+	graphStore = s
+	t.Cleanup(func() { graphStore = previous })
+}
+
+const servicesQuery = `SELECT ?s WHERE { ?s a gm:Service }`
+
+func TestSparqlHandler(t *testing.T) {
+	useTestStore(t)
+	tests := []struct {
+		name        string
+		method      string
+		params      url.Values // The query string.
+		body        string
+		contentType string
+		accept      string
+		wantStatus  int
+		wantType    string
+		want        []string // The services a SELECT returns, or what the body contains.
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet, params: url.Values{"query": {servicesQuery}},
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{"billing", "gateway"},
+		},
+		{
+			name:   "form post",
+			method: http.MethodPost, body: url.Values{"query": {servicesQuery}}.Encode(), contentType: "application/x-www-form-urlencoded",
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{"billing", "gateway"},
+		},
+		{
+			name:   "sparql-query post",
+			method: http.MethodPost, body: servicesQuery, contentType: "application/sparql-query",
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{"billing", "gateway"},
+		},
+		{
+			name:   "commit",
+			method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "commit": {"1111111"}},
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			// The compose graph was written after the commit.
+			want: []string{"orders"},
+		},
+		{
+			name:   "as of a date",
+			method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "asOf": {"2024-05-01"}},
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{"orders"},
+		},
+		{
+			name:   "as of in a form",
+			method: http.MethodPost, body: url.Values{"query": {servicesQuery}, "asOf": {"2024-05-02"}}.Encode(), contentType: "application/x-www-form-urlencoded",
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{"gateway", "orders"},
+		},
+		{
+			name:   "default graph",
+			method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "default-graph-uri": {"http://graphmind.io/graph/compose"}},
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{"gateway"},
+		},
+		{
+			name:   "construct as turtle",
+			method: http.MethodGet, params: url.Values{"query": {`CONSTRUCT WHERE { ?s a gm:Service }`}},
+			wantStatus: http.StatusOK, wantType: "text/turtle",
+			want: []string{"<http://example.com/billing>"},
+		},
+		{
+			name:   "construct as n-triples",
+			method: http.MethodGet, params: url.Values{"query": {`CONSTRUCT WHERE { ?s a gm:Service }`}}, accept: "application/n-triples",
+			wantStatus: http.StatusOK, wantType: "application/n-triples",
+			want: []string{"<http://example.com/gateway> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://graphmind.io/ontology#Service> ."},
+		},
+		{
+			name:   "ask",
+			method: http.MethodGet, params: url.Values{"query": {`ASK { ?s a gm:Service }`}},
+			wantStatus: http.StatusOK, wantType: "application/sparql-results+json",
+			want: []string{`"boolean": true`},
+		},
+		{name: "missing query", method: http.MethodGet, wantStatus: http.StatusBadRequest},
+		{name: "syntax error", method: http.MethodGet, params: url.Values{"query": {"SELECT WHERE"}}, wantStatus: http.StatusBadRequest},
+		{name: "commit and asOf", method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "commit": {"1111111"}, "asOf": {"2024-05-01"}}, wantStatus: http.StatusBadRequest},
+		{name: "short commit", method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "commit": {"111"}}, wantStatus: http.StatusBadRequest},
+		{name: "unknown commit", method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "commit": {"3333333"}}, wantStatus: http.StatusNotFound},
+		{name: "invalid date", method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "asOf": {"yesterday"}}, wantStatus: http.StatusBadRequest},
+		{name: "unknown graph", method: http.MethodGet, params: url.Values{"query": {servicesQuery}, "named-graph-uri": {"http://example.com/none"}}, wantStatus: http.StatusNotFound},
+		{name: "put", method: http.MethodPut, params: url.Values{"query": {servicesQuery}}, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/sparql?"+tt.params.Encode(), strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			sparqlHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %s", contentType, tt.wantType)
+			}
+			if tt.wantType != "application/sparql-results+json" || strings.HasPrefix(tt.params.Get("query"), "ASK") {
+				for _, want := range tt.want {
+					if !strings.Contains(rec.Body.String(), want) {
+						t.Errorf("the response lacks %s:\n%s", want, rec.Body.String())
+					}
+				}
+				return
+			}
+
+			var results struct {
+				Results struct {
+					Bindings []map[string]struct {
+						Value string `json:"value"`
+					} `json:"bindings"`
+				} `json:"results"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, binding := range results.Results.Bindings {
+				got = append(got, strings.TrimPrefix(binding["s"].Value, "http://example.com/"))
+			}
+			sort.Strings(got)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("services = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSparqlHandlerStops(t *testing.T) {
+	useTestStore(t)
+	query := "/sparql?" + url.Values{"query": {servicesQuery}}.Encode()
+
+	previous := sparqlTimeout
+	sparqlTimeout = time.Nanosecond
+	rec := httptest.NewRecorder()
+	sparqlHandler(rec, httptest.NewRequest(http.MethodGet, query, nil))
+	sparqlTimeout = previous
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status of a query over time = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body.String())
+	}
+
+	// A client that went away gets no answer.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	sparqlHandler(rec, httptest.NewRequest(http.MethodGet, query, nil).WithContext(ctx))
+	if rec.Body.Len() != 0 {
+		t.Errorf("a canceled query answered %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/retrieve"
+)
+
+// specContext is the part of the graph a spec prompt is given: a compact overview of the whole system and
+// the subgraph relevant to the spec, as Turtle.
+type specContext struct {
+	Overview string
+	Turtle   string
+	Seeds    []rdf.IRI // The nodes ranked most relevant to the spec, best first.
+}
+
+// retrievers keeps the retriever of the last graph analyzed, so the embeddings of its nodes are computed
+// once per graph version rather than once per spec.
+var retrievers struct {
+	sync.Mutex
+	graph     *rdf.Graph
+	retriever *retrieve.Retriever
+}
+
+// retrieverFor returns a retriever for a graph of the store, which ranks nodes by embeddings too when the
+// SpecRetrieval use has an embeddings API, see llm.NewEmbedder. It is OpenAI by default and follows the
+// LLM_* and LLM_SPEC_RETRIEVAL_* variables, so a repository kept on a local server stays there.
+func retrieverFor(graph *rdf.Graph) *retrieve.Retriever {
+	retrievers.Lock()
+	defer retrievers.Unlock()
+	if retrievers.graph != graph {
+		var embed retrieve.Embedder
+		embedder, err := llm.NewEmbedder(llm.Load(llm.Config{Use: "SpecRetrieval", Provider: llm.OpenAI, Model: "text-embedding-3-small"}))
+		if err != nil {
+			log.Printf("Embeddings are not configured, retrieving by keywords only: %v", err)
+		}
+		if embedder != nil {
+			embed = func(ctx context.Context, texts []string) ([][]float64, error) {
+				vectors, err := embedder.Embed(ctx, texts)
+				if err != nil {
+					// Keyword search still works without embeddings.
+					log.Printf("Embedding failed, retrieving by keywords only: %v", err)
+					return nil, nil
+				}
+				return vectors, nil
+			}
+		}
+		retrievers.graph = graph
+		retrievers.retriever = retrieve.New(graph, embed)
+	}
+	return retrievers.retriever
+}
+
+// retrieveSpecContext selects the part of the graph relevant to a spec, so the prompts stay within the
+// model's context however large the graph is.
+func retrieveSpecContext(ctx context.Context, graph *rdf.Graph, spec string) (specContext, error) {
+	result, err := retrieverFor(graph).Retrieve(ctx, spec, retrieve.DefaultOptions())
+	if err != nil {
+		return specContext{}, err
+	}
+	for _, seed := range result.Seeds {
+		log.Printf("Spec seed %s (%.4f): %v", seed.Name, seed.Score, seed.Reasons)
+	}
+	log.Printf("Retrieved %d nodes and %d triples for the spec from %d triples", len(result.Nodes), result.Subgraph.Len(), graph.Len())
+	seeds := make([]rdf.IRI, len(result.Seeds))
+	for i, seed := range result.Seeds {
+		seeds[i] = seed.Node
+	}
+	return specContext{Overview: retrieve.Overview(graph), Turtle: rdf.ToTurtle(result.Subgraph), Seeds: seeds}, nil
+}
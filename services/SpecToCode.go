@@ -2,18 +2,37 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"text/template"
 
 	"github.com/SaiNageswarS/GraphMind/activities/buildcodegraph"
+	"github.com/SaiNageswarS/GraphMind/impact"
+	"github.com/SaiNageswarS/GraphMind/llm"
+	"github.com/SaiNageswarS/GraphMind/ontology"
+	"github.com/SaiNageswarS/GraphMind/rdf"
+	"github.com/SaiNageswarS/GraphMind/store"
 )
 
+// graphStore holds the graphs the server analyzes specs against and answers queries over.
+var graphStore *store.Store
+
 // startHTTPServer starts an HTTP server that serves the spec input page.
-func StartHTTPServer() {
+func StartHTTPServer(s *store.Store) {
+	graphStore = s
 	http.HandleFunc("/", specHandler)
+	http.HandleFunc("/sparql", sparqlHandler)
+	http.HandleFunc("/export", exportHandler)
+	http.HandleFunc("/provenance", provenanceHandler)
+	http.HandleFunc("/diff", diffHandler)
+	http.HandleFunc("/history", historyHandler)
+	http.HandleFunc("/impact", impactHandler)
+	http.HandleFunc("/diagram", diagramHandler)
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080" // Default port if not set in environment.
@@ -24,11 +43,48 @@ func StartHTTPServer() {
 	}
 }
 
+// specPage is the data of the spec input page.
+type specPage struct {
+	Spec          string
+	Graph         string        // The name of the selected graph, empty for the union of all graphs.
+	Graphs        []graphOption // The graphs in the store.
+	Impact        string        // The impact analysis of the nodes the spec mentions.
+	Result        string
+	MermaidScript string
+}
+
+// graphOption is a graph the user can analyze a spec against.
+type graphOption struct {
+	Name  string
+	Label string
+}
+
+// graphOptions lists the graphs of the store for the graph selector.
+func graphOptions() []graphOption {
+	graphs, err := graphStore.Graphs()
+	if err != nil {
+		log.Printf("Failed to list graphs: %v", err)
+		return nil
+	}
+	options := make([]graphOption, 0, len(graphs))
+	for _, info := range graphs {
+		label := info.Repository
+		if label == "" {
+			label = string(info.Name)
+		}
+		options = append(options, graphOption{
+			Name:  string(info.Name),
+			Label: fmt.Sprintf("%s (version %d, %d triples)", label, info.Version, info.Triples),
+		})
+	}
+	return options
+}
+
 // specHandler handles GET and POST requests on the root endpoint.
 func specHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		renderTemplate(w, "templates/spec_form.html", nil)
+		renderTemplate(w, "templates/spec_form.html", specPage{Graphs: graphOptions()})
 	case http.MethodPost:
 		// Parse form data.
 		if err := r.ParseForm(); err != nil {
@@ -36,31 +92,90 @@ func specHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		spec := r.FormValue("spec")
-		graphFile := r.FormValue("graphLocation")
-
-		// Here, insert your logic to process the spec and the combined RDF graph.
-		// For instance, you could call a Temporal workflow or a local function.
-		// The following is a dummy output to demonstrate the concept.
-		result := processSpec(spec, graphFile)
-
-		mermaidScript := generateMermaidForSpec(spec, graphFile, result)
-		data := struct {
-			Spec          string
-			GraphLocation string
-			Result        string
-			MermaidScript string
-		}{
-			Spec:          spec,
-			GraphLocation: graphFile,
-			Result:        result,
-			MermaidScript: mermaidScript,
+		graphName := r.FormValue("graph")
+		page := specPage{Spec: spec, Graph: graphName, Graphs: graphOptions()}
+
+		graph, err := loadGraph(graphName)
+		if err != nil {
+			log.Printf("Failed to load graph: %v", err)
+			page.Result = "Error loading graph."
+			renderTemplate(w, "templates/spec_form.html", page)
+			return
+		}
+		// Give the prompts only the part of the graph the spec is about; the whole graph outgrows the
+		// model's context.
+		specCtx, err := retrieveSpecContext(r.Context(), graph, spec)
+		if err != nil {
+			log.Printf("Failed to retrieve the graph for the spec: %v", err)
+			page.Result = "Error retrieving the graph for the spec."
+			renderTemplate(w, "templates/spec_form.html", page)
+			return
 		}
-		renderTemplate(w, "templates/spec_form.html", data)
+
+		var results []*impact.Result
+		page.Impact, results = specImpact(graph, spec)
+		page.Result = processSpec(r.Context(), spec, specCtx, page.Impact)
+		// The diagram is drawn from the graph; the LLM only picks the nodes to highlight.
+		page.MermaidScript = specDiagram(r.Context(), graph, results, specCtx, spec, page.Result)
+		renderTemplate(w, "templates/spec_form.html", page)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// loadGraph returns a graph of the store by name, or the union of all graphs if name is empty.
+func loadGraph(name string) (*rdf.Graph, error) {
+	if name == "" {
+		return graphStore.Union()
+	}
+	return graphStore.Graph(rdf.IRI(name))
+}
+
+// snapshotParam returns the graph versions a request selects with the commit or asOf parameter, or nil
+// for the latest versions if it has neither. asOf is a date or an RFC 3339 time.
+func snapshotParam(params url.Values) ([]store.GraphInfo, error) {
+	commit, asOf := params.Get("commit"), params.Get("asOf")
+	switch {
+	case commit != "" && asOf != "":
+		return nil, fmt.Errorf("%w: commit and asOf cannot be combined", errBadParameter)
+	case commit != "":
+		return graphStore.CommitSnapshot(commit)
+	case asOf != "":
+		t, err := store.ParseTime(asOf)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errBadParameter, err)
+		}
+		return graphStore.Snapshot(t)
+	}
+	return nil, nil
+}
+
+// loadSnapshotGraph is loadGraph for the versions of a snapshot, or for the latest versions if snapshot is
+// nil.
+func loadSnapshotGraph(name string, snapshot []store.GraphInfo) (*rdf.Graph, error) {
+	if snapshot == nil {
+		return loadGraph(name)
+	}
+	return graphStore.SnapshotGraph(snapshot, rdf.IRI(name))
+}
+
+// errBadParameter marks errors caused by invalid request parameters.
+var errBadParameter = errors.New("bad parameter")
+
+// writeStoreError reports an error of loading graphs: 404 for a missing graph, 400 for an invalid
+// parameter, and otherwise a logged internal server error.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errBadParameter), errors.Is(err, store.ErrInvalidCommit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
 // renderTemplate is a helper to render HTML templates.
 func renderTemplate(w http.ResponseWriter, tmplPath string, data interface{}) {
 	tmpl, err := template.ParseFiles(tmplPath)
@@ -75,37 +190,23 @@ func renderTemplate(w http.ResponseWriter, tmplPath string, data interface{}) {
 	}
 }
 
-// processSpec is a placeholder function that simulates processing the spec and RDF.
-func processSpec(spec, graphFile string) string {
-	promptFilePath := "prompts/spec_to_code.txt"
-
-	promptTemplate, err := buildcodegraph.ReadFileToString(promptFilePath)
-	if err != nil {
-		log.Printf("Failed to read prompt file: %v", err)
-		return "Error reading prompt file."
-	}
-
-	graphFileContent, err := buildcodegraph.ReadFileToString(graphFile)
-	if err != nil {
-		log.Printf("Failed to read graph file: %v", err)
-		return "Error reading graph file."
-	}
-
-	prompt := strings.ReplaceAll(promptTemplate, "{{.Spec}}", spec)
-	prompt = strings.ReplaceAll(prompt, "{{.CombinedRdf}}", graphFileContent)
+// specToCodeLLM is the LLM that proposes the code changes of a spec unless configured otherwise.
+var specToCodeLLM = llm.Config{Use: "SpecToCode", Provider: llm.Anthropic, Model: "claude-3-5-sonnet-20241022", MaxTokens: 2048}
 
-	response, err := buildcodegraph.CallClaudeApi(context.Background(), prompt)
+// complete sends a prompt to the LLM configured for a use, see llm.Load, and returns the response text.
+func complete(ctx context.Context, defaults llm.Config, prompt string) (string, error) {
+	client, err := llm.New(llm.Load(defaults))
 	if err != nil {
-		log.Printf("LLM call failed: %v", err)
-		return "LLM call failed."
+		return "", err
 	}
-
-	log.Printf("LLM response: %s", response)
-	return response
+	response, err := client.Complete(ctx, llm.Request{Prompt: prompt})
+	return response.Text, err
 }
 
-func generateMermaidForSpec(spec, graphFile, codeChange string) string {
-	promptFilePath := "prompts/spec_to_mermaid.txt"
+// processSpec asks the LLM how to implement the spec, given the overview and relevant subgraph of the graph
+// and the impact analysis of the nodes the spec mentions as the starting point.
+func processSpec(ctx context.Context, spec string, specCtx specContext, impactAnalysis string) string {
+	promptFilePath := "prompts/spec_to_code.txt"
 
 	promptTemplate, err := buildcodegraph.ReadFileToString(promptFilePath)
 	if err != nil {
@@ -113,21 +214,21 @@ func generateMermaidForSpec(spec, graphFile, codeChange string) string {
 		return "Error reading prompt file."
 	}
 
-	graphFileContent, err := buildcodegraph.ReadFileToString(graphFile)
-	if err != nil {
-		log.Printf("Failed to read graph file: %v", err)
-		return "Error reading graph file."
-	}
-
 	prompt := strings.ReplaceAll(promptTemplate, "{{.Spec}}", spec)
-	prompt = strings.ReplaceAll(prompt, "{{.CombinedRdf}}", graphFileContent)
-	prompt = strings.ReplaceAll(prompt, "{{.CodeChangeApproach}}", codeChange)
+	prompt = strings.ReplaceAll(prompt, "{{.Overview}}", specCtx.Overview)
+	prompt = strings.ReplaceAll(prompt, "{{.RelevantRdf}}", specCtx.Turtle)
+	prompt = strings.ReplaceAll(prompt, "{{.Ontology}}", ontology.Summary())
+	if impactAnalysis == "" {
+		impactAnalysis = "The specification does not name any node of the graph."
+	}
+	prompt = strings.ReplaceAll(prompt, "{{.Impact}}", impactAnalysis)
 
-	response, err := buildcodegraph.CallClaudeApi(context.Background(), prompt)
+	response, err := complete(ctx, specToCodeLLM, prompt)
 	if err != nil {
 		log.Printf("LLM call failed: %v", err)
 		return "LLM call failed."
 	}
 
+	log.Printf("LLM response: %s", response)
 	return response
 }
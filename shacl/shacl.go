@@ -0,0 +1,439 @@
+// Package shacl validates RDF graphs against a subset of the SHACL Core constraints.
+//
+// Supported targets are sh:targetClass, sh:targetNode, sh:targetSubjectsOf and sh:targetObjectsOf. Property
+// shapes support predicate and sh:inversePath paths with sh:minCount, sh:maxCount, sh:class, sh:datatype,
+// sh:nodeKind, sh:in, sh:hasValue, sh:pattern, sh:minLength and sh:maxLength. Node shapes support the same
+// value constraints on the focus node itself plus sh:closed with sh:ignoredProperties.
+package shacl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Namespace is the SHACL namespace.
+const Namespace = "http://www.w3.org/ns/shacl#"
+
+const (
+	shNodeShape          = rdf.IRI(Namespace + "NodeShape")
+	shTargetClass        = rdf.IRI(Namespace + "targetClass")
+	shTargetNode         = rdf.IRI(Namespace + "targetNode")
+	shTargetSubjectsOf   = rdf.IRI(Namespace + "targetSubjectsOf")
+	shTargetObjectsOf    = rdf.IRI(Namespace + "targetObjectsOf")
+	shProperty           = rdf.IRI(Namespace + "property")
+	shPath               = rdf.IRI(Namespace + "path")
+	shInversePath        = rdf.IRI(Namespace + "inversePath")
+	shMinCount           = rdf.IRI(Namespace + "minCount")
+	shMaxCount           = rdf.IRI(Namespace + "maxCount")
+	shClass              = rdf.IRI(Namespace + "class")
+	shDatatype           = rdf.IRI(Namespace + "datatype")
+	shNodeKind           = rdf.IRI(Namespace + "nodeKind")
+	shIn                 = rdf.IRI(Namespace + "in")
+	shHasValue           = rdf.IRI(Namespace + "hasValue")
+	shPattern            = rdf.IRI(Namespace + "pattern")
+	shFlags              = rdf.IRI(Namespace + "flags")
+	shMinLength          = rdf.IRI(Namespace + "minLength")
+	shMaxLength          = rdf.IRI(Namespace + "maxLength")
+	shClosed             = rdf.IRI(Namespace + "closed")
+	shIgnoredProperties  = rdf.IRI(Namespace + "ignoredProperties")
+	shMessage            = rdf.IRI(Namespace + "message")
+	shSeverity           = rdf.IRI(Namespace + "severity")
+	shDeactivated        = rdf.IRI(Namespace + "deactivated")
+	shIRI                = rdf.IRI(Namespace + "IRI")
+	shBlankNode          = rdf.IRI(Namespace + "BlankNode")
+	shLiteral            = rdf.IRI(Namespace + "Literal")
+	shBlankNodeOrIRI     = rdf.IRI(Namespace + "BlankNodeOrIRI")
+	shBlankNodeOrLiteral = rdf.IRI(Namespace + "BlankNodeOrLiteral")
+	shIRIOrLiteral       = rdf.IRI(Namespace + "IRIOrLiteral")
+
+	rdfsSubClassOf = rdf.IRI(rdf.RDFSNamespace + "subClassOf")
+)
+
+// Severity levels of validation results.
+const (
+	Violation = rdf.IRI(Namespace + "Violation")
+	Warning   = rdf.IRI(Namespace + "Warning")
+	Info      = rdf.IRI(Namespace + "Info")
+)
+
+// Result is a single validation result.
+type Result struct {
+	FocusNode  rdf.Term
+	Path       rdf.IRI // Empty for node-level constraints.
+	Value      rdf.Term
+	Constraint string // Constraint that failed, for example "sh:minCount".
+	Severity   rdf.IRI
+	Message    string
+}
+
+func (r Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", rdf.LocalName(r.Severity), r.FocusNode)
+	if r.Path != "" {
+		fmt.Fprintf(&b, " %s", r.Path)
+	}
+	if r.Value != nil {
+		fmt.Fprintf(&b, " value %s", r.Value)
+	}
+	fmt.Fprintf(&b, ": %s (%s)", r.Message, r.Constraint)
+	return b.String()
+}
+
+// Report is the outcome of validating a data graph.
+type Report struct {
+	Conforms bool
+	Results  []Result
+}
+
+// String lists the results one per line.
+func (r Report) String() string {
+	lines := make([]string, len(r.Results))
+	for i, result := range r.Results {
+		lines[i] = result.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the data graph against the shapes graph. Class membership for sh:class and
+// sh:targetClass follows rdfs:subClassOf statements from both graphs.
+func Validate(data, shapes *rdf.Graph) Report {
+	v := &validator{data: data, shapes: shapes}
+
+	for _, shape := range nodeShapes(shapes) {
+		if isTrue(shapes.Object(shape, shDeactivated)) {
+			continue
+		}
+		for _, focus := range v.targets(shape) {
+			v.validateNode(shape, focus)
+		}
+	}
+
+	sort.SliceStable(v.results, func(i, j int) bool {
+		return rdf.CompareTerms(v.results[i].FocusNode, v.results[j].FocusNode) < 0
+	})
+	report := Report{Conforms: true, Results: v.results}
+	for _, result := range v.results {
+		if result.Severity == Violation {
+			report.Conforms = false
+		}
+	}
+	return report
+}
+
+type validator struct {
+	data    *rdf.Graph
+	shapes  *rdf.Graph
+	results []Result
+}
+
+// nodeShapes returns every explicit node shape and every shape with a target.
+func nodeShapes(shapes *rdf.Graph) []rdf.Term {
+	seen := map[rdf.Term]bool{}
+	var result []rdf.Term
+	add := func(terms []rdf.Term) {
+		for _, t := range terms {
+			if !seen[t] {
+				seen[t] = true
+				result = append(result, t)
+			}
+		}
+	}
+	add(shapes.Subjects(rdf.RDFType, shNodeShape))
+	for _, predicate := range []rdf.IRI{shTargetClass, shTargetNode, shTargetSubjectsOf, shTargetObjectsOf} {
+		add(shapes.Subjects(predicate, nil))
+	}
+	return result
+}
+
+func (v *validator) targets(shape rdf.Term) []rdf.Term {
+	seen := map[rdf.Term]bool{}
+	var focus []rdf.Term
+	add := func(t rdf.Term) {
+		if !seen[t] {
+			seen[t] = true
+			focus = append(focus, t)
+		}
+	}
+
+	for _, class := range v.shapes.Objects(shape, shTargetClass) {
+		for _, subclass := range v.subclasses(class) {
+			for _, node := range v.data.Subjects(rdf.RDFType, subclass) {
+				add(node)
+			}
+		}
+	}
+	for _, node := range v.shapes.Objects(shape, shTargetNode) {
+		add(node)
+	}
+	for _, predicate := range v.shapes.Objects(shape, shTargetSubjectsOf) {
+		if p, ok := predicate.(rdf.IRI); ok {
+			for _, t := range v.data.Match(nil, p, nil) {
+				add(t.Subject)
+			}
+		}
+	}
+	for _, predicate := range v.shapes.Objects(shape, shTargetObjectsOf) {
+		if p, ok := predicate.(rdf.IRI); ok {
+			for _, t := range v.data.Match(nil, p, nil) {
+				add(t.Object)
+			}
+		}
+	}
+	return focus
+}
+
+// subclasses returns the class and all its transitive subclasses.
+func (v *validator) subclasses(class rdf.Term) []rdf.Term {
+	seen := map[rdf.Term]bool{class: true}
+	queue := []rdf.Term{class}
+	for i := 0; i < len(queue); i++ {
+		for _, g := range []*rdf.Graph{v.data, v.shapes} {
+			for _, sub := range g.Subjects(rdfsSubClassOf, queue[i]) {
+				if !seen[sub] {
+					seen[sub] = true
+					queue = append(queue, sub)
+				}
+			}
+		}
+	}
+	return queue
+}
+
+// isInstance reports whether the node has the class or one of its subclasses as rdf:type.
+func (v *validator) isInstance(node, class rdf.Term) bool {
+	for _, subclass := range v.subclasses(class) {
+		if v.data.Contains(rdf.Triple{Subject: node, Predicate: rdf.RDFType, Object: subclass}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateNode(shape, focus rdf.Term) {
+	v.validateValues(shape, focus, "", []rdf.Term{focus})
+
+	if isTrue(v.shapes.Object(shape, shClosed)) {
+		allowed := map[rdf.IRI]bool{}
+		for _, property := range v.shapes.Objects(shape, shProperty) {
+			if path, ok := v.shapes.Object(property, shPath).(rdf.IRI); ok {
+				allowed[path] = true
+			}
+		}
+		for _, ignored := range v.listItems(v.shapes.Object(shape, shIgnoredProperties)) {
+			if p, ok := ignored.(rdf.IRI); ok {
+				allowed[p] = true
+			}
+		}
+		for _, t := range v.data.Match(focus, "", nil) {
+			if !allowed[t.Predicate] {
+				v.report(shape, focus, t.Predicate, t.Object, "closed",
+					fmt.Sprintf("predicate %s is not allowed on this node", t.Predicate))
+			}
+		}
+	}
+
+	for _, property := range v.shapes.Objects(shape, shProperty) {
+		if isTrue(v.shapes.Object(property, shDeactivated)) {
+			continue
+		}
+		v.validateProperty(property, focus)
+	}
+}
+
+func (v *validator) validateProperty(property, focus rdf.Term) {
+	var path rdf.IRI
+	var values []rdf.Term
+	switch p := v.shapes.Object(property, shPath).(type) {
+	case rdf.IRI:
+		path = p
+		values = v.data.Objects(focus, p)
+	case rdf.BlankNode:
+		inverse, ok := v.shapes.Object(p, shInversePath).(rdf.IRI)
+		if !ok {
+			return
+		}
+		path = inverse
+		values = v.data.Subjects(inverse, focus)
+	default:
+		return
+	}
+
+	if min, ok := intValue(v.shapes.Object(property, shMinCount)); ok && len(values) < min {
+		v.report(property, focus, path, nil, "minCount",
+			fmt.Sprintf("expected at least %d value(s) but found %d", min, len(values)))
+	}
+	if max, ok := intValue(v.shapes.Object(property, shMaxCount)); ok && len(values) > max {
+		v.report(property, focus, path, nil, "maxCount",
+			fmt.Sprintf("expected at most %d value(s) but found %d", max, len(values)))
+	}
+	v.validateValues(property, focus, path, values)
+}
+
+// validateValues checks the value-type, string and enumeration constraints of a shape against each value.
+func (v *validator) validateValues(shape, focus rdf.Term, path rdf.IRI, values []rdf.Term) {
+	for _, class := range v.shapes.Objects(shape, shClass) {
+		for _, value := range values {
+			if !v.isInstance(value, class) {
+				v.report(shape, focus, path, value, "class", fmt.Sprintf("value must be an instance of %s", class))
+			}
+		}
+	}
+
+	if datatype, ok := v.shapes.Object(shape, shDatatype).(rdf.IRI); ok {
+		for _, value := range values {
+			if !hasDatatype(value, datatype) {
+				v.report(shape, focus, path, value, "datatype", fmt.Sprintf("value must be a literal of type %s", datatype))
+			}
+		}
+	}
+
+	if kind, ok := v.shapes.Object(shape, shNodeKind).(rdf.IRI); ok {
+		for _, value := range values {
+			if !hasNodeKind(value, kind) {
+				v.report(shape, focus, path, value, "nodeKind", fmt.Sprintf("value must be of node kind %s", kind))
+			}
+		}
+	}
+
+	if list := v.shapes.Object(shape, shIn); list != nil {
+		allowed := v.listItems(list)
+		for _, value := range values {
+			if !containsTerm(allowed, value) {
+				names := make([]string, len(allowed))
+				for i, a := range allowed {
+					names[i] = a.String()
+				}
+				v.report(shape, focus, path, value, "in", "value must be one of "+strings.Join(names, ", "))
+			}
+		}
+	}
+
+	for _, expected := range v.shapes.Objects(shape, shHasValue) {
+		if !containsTerm(values, expected) {
+			v.report(shape, focus, path, nil, "hasValue", fmt.Sprintf("expected value %s", expected))
+		}
+	}
+
+	if pattern := v.shapes.Object(shape, shPattern); pattern != nil {
+		expr := rdf.Value(pattern)
+		if flags := rdf.Value(v.shapes.Object(shape, shFlags)); strings.Contains(flags, "i") {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		for _, value := range values {
+			if _, blank := value.(rdf.BlankNode); blank || err != nil || !re.MatchString(rdf.Value(value)) {
+				v.report(shape, focus, path, value, "pattern", fmt.Sprintf("value must match %q", rdf.Value(pattern)))
+			}
+		}
+	}
+
+	if min, ok := intValue(v.shapes.Object(shape, shMinLength)); ok {
+		for _, value := range values {
+			if _, blank := value.(rdf.BlankNode); blank || len([]rune(rdf.Value(value))) < min {
+				v.report(shape, focus, path, value, "minLength", fmt.Sprintf("value must have at least %d characters", min))
+			}
+		}
+	}
+	if max, ok := intValue(v.shapes.Object(shape, shMaxLength)); ok {
+		for _, value := range values {
+			if _, blank := value.(rdf.BlankNode); blank || len([]rune(rdf.Value(value))) > max {
+				v.report(shape, focus, path, value, "maxLength", fmt.Sprintf("value must have at most %d characters", max))
+			}
+		}
+	}
+}
+
+func (v *validator) report(shape, focus rdf.Term, path rdf.IRI, value rdf.Term, constraint, message string) {
+	severity := Violation
+	if s, ok := v.shapes.Object(shape, shSeverity).(rdf.IRI); ok {
+		severity = s
+	}
+	if custom := v.shapes.Object(shape, shMessage); custom != nil {
+		message = rdf.Value(custom)
+	}
+	v.results = append(v.results, Result{
+		FocusNode:  focus,
+		Path:       path,
+		Value:      value,
+		Constraint: "sh:" + constraint,
+		Severity:   severity,
+		Message:    message,
+	})
+}
+
+// listItems returns the members of an RDF collection in the shapes graph.
+func (v *validator) listItems(list rdf.Term) []rdf.Term {
+	var items []rdf.Term
+	seen := map[rdf.Term]bool{}
+	for list != nil && list != rdf.RDFNil && !seen[list] {
+		seen[list] = true
+		if first := v.shapes.Object(list, rdf.RDFFirst); first != nil {
+			items = append(items, first)
+		}
+		list = v.shapes.Object(list, rdf.RDFRest)
+	}
+	return items
+}
+
+func hasDatatype(value rdf.Term, datatype rdf.IRI) bool {
+	literal, ok := value.(rdf.Literal)
+	if !ok {
+		return false
+	}
+	switch {
+	case literal.Language != "":
+		return datatype == rdf.RDFLangString
+	case literal.Datatype == "":
+		return datatype == rdf.XSDString
+	}
+	return literal.Datatype == datatype
+}
+
+func hasNodeKind(value rdf.Term, kind rdf.IRI) bool {
+	_, isIRI := value.(rdf.IRI)
+	_, isBlank := value.(rdf.BlankNode)
+	_, isLiteral := value.(rdf.Literal)
+	switch kind {
+	case shIRI:
+		return isIRI
+	case shBlankNode:
+		return isBlank
+	case shLiteral:
+		return isLiteral
+	case shBlankNodeOrIRI:
+		return isIRI || isBlank
+	case shBlankNodeOrLiteral:
+		return isBlank || isLiteral
+	case shIRIOrLiteral:
+		return isIRI || isLiteral
+	}
+	return false
+}
+
+func containsTerm(terms []rdf.Term, t rdf.Term) bool {
+	for _, candidate := range terms {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+func intValue(t rdf.Term) (int, bool) {
+	literal, ok := t.(rdf.Literal)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(literal.Value)
+	return n, err == nil
+}
+
+func isTrue(t rdf.Term) bool {
+	literal, ok := t.(rdf.Literal)
+	return ok && literal.Value == "true"
+}
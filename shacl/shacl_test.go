@@ -0,0 +1,174 @@
+package shacl
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+const prefixes = `
+@prefix sh: <http://www.w3.org/ns/shacl#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix ex: <http://example.com/> .
+`
+
+func mustParse(t *testing.T, document string) *rdf.Graph {
+	t.Helper()
+	g, err := rdf.ParseTurtle(prefixes+document, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// summary lists the results of a report as "focus constraint", sorted, with the example namespace left out
+// and blank nodes written as [].
+func summary(report Report) []string {
+	lines := []string{}
+	for _, result := range report.Results {
+		focus := strings.TrimPrefix(rdf.Value(result.FocusNode), "http://example.com/")
+		if _, blank := result.FocusNode.(rdf.BlankNode); blank {
+			focus = "[]"
+		}
+		lines = append(lines, focus+" "+result.Constraint)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		shapes string
+		data   string
+		want   []string
+	}{
+		{
+			name:   "min and max count",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:name ; sh:minCount 1 ; sh:maxCount 1 ] .`,
+			data:   `ex:a a ex:Service ; ex:name "a" . ex:b a ex:Service . ex:c a ex:Service ; ex:name "c", "see" .`,
+			want:   []string{"b sh:minCount", "c sh:maxCount"},
+		},
+		{
+			name:   "class through subclasses",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:uses ; sh:class ex:Resource ] .`,
+			data: `ex:Database rdfs:subClassOf ex:Resource .
+				ex:a a ex:Service ; ex:uses ex:db . ex:db a ex:Database .
+				ex:b a ex:Service ; ex:uses ex:thing .`,
+			want: []string{"b sh:class"},
+		},
+		{
+			name:   "target class includes subclasses",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Resource ; sh:property [ sh:path ex:name ; sh:minCount 1 ] . ex:Database rdfs:subClassOf ex:Resource .`,
+			data:   `ex:db a ex:Database .`,
+			want:   []string{"db sh:minCount"},
+		},
+		{
+			name:   "datatype",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:port ; sh:datatype xsd:integer ] .`,
+			data:   `ex:a a ex:Service ; ex:port 8080 . ex:b a ex:Service ; ex:port "8080" .`,
+			want:   []string{"b sh:datatype"},
+		},
+		{
+			name:   "node kind",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:calls ; sh:nodeKind sh:IRI ] .`,
+			data:   `ex:a a ex:Service ; ex:calls ex:b . ex:b a ex:Service ; ex:calls "a", [ ex:p 1 ] .`,
+			want:   []string{"b sh:nodeKind", "b sh:nodeKind"},
+		},
+		{
+			name:   "in",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:kind ; sh:in ( "grpc" "http" ) ] .`,
+			data:   `ex:a a ex:Service ; ex:kind "grpc" . ex:b a ex:Service ; ex:kind "soap" .`,
+			want:   []string{"b sh:in"},
+		},
+		{
+			name:   "has value",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:owner ; sh:hasValue ex:team ] .`,
+			data:   `ex:a a ex:Service ; ex:owner ex:team . ex:b a ex:Service ; ex:owner ex:other .`,
+			want:   []string{"b sh:hasValue"},
+		},
+		{
+			name:   "pattern with flags",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:name ; sh:pattern "^[a-z]+$" ; sh:flags "i" ] .`,
+			data:   `ex:a a ex:Service ; ex:name "Orders" . ex:b a ex:Service ; ex:name "orders-2" .`,
+			want:   []string{"b sh:pattern"},
+		},
+		{
+			name:   "string length",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:name ; sh:minLength 2 ; sh:maxLength 4 ] .`,
+			data:   `ex:a a ex:Service ; ex:name "ab" . ex:b a ex:Service ; ex:name "x" . ex:c a ex:Service ; ex:name "éééé" . ex:d a ex:Service ; ex:name "toolong" .`,
+			want:   []string{"b sh:minLength", "d sh:maxLength"},
+		},
+		{
+			name:   "inverse path",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Api ; sh:property [ sh:path [ sh:inversePath ex:exposes ] ; sh:minCount 1 ] .`,
+			data:   `ex:svc ex:exposes ex:a . ex:a a ex:Api . ex:b a ex:Api .`,
+			want:   []string{"b sh:minCount"},
+		},
+		{
+			name:   "target node",
+			shapes: `ex:S a sh:NodeShape ; sh:targetNode ex:a ; sh:property [ sh:path ex:name ; sh:minCount 1 ] .`,
+			data:   `ex:b a ex:Service .`,
+			want:   []string{"a sh:minCount"},
+		},
+		{
+			name:   "target subjects and objects of",
+			shapes: `ex:S a sh:NodeShape ; sh:targetSubjectsOf ex:calls ; sh:targetObjectsOf ex:readsFrom ; sh:property [ sh:path ex:name ; sh:minCount 1 ] .`,
+			data:   `ex:a ex:calls ex:b . ex:c ex:readsFrom ex:db ; ex:name "c" .`,
+			want:   []string{"a sh:minCount", "db sh:minCount"},
+		},
+		{
+			name:   "closed",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:closed true ; sh:ignoredProperties ( rdfs:label <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> ) ; sh:property [ sh:path ex:name ] .`,
+			data:   `ex:a a ex:Service ; ex:name "a" ; rdfs:label "A" . ex:b a ex:Service ; ex:other 1 .`,
+			want:   []string{"b sh:closed"},
+		},
+		{
+			name:   "node constraints on the focus node",
+			shapes: `ex:S a sh:NodeShape ; sh:targetSubjectsOf ex:calls ; sh:nodeKind sh:IRI .`,
+			data:   `ex:a ex:calls ex:b . [] ex:calls ex:b .`,
+			want:   []string{"[] sh:nodeKind"},
+		},
+		{
+			name: "deactivated shapes and properties",
+			shapes: `ex:S a sh:NodeShape ; sh:targetClass ex:Service ; sh:deactivated true ; sh:property [ sh:path ex:name ; sh:minCount 1 ] .
+				ex:T a sh:NodeShape ; sh:targetClass ex:Service ; sh:property [ sh:path ex:port ; sh:minCount 1 ; sh:deactivated true ] .`,
+			data: `ex:a a ex:Service .`,
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Validate(mustParse(t, tt.data), mustParse(t, tt.shapes))
+			got := summary(report)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("results:\n%s\nwant:\n%s", report, strings.Join(tt.want, "\n"))
+			}
+			if report.Conforms != (len(tt.want) == 0) {
+				t.Errorf("Conforms = %v with %d result(s)", report.Conforms, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSeverityAndMessage(t *testing.T) {
+	shapes := mustParse(t, `ex:S a sh:NodeShape ; sh:targetClass ex:Service ;
+		sh:property [ sh:path ex:description ; sh:minCount 1 ; sh:severity sh:Warning ; sh:message "describe the service" ] .`)
+	report := Validate(mustParse(t, `ex:a a ex:Service .`), shapes)
+	if !report.Conforms {
+		t.Error("a report with only warnings does not conform")
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("results:\n%s", report)
+	}
+	result := report.Results[0]
+	if result.Severity != Warning || result.Message != "describe the service" || result.Path != rdf.IRI("http://example.com/description") {
+		t.Errorf("result = %+v", result)
+	}
+	if want := "[Warning] <http://example.com/a> <http://example.com/description>: describe the service (sh:minCount)"; result.String() != want {
+		t.Errorf("String = %q, want %q", result.String(), want)
+	}
+}
@@ -0,0 +1,969 @@
+package sparql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Dataset is the RDF dataset a query runs against: a default graph and any number of named graphs,
+// which GRAPH patterns select.
+type Dataset struct {
+	Default *rdf.Graph
+	Named   map[rdf.IRI]*rdf.Graph
+}
+
+// Solution maps variable names to the terms bound to them.
+type Solution map[string]rdf.Term
+
+// Result is the outcome of a query. SELECT queries fill Variables and Solutions, ASK queries Boolean, and
+// CONSTRUCT and DESCRIBE queries Graph.
+type Result struct {
+	Form      Form
+	Variables []string
+	Solutions []Solution
+	Boolean   bool
+	Graph     *rdf.Graph
+}
+
+// Execute runs a parsed query against a dataset. It stops with the context's error when the context is
+// canceled or its deadline passes.
+func Execute(ctx context.Context, q *Query, ds *Dataset) (*Result, error) {
+	if ds == nil {
+		ds = &Dataset{}
+	}
+	defaultGraph := ds.Default
+	if defaultGraph == nil {
+		defaultGraph = rdf.NewGraph()
+	}
+	ev := &evaluator{
+		ctx:     ctx,
+		dataset: ds,
+		regexps: map[string]*regexp.Regexp{},
+		now:     time.Now().UTC(),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	result := &Result{Form: q.Form}
+	switch q.Form {
+	case Ask:
+		solutions, err := ev.evalGroup(q.where, defaultGraph, []Solution{{}})
+		if err != nil {
+			return nil, err
+		}
+		result.Boolean = len(solutions) > 0
+	case Select:
+		solutions, variables, err := ev.evalSelect(q, defaultGraph)
+		if err != nil {
+			return nil, err
+		}
+		result.Variables, result.Solutions = variables, solutions
+	case Construct:
+		solutions, err := ev.evalSolutions(q, defaultGraph)
+		if err != nil {
+			return nil, err
+		}
+		result.Graph = ev.construct(q, solutions)
+	case Describe:
+		solutions, err := ev.evalSolutions(q, defaultGraph)
+		if err != nil {
+			return nil, err
+		}
+		result.Graph = ev.describe(q, defaultGraph, solutions)
+	}
+	return result, nil
+}
+
+type evaluator struct {
+	ctx        context.Context
+	dataset    *Dataset
+	regexps    map[string]*regexp.Regexp
+	now        time.Time
+	random     *rand.Rand
+	blankCount int
+}
+
+// evalGroup evaluates a group graph pattern for each input solution and returns the extended solutions.
+func (ev *evaluator) evalGroup(g *group, graph *rdf.Graph, input []Solution) ([]Solution, error) {
+	solutions := input
+	var err error
+	for _, el := range g.elements {
+		if solutions, err = ev.evalElement(el, graph, solutions); err != nil {
+			return nil, err
+		}
+		if len(solutions) == 0 {
+			break
+		}
+	}
+	if len(g.filters) == 0 {
+		return solutions, nil
+	}
+
+	ctx := &exprContext{ev: ev, graph: graph}
+	var filtered []Solution
+	for _, s := range solutions {
+		keep := true
+		for _, f := range g.filters {
+			ok, err := evalBoolean(ctx, f, s)
+			if err != nil && !errors.Is(err, errTypeError) {
+				return nil, err
+			}
+			if !ok {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered, nil
+}
+
+func (ev *evaluator) evalElement(el element, graph *rdf.Graph, solutions []Solution) ([]Solution, error) {
+	if err := ev.ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch e := el.(type) {
+	case *triplesBlock:
+		return ev.evalTriples(e.patterns, graph, solutions)
+	case *group:
+		return ev.evalGroup(e, graph, solutions)
+	case *optionalElement:
+		var result []Solution
+		for _, s := range solutions {
+			extended, err := ev.evalGroup(e.group, graph, []Solution{s})
+			if err != nil {
+				return nil, err
+			}
+			if len(extended) == 0 {
+				result = append(result, s)
+			}
+			result = append(result, extended...)
+		}
+		return result, nil
+	case *unionElement:
+		var result []Solution
+		for _, branch := range e.groups {
+			extended, err := ev.evalGroup(branch, graph, solutions)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, extended...)
+		}
+		return result, nil
+	case *minusElement:
+		excluded, err := ev.evalGroup(e.group, graph, []Solution{{}})
+		if err != nil {
+			return nil, err
+		}
+		var result []Solution
+		for _, s := range solutions {
+			keep := true
+			for _, x := range excluded {
+				if compatible(s, x) && sharesVariable(s, x) {
+					keep = false
+					break
+				}
+			}
+			if keep {
+				result = append(result, s)
+			}
+		}
+		return result, nil
+	case *graphElement:
+		return ev.evalGraph(e, solutions)
+	case *bindElement:
+		ctx := &exprContext{ev: ev, graph: graph}
+		result := make([]Solution, 0, len(solutions))
+		for _, s := range solutions {
+			value, err := e.expr.eval(ctx, s)
+			if err != nil && !errors.Is(err, errTypeError) {
+				return nil, err
+			}
+			if err == nil {
+				s = s.with(e.variable, value)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	case *valuesBlock:
+		return join(solutions, e.solutions()), nil
+	case *subQuery:
+		inner, _, err := ev.evalSelect(e.query, graph)
+		if err != nil {
+			return nil, err
+		}
+		return join(solutions, inner), nil
+	}
+	return nil, fmt.Errorf("unsupported pattern %T", el)
+}
+
+// evalGraph evaluates a GRAPH pattern against the named graphs of the dataset.
+func (ev *evaluator) evalGraph(e *graphElement, solutions []Solution) ([]Solution, error) {
+	if !e.name.isVar() {
+		named, ok := ev.dataset.Named[iriOf(e.name.term)]
+		if !ok {
+			return nil, nil
+		}
+		return ev.evalGroup(e.group, named, solutions)
+	}
+
+	names := make([]rdf.IRI, 0, len(ev.dataset.Named))
+	for name := range ev.dataset.Named {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	var result []Solution
+	for _, s := range solutions {
+		if bound, ok := s[e.name.variable]; ok {
+			named, ok := ev.dataset.Named[iriOf(bound)]
+			if !ok {
+				continue
+			}
+			extended, err := ev.evalGroup(e.group, named, []Solution{s})
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, extended...)
+			continue
+		}
+		for _, name := range names {
+			extended, err := ev.evalGroup(e.group, ev.dataset.Named[name], []Solution{s.with(e.name.variable, name)})
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, extended...)
+		}
+	}
+	return result, nil
+}
+
+func iriOf(t rdf.Term) rdf.IRI {
+	iri, _ := t.(rdf.IRI)
+	return iri
+}
+
+// evalTriples matches a basic graph pattern. Patterns are matched one at a time, always picking the
+// pattern with the most bound positions next so that the graph indexes narrow the search.
+func (ev *evaluator) evalTriples(patterns []triplePattern, graph *rdf.Graph, solutions []Solution) ([]Solution, error) {
+	remaining := append([]triplePattern{}, patterns...)
+	bound := map[string]bool{}
+	if len(solutions) > 0 {
+		for name := range solutions[0] {
+			bound[name] = true
+		}
+	}
+
+	for len(remaining) > 0 && len(solutions) > 0 {
+		best, bestScore := 0, -1
+		for i, tp := range remaining {
+			if score := boundScore(tp, bound); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		tp := remaining[best]
+		remaining = append(remaining[:best], remaining[best+1:]...)
+
+		var next []Solution
+		for _, s := range solutions {
+			if err := ev.ctx.Err(); err != nil {
+				return nil, err
+			}
+			next = append(next, ev.matchPattern(tp, graph, s)...)
+		}
+		solutions = next
+		for _, name := range patternVariables(tp) {
+			bound[name] = true
+		}
+	}
+	return solutions, nil
+}
+
+func boundScore(tp triplePattern, bound map[string]bool) int {
+	score := 0
+	isBound := func(n node) bool { return !n.isVar() || bound[n.variable] }
+	if isBound(tp.subject) {
+		score += 2
+	}
+	if isBound(tp.object) {
+		score += 2
+	}
+	switch p := tp.path.(type) {
+	case linkPath:
+		score++
+	case varPath:
+		if bound[p.name] {
+			score++
+		}
+	default:
+		score-- // paths are expensive; evaluate them once their ends are bound
+	}
+	return score
+}
+
+func patternVariables(tp triplePattern) []string {
+	var names []string
+	if tp.subject.isVar() {
+		names = append(names, tp.subject.variable)
+	}
+	if p, ok := tp.path.(varPath); ok {
+		names = append(names, p.name)
+	}
+	if tp.object.isVar() {
+		names = append(names, tp.object.variable)
+	}
+	return names
+}
+
+// matchPattern returns the extensions of s that match one triple pattern.
+func (ev *evaluator) matchPattern(tp triplePattern, graph *rdf.Graph, s Solution) []Solution {
+	subject, object := s.resolve(tp.subject), s.resolve(tp.object)
+	if _, isLiteral := subject.(rdf.Literal); isLiteral {
+		return nil
+	}
+
+	var result []Solution
+	bind := func(extra map[string]rdf.Term) {
+		extended := s
+		for name, value := range extra {
+			if existing, ok := extended[name]; ok {
+				if existing != value {
+					return
+				}
+				continue
+			}
+			extended = extended.with(name, value)
+		}
+		result = append(result, extended)
+	}
+
+	if p, ok := tp.path.(varPath); ok {
+		var predicate rdf.IRI
+		if bound, ok := s[p.name]; ok {
+			if predicate, ok = bound.(rdf.IRI); !ok {
+				return nil
+			}
+		}
+		for _, t := range graph.Match(subject, predicate, object) {
+			extra := map[string]rdf.Term{}
+			if tp.subject.isVar() && subject == nil {
+				extra[tp.subject.variable] = t.Subject
+			}
+			if predicate == "" {
+				if existing, ok := extra[p.name]; ok && existing != t.Predicate {
+					continue
+				}
+				extra[p.name] = t.Predicate
+			}
+			if tp.object.isVar() && object == nil {
+				if existing, ok := extra[tp.object.variable]; ok && existing != t.Object {
+					continue
+				}
+				extra[tp.object.variable] = t.Object
+			}
+			bind(extra)
+		}
+		return result
+	}
+
+	for _, pair := range evalPath(tp.path, graph, subject, object) {
+		extra := map[string]rdf.Term{}
+		if tp.subject.isVar() && subject == nil {
+			extra[tp.subject.variable] = pair[0]
+		}
+		if tp.object.isVar() && object == nil {
+			if existing, ok := extra[tp.object.variable]; ok && existing != pair[1] {
+				continue
+			}
+			extra[tp.object.variable] = pair[1]
+		}
+		bind(extra)
+	}
+	return result
+}
+
+// evalPath returns the (subject, object) pairs connected by a property path. A nil end is unbound.
+func evalPath(p path, graph *rdf.Graph, subject, object rdf.Term) [][2]rdf.Term {
+	switch p := p.(type) {
+	case linkPath:
+		var pairs [][2]rdf.Term
+		for _, t := range graph.Match(subject, p.iri, object) {
+			pairs = append(pairs, [2]rdf.Term{t.Subject, t.Object})
+		}
+		return pairs
+	case inversePath:
+		var pairs [][2]rdf.Term
+		for _, pair := range evalPath(p.path, graph, object, subject) {
+			pairs = append(pairs, [2]rdf.Term{pair[1], pair[0]})
+		}
+		return pairs
+	case alternativePath:
+		return append(evalPath(p.left, graph, subject, object), evalPath(p.right, graph, subject, object)...)
+	case sequencePath:
+		var pairs [][2]rdf.Term
+		if subject == nil && object != nil {
+			for _, mid := range evalPath(p.rest, graph, nil, object) {
+				for _, start := range evalPath(p.first, graph, nil, mid[0]) {
+					pairs = append(pairs, [2]rdf.Term{start[0], mid[1]})
+				}
+			}
+			return pairs
+		}
+		for _, mid := range evalPath(p.first, graph, subject, nil) {
+			for _, end := range evalPath(p.rest, graph, mid[1], object) {
+				pairs = append(pairs, [2]rdf.Term{mid[0], end[1]})
+			}
+		}
+		return pairs
+	case repeatPath:
+		return evalRepeatPath(p, graph, subject, object)
+	case negatedPath:
+		excluded := func(list []rdf.IRI, iri rdf.IRI) bool {
+			for _, x := range list {
+				if x == iri {
+					return true
+				}
+			}
+			return false
+		}
+		var pairs [][2]rdf.Term
+		if len(p.forward) > 0 || len(p.inverse) == 0 {
+			for _, t := range graph.Match(subject, "", object) {
+				if !excluded(p.forward, t.Predicate) {
+					pairs = append(pairs, [2]rdf.Term{t.Subject, t.Object})
+				}
+			}
+		}
+		if len(p.inverse) > 0 {
+			for _, t := range graph.Match(object, "", subject) {
+				if !excluded(p.inverse, t.Predicate) {
+					pairs = append(pairs, [2]rdf.Term{t.Object, t.Subject})
+				}
+			}
+		}
+		return pairs
+	}
+	return nil
+}
+
+// evalRepeatPath evaluates p*, p+ and p? by walking the graph from the bound end, visiting each node once.
+func evalRepeatPath(p repeatPath, graph *rdf.Graph, subject, object rdf.Term) [][2]rdf.Term {
+	reach := func(start rdf.Term, forward bool) []rdf.Term {
+		var reached []rdf.Term
+		visited := map[rdf.Term]bool{}
+		if p.min == 0 {
+			reached = append(reached, start)
+			visited[start] = true
+		}
+		frontier := []rdf.Term{start}
+		for step := 0; len(frontier) > 0 && (p.unbounded || step < 1); step++ {
+			var next []rdf.Term
+			for _, n := range frontier {
+				var pairs [][2]rdf.Term
+				if forward {
+					pairs = evalPath(p.path, graph, n, nil)
+				} else {
+					pairs = evalPath(p.path, graph, nil, n)
+				}
+				for _, pair := range pairs {
+					end := pair[1]
+					if !forward {
+						end = pair[0]
+					}
+					if !visited[end] {
+						visited[end] = true
+						reached = append(reached, end)
+						next = append(next, end)
+					}
+				}
+			}
+			frontier = next
+		}
+		return reached
+	}
+
+	var pairs [][2]rdf.Term
+	switch {
+	case subject != nil:
+		for _, end := range reach(subject, true) {
+			if object == nil || end == object {
+				pairs = append(pairs, [2]rdf.Term{subject, end})
+			}
+		}
+	case object != nil:
+		for _, start := range reach(object, false) {
+			pairs = append(pairs, [2]rdf.Term{start, object})
+		}
+	default:
+		// Both ends unbound: start from every node that can begin the path, or every node for zero length.
+		var starts []rdf.Term
+		seen := map[rdf.Term]bool{}
+		add := func(t rdf.Term) {
+			if !seen[t] {
+				seen[t] = true
+				starts = append(starts, t)
+			}
+		}
+		if p.min == 0 {
+			for _, t := range graph.Triples() {
+				add(t.Subject)
+				add(t.Object)
+			}
+		} else {
+			for _, pair := range evalPath(p.path, graph, nil, nil) {
+				add(pair[0])
+			}
+		}
+		for _, start := range starts {
+			for _, end := range reach(start, true) {
+				pairs = append(pairs, [2]rdf.Term{start, end})
+			}
+		}
+	}
+	return pairs
+}
+
+// evalSolutions evaluates the WHERE clause, trailing VALUES and solution modifiers of a CONSTRUCT or
+// DESCRIBE query.
+func (ev *evaluator) evalSolutions(q *Query, graph *rdf.Graph) ([]Solution, error) {
+	solutions, err := ev.evalGroup(q.where, graph, []Solution{{}})
+	if err != nil {
+		return nil, err
+	}
+	if q.values != nil {
+		solutions = join(solutions, q.values.solutions())
+	}
+	solutions, err = ev.order(q, graph, solutions, nil)
+	if err != nil {
+		return nil, err
+	}
+	return slice(solutions, q.offset, q.limit), nil
+}
+
+// evalSelect evaluates a SELECT query or subquery and returns its projected solutions and variables.
+func (ev *evaluator) evalSelect(q *Query, graph *rdf.Graph) ([]Solution, []string, error) {
+	solutions, err := ev.evalGroup(q.where, graph, []Solution{{}})
+	if err != nil {
+		return nil, nil, err
+	}
+	if q.values != nil {
+		solutions = join(solutions, q.values.solutions())
+	}
+
+	var groups [][]Solution
+	if q.aggregated {
+		if solutions, groups, err = ev.aggregate(q, graph, solutions); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Compute projected expressions in order, so later ones can use earlier ones.
+	for _, proj := range q.projection {
+		if proj.expr == nil {
+			continue
+		}
+		for i, s := range solutions {
+			ctx := &exprContext{ev: ev, graph: graph}
+			if groups != nil {
+				ctx.group = groups[i]
+			}
+			value, err := proj.expr.eval(ctx, s)
+			if err != nil && !errors.Is(err, errTypeError) {
+				return nil, nil, err
+			}
+			if err == nil {
+				solutions[i] = s.with(proj.name, value)
+			}
+		}
+	}
+
+	if solutions, err = ev.order(q, graph, solutions, groups); err != nil {
+		return nil, nil, err
+	}
+
+	variables := q.variables()
+	projected := make([]Solution, 0, len(solutions))
+	seen := map[string]bool{}
+	for _, s := range solutions {
+		p := Solution{}
+		for _, name := range variables {
+			if value, ok := s[name]; ok {
+				p[name] = value
+			}
+		}
+		if q.distinct {
+			key := solutionKey(p, variables)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		projected = append(projected, p)
+	}
+	return slice(projected, q.offset, q.limit), variables, nil
+}
+
+// aggregate groups solutions by the GROUP BY conditions and filters the groups with HAVING. It returns one
+// solution per group, binding the grouping variables, together with the solutions of each group.
+func (ev *evaluator) aggregate(q *Query, graph *rdf.Graph, solutions []Solution) ([]Solution, [][]Solution, error) {
+	var keys []string
+	grouped := map[string][]Solution{}
+	bases := map[string]Solution{}
+	ctx := &exprContext{ev: ev, graph: graph}
+
+	for _, s := range solutions {
+		base := Solution{}
+		var key strings.Builder
+		for i, condition := range q.groupBy {
+			value, err := condition.expr.eval(ctx, s)
+			if err != nil && !errors.Is(err, errTypeError) {
+				return nil, nil, err
+			}
+			if value != nil {
+				key.WriteString(value.String())
+				if condition.name != "" {
+					base[condition.name] = value
+				}
+			}
+			key.WriteString(fmt.Sprintf("|%d|", i))
+		}
+		k := key.String()
+		if _, ok := grouped[k]; !ok {
+			keys = append(keys, k)
+			bases[k] = base
+		}
+		grouped[k] = append(grouped[k], s)
+	}
+	// Without GROUP BY all solutions form one group, even when there are none.
+	if len(q.groupBy) == 0 && len(keys) == 0 {
+		keys = append(keys, "")
+		bases[""] = Solution{}
+		grouped[""] = []Solution{}
+	}
+
+	var result []Solution
+	var groups [][]Solution
+	for _, k := range keys {
+		groupCtx := &exprContext{ev: ev, graph: graph, group: grouped[k]}
+		keep := true
+		for _, condition := range q.having {
+			ok, err := evalBoolean(groupCtx, condition, bases[k])
+			if err != nil && !errors.Is(err, errTypeError) {
+				return nil, nil, err
+			}
+			if !ok {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, bases[k])
+			groups = append(groups, grouped[k])
+		}
+	}
+	return result, groups, nil
+}
+
+// order sorts solutions by the ORDER BY conditions. groups holds the group of each solution in aggregate
+// queries and is nil otherwise.
+func (ev *evaluator) order(q *Query, graph *rdf.Graph, solutions []Solution, groups [][]Solution) ([]Solution, error) {
+	if len(q.orderBy) == 0 {
+		return solutions, nil
+	}
+	keys := make([][]rdf.Term, len(solutions))
+	for i, s := range solutions {
+		ctx := &exprContext{ev: ev, graph: graph}
+		if groups != nil {
+			ctx.group = groups[i]
+		}
+		for _, condition := range q.orderBy {
+			value, err := condition.expr.eval(ctx, s)
+			if err != nil && !errors.Is(err, errTypeError) {
+				return nil, err
+			}
+			keys[i] = append(keys[i], value)
+		}
+	}
+
+	indexes := make([]int, len(solutions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		for c, condition := range q.orderBy {
+			cmp := orderTerms(keys[indexes[a]][c], keys[indexes[b]][c])
+			if condition.descending {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+	sorted := make([]Solution, len(solutions))
+	for i, index := range indexes {
+		sorted[i] = solutions[index]
+	}
+	return sorted, nil
+}
+
+// construct instantiates the CONSTRUCT template for every solution. Blank nodes of the template are
+// fresh for each solution, and triples with unbound or invalid positions are left out.
+func (ev *evaluator) construct(q *Query, solutions []Solution) *rdf.Graph {
+	g := rdf.NewGraph()
+	for prefix, namespace := range q.Prefixes {
+		g.BindPrefix(prefix, namespace)
+	}
+	for _, s := range solutions {
+		blanks := map[string]rdf.BlankNode{}
+		instantiate := func(n node) rdf.Term {
+			if !n.isVar() {
+				return n.term
+			}
+			if strings.HasPrefix(n.variable, "_:") {
+				if _, ok := blanks[n.variable]; !ok {
+					blanks[n.variable] = g.NewBlankNode()
+				}
+				return blanks[n.variable]
+			}
+			return s[n.variable]
+		}
+		for _, tp := range q.template {
+			subject, object := instantiate(tp.subject), instantiate(tp.object)
+			var predicate rdf.IRI
+			switch p := tp.path.(type) {
+			case linkPath:
+				predicate = p.iri
+			case varPath:
+				predicate, _ = s[p.name].(rdf.IRI)
+			}
+			if _, isLiteral := subject.(rdf.Literal); subject == nil || isLiteral || predicate == "" || object == nil {
+				continue
+			}
+			g.AddTriple(subject, predicate, object)
+		}
+	}
+	return g
+}
+
+// describe returns the triples about each described resource, following blank node objects so their
+// descriptions are complete.
+func (ev *evaluator) describe(q *Query, graph *rdf.Graph, solutions []Solution) *rdf.Graph {
+	g := rdf.NewGraph()
+	for prefix, namespace := range q.Prefixes {
+		g.BindPrefix(prefix, namespace)
+	}
+
+	var resources []rdf.Term
+	if q.star {
+		for _, s := range solutions {
+			for _, value := range s {
+				resources = append(resources, value)
+			}
+		}
+	}
+	for _, n := range q.describe {
+		if !n.isVar() {
+			resources = append(resources, n.term)
+			continue
+		}
+		for _, s := range solutions {
+			if value, ok := s[n.variable]; ok {
+				resources = append(resources, value)
+			}
+		}
+	}
+
+	visited := map[rdf.Term]bool{}
+	var visit func(resource rdf.Term)
+	visit = func(resource rdf.Term) {
+		if visited[resource] {
+			return
+		}
+		visited[resource] = true
+		for _, t := range graph.Match(resource, "", nil) {
+			g.Add(t)
+			if _, ok := t.Object.(rdf.BlankNode); ok {
+				visit(t.Object)
+			}
+		}
+	}
+	for _, resource := range resources {
+		if _, ok := resource.(rdf.Literal); !ok {
+			visit(resource)
+		}
+	}
+	return g
+}
+
+// variables returns the projected variables: the selected ones, or for SELECT * every visible variable of
+// the WHERE clause in order of appearance.
+func (q *Query) variables() []string {
+	if !q.star {
+		names := make([]string, len(q.projection))
+		for i, proj := range q.projection {
+			names[i] = proj.name
+		}
+		return names
+	}
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !strings.HasPrefix(name, "_:") && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var walk func(g *group)
+	walk = func(g *group) {
+		for _, el := range g.elements {
+			switch e := el.(type) {
+			case *triplesBlock:
+				for _, tp := range e.patterns {
+					for _, name := range patternVariables(tp) {
+						add(name)
+					}
+				}
+			case *group:
+				walk(e)
+			case *optionalElement:
+				walk(e.group)
+			case *unionElement:
+				for _, branch := range e.groups {
+					walk(branch)
+				}
+			case *graphElement:
+				add(e.name.variable)
+				walk(e.group)
+			case *bindElement:
+				add(e.variable)
+			case *valuesBlock:
+				for _, name := range e.variables {
+					add(name)
+				}
+			case *subQuery:
+				for _, name := range e.query.variables() {
+					add(name)
+				}
+			}
+		}
+	}
+	walk(q.where)
+	if q.values != nil {
+		for _, name := range q.values.variables {
+			add(name)
+		}
+	}
+	return names
+}
+
+func (v *valuesBlock) solutions() []Solution {
+	solutions := make([]Solution, 0, len(v.rows))
+	for _, row := range v.rows {
+		s := Solution{}
+		for i, value := range row {
+			if value != nil {
+				s[v.variables[i]] = value
+			}
+		}
+		solutions = append(solutions, s)
+	}
+	return solutions
+}
+
+// with returns a copy of the solution with one more binding.
+func (s Solution) with(name string, value rdf.Term) Solution {
+	extended := make(Solution, len(s)+1)
+	for k, v := range s {
+		extended[k] = v
+	}
+	extended[name] = value
+	return extended
+}
+
+// resolve returns the term at a pattern position, or nil for an unbound variable.
+func (s Solution) resolve(n node) rdf.Term {
+	if !n.isVar() {
+		return n.term
+	}
+	return s[n.variable]
+}
+
+func compatible(a, b Solution) bool {
+	for name, value := range a {
+		if other, ok := b[name]; ok && other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func sharesVariable(a, b Solution) bool {
+	for name := range a {
+		if _, ok := b[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func join(left, right []Solution) []Solution {
+	var result []Solution
+	for _, l := range left {
+		for _, r := range right {
+			if !compatible(l, r) {
+				continue
+			}
+			merged := make(Solution, len(l)+len(r))
+			for k, v := range l {
+				merged[k] = v
+			}
+			for k, v := range r {
+				merged[k] = v
+			}
+			result = append(result, merged)
+		}
+	}
+	return result
+}
+
+func slice(solutions []Solution, offset, limit int) []Solution {
+	if offset >= len(solutions) {
+		return nil
+	}
+	solutions = solutions[offset:]
+	if limit >= 0 && limit < len(solutions) {
+		solutions = solutions[:limit]
+	}
+	return solutions
+}
+
+// solutionKey identifies a solution for DISTINCT. With nil variables every binding is used.
+func solutionKey(s Solution, variables []string) string {
+	if variables == nil {
+		for name := range s {
+			variables = append(variables, name)
+		}
+		sort.Strings(variables)
+	}
+	var b strings.Builder
+	for _, name := range variables {
+		b.WriteString(name)
+		b.WriteByte('=')
+		if value, ok := s[name]; ok {
+			b.WriteString(value.String())
+		}
+		b.WriteByte('\x00')
+	}
+	return b.String()
+}
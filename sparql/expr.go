@@ -0,0 +1,906 @@
+package sparql
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// errTypeError is the SPARQL expression error: filters treat it as false and BIND leaves the variable
+// unbound.
+var errTypeError = errors.New("type error")
+
+// expr is a SPARQL expression.
+type expr interface {
+	eval(ctx *exprContext, s Solution) (rdf.Term, error)
+}
+
+// exprContext is what expressions may need besides the solution: the active graph for EXISTS and the
+// solutions of the current group for aggregates.
+type exprContext struct {
+	ev    *evaluator
+	graph *rdf.Graph
+	group []Solution // nil outside aggregate queries.
+}
+
+type (
+	varExpr   struct{ name string }
+	constExpr struct{ term rdf.Term }
+	unaryExpr struct {
+		op      string
+		operand expr
+	}
+	binaryExpr struct {
+		op          string
+		left, right expr
+	}
+	inExpr struct {
+		operand expr
+		list    []expr
+		negated bool
+	}
+	callExpr struct {
+		name string // Upper-case builtin name or the IRI of a cast function.
+		args []expr
+	}
+	existsExpr struct {
+		group   *group
+		negated bool
+	}
+	aggregateExpr struct {
+		name      string
+		distinct  bool
+		arg       expr // nil for COUNT(*).
+		separator string
+	}
+)
+
+var (
+	trueLiteral  = rdf.NewTypedLiteral("true", rdf.XSDBoolean)
+	falseLiteral = rdf.NewTypedLiteral("false", rdf.XSDBoolean)
+)
+
+func booleanLiteral(b bool) rdf.Term {
+	if b {
+		return trueLiteral
+	}
+	return falseLiteral
+}
+
+func (e *varExpr) eval(_ *exprContext, s Solution) (rdf.Term, error) {
+	if t, ok := s[e.name]; ok {
+		return t, nil
+	}
+	return nil, errTypeError
+}
+
+func (e *constExpr) eval(*exprContext, Solution) (rdf.Term, error) {
+	return e.term, nil
+}
+
+func (e *unaryExpr) eval(ctx *exprContext, s Solution) (rdf.Term, error) {
+	value, err := e.operand.eval(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "!":
+		b, err := effectiveBoolean(value)
+		if err != nil {
+			return nil, err
+		}
+		return booleanLiteral(!b), nil
+	case "-":
+		n, ok := toNumeric(value)
+		if !ok {
+			return nil, errTypeError
+		}
+		return arithmetic("-", numeric{kind: n.kind}, n)
+	default:
+		if _, ok := toNumeric(value); !ok {
+			return nil, errTypeError
+		}
+		return value, nil
+	}
+}
+
+func (e *binaryExpr) eval(ctx *exprContext, s Solution) (rdf.Term, error) {
+	// Logical operators tolerate an error on one side if the other side decides the result.
+	if e.op == "||" || e.op == "&&" {
+		left, leftErr := evalBoolean(ctx, e.left, s)
+		right, rightErr := evalBoolean(ctx, e.right, s)
+		decisive := e.op == "||"
+		switch {
+		case leftErr == nil && left == decisive, rightErr == nil && right == decisive:
+			return booleanLiteral(decisive), nil
+		case leftErr != nil:
+			return nil, leftErr
+		case rightErr != nil:
+			return nil, rightErr
+		}
+		return booleanLiteral(!decisive), nil
+	}
+
+	left, err := e.left.eval(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "=", "!=":
+		equal, err := valuesEqual(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return booleanLiteral(equal == (e.op == "=")), nil
+	case "<", ">", "<=", ">=":
+		c, err := compareValues(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "<":
+			return booleanLiteral(c < 0), nil
+		case ">":
+			return booleanLiteral(c > 0), nil
+		case "<=":
+			return booleanLiteral(c <= 0), nil
+		}
+		return booleanLiteral(c >= 0), nil
+	}
+	a, ok := toNumeric(left)
+	b, ok2 := toNumeric(right)
+	if !ok || !ok2 {
+		return nil, errTypeError
+	}
+	return arithmetic(e.op, a, b)
+}
+
+func (e *inExpr) eval(ctx *exprContext, s Solution) (rdf.Term, error) {
+	value, err := e.operand.eval(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, item := range e.list {
+		candidate, err := item.eval(ctx, s)
+		if err == nil {
+			var equal bool
+			if equal, err = valuesEqual(value, candidate); err == nil && equal {
+				return booleanLiteral(!e.negated), nil
+			}
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return booleanLiteral(e.negated), nil
+}
+
+func (e *existsExpr) eval(ctx *exprContext, s Solution) (rdf.Term, error) {
+	solutions, err := ctx.ev.evalGroup(e.group, ctx.graph, []Solution{s})
+	if err != nil {
+		return nil, err
+	}
+	return booleanLiteral((len(solutions) > 0) != e.negated), nil
+}
+
+func (e *aggregateExpr) eval(ctx *exprContext, _ Solution) (rdf.Term, error) {
+	if ctx.group == nil {
+		return nil, fmt.Errorf("aggregate %s used outside of an aggregate query", e.name)
+	}
+
+	var values []rdf.Term
+	seen := map[rdf.Term]bool{}
+	for _, s := range ctx.group {
+		var value rdf.Term = trueLiteral // COUNT(*) counts solutions.
+		if e.arg != nil {
+			v, err := e.arg.eval(&exprContext{ev: ctx.ev, graph: ctx.graph}, s)
+			if err != nil {
+				continue
+			}
+			value = v
+		} else if e.distinct {
+			value = rdf.NewLiteral(solutionKey(s, nil))
+		}
+		if e.distinct {
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+		}
+		values = append(values, value)
+	}
+
+	switch e.name {
+	case "COUNT":
+		return rdf.NewTypedLiteral(strconv.Itoa(len(values)), rdf.XSDInteger), nil
+	case "SUM", "AVG":
+		sum := numeric{kind: numInteger}
+		for _, v := range values {
+			n, ok := toNumeric(v)
+			if !ok {
+				return nil, errTypeError
+			}
+			total, err := arithmetic("+", sum, n)
+			if err != nil {
+				return nil, err
+			}
+			sum, _ = toNumeric(total)
+		}
+		if e.name == "SUM" {
+			return sum.literal(), nil
+		}
+		if len(values) == 0 {
+			return rdf.NewTypedLiteral("0", rdf.XSDInteger), nil
+		}
+		return arithmetic("/", sum, numeric{kind: numInteger, i: int64(len(values))})
+	case "MIN", "MAX":
+		if len(values) == 0 {
+			return nil, errTypeError
+		}
+		best := values[0]
+		for _, v := range values[1:] {
+			c := orderTerms(v, best)
+			if (e.name == "MIN" && c < 0) || (e.name == "MAX" && c > 0) {
+				best = v
+			}
+		}
+		return best, nil
+	case "SAMPLE":
+		if len(values) == 0 {
+			return nil, errTypeError
+		}
+		return values[0], nil
+	case "GROUP_CONCAT":
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = rdf.Value(v)
+		}
+		return rdf.NewLiteral(strings.Join(parts, e.separator)), nil
+	}
+	return nil, fmt.Errorf("unknown aggregate %s", e.name)
+}
+
+// builtins are the functions callExpr evaluates, besides casts to XSD datatypes.
+var builtins = map[string]bool{
+	"STR": true, "LANG": true, "LANGMATCHES": true, "DATATYPE": true, "BOUND": true, "IRI": true,
+	"URI": true, "BNODE": true, "RAND": true, "ABS": true, "CEIL": true, "FLOOR": true, "ROUND": true,
+	"CONCAT": true, "STRLEN": true, "UCASE": true, "LCASE": true, "ENCODE_FOR_URI": true,
+	"CONTAINS": true, "STRSTARTS": true, "STRENDS": true, "STRBEFORE": true, "STRAFTER": true,
+	"YEAR": true, "MONTH": true, "DAY": true, "HOURS": true, "MINUTES": true, "SECONDS": true,
+	"NOW": true, "MD5": true, "SHA1": true, "SHA256": true, "COALESCE": true, "IF": true,
+	"STRLANG": true, "STRDT": true, "SAMETERM": true, "ISIRI": true, "ISURI": true, "ISBLANK": true,
+	"ISLITERAL": true, "ISNUMERIC": true, "REGEX": true, "SUBSTR": true, "REPLACE": true,
+}
+
+func (e *callExpr) eval(ctx *exprContext, s Solution) (rdf.Term, error) {
+	// Functions that do not evaluate all their arguments up front.
+	switch e.name {
+	case "BOUND":
+		_, ok := s[e.args[0].(*varExpr).name]
+		return booleanLiteral(ok), nil
+	case "COALESCE":
+		for _, arg := range e.args {
+			if v, err := arg.eval(ctx, s); err == nil {
+				return v, nil
+			}
+		}
+		return nil, errTypeError
+	case "IF":
+		if len(e.args) != 3 {
+			return nil, errTypeError
+		}
+		condition, err := evalBoolean(ctx, e.args[0], s)
+		if err != nil {
+			return nil, err
+		}
+		if condition {
+			return e.args[1].eval(ctx, s)
+		}
+		return e.args[2].eval(ctx, s)
+	}
+
+	args := make([]rdf.Term, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.eval(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if strings.HasPrefix(e.name, rdf.XSDNamespace) {
+		return cast(rdf.IRI(e.name), args)
+	}
+	return callBuiltin(ctx, e.name, args)
+}
+
+func callBuiltin(ctx *exprContext, name string, args []rdf.Term) (rdf.Term, error) {
+	arity := map[string]int{
+		"STR": 1, "LANG": 1, "LANGMATCHES": 2, "DATATYPE": 1, "IRI": 1, "URI": 1, "RAND": 0, "ABS": 1,
+		"CEIL": 1, "FLOOR": 1, "ROUND": 1, "STRLEN": 1, "UCASE": 1, "LCASE": 1, "ENCODE_FOR_URI": 1,
+		"CONTAINS": 2, "STRSTARTS": 2, "STRENDS": 2, "STRBEFORE": 2, "STRAFTER": 2, "YEAR": 1,
+		"MONTH": 1, "DAY": 1, "HOURS": 1, "MINUTES": 1, "SECONDS": 1, "NOW": 0, "MD5": 1, "SHA1": 1,
+		"SHA256": 1, "STRLANG": 2, "STRDT": 2, "SAMETERM": 2, "ISIRI": 1, "ISURI": 1, "ISBLANK": 1,
+		"ISLITERAL": 1, "ISNUMERIC": 1,
+	}
+	if n, ok := arity[name]; ok && len(args) != n {
+		return nil, fmt.Errorf("%s takes %d arguments", name, n)
+	}
+
+	switch name {
+	case "STR":
+		if _, ok := args[0].(rdf.BlankNode); ok {
+			return nil, errTypeError
+		}
+		return rdf.NewLiteral(rdf.Value(args[0])), nil
+	case "LANG":
+		l, ok := args[0].(rdf.Literal)
+		if !ok {
+			return nil, errTypeError
+		}
+		return rdf.NewLiteral(l.Language), nil
+	case "LANGMATCHES":
+		tag, rangeTag := strings.ToLower(rdf.Value(args[0])), strings.ToLower(rdf.Value(args[1]))
+		matches := (rangeTag == "*" && tag != "") || tag == rangeTag || strings.HasPrefix(tag, rangeTag+"-")
+		return booleanLiteral(matches), nil
+	case "DATATYPE":
+		l, ok := args[0].(rdf.Literal)
+		if !ok {
+			return nil, errTypeError
+		}
+		return datatypeOf(l), nil
+	case "IRI", "URI":
+		switch v := args[0].(type) {
+		case rdf.IRI:
+			return v, nil
+		case rdf.Literal:
+			return rdf.IRI(v.Value), nil
+		}
+		return nil, errTypeError
+	case "BNODE":
+		ctx.ev.blankCount++
+		return rdf.BlankNode(fmt.Sprintf("q%d", ctx.ev.blankCount)), nil
+	case "RAND":
+		return rdf.NewTypedLiteral(strconv.FormatFloat(ctx.ev.random.Float64(), 'g', -1, 64), rdf.XSDDouble), nil
+	case "ABS", "CEIL", "FLOOR", "ROUND":
+		n, ok := toNumeric(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		if n.kind == numInteger {
+			if name == "ABS" && n.i < 0 {
+				n.i = -n.i
+			}
+			return n.literal(), nil
+		}
+		switch name {
+		case "ABS":
+			n.f = math.Abs(n.f)
+		case "CEIL":
+			n.f = math.Ceil(n.f)
+		case "FLOOR":
+			n.f = math.Floor(n.f)
+		case "ROUND":
+			n.f = math.Floor(n.f + 0.5)
+		}
+		return n.literal(), nil
+	case "CONCAT":
+		var b strings.Builder
+		language := ""
+		for i, arg := range args {
+			l, ok := stringLiteral(arg)
+			if !ok {
+				return nil, errTypeError
+			}
+			if i == 0 {
+				language = l.Language
+			} else if language != l.Language {
+				language = ""
+			}
+			b.WriteString(l.Value)
+		}
+		return rdf.Literal{Value: b.String(), Language: language}, nil
+	case "STRLEN":
+		l, ok := stringLiteral(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		return rdf.NewTypedLiteral(strconv.Itoa(utf8.RuneCountInString(l.Value)), rdf.XSDInteger), nil
+	case "UCASE", "LCASE":
+		l, ok := stringLiteral(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		if name == "UCASE" {
+			l.Value = strings.ToUpper(l.Value)
+		} else {
+			l.Value = strings.ToLower(l.Value)
+		}
+		return l, nil
+	case "ENCODE_FOR_URI":
+		l, ok := stringLiteral(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		return rdf.NewLiteral(strings.ReplaceAll(url.QueryEscape(l.Value), "+", "%20")), nil
+	case "CONTAINS", "STRSTARTS", "STRENDS", "STRBEFORE", "STRAFTER":
+		a, ok := stringLiteral(args[0])
+		b, ok2 := stringLiteral(args[1])
+		if !ok || !ok2 || (b.Language != "" && a.Language != b.Language) {
+			return nil, errTypeError
+		}
+		switch name {
+		case "CONTAINS":
+			return booleanLiteral(strings.Contains(a.Value, b.Value)), nil
+		case "STRSTARTS":
+			return booleanLiteral(strings.HasPrefix(a.Value, b.Value)), nil
+		case "STRENDS":
+			return booleanLiteral(strings.HasSuffix(a.Value, b.Value)), nil
+		}
+		i := strings.Index(a.Value, b.Value)
+		if i < 0 {
+			return rdf.NewLiteral(""), nil
+		}
+		if name == "STRBEFORE" {
+			a.Value = a.Value[:i]
+		} else {
+			a.Value = a.Value[i+len(b.Value):]
+		}
+		return a, nil
+	case "YEAR", "MONTH", "DAY", "HOURS", "MINUTES", "SECONDS":
+		t, err := time.Parse(time.RFC3339Nano, rdf.Value(args[0]))
+		if err != nil {
+			return nil, errTypeError
+		}
+		part := map[string]int{"YEAR": t.Year(), "MONTH": int(t.Month()), "DAY": t.Day(), "HOURS": t.Hour(), "MINUTES": t.Minute()}
+		if name == "SECONDS" {
+			seconds := float64(t.Second()) + float64(t.Nanosecond())/1e9
+			return rdf.NewTypedLiteral(strconv.FormatFloat(seconds, 'f', -1, 64), rdf.XSDDecimal), nil
+		}
+		return rdf.NewTypedLiteral(strconv.Itoa(part[name]), rdf.XSDInteger), nil
+	case "NOW":
+		return rdf.NewTypedLiteral(ctx.ev.now.Format(time.RFC3339Nano), rdf.XSDDateTime), nil
+	case "MD5", "SHA1", "SHA256":
+		l, ok := stringLiteral(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		var sum []byte
+		switch name {
+		case "MD5":
+			h := md5.Sum([]byte(l.Value))
+			sum = h[:]
+		case "SHA1":
+			h := sha1.Sum([]byte(l.Value))
+			sum = h[:]
+		default:
+			h := sha256.Sum256([]byte(l.Value))
+			sum = h[:]
+		}
+		return rdf.NewLiteral(hex.EncodeToString(sum)), nil
+	case "STRLANG":
+		l, ok := args[0].(rdf.Literal)
+		if !ok || l.Language != "" || l.Datatype != "" {
+			return nil, errTypeError
+		}
+		return rdf.NewLangLiteral(l.Value, rdf.Value(args[1])), nil
+	case "STRDT":
+		l, ok := args[0].(rdf.Literal)
+		datatype, ok2 := args[1].(rdf.IRI)
+		if !ok || !ok2 || l.Language != "" || l.Datatype != "" {
+			return nil, errTypeError
+		}
+		return rdf.NewTypedLiteral(l.Value, datatype), nil
+	case "SAMETERM":
+		return booleanLiteral(args[0] == args[1]), nil
+	case "ISIRI", "ISURI":
+		_, ok := args[0].(rdf.IRI)
+		return booleanLiteral(ok), nil
+	case "ISBLANK":
+		_, ok := args[0].(rdf.BlankNode)
+		return booleanLiteral(ok), nil
+	case "ISLITERAL":
+		_, ok := args[0].(rdf.Literal)
+		return booleanLiteral(ok), nil
+	case "ISNUMERIC":
+		_, ok := toNumeric(args[0])
+		return booleanLiteral(ok), nil
+	case "REGEX":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("REGEX takes 2 or 3 arguments")
+		}
+		l, ok := stringLiteral(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		re, err := ctx.ev.compileRegex(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		return booleanLiteral(re.MatchString(l.Value)), nil
+	case "REPLACE":
+		if len(args) < 3 || len(args) > 4 {
+			return nil, fmt.Errorf("REPLACE takes 3 or 4 arguments")
+		}
+		l, ok := stringLiteral(args[0])
+		if !ok {
+			return nil, errTypeError
+		}
+		re, err := ctx.ev.compileRegex(append([]rdf.Term{args[1]}, args[3:]...))
+		if err != nil {
+			return nil, err
+		}
+		// SPARQL uses $1 for groups like Go, but Go needs ${1} when a name character follows.
+		replacement := regexp.MustCompile(`\$(\d+)`).ReplaceAllString(rdf.Value(args[2]), "$${$1}")
+		l.Value = re.ReplaceAllString(l.Value, replacement)
+		return l, nil
+	case "SUBSTR":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("SUBSTR takes 2 or 3 arguments")
+		}
+		l, ok := stringLiteral(args[0])
+		start, ok2 := toNumeric(args[1])
+		if !ok || !ok2 {
+			return nil, errTypeError
+		}
+		runes := []rune(l.Value)
+		from := int(math.Round(start.float())) - 1 // SPARQL positions start at 1.
+		to := len(runes)
+		if len(args) == 3 {
+			length, ok := toNumeric(args[2])
+			if !ok {
+				return nil, errTypeError
+			}
+			to = from + int(math.Round(length.float()))
+		}
+		from = max(from, 0)
+		to = min(to, len(runes))
+		if from >= to {
+			l.Value = ""
+		} else {
+			l.Value = string(runes[from:to])
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+// compileRegex compiles a SPARQL pattern with optional flags, caching the result.
+func (ev *evaluator) compileRegex(args []rdf.Term) (*regexp.Regexp, error) {
+	pattern, ok := stringLiteral(args[0])
+	if !ok {
+		return nil, errTypeError
+	}
+	flags := ""
+	if len(args) > 1 {
+		f, ok := stringLiteral(args[1])
+		if !ok {
+			return nil, errTypeError
+		}
+		for _, flag := range f.Value {
+			switch flag {
+			case 'i', 's', 'm':
+				flags += string(flag)
+			case 'q':
+				pattern.Value = regexp.QuoteMeta(pattern.Value)
+			default:
+				return nil, fmt.Errorf("unsupported regex flag %q", flag)
+			}
+		}
+	}
+	source := pattern.Value
+	if flags != "" {
+		source = "(?" + flags + ")" + source
+	}
+	if re, ok := ev.regexps[source]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", pattern.Value, err)
+	}
+	ev.regexps[source] = re
+	return re, nil
+}
+
+// cast converts a term with an XSD constructor function such as xsd:integer(?x).
+func cast(datatype rdf.IRI, args []rdf.Term) (rdf.Term, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s takes one argument", datatype)
+	}
+	if _, ok := args[0].(rdf.BlankNode); ok {
+		return nil, errTypeError
+	}
+	value := strings.TrimSpace(rdf.Value(args[0]))
+	switch datatype {
+	case rdf.XSDString:
+		return rdf.NewLiteral(rdf.Value(args[0])), nil
+	case rdf.XSDInteger:
+		if n, ok := toNumeric(args[0]); ok {
+			return rdf.NewTypedLiteral(strconv.FormatInt(int64(n.float()), 10), rdf.XSDInteger), nil
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, errTypeError
+		}
+		return rdf.NewTypedLiteral(value, rdf.XSDInteger), nil
+	case rdf.XSDDecimal, rdf.XSDDouble, rdf.IRI(rdf.XSDNamespace + "float"):
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			if b, ok := args[0].(rdf.Literal); ok && b.Datatype == rdf.XSDBoolean {
+				f = map[bool]float64{true: 1}[value == "true"]
+			} else {
+				return nil, errTypeError
+			}
+		}
+		kind := numDouble
+		if datatype == rdf.XSDDecimal {
+			kind = numDecimal
+		}
+		return numeric{kind: kind, f: f}.literal(), nil
+	case rdf.XSDBoolean:
+		switch value {
+		case "true", "1":
+			return trueLiteral, nil
+		case "false", "0":
+			return falseLiteral, nil
+		}
+		if n, ok := toNumeric(args[0]); ok {
+			return booleanLiteral(n.float() != 0), nil
+		}
+		return nil, errTypeError
+	case rdf.XSDDateTime:
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return nil, errTypeError
+		}
+		return rdf.NewTypedLiteral(value, rdf.XSDDateTime), nil
+	}
+	return nil, fmt.Errorf("unsupported cast to %s", datatype)
+}
+
+func evalBoolean(ctx *exprContext, e expr, s Solution) (bool, error) {
+	value, err := e.eval(ctx, s)
+	if err != nil {
+		return false, err
+	}
+	return effectiveBoolean(value)
+}
+
+// effectiveBoolean computes the effective boolean value of a term.
+func effectiveBoolean(t rdf.Term) (bool, error) {
+	l, ok := t.(rdf.Literal)
+	if !ok {
+		return false, errTypeError
+	}
+	if l.Datatype == rdf.XSDBoolean {
+		return l.Value == "true" || l.Value == "1", nil
+	}
+	if n, ok := toNumeric(l); ok {
+		f := n.float()
+		return f != 0 && !math.IsNaN(f), nil
+	}
+	if _, ok := stringLiteral(l); ok {
+		return l.Value != "", nil
+	}
+	return false, errTypeError
+}
+
+// stringLiteral returns the term if it is a simple, xsd:string or language-tagged literal.
+func stringLiteral(t rdf.Term) (rdf.Literal, bool) {
+	l, ok := t.(rdf.Literal)
+	if !ok || (l.Datatype != "" && l.Datatype != rdf.XSDString) {
+		return rdf.Literal{}, false
+	}
+	return l, true
+}
+
+func datatypeOf(l rdf.Literal) rdf.IRI {
+	switch {
+	case l.Language != "":
+		return rdf.RDFLangString
+	case l.Datatype == "":
+		return rdf.XSDString
+	}
+	return l.Datatype
+}
+
+// valuesEqual implements the SPARQL = operator.
+func valuesEqual(a, b rdf.Term) (bool, error) {
+	if x, ok := toNumeric(a); ok {
+		if y, ok := toNumeric(b); ok {
+			if x.kind == numInteger && y.kind == numInteger {
+				return x.i == y.i, nil
+			}
+			return x.float() == y.float(), nil
+		}
+	}
+	la, okA := a.(rdf.Literal)
+	lb, okB := b.(rdf.Literal)
+	if okA && okB {
+		return la.Value == lb.Value && la.Language == lb.Language && datatypeOf(la) == datatypeOf(lb), nil
+	}
+	return a == b, nil
+}
+
+// compareValues implements the SPARQL <, >, <= and >= operators.
+func compareValues(a, b rdf.Term) (int, error) {
+	if x, ok := toNumeric(a); ok {
+		if y, ok := toNumeric(b); ok {
+			return compareNumeric(x, y), nil
+		}
+		return 0, errTypeError
+	}
+	la, okA := a.(rdf.Literal)
+	lb, okB := b.(rdf.Literal)
+	if !okA || !okB || la.Language != lb.Language || datatypeOf(la) != datatypeOf(lb) {
+		return 0, errTypeError
+	}
+	switch datatypeOf(la) {
+	case rdf.XSDString, rdf.RDFLangString, rdf.XSDDateTime, rdf.XSDBoolean:
+		return strings.Compare(la.Value, lb.Value), nil
+	}
+	return 0, errTypeError
+}
+
+// orderTerms orders terms for ORDER BY, MIN and MAX: unbound, then blank nodes, IRIs and literals.
+// Numbers compare numerically and other literals lexically.
+func orderTerms(a, b rdf.Term) int {
+	rank := func(t rdf.Term) int {
+		switch t.(type) {
+		case nil:
+			return 0
+		case rdf.BlankNode:
+			return 1
+		case rdf.IRI:
+			return 2
+		}
+		return 3
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	if a == nil {
+		return 0
+	}
+	if c, err := compareValues(a, b); err == nil {
+		return c
+	}
+	if c := strings.Compare(rdf.Value(a), rdf.Value(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+type numericKind int
+
+const (
+	numInteger numericKind = iota
+	numDecimal
+	numDouble
+)
+
+// numeric is the value of a numeric literal. Integers are exact; decimals and doubles use float64.
+type numeric struct {
+	kind numericKind
+	i    int64
+	f    float64
+}
+
+var integerTypes = map[rdf.IRI]bool{}
+
+func init() {
+	for _, name := range []string{"integer", "int", "long", "short", "byte", "nonNegativeInteger", "positiveInteger",
+		"nonPositiveInteger", "negativeInteger", "unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte"} {
+		integerTypes[rdf.IRI(rdf.XSDNamespace+name)] = true
+	}
+}
+
+func toNumeric(t rdf.Term) (numeric, bool) {
+	l, ok := t.(rdf.Literal)
+	if !ok {
+		return numeric{}, false
+	}
+	switch {
+	case integerTypes[l.Datatype]:
+		i, err := strconv.ParseInt(strings.TrimPrefix(l.Value, "+"), 10, 64)
+		return numeric{kind: numInteger, i: i}, err == nil
+	case l.Datatype == rdf.XSDDecimal:
+		f, err := strconv.ParseFloat(l.Value, 64)
+		return numeric{kind: numDecimal, f: f}, err == nil
+	case l.Datatype == rdf.XSDDouble || l.Datatype == rdf.IRI(rdf.XSDNamespace+"float"):
+		f, err := strconv.ParseFloat(strings.Replace(l.Value, "INF", "Inf", 1), 64)
+		return numeric{kind: numDouble, f: f}, err == nil
+	}
+	return numeric{}, false
+}
+
+func (n numeric) float() float64 {
+	if n.kind == numInteger {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+func (n numeric) literal() rdf.Literal {
+	switch n.kind {
+	case numInteger:
+		return rdf.NewTypedLiteral(strconv.FormatInt(n.i, 10), rdf.XSDInteger)
+	case numDecimal:
+		s := strconv.FormatFloat(n.f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return rdf.NewTypedLiteral(s, rdf.XSDDecimal)
+	}
+	return rdf.NewTypedLiteral(strconv.FormatFloat(n.f, 'E', -1, 64), rdf.XSDDouble)
+}
+
+func compareNumeric(a, b numeric) int {
+	if a.kind == numInteger && b.kind == numInteger {
+		switch {
+		case a.i < b.i:
+			return -1
+		case a.i > b.i:
+			return 1
+		}
+		return 0
+	}
+	switch x, y := a.float(), b.float(); {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func arithmetic(op string, a, b numeric) (rdf.Term, error) {
+	kind := max(a.kind, b.kind)
+	if kind == numInteger && op != "/" {
+		switch op {
+		case "+":
+			return numeric{kind: kind, i: a.i + b.i}.literal(), nil
+		case "-":
+			return numeric{kind: kind, i: a.i - b.i}.literal(), nil
+		case "*":
+			return numeric{kind: kind, i: a.i * b.i}.literal(), nil
+		}
+	}
+	if op == "/" && kind == numInteger {
+		kind = numDecimal
+	}
+	x, y := a.float(), b.float()
+	var f float64
+	switch op {
+	case "+":
+		f = x + y
+	case "-":
+		f = x - y
+	case "*":
+		f = x * y
+	case "/":
+		if y == 0 && kind != numDouble {
+			return nil, errTypeError
+		}
+		f = x / y
+	}
+	return numeric{kind: kind, f: f}.literal(), nil
+}
@@ -0,0 +1,366 @@
+package sparql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF     tokenKind = iota
+	tokIRI               // <http://...>, text is the IRI without brackets
+	tokPName             // prefix:local, text is the raw prefixed name
+	tokVar               // ?x or $x, text is the variable name
+	tokBlank             // _:label, text is the label
+	tokString            // text is the unescaped string
+	tokLang              // @en, text is the language tag
+	tokInteger           // 42
+	tokDecimal           // 4.2
+	tokDouble            // 4.2e1
+	tokWord              // keywords, function names, "a", "true" and "false"
+	tokPunct             // { } ( ) [ ] . , ; * / | ^ + - ? ! = != < > <= >= && || ^^
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokIRI:
+		return "<" + t.text + ">"
+	case tokVar:
+		return "?" + t.text
+	case tokBlank:
+		return "_:" + t.text
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokLang:
+		return "@" + t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// SyntaxError describes a SPARQL syntax error and where it occurred.
+type SyntaxError struct {
+	Line    int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("sparql syntax error on line %d: %s", e.Line, e.Message)
+}
+
+var iriRef = regexp.MustCompile(`^<([^<>"{}|^` + "`" + `\\\x00-\x20]*)>`)
+
+// lex splits a query into tokens.
+func lex(input string) ([]token, error) {
+	l := &lexer{input: input, line: 1}
+	var tokens []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		if t.kind == tokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+type lexer struct {
+	input string
+	pos   int
+	line  int
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: l.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) peek() rune {
+	if l.pos >= len(l.input) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+	return r
+}
+
+func (l *lexer) peekAt(offset int) byte {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+offset]
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+	l.pos += size
+	if r == '\n' {
+		l.line++
+	}
+	return r
+}
+
+func (l *lexer) skipWhitespaceAndComments() {
+	for l.pos < len(l.input) {
+		r := l.peek()
+		switch {
+		case unicode.IsSpace(r):
+			l.advance()
+		case r == '#':
+			for l.pos < len(l.input) && l.peek() != '\n' {
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipWhitespaceAndComments()
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+	line := l.line
+	tok := func(kind tokenKind, text string) (token, error) {
+		return token{kind: kind, text: text, line: line}, nil
+	}
+
+	r := l.peek()
+	switch {
+	case r == '<':
+		if m := iriRef.FindStringSubmatch(l.input[l.pos:]); m != nil {
+			l.pos += len(m[0])
+			return tok(tokIRI, m[1])
+		}
+		l.advance()
+		if l.peek() == '=' {
+			l.advance()
+			return tok(tokPunct, "<=")
+		}
+		return tok(tokPunct, "<")
+	case r == '?' || r == '$':
+		if next, _ := utf8.DecodeRuneInString(l.input[l.pos+1:]); l.pos+1 < len(l.input) && isVarChar(next) {
+			l.advance()
+			start := l.pos
+			for l.pos < len(l.input) && isVarChar(l.peek()) {
+				l.advance()
+			}
+			return tok(tokVar, l.input[start:l.pos])
+		}
+		l.advance()
+		return tok(tokPunct, string(r))
+	case r == '_' && l.peekAt(1) == ':':
+		l.pos += 2
+		start := l.pos
+		for l.pos < len(l.input) && (isNameChar(l.peek()) || l.peek() == '.') {
+			l.advance()
+		}
+		for l.pos > start && l.input[l.pos-1] == '.' {
+			l.pos--
+		}
+		label := l.input[start:l.pos]
+		if label == "" {
+			return token{}, l.errorf("empty blank node label")
+		}
+		return tok(tokBlank, label)
+	case r == '"' || r == '\'':
+		s, err := l.scanString()
+		if err != nil {
+			return token{}, err
+		}
+		return tok(tokString, s)
+	case r == '@':
+		l.advance()
+		start := l.pos
+		for l.pos < len(l.input) && (l.peek() == '-' || unicode.IsLetter(l.peek()) || unicode.IsDigit(l.peek())) {
+			l.advance()
+		}
+		if l.pos == start {
+			return token{}, l.errorf("empty language tag")
+		}
+		return tok(tokLang, l.input[start:l.pos])
+	case r >= '0' && r <= '9' || (r == '.' && l.peekAt(1) >= '0' && l.peekAt(1) <= '9'):
+		return l.scanNumber(line)
+	case r == ':' || unicode.IsLetter(r):
+		return l.scanWordOrPName(line)
+	}
+
+	for _, punct := range []string{"&&", "||", "!=", ">=", "^^"} {
+		if strings.HasPrefix(l.input[l.pos:], punct) {
+			l.pos += len(punct)
+			return tok(tokPunct, punct)
+		}
+	}
+	if strings.ContainsRune("{}()[].,;*/|^+-!=>", r) {
+		l.advance()
+		return tok(tokPunct, string(r))
+	}
+	return token{}, l.errorf("unexpected character %q", r)
+}
+
+func (l *lexer) scanWordOrPName(line int) (token, error) {
+	start := l.pos
+	for l.pos < len(l.input) && (isNameChar(l.peek()) || l.peek() == '.') {
+		l.advance()
+	}
+	for l.pos > start && l.input[l.pos-1] == '.' {
+		l.pos--
+	}
+	if l.peek() != ':' {
+		return token{kind: tokWord, text: l.input[start:l.pos], line: line}, nil
+	}
+	prefix := l.input[start:l.pos]
+	l.advance() // ':'
+
+	// The local part may contain ':', '.', percent encodings and backslash escapes, but not end with '.'.
+	var local strings.Builder
+	for l.pos < len(l.input) {
+		r := l.peek()
+		switch {
+		case isNameChar(r) || r == ':':
+			local.WriteRune(l.advance())
+			continue
+		case r == '.':
+			next := l.peekAt(1)
+			if next != 0 && (isNameChar(rune(next)) || next == ':' || next == '%' || next == '\\') {
+				local.WriteRune(l.advance())
+				continue
+			}
+		case r == '%' && l.pos+2 < len(l.input):
+			local.WriteString(l.input[l.pos : l.pos+3])
+			l.pos += 3
+			continue
+		case r == '\\' && l.pos+1 < len(l.input):
+			l.advance()
+			local.WriteRune(l.advance())
+			continue
+		}
+		break
+	}
+	return token{kind: tokPName, text: prefix + ":" + local.String(), line: line}, nil
+}
+
+func (l *lexer) scanNumber(line int) (token, error) {
+	start := l.pos
+	digits := func() {
+		for l.pos < len(l.input) && l.peek() >= '0' && l.peek() <= '9' {
+			l.advance()
+		}
+	}
+	kind := tokInteger
+	digits()
+	if l.peek() == '.' && l.peekAt(1) >= '0' && l.peekAt(1) <= '9' {
+		l.advance()
+		digits()
+		kind = tokDecimal
+	}
+	if r := l.peek(); r == 'e' || r == 'E' {
+		l.advance()
+		if r := l.peek(); r == '+' || r == '-' {
+			l.advance()
+		}
+		exponent := l.pos
+		digits()
+		if l.pos == exponent {
+			return token{}, l.errorf("invalid exponent in number %q", l.input[start:l.pos])
+		}
+		kind = tokDouble
+	}
+	return token{kind: kind, text: l.input[start:l.pos], line: line}, nil
+}
+
+func (l *lexer) scanString() (string, error) {
+	quote := l.input[l.pos]
+	delimiter := strings.Repeat(string(quote), 3)
+	long := strings.HasPrefix(l.input[l.pos:], delimiter)
+	if long {
+		l.pos += 3
+	} else {
+		l.pos++
+	}
+
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.input) {
+			return "", l.errorf("unterminated string literal")
+		}
+		if long {
+			if strings.HasPrefix(l.input[l.pos:], delimiter) {
+				for l.pos+3 < len(l.input) && l.input[l.pos+3] == quote {
+					b.WriteByte(quote)
+					l.pos++
+				}
+				l.pos += 3
+				return b.String(), nil
+			}
+		} else if l.input[l.pos] == quote {
+			l.pos++
+			return b.String(), nil
+		}
+
+		r := l.advance()
+		switch {
+		case r == '\\':
+			if l.pos >= len(l.input) {
+				return "", l.errorf("unterminated escape sequence")
+			}
+			switch e := l.advance(); e {
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 'f':
+				b.WriteByte('\f')
+			case '"', '\'', '\\':
+				b.WriteRune(e)
+			case 'u', 'U':
+				digits := 4
+				if e == 'U' {
+					digits = 8
+				}
+				if l.pos+digits > len(l.input) {
+					return "", l.errorf("incomplete unicode escape")
+				}
+				value, err := strconv.ParseUint(l.input[l.pos:l.pos+digits], 16, 32)
+				if err != nil {
+					return "", l.errorf("invalid unicode escape %q", l.input[l.pos:l.pos+digits])
+				}
+				l.pos += digits
+				b.WriteRune(rune(value))
+			default:
+				return "", l.errorf("invalid escape sequence \\%c", e)
+			}
+		case !long && (r == '\n' || r == '\r'):
+			return "", l.errorf("line break in single-line string literal")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// isVarChar reports whether r may appear in a variable name, which unlike other names cannot contain '-'.
+func isVarChar(r rune) bool {
+	return r != '-' && isNameChar(r)
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r) || r == 0xB7 ||
+		(r >= 0x0300 && r <= 0x036F) || (r >= 0x203F && r <= 0x2040)
+}
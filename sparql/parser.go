@@ -0,0 +1,1162 @@
+package sparql
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Parse parses a SPARQL 1.1 query. prefixes are declared before the query's own PREFIX declarations, so
+// callers can offer well-known prefixes; it may be nil.
+func Parse(query string, prefixes map[string]string) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, prefixes: map[string]string{}}
+	for prefix, namespace := range prefixes {
+		p.prefixes[prefix] = namespace
+	}
+
+	if err := p.parsePrologue(); err != nil {
+		return nil, err
+	}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf("unexpected %s after query", t)
+	}
+	return q, nil
+}
+
+type parser struct {
+	tokens   []token
+	pos      int
+	base     string
+	prefixes map[string]string
+	blanks   int    // Counter for the hidden variables standing for blank nodes.
+	query    *Query // The query or subquery being parsed.
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: p.peek().line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isWord(words ...string) bool {
+	t := p.peek()
+	if t.kind != tokWord {
+		return false
+	}
+	for _, word := range words {
+		if strings.EqualFold(t.text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) acceptWord(word string) bool {
+	if p.isWord(word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectWord(word string) error {
+	if !p.acceptWord(word) {
+		return p.errorf("expected %s but found %s", word, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) isPunct(punct string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == punct
+}
+
+func (p *parser) acceptPunct(punct string) bool {
+	if p.isPunct(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectPunct(punct string) error {
+	if !p.acceptPunct(punct) {
+		return p.errorf("expected %q but found %s", punct, p.peek())
+	}
+	return nil
+}
+
+// freshVariable returns a hidden variable standing for an anonymous blank node.
+func (p *parser) freshVariable() node {
+	p.blanks++
+	return node{variable: fmt.Sprintf("_:anon%d", p.blanks)}
+}
+
+func (p *parser) parsePrologue() error {
+	for {
+		switch {
+		case p.acceptWord("BASE"):
+			t := p.next()
+			if t.kind != tokIRI {
+				return p.errorf("expected IRI after BASE")
+			}
+			p.base = string(p.resolve(t.text))
+		case p.acceptWord("PREFIX"):
+			t := p.next()
+			if t.kind != tokPName || !strings.HasSuffix(t.text, ":") {
+				return p.errorf("expected prefix name after PREFIX")
+			}
+			iri := p.next()
+			if iri.kind != tokIRI {
+				return p.errorf("expected IRI for prefix %s", t.text)
+			}
+			p.prefixes[strings.TrimSuffix(t.text, ":")] = string(p.resolve(iri.text))
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{Prefixes: map[string]string{}, limit: -1}
+	for prefix, namespace := range p.prefixes {
+		q.Prefixes[prefix] = namespace
+	}
+	outer := p.query
+	p.query = q
+	defer func() { p.query = outer }()
+
+	var err error
+	switch {
+	case p.acceptWord("SELECT"):
+		q.Form = Select
+		err = p.parseSelectClause(q)
+	case p.acceptWord("CONSTRUCT"):
+		q.Form = Construct
+		if p.isPunct("{") {
+			q.template, err = p.parseTemplate()
+		}
+	case p.acceptWord("ASK"):
+		q.Form = Ask
+	case p.acceptWord("DESCRIBE"):
+		q.Form = Describe
+		err = p.parseDescribeClause(q)
+	default:
+		return nil, p.errorf("expected SELECT, CONSTRUCT, ASK or DESCRIBE but found %s", p.peek())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptWord("FROM") {
+		return nil, p.errorf("FROM clauses are not supported; use GRAPH patterns instead")
+	}
+
+	// The WHERE keyword is optional except in the short CONSTRUCT WHERE form.
+	shortConstruct := q.Form == Construct && q.template == nil
+	if p.acceptWord("WHERE") || p.isPunct("{") {
+		if q.where, err = p.parseGroup(); err != nil {
+			return nil, err
+		}
+	} else if q.Form != Describe {
+		return nil, p.errorf("expected WHERE but found %s", p.peek())
+	}
+	if shortConstruct {
+		if len(q.where.elements) != 1 || len(q.where.filters) > 0 {
+			return nil, p.errorf("CONSTRUCT WHERE only allows triple patterns")
+		}
+		block, ok := q.where.elements[0].(*triplesBlock)
+		if !ok {
+			return nil, p.errorf("CONSTRUCT WHERE only allows triple patterns")
+		}
+		q.template = block.patterns
+	}
+	if q.where == nil {
+		q.where = &group{}
+	}
+
+	if err := p.parseSolutionModifiers(q); err != nil {
+		return nil, err
+	}
+	if p.acceptWord("VALUES") {
+		if q.values, err = p.parseValues(); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+func (p *parser) parseSelectClause(q *Query) error {
+	if p.acceptWord("DISTINCT") || p.acceptWord("REDUCED") {
+		q.distinct = true
+	}
+	if p.acceptPunct("*") {
+		q.star = true
+		return nil
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokVar:
+			p.next()
+			q.projection = append(q.projection, projection{name: t.text})
+		case p.acceptPunct("("):
+			e, err := p.parseExpression()
+			if err != nil {
+				return err
+			}
+			if err := p.expectWord("AS"); err != nil {
+				return err
+			}
+			v := p.next()
+			if v.kind != tokVar {
+				return p.errorf("expected variable after AS")
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return err
+			}
+			q.projection = append(q.projection, projection{name: v.text, expr: e})
+		default:
+			if len(q.projection) == 0 {
+				return p.errorf("expected variables to select but found %s", t)
+			}
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseDescribeClause(q *Query) error {
+	if p.acceptPunct("*") {
+		q.star = true
+		return nil
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokVar && t.kind != tokIRI && t.kind != tokPName {
+			break
+		}
+		n, err := p.parseVarOrTerm()
+		if err != nil {
+			return err
+		}
+		q.describe = append(q.describe, n)
+	}
+	if len(q.describe) == 0 {
+		return p.errorf("expected resources to describe but found %s", p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseTemplate() ([]triplePattern, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var patterns []triplePattern
+	for !p.acceptPunct("}") {
+		if p.acceptPunct(".") {
+			continue
+		}
+		if err := p.parseTriplesSameSubject(&patterns, false); err != nil {
+			return nil, err
+		}
+	}
+	return patterns, nil
+}
+
+func (p *parser) parseSolutionModifiers(q *Query) error {
+	if p.isWord("GROUP") {
+		p.next()
+		if err := p.expectWord("BY"); err != nil {
+			return err
+		}
+		q.aggregated = true
+		for {
+			t := p.peek()
+			switch {
+			case t.kind == tokVar:
+				p.next()
+				q.groupBy = append(q.groupBy, projection{name: t.text, expr: &varExpr{name: t.text}})
+				continue
+			case p.acceptPunct("("):
+				e, err := p.parseExpression()
+				if err != nil {
+					return err
+				}
+				condition := projection{expr: e}
+				if p.acceptWord("AS") {
+					v := p.next()
+					if v.kind != tokVar {
+						return p.errorf("expected variable after AS")
+					}
+					condition.name = v.text
+				}
+				if err := p.expectPunct(")"); err != nil {
+					return err
+				}
+				q.groupBy = append(q.groupBy, condition)
+				continue
+			case t.kind == tokWord && !p.isWord("HAVING", "ORDER", "LIMIT", "OFFSET", "VALUES"), t.kind == tokIRI, t.kind == tokPName:
+				e, err := p.parsePrimaryExpression()
+				if err != nil {
+					return err
+				}
+				q.groupBy = append(q.groupBy, projection{expr: e})
+				continue
+			}
+			break
+		}
+		if len(q.groupBy) == 0 {
+			return p.errorf("expected GROUP BY conditions")
+		}
+	}
+
+	if p.acceptWord("HAVING") {
+		for p.isPunct("(") || (p.peek().kind == tokWord && !p.isWord("ORDER", "LIMIT", "OFFSET", "VALUES")) {
+			e, err := p.parsePrimaryExpression()
+			if err != nil {
+				return err
+			}
+			q.having = append(q.having, e)
+		}
+		if len(q.having) == 0 {
+			return p.errorf("expected HAVING conditions")
+		}
+	}
+
+	if p.isWord("ORDER") {
+		p.next()
+		if err := p.expectWord("BY"); err != nil {
+			return err
+		}
+		for {
+			condition := orderCondition{}
+			switch {
+			case p.isWord("ASC", "DESC"):
+				condition.descending = p.isWord("DESC")
+				p.next()
+				if !p.isPunct("(") {
+					return p.errorf("expected ( after ASC or DESC")
+				}
+				e, err := p.parsePrimaryExpression()
+				if err != nil {
+					return err
+				}
+				condition.expr = e
+			case p.peek().kind == tokVar:
+				condition.expr = &varExpr{name: p.next().text}
+			case p.isPunct("(") || (p.peek().kind == tokWord && !p.isWord("LIMIT", "OFFSET", "VALUES")):
+				e, err := p.parsePrimaryExpression()
+				if err != nil {
+					return err
+				}
+				condition.expr = e
+			}
+			if condition.expr == nil {
+				break
+			}
+			q.orderBy = append(q.orderBy, condition)
+		}
+		if len(q.orderBy) == 0 {
+			return p.errorf("expected ORDER BY conditions")
+		}
+	}
+
+	for p.isWord("LIMIT", "OFFSET") {
+		isLimit := p.isWord("LIMIT")
+		p.next()
+		t := p.next()
+		var n int
+		if _, err := fmt.Sscanf(t.text, "%d", &n); t.kind != tokInteger || err != nil {
+			return p.errorf("expected integer after LIMIT or OFFSET")
+		}
+		if isLimit {
+			q.limit = n
+		} else {
+			q.offset = n
+		}
+	}
+	return nil
+}
+
+// parseGroup parses a group graph pattern or a subquery enclosed in braces.
+func (p *parser) parseGroup() (*group, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	if p.isWord("SELECT") {
+		sub, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		if sub.Form != Select {
+			return nil, p.errorf("subqueries must be SELECT queries")
+		}
+		if err := p.expectPunct("}"); err != nil {
+			return nil, err
+		}
+		return &group{elements: []element{&subQuery{query: sub}}}, nil
+	}
+
+	g := &group{}
+	for {
+		switch {
+		case p.acceptPunct("}"):
+			return g, nil
+		case p.acceptPunct("."):
+		case p.acceptWord("OPTIONAL"):
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, &optionalElement{group: sub})
+		case p.acceptWord("MINUS"):
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, &minusElement{group: sub})
+		case p.acceptWord("GRAPH"):
+			name, err := p.parseVarOrTerm()
+			if err != nil {
+				return nil, err
+			}
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, &graphElement{name: name, group: sub})
+		case p.acceptWord("FILTER"):
+			e, err := p.parsePrimaryExpression()
+			if err != nil {
+				return nil, err
+			}
+			g.filters = append(g.filters, e)
+		case p.acceptWord("BIND"):
+			if err := p.expectPunct("("); err != nil {
+				return nil, err
+			}
+			e, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectWord("AS"); err != nil {
+				return nil, err
+			}
+			v := p.next()
+			if v.kind != tokVar {
+				return nil, p.errorf("expected variable after AS")
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, &bindElement{expr: e, variable: v.text})
+		case p.acceptWord("VALUES"):
+			values, err := p.parseValues()
+			if err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, values)
+		case p.isWord("SERVICE"):
+			return nil, p.errorf("SERVICE is not supported")
+		case p.isPunct("{"):
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isWord("UNION") {
+				g.elements = append(g.elements, sub)
+				continue
+			}
+			union := &unionElement{groups: []*group{sub}}
+			for p.acceptWord("UNION") {
+				next, err := p.parseGroup()
+				if err != nil {
+					return nil, err
+				}
+				union.groups = append(union.groups, next)
+			}
+			g.elements = append(g.elements, union)
+		case p.peek().kind == tokEOF:
+			return nil, p.errorf("unterminated group pattern")
+		default:
+			block := &triplesBlock{}
+			if err := p.parseTriplesSameSubject(&block.patterns, true); err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, block)
+		}
+	}
+}
+
+// parseTriplesSameSubject parses a subject with its property list and appends the resulting patterns.
+// Property paths are only allowed in WHERE clauses.
+func (p *parser) parseTriplesSameSubject(patterns *[]triplePattern, allowPaths bool) error {
+	var subject node
+	var err error
+	switch {
+	case p.isPunct("["):
+		if subject, err = p.parseBlankNodePropertyList(patterns, allowPaths); err != nil {
+			return err
+		}
+		if !p.startsVerb() {
+			return nil
+		}
+	case p.isPunct("("):
+		if subject, err = p.parseCollection(patterns, allowPaths); err != nil {
+			return err
+		}
+	default:
+		if subject, err = p.parseVarOrTerm(); err != nil {
+			return err
+		}
+	}
+	return p.parsePropertyList(subject, patterns, allowPaths)
+}
+
+func (p *parser) startsVerb() bool {
+	t := p.peek()
+	return t.kind == tokVar || t.kind == tokIRI || t.kind == tokPName || p.isWord("a") ||
+		p.isPunct("^") || p.isPunct("!") || p.isPunct("(")
+}
+
+func (p *parser) parsePropertyList(subject node, patterns *[]triplePattern, allowPaths bool) error {
+	for {
+		verb, err := p.parseVerb(allowPaths)
+		if err != nil {
+			return err
+		}
+		for {
+			object, err := p.parseObject(patterns, allowPaths)
+			if err != nil {
+				return err
+			}
+			*patterns = append(*patterns, triplePattern{subject: subject, path: verb, object: object})
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if !p.acceptPunct(";") {
+			return nil
+		}
+		for p.acceptPunct(";") {
+		}
+		if !p.startsVerb() {
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseVerb(allowPaths bool) (path, error) {
+	if t := p.peek(); t.kind == tokVar {
+		p.next()
+		return varPath{name: t.text}, nil
+	}
+	if !allowPaths {
+		if p.acceptWord("a") {
+			return linkPath{iri: rdf.RDFType}, nil
+		}
+		iri, err := p.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+		return linkPath{iri: iri}, nil
+	}
+	return p.parsePath()
+}
+
+func (p *parser) parseObject(patterns *[]triplePattern, allowPaths bool) (node, error) {
+	switch {
+	case p.isPunct("["):
+		return p.parseBlankNodePropertyList(patterns, allowPaths)
+	case p.isPunct("("):
+		return p.parseCollection(patterns, allowPaths)
+	}
+	return p.parseVarOrTerm()
+}
+
+func (p *parser) parseBlankNodePropertyList(patterns *[]triplePattern, allowPaths bool) (node, error) {
+	if err := p.expectPunct("["); err != nil {
+		return node{}, err
+	}
+	subject := p.freshVariable()
+	if p.acceptPunct("]") {
+		return subject, nil
+	}
+	if err := p.parsePropertyList(subject, patterns, allowPaths); err != nil {
+		return node{}, err
+	}
+	return subject, p.expectPunct("]")
+}
+
+func (p *parser) parseCollection(patterns *[]triplePattern, allowPaths bool) (node, error) {
+	if err := p.expectPunct("("); err != nil {
+		return node{}, err
+	}
+	if p.acceptPunct(")") {
+		return node{term: rdf.RDFNil}, nil
+	}
+	head := p.freshVariable()
+	current := head
+	for {
+		item, err := p.parseObject(patterns, allowPaths)
+		if err != nil {
+			return node{}, err
+		}
+		*patterns = append(*patterns, triplePattern{subject: current, path: linkPath{iri: rdf.RDFFirst}, object: item})
+		if p.acceptPunct(")") {
+			*patterns = append(*patterns, triplePattern{subject: current, path: linkPath{iri: rdf.RDFRest}, object: node{term: rdf.RDFNil}})
+			return head, nil
+		}
+		next := p.freshVariable()
+		*patterns = append(*patterns, triplePattern{subject: current, path: linkPath{iri: rdf.RDFRest}, object: next})
+		current = next
+	}
+}
+
+func (p *parser) parsePath() (path, error) {
+	left, err := p.parsePathSequence()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptPunct("|") {
+		right, err := p.parsePathSequence()
+		if err != nil {
+			return nil, err
+		}
+		left = alternativePath{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parsePathSequence() (path, error) {
+	first, err := p.parsePathElementOrInverse()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptPunct("/") {
+		rest, err := p.parsePathElementOrInverse()
+		if err != nil {
+			return nil, err
+		}
+		first = sequencePath{first: first, rest: rest}
+	}
+	return first, nil
+}
+
+func (p *parser) parsePathElementOrInverse() (path, error) {
+	if p.acceptPunct("^") {
+		element, err := p.parsePathElement()
+		if err != nil {
+			return nil, err
+		}
+		return inversePath{path: element}, nil
+	}
+	return p.parsePathElement()
+}
+
+func (p *parser) parsePathElement() (path, error) {
+	primary, err := p.parsePathPrimary()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.acceptPunct("*"):
+		return repeatPath{path: primary, min: 0, unbounded: true}, nil
+	case p.acceptPunct("+"):
+		return repeatPath{path: primary, min: 1, unbounded: true}, nil
+	case p.acceptPunct("?"):
+		return repeatPath{path: primary, min: 0}, nil
+	}
+	return primary, nil
+}
+
+func (p *parser) parsePathPrimary() (path, error) {
+	switch {
+	case p.acceptWord("a"):
+		return linkPath{iri: rdf.RDFType}, nil
+	case p.acceptPunct("("):
+		inner, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expectPunct(")")
+	case p.acceptPunct("!"):
+		negated := negatedPath{}
+		one := func() error {
+			inverse := p.acceptPunct("^")
+			var iri rdf.IRI
+			if p.acceptWord("a") {
+				iri = rdf.RDFType
+			} else {
+				var err error
+				if iri, err = p.parseIRI(); err != nil {
+					return err
+				}
+			}
+			if inverse {
+				negated.inverse = append(negated.inverse, iri)
+			} else {
+				negated.forward = append(negated.forward, iri)
+			}
+			return nil
+		}
+		if p.acceptPunct("(") {
+			for !p.acceptPunct(")") {
+				if err := one(); err != nil {
+					return nil, err
+				}
+				if !p.acceptPunct("|") && !p.isPunct(")") {
+					return nil, p.errorf("expected | or ) in negated property set")
+				}
+			}
+		} else if err := one(); err != nil {
+			return nil, err
+		}
+		return negated, nil
+	}
+	iri, err := p.parseIRI()
+	if err != nil {
+		return nil, err
+	}
+	return linkPath{iri: iri}, nil
+}
+
+// parseVarOrTerm parses a variable, IRI, literal or labelled blank node.
+func (p *parser) parseVarOrTerm() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokVar:
+		p.next()
+		return node{variable: t.text}, nil
+	case tokBlank:
+		p.next()
+		return node{variable: "_:" + t.text}, nil
+	}
+	if p.isPunct("[") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == "]" {
+		p.pos += 2
+		return p.freshVariable(), nil
+	}
+	term, err := p.parseTerm()
+	if err != nil {
+		return node{}, err
+	}
+	return node{term: term}, nil
+}
+
+// parseTerm parses an IRI or literal.
+func (p *parser) parseTerm() (rdf.Term, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokIRI, tokPName:
+		return p.parseIRI()
+	case tokString:
+		p.next()
+		if lang := p.peek(); lang.kind == tokLang {
+			p.next()
+			return rdf.NewLangLiteral(t.text, lang.text), nil
+		}
+		if p.acceptPunct("^^") {
+			datatype, err := p.parseIRI()
+			if err != nil {
+				return nil, err
+			}
+			return rdf.NewTypedLiteral(t.text, datatype), nil
+		}
+		return rdf.NewLiteral(t.text), nil
+	case tokInteger, tokDecimal, tokDouble:
+		p.next()
+		return numericLiteral(t), nil
+	case tokWord:
+		if p.isWord("true", "false") {
+			p.next()
+			return rdf.NewTypedLiteral(strings.ToLower(t.text), rdf.XSDBoolean), nil
+		}
+	case tokPunct:
+		if (t.text == "-" || t.text == "+") && isNumberToken(p.peekAt(1)) {
+			p.next()
+			number := p.next()
+			if t.text == "-" {
+				number.text = "-" + number.text
+			}
+			return numericLiteral(number), nil
+		}
+	}
+	return nil, p.errorf("expected an RDF term but found %s", t)
+}
+
+func (p *parser) parseIRI() (rdf.IRI, error) {
+	t := p.next()
+	switch t.kind {
+	case tokIRI:
+		return p.resolve(t.text), nil
+	case tokPName:
+		prefix, local, _ := strings.Cut(t.text, ":")
+		namespace, ok := p.prefixes[prefix]
+		if !ok {
+			return "", &SyntaxError{Line: t.line, Message: fmt.Sprintf("undefined prefix %q", prefix)}
+		}
+		return rdf.IRI(namespace + local), nil
+	}
+	return "", &SyntaxError{Line: t.line, Message: fmt.Sprintf("expected IRI but found %s", t)}
+}
+
+func (p *parser) resolve(ref string) rdf.IRI {
+	if p.base == "" {
+		return rdf.IRI(ref)
+	}
+	base, err := url.Parse(p.base)
+	if err != nil {
+		return rdf.IRI(ref)
+	}
+	relative, err := url.Parse(ref)
+	if err != nil {
+		return rdf.IRI(p.base + ref)
+	}
+	return rdf.IRI(base.ResolveReference(relative).String())
+}
+
+func (p *parser) parseValues() (*valuesBlock, error) {
+	values := &valuesBlock{}
+	single := false
+	if t := p.peek(); t.kind == tokVar {
+		p.next()
+		values.variables = []string{t.text}
+		single = true
+	} else {
+		if err := p.expectPunct("("); err != nil {
+			return nil, err
+		}
+		for !p.acceptPunct(")") {
+			t := p.next()
+			if t.kind != tokVar {
+				return nil, p.errorf("expected variable in VALUES")
+			}
+			values.variables = append(values.variables, t.text)
+		}
+	}
+
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	value := func() (rdf.Term, error) {
+		if p.acceptWord("UNDEF") {
+			return nil, nil
+		}
+		return p.parseTerm()
+	}
+	for !p.acceptPunct("}") {
+		var row []rdf.Term
+		if single {
+			term, err := value()
+			if err != nil {
+				return nil, err
+			}
+			row = []rdf.Term{term}
+		} else {
+			if err := p.expectPunct("("); err != nil {
+				return nil, err
+			}
+			for !p.acceptPunct(")") {
+				term, err := value()
+				if err != nil {
+					return nil, err
+				}
+				row = append(row, term)
+			}
+			if len(row) != len(values.variables) {
+				return nil, p.errorf("VALUES row has %d values for %d variables", len(row), len(values.variables))
+			}
+		}
+		values.rows = append(values.rows, row)
+	}
+	return values, nil
+}
+
+// parseExpression parses a full expression with operators.
+func (p *parser) parseExpression() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptPunct("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptPunct("&&") {
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseRelational() (expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<", ">", "<=", ">="} {
+		if p.acceptPunct(op) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryExpr{op: op, left: left, right: right}, nil
+		}
+	}
+	negated := false
+	if p.isWord("NOT") && p.peekAt(1).kind == tokWord && strings.EqualFold(p.peekAt(1).text, "IN") {
+		p.next()
+		negated = true
+	}
+	if p.acceptWord("IN") {
+		list, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		return &inExpr{operand: left, list: list, negated: negated}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		switch {
+		case p.acceptPunct("+"):
+			op = "+"
+		case p.acceptPunct("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		switch {
+		case p.acceptPunct("*"):
+			op = "*"
+		case p.acceptPunct("/"):
+			op = "/"
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	for _, op := range []string{"!", "-", "+"} {
+		if p.acceptPunct(op) {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryExpr{op: op, operand: operand}, nil
+		}
+	}
+	return p.parsePrimaryExpression()
+}
+
+// parsePrimaryExpression parses a bracketed expression, a function call, a variable or a constant.
+func (p *parser) parsePrimaryExpression() (expr, error) {
+	t := p.peek()
+	switch {
+	case p.acceptPunct("("):
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expectPunct(")")
+	case t.kind == tokVar:
+		p.next()
+		return &varExpr{name: t.text}, nil
+	case t.kind == tokIRI || t.kind == tokPName:
+		iri, err := p.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isPunct("(") {
+			return &constExpr{term: iri}, nil
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		return &callExpr{name: string(iri), args: args}, nil
+	case t.kind == tokWord && !p.isWord("true", "false"):
+		return p.parseBuiltinCall()
+	}
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	return &constExpr{term: term}, nil
+}
+
+func (p *parser) parseBuiltinCall() (expr, error) {
+	name := strings.ToUpper(p.next().text)
+	switch name {
+	case "NOT", "EXISTS":
+		negated := name == "NOT"
+		if negated {
+			if err := p.expectWord("EXISTS"); err != nil {
+				return nil, err
+			}
+		}
+		g, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &existsExpr{group: g, negated: negated}, nil
+	case "COUNT", "SUM", "MIN", "MAX", "AVG", "SAMPLE", "GROUP_CONCAT":
+		return p.parseAggregate(name)
+	}
+	if !builtins[name] {
+		return nil, p.errorf("unknown function %s", name)
+	}
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if name == "BOUND" {
+		if len(args) != 1 {
+			return nil, p.errorf("BOUND takes one variable")
+		}
+		if _, ok := args[0].(*varExpr); !ok {
+			return nil, p.errorf("BOUND takes one variable")
+		}
+	}
+	return &callExpr{name: name, args: args}, nil
+}
+
+func (p *parser) parseAggregate(name string) (expr, error) {
+	if p.query != nil {
+		p.query.aggregated = true
+	}
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	aggregate := &aggregateExpr{name: name, separator: " "}
+	aggregate.distinct = p.acceptWord("DISTINCT")
+	if name == "COUNT" && p.acceptPunct("*") {
+		return aggregate, p.expectPunct(")")
+	}
+	arg, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	aggregate.arg = arg
+	if name == "GROUP_CONCAT" && p.acceptPunct(";") {
+		if err := p.expectWord("SEPARATOR"); err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+		t := p.next()
+		if t.kind != tokString {
+			return nil, p.errorf("expected string after SEPARATOR =")
+		}
+		aggregate.separator = t.text
+	}
+	return aggregate, p.expectPunct(")")
+}
+
+// parseArguments parses a parenthesised, comma separated expression list.
+func (p *parser) parseArguments() ([]expr, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var args []expr
+	if p.acceptPunct(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.acceptPunct(")") {
+			return args, nil
+		}
+		if err := p.expectPunct(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isNumberToken(t token) bool {
+	return t.kind == tokInteger || t.kind == tokDecimal || t.kind == tokDouble
+}
+
+func numericLiteral(t token) rdf.Literal {
+	switch t.kind {
+	case tokDecimal:
+		return rdf.NewTypedLiteral(t.text, rdf.XSDDecimal)
+	case tokDouble:
+		return rdf.NewTypedLiteral(t.text, rdf.XSDDouble)
+	}
+	return rdf.NewTypedLiteral(t.text, rdf.XSDInteger)
+}
@@ -0,0 +1,143 @@
+package sparql
+
+import (
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// Form is the kind of result a query produces.
+type Form int
+
+const (
+	Select Form = iota
+	Construct
+	Ask
+	Describe
+)
+
+func (f Form) String() string {
+	switch f {
+	case Construct:
+		return "CONSTRUCT"
+	case Ask:
+		return "ASK"
+	case Describe:
+		return "DESCRIBE"
+	}
+	return "SELECT"
+}
+
+// Query is a parsed SPARQL query.
+type Query struct {
+	Form     Form
+	Prefixes map[string]string // Prefixes declared by the query, used to abbreviate graph results.
+
+	distinct   bool
+	star       bool
+	projection []projection
+	template   []triplePattern // CONSTRUCT template.
+	describe   []node          // DESCRIBE resources.
+	where      *group
+	groupBy    []projection
+	having     []expr
+	orderBy    []orderCondition
+	limit      int // -1 when absent.
+	offset     int
+	values     *valuesBlock // Trailing VALUES clause.
+	aggregated bool         // The query uses GROUP BY or aggregate functions.
+}
+
+// projection is a selected variable, optionally computed by an expression.
+type projection struct {
+	name string
+	expr expr // nil for a plain variable.
+}
+
+type orderCondition struct {
+	expr       expr
+	descending bool
+}
+
+// node is a position of a triple pattern: a variable or a constant term.
+type node struct {
+	variable string
+	term     rdf.Term
+}
+
+func (n node) isVar() bool { return n.variable != "" }
+
+// triplePattern matches triples. The predicate is a property path; a simple IRI or variable predicate is
+// a linkPath or varPath.
+type triplePattern struct {
+	subject node
+	path    path
+	object  node
+}
+
+// group is a group graph pattern: a sequence of elements whose solutions are joined, then filtered.
+type group struct {
+	elements []element
+	filters  []expr
+}
+
+type element interface{ isElement() }
+
+type (
+	triplesBlock    struct{ patterns []triplePattern }
+	optionalElement struct{ group *group }
+	minusElement    struct{ group *group }
+	unionElement    struct{ groups []*group }
+	graphElement    struct {
+		name  node
+		group *group
+	}
+	bindElement struct {
+		expr     expr
+		variable string
+	}
+	valuesBlock struct {
+		variables []string
+		rows      [][]rdf.Term // nil entries are UNDEF.
+	}
+	subQuery struct{ query *Query }
+)
+
+func (*triplesBlock) isElement()    {}
+func (*optionalElement) isElement() {}
+func (*minusElement) isElement()    {}
+func (*unionElement) isElement()    {}
+func (*graphElement) isElement()    {}
+func (*bindElement) isElement()     {}
+func (*valuesBlock) isElement()     {}
+func (*group) isElement()           {}
+func (*subQuery) isElement()        {}
+
+// path is a property path expression.
+type path interface{ isPath() }
+
+type (
+	linkPath     struct{ iri rdf.IRI }
+	varPath      struct{ name string }
+	inversePath  struct{ path path }
+	sequencePath struct {
+		first, rest path
+	}
+	alternativePath struct {
+		left, right path
+	}
+	repeatPath struct {
+		path      path
+		min       int
+		unbounded bool // max is infinite; otherwise max is 1.
+	}
+	negatedPath struct {
+		forward, inverse []rdf.IRI
+	}
+)
+
+func (linkPath) isPath()        {}
+func (varPath) isPath()         {}
+func (inversePath) isPath()     {}
+func (sequencePath) isPath()    {}
+func (alternativePath) isPath() {}
+func (repeatPath) isPath()      {}
+func (negatedPath) isPath()     {}
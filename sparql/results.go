@@ -0,0 +1,87 @@
+package sparql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+type jsonTerm struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Lang     string `json:"xml:lang,omitempty"`
+	Datatype string `json:"datatype,omitempty"`
+}
+
+type jsonHead struct {
+	Vars []string `json:"vars,omitempty"`
+}
+
+type jsonResults struct {
+	Bindings []map[string]jsonTerm `json:"bindings"`
+}
+
+type jsonDocument struct {
+	Head    jsonHead     `json:"head"`
+	Results *jsonResults `json:"results,omitempty"`
+	Boolean *bool        `json:"boolean,omitempty"`
+}
+
+// WriteJSON writes a SELECT or ASK result in the SPARQL 1.1 Query Results JSON format.
+func (r *Result) WriteJSON(w io.Writer) error {
+	var doc jsonDocument
+	switch r.Form {
+	case Ask:
+		doc.Boolean = &r.Boolean
+	case Select:
+		doc.Head.Vars = r.Variables
+		doc.Results = &jsonResults{Bindings: make([]map[string]jsonTerm, 0, len(r.Solutions))}
+		for _, s := range r.Solutions {
+			binding := map[string]jsonTerm{}
+			for name, value := range s {
+				binding[name] = toJSONTerm(value)
+			}
+			doc.Results.Bindings = append(doc.Results.Bindings, binding)
+		}
+	default:
+		return fmt.Errorf("%s results are graphs, not JSON solutions", r.Form)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+func toJSONTerm(t rdf.Term) jsonTerm {
+	switch v := t.(type) {
+	case rdf.IRI:
+		return jsonTerm{Type: "uri", Value: string(v)}
+	case rdf.BlankNode:
+		return jsonTerm{Type: "bnode", Value: string(v)}
+	case rdf.Literal:
+		term := jsonTerm{Type: "literal", Value: v.Value, Lang: v.Language}
+		if v.Language == "" && v.Datatype != "" && v.Datatype != rdf.XSDString {
+			term.Datatype = string(v.Datatype)
+		}
+		return term
+	}
+	return jsonTerm{Type: "literal", Value: t.String()}
+}
+
+// WriteTurtle writes a CONSTRUCT or DESCRIBE result as Turtle.
+func (r *Result) WriteTurtle(w io.Writer) error {
+	if r.Graph == nil {
+		return fmt.Errorf("%s results are solutions, not a graph", r.Form)
+	}
+	return rdf.WriteTurtle(w, r.Graph)
+}
+
+// WriteNTriples writes a CONSTRUCT or DESCRIBE result as N-Triples.
+func (r *Result) WriteNTriples(w io.Writer) error {
+	if r.Graph == nil {
+		return fmt.Errorf("%s results are solutions, not a graph", r.Form)
+	}
+	return rdf.WriteNTriples(w, r.Graph)
+}
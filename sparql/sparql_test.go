@@ -0,0 +1,385 @@
+package sparql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+const testData = `
+@prefix ex: <http://example.com/> .
+@prefix gm: <http://graphmind.io/ontology#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+
+ex:orders a gm:Service ; gm:name "orders" ; gm:calls ex:billing, ex:auth ; gm:readsFrom ex:db ; ex:port 8080 .
+ex:billing a gm:Service ; gm:name "billing" ; gm:calls ex:ledger ; ex:port 8081 .
+ex:auth a gm:Service ; gm:name "auth" ; ex:port 8082 .
+ex:ledger a gm:Service ; gm:name "ledger" ; rdfs:label "Ledger"@en, "Grand livre"@fr .
+ex:db a gm:Database ; gm:name "orders-db" .
+`
+
+// testPrefixes are declared for every test query.
+var testPrefixes = map[string]string{
+	"ex":   "http://example.com/",
+	"gm":   "http://graphmind.io/ontology#",
+	"rdfs": "http://www.w3.org/2000/01/rdf-schema#",
+}
+
+func testDataset(t *testing.T) *Dataset {
+	t.Helper()
+	g, err := rdf.ParseTurtle(testData, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := rdf.NewGraph()
+	other.AddTriple(rdf.IRI("http://example.com/orders"), "http://example.com/owner", rdf.NewLiteral("team-a"))
+	return &Dataset{Default: g, Named: map[rdf.IRI]*rdf.Graph{
+		"http://example.com/graph/services": g,
+		"http://example.com/graph/owners":   other,
+	}}
+}
+
+func run(t *testing.T, query string) *Result {
+	t.Helper()
+	q, err := Parse(query, testPrefixes)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	result, err := Execute(context.Background(), q, testDataset(t))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	return result
+}
+
+// rows renders the solutions of a result one per line, with the values of its variables separated by
+// spaces: IRIs without the example namespace, literals by their value and language, and "-" for unbound
+// variables.
+func rows(result *Result) []string {
+	var lines []string
+	for _, solution := range result.Solutions {
+		var values []string
+		for _, name := range result.Variables {
+			values = append(values, short(solution[name]))
+		}
+		lines = append(lines, strings.Join(values, " "))
+	}
+	return lines
+}
+
+func short(term rdf.Term) string {
+	switch v := term.(type) {
+	case nil:
+		return "-"
+	case rdf.IRI:
+		return strings.TrimPrefix(strings.TrimPrefix(string(v), "http://example.com/"), "http://graphmind.io/ontology#")
+	case rdf.Literal:
+		if v.Language != "" {
+			return v.Value + "@" + v.Language
+		}
+		return v.Value
+	}
+	return term.String()
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		ordered bool // Whether the query orders its solutions; others are compared sorted.
+	}{
+		{
+			name:  "basic graph pattern",
+			query: `SELECT ?s WHERE { ?s a gm:Service }`,
+			want:  []string{"auth", "billing", "ledger", "orders"},
+		},
+		{
+			name:  "optional",
+			query: `SELECT ?n ?p WHERE { ?s gm:name ?n OPTIONAL { ?s ex:port ?p } }`,
+			want:  []string{"auth 8082", "billing 8081", "ledger -", "orders 8080", "orders-db -"},
+		},
+		{
+			name:  "optional with a filter",
+			query: `SELECT ?n ?p WHERE { ?s a gm:Service ; gm:name ?n OPTIONAL { ?s ex:port ?p FILTER(?p > 8080) } }`,
+			want:  []string{"auth 8082", "billing 8081", "ledger -", "orders -"},
+		},
+		{
+			name:  "filter",
+			query: `SELECT ?n WHERE { ?s gm:name ?n ; ex:port ?p FILTER(?p >= 8081 && STRSTARTS(?n, "b")) }`,
+			want:  []string{"billing"},
+		},
+		{
+			name:  "union",
+			query: `SELECT ?x WHERE { { ex:orders gm:calls ?x } UNION { ex:orders gm:readsFrom ?x } }`,
+			want:  []string{"auth", "billing", "db"},
+		},
+		{
+			name:  "one or more path",
+			query: `SELECT ?t WHERE { ex:orders gm:calls+ ?t }`,
+			want:  []string{"auth", "billing", "ledger"},
+		},
+		{
+			name:  "zero or more path",
+			query: `SELECT ?t WHERE { ex:orders gm:calls* ?t }`,
+			want:  []string{"auth", "billing", "ledger", "orders"},
+		},
+		{
+			name:  "zero or one path",
+			query: `SELECT ?t WHERE { ex:billing gm:calls? ?t }`,
+			want:  []string{"billing", "ledger"},
+		},
+		{
+			name:  "sequence path",
+			query: `SELECT ?n WHERE { ex:orders gm:calls/gm:name ?n }`,
+			want:  []string{"auth", "billing"},
+		},
+		{
+			name:  "inverse path",
+			query: `SELECT ?x WHERE { ex:ledger ^gm:calls ?x }`,
+			want:  []string{"billing"},
+		},
+		{
+			name:  "alternative path",
+			query: `SELECT ?x WHERE { ex:orders (gm:calls|gm:readsFrom) ?x }`,
+			want:  []string{"auth", "billing", "db"},
+		},
+		{
+			name:  "group by",
+			query: `SELECT ?s (COUNT(?t) AS ?n) WHERE { ?s gm:calls ?t } GROUP BY ?s`,
+			want:  []string{"billing 1", "orders 2"},
+		},
+		{
+			name:  "having",
+			query: `SELECT ?s (COUNT(?t) AS ?n) WHERE { ?s gm:calls ?t } GROUP BY ?s HAVING (COUNT(?t) > 1)`,
+			want:  []string{"orders 2"},
+		},
+		{
+			name:  "aggregates without group by",
+			query: `SELECT (SUM(?p) AS ?sum) (MIN(?p) AS ?min) (MAX(?p) AS ?max) (COUNT(*) AS ?n) WHERE { ?s ex:port ?p }`,
+			want:  []string{"24243 8080 8082 3"},
+		},
+		{
+			name:  "count distinct",
+			query: `SELECT (COUNT(DISTINCT ?type) AS ?n) WHERE { ?s a ?type }`,
+			want:  []string{"2"},
+		},
+		{
+			name:  "minus",
+			query: `SELECT ?s WHERE { ?s a gm:Service MINUS { ?s gm:calls ?x } }`,
+			want:  []string{"auth", "ledger"},
+		},
+		{
+			name:  "not exists",
+			query: `SELECT ?s WHERE { ?s a gm:Service FILTER NOT EXISTS { ?s ex:port ?p } }`,
+			want:  []string{"ledger"},
+		},
+		{
+			name:  "exists",
+			query: `SELECT ?s WHERE { ?s a gm:Service FILTER EXISTS { ?s gm:calls ex:ledger } }`,
+			want:  []string{"billing"},
+		},
+		{
+			name:  "values",
+			query: `SELECT ?n WHERE { VALUES ?s { ex:auth ex:db } ?s gm:name ?n }`,
+			want:  []string{"auth", "orders-db"},
+		},
+		{
+			name:  "trailing values",
+			query: `SELECT ?s ?n WHERE { ?s gm:name ?n } VALUES (?s) { (ex:billing) (ex:ledger) }`,
+			want:  []string{"billing billing", "ledger ledger"},
+		},
+		{
+			name:  "subquery",
+			query: `SELECT ?s ?n WHERE { { SELECT ?s (COUNT(?t) AS ?n) WHERE { ?s gm:calls ?t } GROUP BY ?s } FILTER(?n > 1) }`,
+			want:  []string{"orders 2"},
+		},
+		{
+			name:  "bind",
+			query: `SELECT ?u WHERE { ?s ex:port ?p BIND(CONCAT(UCASE(STR(?s)), ":", STR(?p)) AS ?u) FILTER(?p = 8080) }`,
+			want:  []string{"HTTP://EXAMPLE.COM/ORDERS:8080"},
+		},
+		{
+			name:  "language filter",
+			query: `SELECT ?l WHERE { ex:ledger rdfs:label ?l FILTER(LANG(?l) = "fr") }`,
+			want:  []string{"Grand livre@fr"},
+		},
+		{
+			name:    "order, limit and offset",
+			query:   `SELECT ?n WHERE { ?s ex:port ?p ; gm:name ?n } ORDER BY DESC(?p) LIMIT 2 OFFSET 1`,
+			want:    []string{"billing", "orders"},
+			ordered: true,
+		},
+		{
+			name:  "distinct",
+			query: `SELECT DISTINCT ?type WHERE { ?s a ?type }`,
+			want:  []string{"Database", "Service"},
+		},
+		{
+			name:  "named graphs",
+			query: `SELECT DISTINCT ?g WHERE { GRAPH ?g { ex:orders ?p ?o } }`,
+			want:  []string{"graph/owners", "graph/services"},
+		},
+		{
+			name:  "named graph by name",
+			query: `SELECT ?o WHERE { GRAPH ex:graph\/owners { ?s ex:owner ?o } }`,
+			want:  []string{"team-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rows(run(t, tt.query))
+			want := append([]string(nil), tt.want...)
+			if !tt.ordered {
+				sort.Strings(got)
+				sort.Strings(want)
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("solutions:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestAsk(t *testing.T) {
+	tests := map[string]bool{
+		`ASK { ex:orders gm:calls ex:billing }`:         true,
+		`ASK { ex:orders gm:calls+ ex:ledger }`:         true,
+		`ASK { ex:auth gm:calls ?x }`:                   false,
+		`ASK WHERE { ?s ex:port ?p FILTER(?p > 9000) }`: false,
+	}
+	for query, want := range tests {
+		if got := run(t, query).Boolean; got != want {
+			t.Errorf("%s = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestConstructAndDescribe(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string // The triples of the result graph, with short terms.
+	}{
+		{
+			name:  "construct",
+			query: `CONSTRUCT { ?b ex:calledBy ?a } WHERE { ?a gm:calls ?b }`,
+			want:  []string{"auth calledBy orders", "billing calledBy orders", "ledger calledBy billing"},
+		},
+		{
+			name:  "construct where",
+			query: `CONSTRUCT WHERE { ?s gm:readsFrom ?o }`,
+			want:  []string{"orders readsFrom db"},
+		},
+		{
+			name:  "describe",
+			query: `DESCRIBE ex:auth`,
+			want:  []string{"auth name auth", "auth port 8082", "auth type Service"},
+		},
+		{
+			name:  "describe a variable",
+			query: `DESCRIBE ?s WHERE { ?s gm:name "orders-db" }`,
+			want:  []string{"db name orders-db", "db type Database"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := run(t, tt.query)
+			if result.Graph == nil {
+				t.Fatal("the result has no graph")
+			}
+			var got []string
+			for _, triple := range result.Graph.Triples() {
+				predicate := short(triple.Predicate)
+				if triple.Predicate == rdf.IRI(rdf.RDFNamespace+"type") {
+					predicate = "type"
+				}
+				got = append(got, short(triple.Subject)+" "+predicate+" "+short(triple.Object))
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("triples:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"incomplete triple":   `SELECT ?s WHERE { ?s ?p }`,
+		"unknown keyword":     `SELEC * WHERE { ?s ?p ?o }`,
+		"undeclared prefix":   `SELECT * WHERE { ?s nope:p ?o }`,
+		"unclosed group":      `SELECT * WHERE { ?s ?p ?o`,
+		"trailing input":      `ASK { ?s ?p ?o } ?extra`,
+		"unterminated string": `SELECT * WHERE { ?s ?p "open }`,
+		"bad aggregate":       `SELECT (COUNT(?s) ?n) WHERE { ?s ?p ?o }`,
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(query, testPrefixes)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("error = %v, want a SyntaxError", err)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf strings.Builder
+	if err := run(t, `SELECT ?l WHERE { ex:ledger rdfs:label ?l FILTER(LANG(?l) = "en") }`).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"vars": [`, `"type": "literal"`, `"value": "Ledger"`, `"xml:lang": "en"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("JSON lacks %s:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := run(t, `ASK { ?s ?p ?o }`).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"boolean": true`) {
+		t.Errorf("ASK JSON = %s", buf.String())
+	}
+}
+
+// expiringContext reaches its deadline after its error has been checked a number of times.
+type expiringContext struct {
+	context.Context
+	checks int
+}
+
+func (c *expiringContext) Err() error {
+	if c.checks == 0 {
+		return context.DeadlineExceeded
+	}
+	c.checks--
+	return nil
+}
+
+func TestExecuteStops(t *testing.T) {
+	q, err := Parse(`SELECT ?a ?b WHERE { ?a gm:calls ?x . ?b gm:calls ?y OPTIONAL { ?x gm:name ?n } }`, testPrefixes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Execute(canceled, q, testDataset(t)); !errors.Is(err, context.Canceled) {
+		t.Errorf("error of a canceled query = %v", err)
+	}
+	// The query is stopped while it matches the triple patterns, not only between them.
+	for checks := 0; checks < 4; checks++ {
+		if _, err := Execute(&expiringContext{Context: context.Background(), checks: checks}, q, testDataset(t)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error of a query expiring after %d checks = %v", checks, err)
+		}
+	}
+	if _, err := Execute(&expiringContext{Context: context.Background(), checks: 1000}, q, testDataset(t)); err != nil {
+		t.Errorf("error of a query in time = %v", err)
+	}
+}
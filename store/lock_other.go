@@ -0,0 +1,36 @@
+//go:build !unix
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleLockAge is how old catalog.lock must be before it is taken to be left behind by a process that
+// exited while holding it. Catalog changes take well under a second.
+const staleLockAge = time.Minute
+
+// lockCatalog takes an exclusive lock on the store in dir by creating catalog.lock, waiting for other
+// processes to remove it. The lock is released by the returned function.
+func lockCatalog(dir string) (func(), error) {
+	path := filepath.Join(dir, "catalog.lock")
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock graph store: %w", err)
+		}
+		if stat, err := os.Stat(path); err == nil && time.Since(stat.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,588 @@
+// Package store is GraphMind's embedded, persistent graph store. It holds named graphs, typically one per
+// repository, and keeps every version written to a graph so earlier builds stay available. The latest
+// versions of all graphs together form the union graph that is queried and served.
+//
+// A store is a directory containing catalog.json, which lists the versions of every graph, and one Turtle
+// file per version under graphs/.
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+// ErrNotFound is returned when a graph or graph version does not exist.
+var ErrNotFound = errors.New("graph not found")
+
+// ErrInvalidCommit is returned when a commit is too short or matches more than one commit.
+var ErrInvalidCommit = errors.New("invalid commit")
+
+// GraphInfo describes one version of a named graph.
+type GraphInfo struct {
+	Name       rdf.IRI   `json:"name"`
+	Version    int       `json:"version"`              // Versions of a graph are numbered from 1.
+	Repository string    `json:"repository,omitempty"` // The repository URL, for repository graphs.
+	Branch     string    `json:"branch,omitempty"`     // The branch the repository was built from.
+	Commit     string    `json:"commit,omitempty"`     // The SHA of the commit the repository was built from.
+	CommitTime time.Time `json:"commitTime"`           // The committer date of Commit, zero for graphs not built from a commit.
+	Build      string    `json:"build,omitempty"`      // The build that wrote the version, for example a workflow run ID.
+	Created    time.Time `json:"created"`
+	Triples    int       `json:"triples"`
+	File       string    `json:"file"` // Relative to the store directory.
+}
+
+// Time returns the point in history the version describes: the date of its commit, or the time it was
+// written for graphs not built from a commit.
+func (info GraphInfo) Time() time.Time {
+	if !info.CommitTime.IsZero() {
+		return info.CommitTime
+	}
+	return info.Created
+}
+
+type catalog struct {
+	Graphs []GraphInfo `json:"graphs"`
+}
+
+// Store is a graph store in a directory. It is safe for concurrent use within a process and by several
+// processes sharing the directory: changes to the catalog are made under catalog.lock, and the catalog is
+// reloaded when another process changes it.
+type Store struct {
+	dir string
+
+	mu          sync.Mutex
+	catalog     catalog
+	catalogStat os.FileInfo           // The catalog file last read or written, nil if there was none.
+	graphs      map[string]*rdf.Graph // Parsed versions by cacheKey.
+	union       *rdf.Graph            // Union of the latest versions, nil until requested.
+}
+
+// Open opens the store in dir, creating it if it does not exist.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "graphs"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create graph store %s: %w", dir, err)
+	}
+	s := &Store{dir: dir, graphs: map[string]*rdf.Graph{}}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Put writes g as a new version of the named graph and returns the description of the new version.
+// Repository, Branch, Commit, CommitTime and Build are taken from info; the other fields are filled in by
+// the store.
+func (s *Store) Put(name rdf.IRI, g *rdf.Graph, info GraphInfo) (GraphInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockCatalog(s.dir)
+	if err != nil {
+		return GraphInfo{}, err
+	}
+	defer unlock()
+	if err := s.reload(); err != nil {
+		return GraphInfo{}, err
+	}
+
+	info.Name = name
+	info.Version = 1
+	if latest, ok := s.latest(name); ok {
+		info.Version = latest.Version + 1
+	}
+	info.Created = time.Now().UTC()
+	info.Triples = g.Len()
+	info.File = filepath.ToSlash(filepath.Join("graphs", graphDirName(name), strconv.Itoa(info.Version)+".ttl"))
+
+	path := filepath.Join(s.dir, filepath.FromSlash(info.File))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return GraphInfo{}, fmt.Errorf("failed to create graph directory: %w", err)
+	}
+	if err := writeAtomically(path, []byte(rdf.ToTurtle(g))); err != nil {
+		return GraphInfo{}, fmt.Errorf("failed to write graph %s: %w", name, err)
+	}
+
+	s.catalog.Graphs = append(s.catalog.Graphs, info)
+	if err := s.saveCatalog(); err != nil {
+		return GraphInfo{}, err
+	}
+	s.graphs[cacheKey(info)] = g.Clone()
+	s.union = nil
+	return info, nil
+}
+
+// Graphs returns the latest version of every graph, ordered by name.
+func (s *Store) Graphs() ([]GraphInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s.latestVersions(), nil
+}
+
+// Versions returns every version of a graph, oldest first.
+func (s *Store) Versions(name rdf.IRI) ([]GraphInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	var versions []GraphInfo
+	for _, info := range s.catalog.Graphs {
+		if info.Name == name {
+			versions = append(versions, info)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return versions, nil
+}
+
+// Graph returns the latest version of a graph. The returned graph is shared and must not be modified.
+func (s *Store) Graph(name rdf.IRI) (*rdf.Graph, error) {
+	return s.GraphVersion(name, 0)
+}
+
+// GraphVersion returns a version of a graph, or the latest version if version is 0. The returned graph is
+// shared and must not be modified.
+func (s *Store) GraphVersion(name rdf.IRI, version int) (*rdf.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	for i := len(s.catalog.Graphs) - 1; i >= 0; i-- {
+		info := s.catalog.Graphs[i]
+		if info.Name == name && (version == 0 || info.Version == version) {
+			return s.load(info)
+		}
+	}
+	if version != 0 {
+		return nil, fmt.Errorf("%w: %s version %d", ErrNotFound, name, version)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Union returns the union of the latest versions of all graphs. The returned graph is shared and must not
+// be modified.
+func (s *Store) Union() (*rdf.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	if s.union != nil {
+		return s.union, nil
+	}
+
+	union := rdf.NewGraph()
+	for _, info := range s.latestVersions() {
+		g, err := s.load(info)
+		if err != nil {
+			return nil, err
+		}
+		union.Merge(g)
+	}
+	s.union = union
+	return union, nil
+}
+
+// Builds returns the builds that wrote graph versions, in the order they first wrote one.
+func (s *Store) Builds() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	var builds []string
+	seen := map[string]bool{}
+	for _, info := range s.catalog.Graphs {
+		if info.Build != "" && !seen[info.Build] {
+			seen[info.Build] = true
+			builds = append(builds, info.Build)
+		}
+	}
+	return builds, nil
+}
+
+// BuildUnion returns the union of the graphs as a build left them: for every graph, the latest version
+// written by the build or before it. Graphs the build did not write are included as they were at the
+// time, so the unions of two builds differ only by what happened in between.
+func (s *Store) BuildUnion(build string) (*rdf.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	last := -1
+	for i, info := range s.catalog.Graphs {
+		if info.Build == build {
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%w: no graphs written by build %s", ErrNotFound, build)
+	}
+
+	union := rdf.NewGraph()
+	for _, info := range latestOf(s.catalog.Graphs[:last+1]) {
+		g, err := s.load(info)
+		if err != nil {
+			return nil, err
+		}
+		union.Merge(g)
+	}
+	return union, nil
+}
+
+// Snapshot returns the version of every graph that was current at a point in time: the version with the
+// latest Time not after t. Versions are compared by commit date, so rebuilding an old commit does not
+// make it current. Graphs without a version that old are left out. The result is ordered by name.
+func (s *Store) Snapshot(t time.Time) ([]GraphInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s.snapshot(t, GraphInfo{}), nil
+}
+
+// CommitSnapshot returns the graph versions as of a commit: the latest version built from the commit for
+// its repository's graph, and the versions current at the commit's date for the other graphs. The commit
+// may be abbreviated to a unique prefix of at least 7 characters.
+func (s *Store) CommitSnapshot(commit string) ([]GraphInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	pinned, err := s.commitVersion("", commit)
+	if err != nil {
+		return nil, err
+	}
+	return s.snapshot(pinned.Time(), pinned), nil
+}
+
+// CommitVersion returns the latest version of a graph built from a commit, which may be abbreviated to a
+// unique prefix of at least 7 characters.
+func (s *Store) CommitVersion(name rdf.IRI, commit string) (GraphInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return GraphInfo{}, err
+	}
+	return s.commitVersion(name, commit)
+}
+
+// Load returns the graph of a version listed by the store. The returned graph is shared and must not be
+// modified.
+func (s *Store) Load(info GraphInfo) (*rdf.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(info)
+}
+
+// UnionOf returns the union of the graphs of the given versions.
+func (s *Store) UnionOf(infos []GraphInfo) (*rdf.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	union := rdf.NewGraph()
+	for _, info := range infos {
+		g, err := s.load(info)
+		if err != nil {
+			return nil, err
+		}
+		union.Merge(g)
+	}
+	return union, nil
+}
+
+// SnapshotGraph returns the version of a graph in a snapshot, or the union of the snapshot if name is
+// empty. The returned graph must not be modified.
+func (s *Store) SnapshotGraph(snapshot []GraphInfo, name rdf.IRI) (*rdf.Graph, error) {
+	if name == "" {
+		return s.UnionOf(snapshot)
+	}
+	for _, info := range snapshot {
+		if info.Name == name {
+			return s.Load(info)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s did not exist at that point in history", ErrNotFound, name)
+}
+
+// Delete removes every version of a graph.
+func (s *Store) Delete(name rdf.IRI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockCatalog(s.dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	var kept []GraphInfo
+	var removed []GraphInfo
+	for _, info := range s.catalog.Graphs {
+		if info.Name == name {
+			removed = append(removed, info)
+		} else {
+			kept = append(kept, info)
+		}
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	// Save the catalog first so a failure never leaves it pointing at missing files.
+	s.catalog.Graphs = kept
+	if err := s.saveCatalog(); err != nil {
+		return err
+	}
+	for _, info := range removed {
+		delete(s.graphs, cacheKey(info))
+	}
+	s.union = nil
+	if err := os.RemoveAll(filepath.Join(s.dir, "graphs", graphDirName(name))); err != nil {
+		return fmt.Errorf("failed to remove graph files of %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *Store) latest(name rdf.IRI) (GraphInfo, bool) {
+	for i := len(s.catalog.Graphs) - 1; i >= 0; i-- {
+		if s.catalog.Graphs[i].Name == name {
+			return s.catalog.Graphs[i], true
+		}
+	}
+	return GraphInfo{}, false
+}
+
+func (s *Store) latestVersions() []GraphInfo {
+	return latestOf(s.catalog.Graphs)
+}
+
+// snapshot returns the version of every graph current at t, with pinned replacing the version of its graph
+// if it is set.
+func (s *Store) snapshot(t time.Time, pinned GraphInfo) []GraphInfo {
+	current := map[rdf.IRI]GraphInfo{}
+	for _, info := range s.catalog.Graphs {
+		if info.Time().After(t) {
+			continue
+		}
+		if previous, ok := current[info.Name]; !ok || !info.Time().Before(previous.Time()) {
+			current[info.Name] = info
+		}
+	}
+	if pinned.Name != "" {
+		current[pinned.Name] = pinned
+	}
+	infos := make([]GraphInfo, 0, len(current))
+	for _, info := range current {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// commitVersion returns the latest version built from a commit, of the named graph or of any graph if name
+// is empty.
+func (s *Store) commitVersion(name rdf.IRI, commit string) (GraphInfo, error) {
+	if len(commit) < 7 {
+		return GraphInfo{}, fmt.Errorf("%w: %q is too short, use at least 7 characters", ErrInvalidCommit, commit)
+	}
+	var found GraphInfo
+	for _, info := range s.catalog.Graphs {
+		if (name != "" && info.Name != name) || !strings.HasPrefix(info.Commit, commit) {
+			continue
+		}
+		if found.Commit != "" && found.Commit != info.Commit {
+			return GraphInfo{}, fmt.Errorf("%w: %s matches %s and %s", ErrInvalidCommit, commit, found.Commit, info.Commit)
+		}
+		found = info
+	}
+	if found.Commit == "" {
+		if name != "" {
+			return GraphInfo{}, fmt.Errorf("%w: %s at commit %s", ErrNotFound, name, commit)
+		}
+		return GraphInfo{}, fmt.Errorf("%w: no graph built from commit %s", ErrNotFound, commit)
+	}
+	return found, nil
+}
+
+// ParseTime parses the time of an "as of" query: an RFC 3339 time, or a date, which stands for the end of
+// that day in UTC.
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected a date like 2024-05-31 or an RFC 3339 time", value)
+	}
+	return day.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// latestOf returns the last version of every graph in a part of the catalog, ordered by name.
+func latestOf(graphs []GraphInfo) []GraphInfo {
+	latest := map[rdf.IRI]GraphInfo{}
+	for _, info := range graphs {
+		latest[info.Name] = info
+	}
+	infos := make([]GraphInfo, 0, len(latest))
+	for _, info := range latest {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// load returns the parsed graph of a version, parsing it on first use.
+func (s *Store) load(info GraphInfo) (*rdf.Graph, error) {
+	if g, ok := s.graphs[cacheKey(info)]; ok {
+		return g, nil
+	}
+	g, err := rdf.ParseTurtleFile(filepath.Join(s.dir, filepath.FromSlash(info.File)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph %s version %d: %w", info.Name, info.Version, err)
+	}
+	s.graphs[cacheKey(info)] = g
+	return g, nil
+}
+
+// cacheKey identifies a version in the cache of parsed graphs. Files are reused when a deleted graph is
+// written again, so the file alone does not tell versions apart.
+func cacheKey(info GraphInfo) string {
+	return info.File + "@" + info.Created.Format(time.RFC3339Nano)
+}
+
+// refresh reloads the catalog if it changed on disk since it was last read. The catalog is replaced by a
+// rename on every save, so a change shows as a different file even when the size and modification time
+// happen to match.
+func (s *Store) refresh() error {
+	stat, err := os.Stat(s.catalogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read graph store catalog: %w", err)
+	}
+	if previous := s.catalogStat; previous != nil && os.SameFile(previous, stat) &&
+		previous.Size() == stat.Size() && previous.ModTime().Equal(stat.ModTime()) {
+		return nil
+	}
+	return s.reload()
+}
+
+// reload reads the catalog from disk. Changes to the catalog reload it unconditionally while holding the
+// catalog lock, so they always start from the catalog other processes last saved.
+func (s *Store) reload() error {
+	file, err := os.Open(s.catalogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read graph store catalog: %w", err)
+	}
+	defer file.Close()
+
+	// Stat the open file, so the recorded identity is that of the catalog that was read.
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read graph store catalog: %w", err)
+	}
+	var c catalog
+	if err := json.NewDecoder(file).Decode(&c); err != nil {
+		return fmt.Errorf("failed to parse graph store catalog: %w", err)
+	}
+	s.catalog = c
+	s.catalogStat = stat
+	s.union = nil
+
+	// Drop the parsed versions another process deleted.
+	current := map[string]bool{}
+	for _, info := range c.Graphs {
+		current[cacheKey(info)] = true
+	}
+	for key := range s.graphs {
+		if !current[key] {
+			delete(s.graphs, key)
+		}
+	}
+	return nil
+}
+
+func (s *Store) saveCatalog() error {
+	data, err := json.MarshalIndent(s.catalog, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode graph store catalog: %w", err)
+	}
+	path := s.catalogPath()
+	if err := writeAtomically(path, data); err != nil {
+		return fmt.Errorf("failed to write graph store catalog: %w", err)
+	}
+	if stat, err := os.Stat(path); err == nil {
+		s.catalogStat = stat
+	} else {
+		s.catalogStat = nil
+	}
+	return nil
+}
+
+func (s *Store) catalogPath() string {
+	return filepath.Join(s.dir, "catalog.json")
+}
+
+// writeAtomically writes data to a temporary file and renames it over path, so readers never see a
+// partially written file.
+func writeAtomically(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// graphDirName derives a file name from a graph name, which may contain characters that are not valid in
+// file names.
+func graphDirName(name rdf.IRI) string {
+	sum := sha1.Sum([]byte(name))
+	return hex.EncodeToString(sum[:8])
+}
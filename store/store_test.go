@@ -0,0 +1,197 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/GraphMind/rdf"
+)
+
+func graphOf(n int) *rdf.Graph {
+	g := rdf.NewGraph()
+	for i := 0; i < n; i++ {
+		g.AddTriple(rdf.IRI(fmt.Sprintf("http://example.com/s%d", i)), "http://example.com/p", rdf.NewLiteral("o"))
+	}
+	return g
+}
+
+func TestConcurrentPutsFromSeveralStores(t *testing.T) {
+	dir := t.TempDir()
+	const stores, puts = 4, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, stores*puts)
+	for i := 0; i < stores; i++ {
+		// Separate Store values share only the directory, like separate processes.
+		s, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int, s *Store) {
+			defer wg.Done()
+			for j := 0; j < puts; j++ {
+				name := rdf.IRI(fmt.Sprintf("http://example.com/graph%d", j%2))
+				if _, err := s.Put(name, graphOf(1), GraphInfo{Build: fmt.Sprintf("build-%d-%d", i, j)}); err != nil {
+					errs <- err
+				}
+			}
+		}(i, s)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builds, err := s.Builds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(builds) != stores*puts {
+		t.Fatalf("catalog lists %d builds, want %d", len(builds), stores*puts)
+	}
+	for _, name := range []rdf.IRI{"http://example.com/graph0", "http://example.com/graph1"} {
+		versions, err := s.Versions(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, info := range versions {
+			if info.Version != i+1 {
+				t.Fatalf("%s: version %d at position %d, want versions numbered 1 to %d", name, info.Version, i, len(versions))
+			}
+		}
+	}
+}
+
+func TestStoreSeesChangesByAnotherStore(t *testing.T) {
+	dir := t.TempDir()
+	reader, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := rdf.IRI("http://example.com/repo")
+	if _, err := writer.Put(name, graphOf(2), GraphInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := reader.Graph(name); err != nil || g.Len() != 2 {
+		t.Fatalf("Graph after the first Put = %v, %v, want 2 triples", g, err)
+	}
+	// A second version is saved within the same modification time granularity as the first.
+	if _, err := writer.Put(name, graphOf(3), GraphInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := reader.Graph(name); err != nil || g.Len() != 3 {
+		t.Fatalf("Graph after the second Put = %v, %v, want 3 triples", g, err)
+	}
+
+	if err := writer.Delete(name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Graph(name); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Graph after Delete: error %v, want ErrNotFound", err)
+	}
+
+	// Writing the graph again reuses the file of version 1, which the reader parsed before.
+	if _, err := reader.GraphVersion(name, 1); err == nil {
+		t.Fatal("GraphVersion of a deleted graph succeeded")
+	}
+	if _, err := writer.Put(name, graphOf(4), GraphInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := reader.GraphVersion(name, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Len() != 4 {
+		t.Fatalf("GraphVersion 1 after writing the graph again has %d triples, want 4", g.Len())
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	repo, other := rdf.IRI("http://example.com/repo"), rdf.IRI("http://example.com/other")
+	puts := []struct {
+		name    rdf.IRI
+		triples int
+		info    GraphInfo
+	}{
+		{repo, 1, GraphInfo{Commit: "aaaaaaa111", CommitTime: day(1), Build: "b1"}},
+		{other, 2, GraphInfo{Commit: "bbbbbbb111", CommitTime: day(2), Build: "b1"}},
+		{repo, 3, GraphInfo{Commit: "aaaaaaa222", CommitTime: day(3), Build: "b2"}},
+		// A rebuild of an old commit does not make it current.
+		{repo, 4, GraphInfo{Commit: "ccccccc111", CommitTime: day(1), Build: "b3"}},
+	}
+	for _, put := range puts {
+		if _, err := s.Put(put.name, graphOf(put.triples), put.info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versionsOf := func(infos []GraphInfo) map[rdf.IRI]int {
+		versions := map[rdf.IRI]int{}
+		for _, info := range infos {
+			versions[info.Name] = info.Version
+		}
+		return versions
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want map[rdf.IRI]int
+	}{
+		{"before everything", day(1).Add(-time.Hour), map[rdf.IRI]int{}},
+		{"first day", day(1), map[rdf.IRI]int{repo: 3}},
+		{"second day", day(2), map[rdf.IRI]int{repo: 3, other: 1}},
+		{"latest", day(4), map[rdf.IRI]int{repo: 2, other: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos, err := s.Snapshot(tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := versionsOf(infos); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("Snapshot = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	infos, err := s.CommitSnapshot("aaaaaaa1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := versionsOf(infos), map[rdf.IRI]int{repo: 1}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("CommitSnapshot = %v, want %v", got, want)
+	}
+	if _, err := s.CommitVersion(repo, "aaaaaaa"); !errors.Is(err, ErrInvalidCommit) {
+		t.Fatalf("CommitVersion of an ambiguous prefix: error %v, want ErrInvalidCommit", err)
+	}
+	if _, err := s.CommitVersion(repo, "aaaa"); !errors.Is(err, ErrInvalidCommit) {
+		t.Fatalf("CommitVersion of a short prefix: error %v, want ErrInvalidCommit", err)
+	}
+
+	union, err := s.BuildUnion("b2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if union.Len() != 3 {
+		t.Fatalf("BuildUnion(b2) has %d triples, want the 3 of the repository version it wrote", union.Len())
+	}
+}
@@ -8,13 +8,18 @@ import (
 )
 
 type BuildMultipleCodeGraphsWorkflowInput struct {
-	RepoURLs     []string // Array of repository URLs to process.
-	CommonFolder string   // Common folder to store the generated files.
+	RepoURLs     []string          // Array of repository URLs to process.
+	Branches     map[string]string // Optional branch to build by repository URL; the default branch otherwise.
+	Commits      map[string]string // Optional commit to build by repository URL; the head of the branch otherwise.
+	CommonFolder string            // Common folder to store the generated files.
+	ComposeFiles []string          // Optional docker-compose files describing how the repositories run together.
 }
 
 // BuildMultipleCodeGraphsWorkflow takes an array of repo URLs and a common folder (temp folder in this case).
-// It launches the BuildCodeGraphWorkflow as a child workflow for each repo URL and then copies all the generated
-// AstControlRdfGraph files into the common folder.
+// It launches the BuildCodeGraphWorkflow as a child workflow for each repo URL, imports any docker-compose
+// topology, links gRPC calls between the repositories, copies all the generated AstControlRdfGraph files
+// into the common folder, merges duplicate entities in the combined graph, checks it for quality problems,
+// saves the graphs of the build in the graph store and compares them with the previous build.
 func BuildMultipleCodeGraphsWorkflow(ctx workflow.Context, input BuildMultipleCodeGraphsWorkflowInput) (string, error) {
 	// Set child workflow options.
 	childWorkflowOpts := workflow.ChildWorkflowOptions{
@@ -28,6 +33,8 @@ func BuildMultipleCodeGraphsWorkflow(ctx workflow.Context, input BuildMultipleCo
 		// Prepare the initial state for each repository.
 		state := buildcodegraph.BuildCodeGraphState{
 			RepoURL: repoURL,
+			Branch:  input.Branches[repoURL],
+			Commit:  input.Commits[repoURL],
 		}
 		future := workflow.ExecuteChildWorkflow(ctx, BuildCodeGraphWorkflow, state)
 		childFutures = append(childFutures, future)
@@ -51,9 +58,46 @@ func BuildMultipleCodeGraphsWorkflow(ctx workflow.Context, input BuildMultipleCo
 
 	activities := &buildcodegraph.Activities{}
 
+	// Import the docker-compose topology into the common folder so it is merged with the code graphs.
+	err := workflow.ExecuteActivity(ctx, activities.ImportComposeTopology, results, input.ComposeFiles, input.CommonFolder).Get(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Link the gRPC calls of each repository to the services other repositories define.
+	err = workflow.ExecuteActivity(ctx, activities.LinkServiceCalls, results, input.CommonFolder).Get(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
 	// Call the CopyAstControlRdfGraphs activity with the collected results and the common (temp) folder.
 	var combinedRdfFilePath string
-	err := workflow.ExecuteActivity(ctx, activities.CopyAstControlRdfGraphs, results, input.CommonFolder).Get(ctx, &combinedRdfFilePath)
+	err = workflow.ExecuteActivity(ctx, activities.CopyAstControlRdfGraphs, results, input.CommonFolder).Get(ctx, &combinedRdfFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Merge nodes that different repositories describe under different URIs.
+	err = workflow.ExecuteActivity(ctx, activities.ResolveEntities, combinedRdfFilePath).Get(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Check the resolved graph for quality problems.
+	err = workflow.ExecuteActivity(ctx, activities.LintGraph, combinedRdfFilePath).Get(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Save the build in the graph store, one named graph per repository.
+	buildID := workflow.GetInfo(ctx).WorkflowExecution.RunID
+	err = workflow.ExecuteActivity(ctx, activities.StoreGraphs, results, input.CommonFolder, buildID).Get(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Compare the build with the previous one.
+	err = workflow.ExecuteActivity(ctx, activities.DiffBuild, input.CommonFolder, buildID).Get(ctx, nil)
 	if err != nil {
 		return "", err
 	}